@@ -0,0 +1,40 @@
+package blindsig
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+// infoDomain - префикс вывода показателя из открытой информации
+const infoDomain = "blindsig info exponent\n"
+
+// InfoExponent - открытый показатель частично слепой подписи с открытой информацией
+// info: 128-битное простое, выведенное из SHA-256. Простое число взаимно просто
+// с φ(N), если только не делит P - 1 или Q - 1.
+func InfoExponent(info []byte) *big.Int {
+	sum := sha256.Sum256(append([]byte(infoDomain), info...))
+	e := new(big.Int).SetBytes(sum[:16])
+	e.SetBit(e, 127, 1).SetBit(e, 0, 1)
+	for !e.ProbablyPrime(20) {
+		e.Add(e, big.NewInt(2))
+	}
+	return e
+}
+
+// Derive - открытый ключ частично слепой подписи: модуль тот же, показатель -
+// InfoExponent(info). Информацию видят обе стороны, сообщение подписывающий не видит;
+// подпись, полученная с одной информацией, не проходит проверку с другой.
+func (pk *PublicKey) Derive(info []byte) *PublicKey {
+	return &PublicKey{N: pk.N, D: InfoExponent(info)}
+}
+
+// Derive - закрытый ключ частично слепой подписи с информацией info:
+// C = InfoExponent(info)^-1 mod φ(N)
+func (sk *PrivateKey) Derive(info []byte) (*PrivateKey, error) {
+	key, err := newPrivateKey(sk.N, InfoExponent(info), sk.P, sk.Q)
+	if err != nil {
+		return nil, fmt.Errorf("info %q: %w", info, err)
+	}
+	return key, nil
+}
@@ -0,0 +1,257 @@
+// Package blindsig - слепые подписи: RSA с хэшированием на всю область (FDH, по образцу
+// RFC 9474), частично слепые RSA с показателем, выведенным из открытой информации,
+// и слепые подписи Шнорра. Запрашивающий ослепляет сообщение (Blind), подписывающий
+// подписывает не видя его (BlindSign, Respond), запрашивающий снимает слепоту
+// и проверяет подпись (Finalize).
+package blindsig
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"io"
+	"math/big"
+)
+
+// fdhDomain - префикс хэширования сообщения на всю область
+const fdhDomain = "blindsig fdh\n"
+
+// PublicKey - открытый ключ RSA: подпись s под сообщением m верна, если 0 < s < N
+// и s^D = FDH(N, m) mod N
+type PublicKey struct {
+	N, D *big.Int
+}
+
+// PrivateKey - закрытый показатель C = D^-1 mod φ(N) и множители N = P * Q.
+// Создаётся GenerateKey, NewPrivateKey или Derive.
+type PrivateKey struct {
+	PublicKey
+	C, P, Q *big.Int
+	mc      *common.ModContext // Арифметика по модулю N
+}
+
+// BlindingState - секрет запрашивающего между Blind и Finalize
+type BlindingState struct {
+	message []byte
+	rInv    *big.Int
+}
+
+// GenerateKey - ключ с модулем из bits бит и случайным открытым показателем,
+// взаимно простым с φ(N)
+func GenerateKey(random io.Reader, bits int) (*PrivateKey, error) {
+	if bits < 64 || bits%2 != 0 {
+		return nil, &common.ParameterError{Name: "bits", Reason: "modulus size must be even and at least 64 bits"}
+	}
+	for {
+		p, err := rand.Prime(random, bits/2)
+		if err != nil {
+			return nil, err
+		}
+		q, err := rand.Prime(random, bits/2)
+		if err != nil {
+			return nil, err
+		}
+		n := new(big.Int).Mul(p, q)
+		if p.Cmp(q) == 0 || n.BitLen() != bits {
+			continue
+		}
+		phi := totient(p, q)
+		d, err := common.GenCoprimeBig(phi, big.NewInt(3), phi)
+		if err != nil {
+			return nil, err
+		}
+		return newPrivateKey(n, d, p, q)
+	}
+}
+
+// NewPrivateKey - ключ по (N, D, C); множители восстанавливаются из D * C ≡ 1 (mod φ(N))
+func NewPrivateKey(n, d, c *big.Int) (*PrivateKey, error) {
+	if n == nil || d == nil || c == nil || n.Sign() <= 0 || d.Sign() <= 0 || c.Sign() <= 0 {
+		return nil, &common.ParameterError{Name: "rsa key", Reason: "missing parameter"}
+	}
+	p, q, err := factorModulus(n, d, c)
+	if err != nil {
+		return nil, err
+	}
+	return newPrivateKey(n, d, p, q)
+}
+
+// newPrivateKey - ключ по множителям: C = D^-1 mod φ(N)
+func newPrivateKey(n, d, p, q *big.Int) (*PrivateKey, error) {
+	c, err := common.ModInverseConstantTime(d, totient(p, q))
+	if err != nil {
+		return nil, fmt.Errorf("rsa key: %w", err)
+	}
+	mc, err := common.NewModContext(n)
+	if err != nil {
+		return nil, err
+	}
+	if common.Tracing() {
+		common.Trace("blindsig", "rsa keygen", common.Val("N", n), common.Val("D", d))
+	}
+	return &PrivateKey{PublicKey: PublicKey{N: n, D: d}, C: c, P: p, Q: q, mc: mc}, nil
+}
+
+// totient - φ(N) = (P - 1) * (Q - 1)
+func totient(p, q *big.Int) *big.Int {
+	one := big.NewInt(1)
+	return new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+}
+
+// factorModulus - множители n по паре показателей d*c ≡ 1 (mod φ(n)). Для d*c - 1 = 2^s * t
+// и случайного a в ряду a^t, a^2t, ... с вероятностью не меньше 1/2 встречается
+// нетривиальный корень x из 1, и gcd(x - 1, n) = p.
+func factorModulus(n, d, c *big.Int) (p, q *big.Int, err error) {
+	one := big.NewInt(1)
+	minusOne := new(big.Int).Sub(n, one)
+	t := new(big.Int).Mul(d, c)
+	t.Sub(t, one)
+	s := 0
+	for t.Sign() > 0 && t.Bit(0) == 0 {
+		t.Rsh(t, 1)
+		s++
+	}
+	for attempt := 0; attempt < 64 && s > 0 && n.Cmp(big.NewInt(5)) > 0; attempt++ {
+		a, err := rand.Int(rand.Reader, new(big.Int).Sub(n, big.NewInt(3)))
+		if err != nil {
+			return nil, nil, err
+		}
+		a.Add(a, big.NewInt(2))
+		if g := common.GCDBig(a, n); g.Cmp(one) != 0 {
+			return g, new(big.Int).Quo(n, g), nil
+		}
+		x := new(big.Int).Exp(a, t, n)
+		for i := 0; i < s; i++ {
+			y := new(big.Int).Mul(x, x)
+			y.Mod(y, n)
+			if y.Cmp(one) == 0 {
+				if x.Cmp(one) != 0 && x.Cmp(minusOne) != 0 {
+					p = common.GCDBig(new(big.Int).Sub(x, one), n)
+					return p, new(big.Int).Quo(n, p), nil
+				}
+				break
+			}
+			x = y
+		}
+	}
+	return nil, nil, &common.ParameterError{Name: "rsa key", Reason: "cannot factor N with (D, C)"}
+}
+
+// FDH - хэш сообщения на всю область: MGF1 на SHA-256 с префиксом домена, обрезанный
+// до N.BitLen() - 1 бит, поэтому значение всегда меньше N
+func FDH(n *big.Int, message []byte) *big.Int {
+	bits := n.BitLen() - 1
+	size := (bits + 7) / 8
+	out := make([]byte, 0, size+sha256.Size)
+	var counter [4]byte
+	for i := uint32(0); len(out) < size; i++ {
+		binary.BigEndian.PutUint32(counter[:], i)
+		h := sha256.New()
+		h.Write([]byte(fdhDomain))
+		h.Write(counter[:])
+		h.Write(message)
+		out = h.Sum(out)
+	}
+	x := new(big.Int).SetBytes(out[:size])
+	return x.Rsh(x, uint(8*size-bits))
+}
+
+// validate - модуль и показатель заданы и N нечётен
+func (pk *PublicKey) validate() error {
+	if pk == nil || pk.N == nil || pk.D == nil || pk.N.Cmp(big.NewInt(3)) < 0 || pk.N.Bit(0) == 0 || pk.D.Sign() <= 0 {
+		return &common.ParameterError{Name: "rsa key", Reason: "N must be odd and D positive"}
+	}
+	return nil
+}
+
+// Blind - слепое сообщение FDH(N, m) * r^D mod N для случайного r, взаимно простого с N
+func (pk *PublicKey) Blind(random io.Reader, message []byte) (*big.Int, *BlindingState, error) {
+	if err := pk.validate(); err != nil {
+		return nil, nil, err
+	}
+	var r, rInv *big.Int
+	for rInv == nil {
+		var err error
+		if r, err = randomBelow(random, pk.N); err != nil {
+			return nil, nil, err
+		}
+		if r.Sign() > 0 {
+			rInv = new(big.Int).ModInverse(r, pk.N)
+		}
+	}
+	h := FDH(pk.N, message)
+	blinded := new(big.Int).Exp(r, pk.D, pk.N)
+	blinded.Mul(blinded, h).Mod(blinded, pk.N)
+	if common.Tracing() {
+		// r не трассируется: по нему слепое сообщение связывается с подписью
+		common.Trace("blindsig", "rsa blind", common.Val("h", h), common.Val("blinded", blinded))
+	}
+	return blinded, &BlindingState{message: append([]byte(nil), message...), rInv: rInv}, nil
+}
+
+// BlindSign - подпись слепого сообщения blinded^C mod N. Результат проверяется открытым
+// ключом: ошибка при вычислении подписи могла бы раскрыть множители N.
+func (sk *PrivateKey) BlindSign(blinded *big.Int) (*big.Int, error) {
+	if blinded == nil || blinded.Sign() <= 0 || blinded.Cmp(sk.N) >= 0 {
+		return nil, &common.ParameterError{Name: "blinded", Reason: "blinded message is not in [1, N)"}
+	}
+	s, err := sk.mc.ExpConstantTime(blinded, sk.C, sk.N.BitLen())
+	if err != nil {
+		return nil, err
+	}
+	if sk.mc.Exp(s, sk.D).Cmp(blinded) != 0 {
+		return nil, fmt.Errorf("blind signature self-check: %w", common.ErrVerification)
+	}
+	if common.Tracing() {
+		common.Trace("blindsig", "rsa blind sign", common.Val("blinded", blinded), common.Val("signature", s))
+	}
+	return s, nil
+}
+
+// Finalize - снятие слепоты s = s' * r^-1 mod N и проверка подписи под сообщением
+func (pk *PublicKey) Finalize(state *BlindingState, blindSignature *big.Int) (*big.Int, error) {
+	if state == nil || blindSignature == nil {
+		return nil, &common.ParameterError{Name: "blind signature", Reason: "missing"}
+	}
+	s := new(big.Int).Mul(blindSignature, state.rInv)
+	s.Mod(s, pk.N)
+	if err := pk.Verify(state.message, s); err != nil {
+		return nil, err
+	}
+	if common.Tracing() {
+		common.Trace("blindsig", "rsa unblind", common.Val("signature", s))
+	}
+	return s, nil
+}
+
+// Verify - 0 < s < N и s^D = FDH(N, m) mod N; представитель подписи единственный
+func (pk *PublicKey) Verify(message []byte, signature *big.Int) error {
+	if err := pk.validate(); err != nil {
+		return err
+	}
+	if signature == nil || signature.Sign() <= 0 || signature.Cmp(pk.N) >= 0 {
+		return fmt.Errorf("signature is not in [1, N): %w", common.ErrVerification)
+	}
+	if new(big.Int).Exp(signature, pk.D, pk.N).Cmp(FDH(pk.N, message)) != 0 {
+		return fmt.Errorf("rsa signature: %w", common.ErrVerification)
+	}
+	return nil
+}
+
+// randomBelow - равномерное число из [0, n): случайные байты с отбрасыванием
+// лишних старших бит и значений, не меньших n
+func randomBelow(random io.Reader, n *big.Int) (*big.Int, error) {
+	bits := n.BitLen()
+	buf := make([]byte, (bits+7)/8)
+	for {
+		if _, err := io.ReadFull(random, buf); err != nil {
+			return nil, err
+		}
+		buf[0] &= byte(0xff >> (8*len(buf) - bits))
+		if x := new(big.Int).SetBytes(buf); x.Cmp(n) < 0 {
+			return x, nil
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package blindsig
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+	"testing"
+)
+
+// testReader - детерминированный поток SHA-256(seed || счётчик) для тестовых векторов
+type testReader struct {
+	seed    string
+	counter uint32
+	buf     []byte
+}
+
+func (r *testReader) Read(p []byte) (int, error) {
+	for len(r.buf) < len(p) {
+		var c [4]byte
+		binary.BigEndian.PutUint32(c[:], r.counter)
+		r.counter++
+		h := sha256.Sum256(append([]byte(r.seed), c[:]...))
+		r.buf = append(r.buf, h[:]...)
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// hexInt - число из шестнадцатеричной записи теста
+func hexInt(t *testing.T, s string) *big.Int {
+	t.Helper()
+	x, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		t.Fatalf("bad hex %q", s)
+	}
+	return x
+}
+
+// vectorKey - 1024-битный ключ тестовых векторов с D = 65537
+func vectorKey(t *testing.T) *PrivateKey {
+	t.Helper()
+	p := hexInt(t, "e59a9ae4392b256b4c4aaef38562e33418f9a78cfe8bac2ca107edc85de6cc4f07a2f86efb781a22a2bf021dfa56d6874652ddac13374b77b821579748636b57")
+	q := hexInt(t, "e019b49d6cdee50dd7a7d1effdfc83041fe83a90f03760c1ac002d15768ca0d6a39982bd27dce41f4faee0f3314601f732e955f5862da313047c8bd130d1dadd")
+	key, err := newPrivateKey(new(big.Int).Mul(p, q), big.NewInt(65537), p, q)
+	if err != nil {
+		t.Fatalf("newPrivateKey() error = %v", err)
+	}
+	return key
+}
+
+// TestRSAVectors - известные ответы для ключа vectorKey; случайность ослепления
+// берётся из testReader{seed: "rsa " + message}
+func TestRSAVectors(t *testing.T) {
+	key := vectorKey(t)
+	tests := []struct {
+		message   string
+		fdh       string
+		blinded   string
+		signature string
+	}{
+		{
+			message:   "",
+			fdh:       "5333b973af58e34a1e9f025078204480054987ff55029252e157f55fe9e3bad7ea34a808e3f19948cf10f45baedd646e62c700464d562590442f4b2c8c47d97b1e142ce84f768345a1c3d18cf8525610c1178d7c3150b659ace92e1f41b1ad51030fd7efa5b2d0dc4061e8bbbc23cc68e1d211f3260b45ad34e2f355ca663d07",
+			blinded:   "389b8cecdea7bd7773ee50fad006795840307afae80d72a29234a016bd408f902485279f8395e9428660d6c26d76121bc7f179d7a96df14ed55d3c5aa1a29d2569fc83ca6be74ceb3081c2d883d0133e0db763ead0466951eab66be7cfc63884c9d0abfb52aa1685fc08b0dd47f21498ea65ea08628e76ba21febb1fda3c76e6",
+			signature: "356560ca2c9d92ffcdf864205e93ffd6bf1223fe8e8f2021bb0762dab96712a9aa664b9e05bf10033cf71a75621531906ca3c6a7651e5df774ed4bae39be42b23a0b2ec0e6138a2ce254491d44c475b660f72bdc859ed74e0b9cd910232d687e89837ef72764e533caa0504d38e30617d93be551976824ed230d5fe07795e149",
+		},
+		{
+			message:   "yes",
+			fdh:       "68b0d5dd84940a9e8438ec9a8d30fdab330730a366f8a198becafc776954dff585905c3240fbc9acc09b983e0528be72ed8d1724d928ed9f827ef1c038140f1eb4cbc4599c05c34178b64b90adf196a20d5d3ba03d9de9e583eb3c13a4d187d9c428595c91d94f8c7a9a7311dcd8dea33f8c78d54dfd758b86512af8f0eed1a2",
+			blinded:   "a8843236ad1a3ccb656e0e5e5a28f4737a0f88c6c65d74cddc769eb6d4456b521d1050dcb0403bb77fad58a94a80e7d7ae233b2fa91a131ab49b79052c0ba6973ad760690136ccd42f02bf2e6cf00bc631b6d0eb70962d9f64e7d3b59344154b56348c468a3dc70c6fe3ae6c7f15c7731b12d361c44bed1b551f6fd3667a99b4",
+			signature: "759bf29ec46ccf137520c0af05949b0f65af7e768d4726a965f43f847e34c99f4c61fb6084d413430488148e873cce7f0fe9432c84ba679179ecdbee2196dd85ef06f914aeea313138fb5e268548fd4eaba17a0adbe6ebd2a1671f8ac4296265343d2f10a2c4f5fc0e999f39341b788dc6e0b4fdb54bbbff712758f0d9207200",
+		},
+		{
+			message:   "lab5 ballot",
+			fdh:       "56964fc6b01c2f7c7d522df0e49efb3dbb22cf72257d2d7bc930ef081c6d868c29940bf6e5856de6df950e4fbc1850a5f3e867266efcd59c41291d231ecfcf34410ae4082a015b8a2636c0e02f1ddcc80dcd4762414b29926a1daf897b6676bf04175b0da041d86b1b681e06d63c8b03c802b48de595245e153e9563ba72083",
+			blinded:   "7a498968773b7eacb644ec450965a484ddb0e9de5bf1d76e41e4282021205a26c09e7cd3ffe6debae3cc09b967dac5240d3a6a66dc5ae4378c3b89757e77cb33834971124b1fb72a9cf83c4cb27880c03892e7f1741c7cb664cb25d571209335c84421adc2d11108e030e4d069a448b219f9288965ea957625095df4db46866f",
+			signature: "ad14603c541946e29306b9daad340eb4c36dcc7c326fde394a1a35c8c76d5c0f4bccaf1c11df24851887c086ff9912ee674880d76f6c6fb9970515a92932a0e60a89ffdd1fc693118bb59a516cae3f8ae52a250ae7e24a002d704ab9885a36e232302abcacce0c1437279ec3b19272ee9f2f82bc40e850dd53bb261a594dc7e9",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			message := []byte(tt.message)
+			if got := FDH(key.N, message); got.Cmp(hexInt(t, tt.fdh)) != 0 {
+				t.Errorf("FDH() = %x, want %s", got, tt.fdh)
+			}
+			blinded, state, err := key.Blind(&testReader{seed: "rsa " + tt.message}, message)
+			if err != nil {
+				t.Fatalf("Blind() error = %v", err)
+			}
+			if blinded.Cmp(hexInt(t, tt.blinded)) != 0 {
+				t.Errorf("Blind() = %x, want %s", blinded, tt.blinded)
+			}
+			blindSignature, err := key.BlindSign(blinded)
+			if err != nil {
+				t.Fatalf("BlindSign() error = %v", err)
+			}
+			signature, err := key.Finalize(state, blindSignature)
+			if err != nil {
+				t.Fatalf("Finalize() error = %v", err)
+			}
+			want := hexInt(t, tt.signature)
+			if signature.Cmp(want) != 0 {
+				t.Errorf("Finalize() = %x, want %s", signature, tt.signature)
+			}
+			// Подпись совпадает с обычной FDH-подписью FDH(m)^C mod N
+			if direct := new(big.Int).Exp(FDH(key.N, message), key.C, key.N); direct.Cmp(want) != 0 {
+				t.Errorf("FDH(m)^C = %x, want %s", direct, tt.signature)
+			}
+		})
+	}
+}
+
+func TestBlindRSA(t *testing.T) {
+	key := vectorKey(t)
+	message := []byte("yes")
+	blinded, state, err := key.Blind(rand.Reader, message)
+	if err != nil {
+		t.Fatalf("Blind() error = %v", err)
+	}
+	blindSignature, err := key.BlindSign(blinded)
+	if err != nil {
+		t.Fatalf("BlindSign() error = %v", err)
+	}
+	signature, err := key.Finalize(state, blindSignature)
+	if err != nil {
+		t.Fatalf("Finalize() error = %v", err)
+	}
+	// Подписывающий видит только ослеплённые значения: второе ослепление того же
+	// сообщения даёт другое blinded, но ту же подпись
+	blinded2, state2, err := key.Blind(rand.Reader, message)
+	if err != nil {
+		t.Fatalf("Blind() error = %v", err)
+	}
+	if blinded2.Cmp(blinded) == 0 {
+		t.Error("two blindings of the same message are equal")
+	}
+	blindSignature2, _ := key.BlindSign(blinded2)
+	if signature2, err := key.Finalize(state2, blindSignature2); err != nil || signature2.Cmp(signature) != 0 {
+		t.Errorf("second Finalize() = %x, %v, want %x", signature2, err, signature)
+	}
+
+	tests := []struct {
+		name      string
+		message   []byte
+		signature *big.Int
+	}{
+		{name: "другое сообщение", message: []byte("no"), signature: signature},
+		{name: "подпись s + N", message: message, signature: new(big.Int).Add(signature, key.N)},
+		{name: "подпись N - s", message: message, signature: new(big.Int).Sub(key.N, signature)},
+		{name: "нулевая подпись", message: message, signature: big.NewInt(0)},
+		{name: "нет подписи", message: message},
+		{name: "неснятая слепота", message: message, signature: blindSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := key.Verify(tt.message, tt.signature); !errors.Is(err, common.ErrVerification) {
+				t.Errorf("Verify() error = %v, want %v", err, common.ErrVerification)
+			}
+		})
+	}
+
+	if _, err = key.Finalize(state, new(big.Int).Add(blindSignature, big.NewInt(1))); !errors.Is(err, common.ErrVerification) {
+		t.Errorf("Finalize() with a wrong blind signature error = %v, want %v", err, common.ErrVerification)
+	}
+	for _, blinded := range []*big.Int{nil, big.NewInt(0), key.N} {
+		if _, err = key.BlindSign(blinded); !errors.Is(err, common.ErrInvalidParameters) {
+			t.Errorf("BlindSign(%v) error = %v, want %v", blinded, err, common.ErrInvalidParameters)
+		}
+	}
+}
+
+func TestNewPrivateKey(t *testing.T) {
+	key := vectorKey(t)
+	restored, err := NewPrivateKey(key.N, key.D, key.C)
+	if err != nil {
+		t.Fatalf("NewPrivateKey() error = %v", err)
+	}
+	if new(big.Int).Mul(restored.P, restored.Q).Cmp(key.N) != 0 || restored.C.Cmp(key.C) != 0 {
+		t.Errorf("NewPrivateKey() = {P: %x, Q: %x}, want factors of N", restored.P, restored.Q)
+	}
+	// C, не обратный к D, не позволяет разложить модуль
+	if _, err = NewPrivateKey(key.N, key.D, new(big.Int).Add(key.C, big.NewInt(2))); !errors.Is(err, common.ErrInvalidParameters) {
+		t.Errorf("NewPrivateKey() with a wrong C error = %v, want %v", err, common.ErrInvalidParameters)
+	}
+
+	generated, err := GenerateKey(rand.Reader, 512)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	if generated.N.BitLen() != 512 {
+		t.Errorf("GenerateKey(512) modulus has %d bits", generated.N.BitLen())
+	}
+	if _, err = GenerateKey(rand.Reader, 63); !errors.Is(err, common.ErrInvalidParameters) {
+		t.Errorf("GenerateKey(63) error = %v, want %v", err, common.ErrInvalidParameters)
+	}
+}
+
+func TestPartiallyBlind(t *testing.T) {
+	key := vectorKey(t)
+	info := []byte("council")
+	if e := InfoExponent(info); e.BitLen() != 128 || !e.ProbablyPrime(20) || e.Cmp(hexInt(t, "cb68eb1927f49eab3d9acf933a3393fd")) != 0 {
+		t.Errorf("InfoExponent() = %x, want cb68eb1927f49eab3d9acf933a3393fd", e)
+	}
+	public := key.PublicKey.Derive(info)
+	private, err := key.Derive(info)
+	if err != nil {
+		t.Fatalf("Derive() error = %v", err)
+	}
+	if private.D.Cmp(public.D) != 0 {
+		t.Fatalf("Derive() exponents differ: %x and %x", private.D, public.D)
+	}
+	blinded, state, err := public.Blind(&testReader{seed: "partial"}, []byte("yes"))
+	if err != nil {
+		t.Fatalf("Blind() error = %v", err)
+	}
+	blindSignature, err := private.BlindSign(blinded)
+	if err != nil {
+		t.Fatalf("BlindSign() error = %v", err)
+	}
+	signature, err := public.Finalize(state, blindSignature)
+	if err != nil {
+		t.Fatalf("Finalize() error = %v", err)
+	}
+	const want = "18555a21fb2c4842657ee38fdea54b545bbff692ee014c06fadc4c9f4e144b84290fe460af7bda7519b6f30f650ea2ce53cd510a7b151761af0b229bdebfd28b6ff188d052cf32bf085aaa84dad4f2f42ab27c8bf41c5bfc02c2cedcf584d0034307bed4001ed4b543e31f400f27f76ee54ed3d877523f4d885c00cde87c6fc9"
+	if signature.Cmp(hexInt(t, want)) != 0 {
+		t.Errorf("Finalize() = %x, want %s", signature, want)
+	}
+
+	// Подпись с одной открытой информацией не годится для другой и для основного ключа
+	tests := []struct {
+		name string
+		key  *PublicKey
+	}{
+		{name: "другая информация", key: key.PublicKey.Derive([]byte("trip"))},
+		{name: "основной ключ", key: &key.PublicKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.key.Verify([]byte("yes"), signature); !errors.Is(err, common.ErrVerification) {
+				t.Errorf("Verify() error = %v, want %v", err, common.ErrVerification)
+			}
+		})
+	}
+}
+
+// TestBlindTrace - в трассировке ослепления нет множителя r и его обратного
+func TestBlindTrace(t *testing.T) {
+	sk := vectorKey(t)
+	var buf bytes.Buffer
+	prev := common.SetTracer(common.NewJSONTracer(&buf))
+	defer common.SetTracer(prev)
+	blinded, state, err := sk.Blind(rand.Reader, []byte("бюллетень"))
+	if err != nil {
+		t.Fatalf("Blind() error = %v", err)
+	}
+	s, err := sk.BlindSign(blinded)
+	if err != nil {
+		t.Fatalf("BlindSign() error = %v", err)
+	}
+	if _, err := sk.Finalize(state, s); err != nil {
+		t.Fatalf("Finalize() error = %v", err)
+	}
+	scanner := bufio.NewScanner(&buf)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var rec common.TraceRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		for _, v := range rec.Values {
+			if v.Label == "r" || v.Label == "r^-1" || v.Value == state.rInv.String() {
+				t.Errorf("%s step traces blinding factor %s", rec.Step, v.Label)
+			}
+		}
+	}
+}
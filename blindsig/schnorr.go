@@ -0,0 +1,211 @@
+package blindsig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"io"
+	"math/big"
+	"sync"
+)
+
+// ErrSessionUsed - сеанс подписывающего уже выдал ответ
+var ErrSessionUsed = errors.New("blindsig: signing session already used")
+
+// SchnorrPublicKey - подгруппа порядка Q по модулю P с образующей G и ключ Y = G^X
+type SchnorrPublicKey struct {
+	P, Q, G, Y *big.Int
+}
+
+// SchnorrPrivateKey - секретный показатель X из [1, Q)
+type SchnorrPrivateKey struct {
+	SchnorrPublicKey
+	X *big.Int
+}
+
+// SchnorrSignature - подпись (E, S): E = H(G^S * Y^-E, m)
+type SchnorrSignature struct {
+	E, S *big.Int
+}
+
+// SchnorrSession - один сеанс подписывающего: обязательство R = G^k и один ответ.
+// Параллельные сеансы с одним ключом уязвимы к атаке ROS, поэтому подписывающему
+// следует завершать сеанс до начала следующего.
+type SchnorrSession struct {
+	key  *SchnorrPrivateKey
+	k, R *big.Int
+	mu   sync.Mutex
+}
+
+// SchnorrRequest - секрет запрашивающего между Blind и Finalize
+type SchnorrRequest struct {
+	key   *SchnorrPublicKey
+	r, e  *big.Int // R' и E' итоговой подписи
+	alpha *big.Int // сдвиг ответа подписывающего
+}
+
+// GenerateSchnorrKey - ключ для группы (p, q, g)
+func GenerateSchnorrKey(random io.Reader, p, q, g *big.Int) (*SchnorrPrivateKey, error) {
+	pk := &SchnorrPublicKey{P: p, Q: q, G: g, Y: big.NewInt(1)}
+	if err := pk.validateGroup(); err != nil {
+		return nil, err
+	}
+	x, err := randomScalar(random, q)
+	if err != nil {
+		return nil, err
+	}
+	pk.Y = new(big.Int).Exp(g, x, p)
+	return &SchnorrPrivateKey{SchnorrPublicKey: *pk, X: x}, nil
+}
+
+// validateGroup - P нечётно, Q < P, G > 1 и G^Q = 1 mod P
+func (pk *SchnorrPublicKey) validateGroup() error {
+	if pk == nil || pk.P == nil || pk.Q == nil || pk.G == nil {
+		return &common.ParameterError{Name: "schnorr key", Reason: "missing parameter"}
+	}
+	if pk.P.Bit(0) == 0 || pk.Q.Cmp(big.NewInt(2)) < 0 || pk.Q.Cmp(pk.P) >= 0 {
+		return &common.ParameterError{Name: "schnorr key", Reason: "P must be odd and Q must lie in [2, P)"}
+	}
+	if pk.G.Cmp(big.NewInt(1)) <= 0 || pk.G.Cmp(pk.P) >= 0 || new(big.Int).Exp(pk.G, pk.Q, pk.P).Cmp(big.NewInt(1)) != 0 {
+		return &common.ParameterError{Name: "schnorr key", Reason: "G is not of order Q modulo P"}
+	}
+	return nil
+}
+
+// Validate - проверка группы и того, что Y лежит в подгруппе порядка Q
+func (pk *SchnorrPublicKey) Validate() error {
+	if err := pk.validateGroup(); err != nil {
+		return err
+	}
+	if !pk.inGroup(pk.Y) {
+		return &common.ParameterError{Name: "schnorr key", Reason: "Y is not in the subgroup of order Q"}
+	}
+	return nil
+}
+
+// inGroup - 1 < x < P и x^Q = 1 mod P
+func (pk *SchnorrPublicKey) inGroup(x *big.Int) bool {
+	return x != nil && x.Cmp(big.NewInt(1)) > 0 && x.Cmp(pk.P) < 0 && new(big.Int).Exp(x, pk.Q, pk.P).Cmp(big.NewInt(1)) == 0
+}
+
+// challenge - E = SHA-256(P, Q, G, Y, R, m) mod Q
+func (pk *SchnorrPublicKey) challenge(r *big.Int, message []byte) *big.Int {
+	var buf bytes.Buffer
+	_ = common.WriteBigNumbers(&buf, []*big.Int{pk.P, pk.Q, pk.G, pk.Y, r}) // запись в bytes.Buffer не завершается ошибкой
+	buf.Write(message)
+	h := sha256.Sum256(buf.Bytes())
+	e := new(big.Int).SetBytes(h[:])
+	return e.Mod(e, pk.Q)
+}
+
+// NewSession - сеанс подписи со случайным k; Commitment отправляется запрашивающему
+func (sk *SchnorrPrivateKey) NewSession(random io.Reader) (*SchnorrSession, error) {
+	k, err := randomScalar(random, sk.Q)
+	if err != nil {
+		return nil, err
+	}
+	return &SchnorrSession{key: sk, k: k, R: new(big.Int).Exp(sk.G, k, sk.P)}, nil
+}
+
+// Commitment - обязательство R = G^k
+func (s *SchnorrSession) Commitment() *big.Int {
+	return new(big.Int).Set(s.R)
+}
+
+// Respond - ответ S = k + E * X mod Q на слепой вызов E. Второй ответ того же сеанса
+// раскрыл бы X, поэтому k стирается после первого.
+func (s *SchnorrSession) Respond(e *big.Int) (*big.Int, error) {
+	if e == nil || e.Sign() < 0 || e.Cmp(s.key.Q) >= 0 {
+		return nil, &common.ParameterError{Name: "challenge", Reason: "challenge is not in [0, Q)"}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.k == nil {
+		return nil, ErrSessionUsed
+	}
+	resp := new(big.Int).Mul(e, s.key.X)
+	resp.Add(resp, s.k).Mod(resp, s.key.Q)
+	s.k = nil
+	if common.Tracing() {
+		common.Trace("blindsig", "schnorr blind sign", common.Val("e", e), common.Val("s", resp))
+	}
+	return resp, nil
+}
+
+// Blind - ослепление обязательства: R' = R * G^α * Y^β, E' = H(R', m). Подписывающему
+// отправляется вызов E = E' + β mod Q; он не связан ни с R', ни с сообщением.
+func (pk *SchnorrPublicKey) Blind(random io.Reader, commitment *big.Int, message []byte) (*SchnorrRequest, *big.Int, error) {
+	if err := pk.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if !pk.inGroup(commitment) {
+		return nil, nil, fmt.Errorf("commitment is not in the subgroup: %w", common.ErrVerification)
+	}
+	alpha, err := randomBelow(random, pk.Q)
+	if err != nil {
+		return nil, nil, err
+	}
+	beta, err := randomBelow(random, pk.Q)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := new(big.Int).Exp(pk.G, alpha, pk.P)
+	r.Mul(r, new(big.Int).Exp(pk.Y, beta, pk.P)).Mul(r, commitment).Mod(r, pk.P)
+	e := pk.challenge(r, message)
+	eSn := new(big.Int).Add(e, beta)
+	eSn.Mod(eSn, pk.Q)
+	if common.Tracing() {
+		common.Trace("blindsig", "schnorr blind", common.Val("R'", r), common.Val("e'", e), common.Val("e", eSn))
+	}
+	return &SchnorrRequest{key: pk, r: r, e: e, alpha: alpha}, eSn, nil
+}
+
+// Finalize - подпись (E', S + α); неверный ответ подписывающего не проходит проверку
+func (req *SchnorrRequest) Finalize(s *big.Int) (*SchnorrSignature, error) {
+	if s == nil || s.Sign() < 0 || s.Cmp(req.key.Q) >= 0 {
+		return nil, fmt.Errorf("response is not in [0, Q): %w", common.ErrVerification)
+	}
+	sig := &SchnorrSignature{E: new(big.Int).Set(req.e), S: new(big.Int).Add(s, req.alpha)}
+	sig.S.Mod(sig.S, req.key.Q)
+	// G^S' * Y^-E' = R', иначе ответ не соответствует обязательству и вызову
+	if req.key.commitment(sig).Cmp(req.r) != 0 {
+		return nil, fmt.Errorf("schnorr response: %w", common.ErrVerification)
+	}
+	return sig, nil
+}
+
+// commitment - R = G^S * Y^-E mod P
+func (pk *SchnorrPublicKey) commitment(sig *SchnorrSignature) *big.Int {
+	r := new(big.Int).Exp(pk.G, sig.S, pk.P)
+	negE := new(big.Int).Sub(pk.Q, sig.E)
+	return r.Mul(r, new(big.Int).Exp(pk.Y, negE, pk.P)).Mod(r, pk.P)
+}
+
+// Verify - E, S из [0, Q) и E = H(G^S * Y^-E, m)
+func (pk *SchnorrPublicKey) Verify(message []byte, sig *SchnorrSignature) error {
+	if err := pk.Validate(); err != nil {
+		return err
+	}
+	if sig == nil || sig.E == nil || sig.S == nil || sig.E.Sign() < 0 || sig.E.Cmp(pk.Q) >= 0 || sig.S.Sign() < 0 || sig.S.Cmp(pk.Q) >= 0 {
+		return fmt.Errorf("schnorr signature is malformed: %w", common.ErrVerification)
+	}
+	if pk.challenge(pk.commitment(sig), message).Cmp(sig.E) != 0 {
+		return fmt.Errorf("schnorr signature: %w", common.ErrVerification)
+	}
+	return nil
+}
+
+// randomScalar - равномерное число из [1, q)
+func randomScalar(random io.Reader, q *big.Int) (*big.Int, error) {
+	for {
+		x, err := randomBelow(random, q)
+		if err != nil {
+			return nil, err
+		}
+		if x.Sign() > 0 {
+			return x, nil
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package blindsig
+
+import (
+	"crypto/rand"
+	"errors"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+	"testing"
+)
+
+// vectorSchnorrKey - группа 512/160 бит и ключ из testReader{seed: "schnorr key"}
+func vectorSchnorrKey(t *testing.T) *SchnorrPrivateKey {
+	t.Helper()
+	key, err := GenerateSchnorrKey(&testReader{seed: "schnorr key"},
+		hexInt(t, "ca444258f87761e204dd25f5a05111d415c8a6b689c0d4cff7225693f1d5ed81bf47eb687e2d8796a8940dce4525db041b2a625a633f2644a24169c8202d8bc1"),
+		hexInt(t, "fe974b494ab492698373d1109d75f4cec3fb11ed"),
+		hexInt(t, "699a97aa6a06c167dc68e3d353c5b37100a95bedbcabaae6a333bf1cd8b31ad8a31e7beda97ead944d522af86288de5cb0f55957b83fb0b28764e181d24d4d45"))
+	if err != nil {
+		t.Fatalf("GenerateSchnorrKey() error = %v", err)
+	}
+	return key
+}
+
+// TestSchnorrVectors - известные ответы: k из testReader{seed: "schnorr k"},
+// ослепление из testReader{seed: "schnorr blind"}, сообщение "yes"
+func TestSchnorrVectors(t *testing.T) {
+	key := vectorSchnorrKey(t)
+	if want := hexInt(t, "a9ad54f7f3a4e9d63a3d520f0563cbf33a99e95c"); key.X.Cmp(want) != 0 {
+		t.Errorf("X = %x, want %x", key.X, want)
+	}
+	session, err := key.NewSession(&testReader{seed: "schnorr k"})
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	request, e, err := key.Blind(&testReader{seed: "schnorr blind"}, session.Commitment(), []byte("yes"))
+	if err != nil {
+		t.Fatalf("Blind() error = %v", err)
+	}
+	s, err := session.Respond(e)
+	if err != nil {
+		t.Fatalf("Respond() error = %v", err)
+	}
+	signature, err := request.Finalize(s)
+	if err != nil {
+		t.Fatalf("Finalize() error = %v", err)
+	}
+	for _, v := range []struct {
+		name      string
+		got, want *big.Int
+	}{
+		{"R", session.R, hexInt(t, "1de6a7c74b44034f897595d1ae4f29ecd800deafedd179d73962e5d8b324ed58f0f2e22f995ccdce16313dda22fb743317257c276fb076a343e51641d18bf811")},
+		{"e", e, hexInt(t, "2ed2bf363269d0d3a4e0d1b7f985004f217a2617")},
+		{"s", s, hexInt(t, "67c144b35d88974aa12a1e08fc02d38fec3abd1b")},
+		{"E", signature.E, hexInt(t, "ecbf674da553d943227b24c6cf90b7c07cee7fb5")},
+		{"S", signature.S, hexInt(t, "3bfef2b99da284ca7b2eb770430f08cb4fea2039")},
+	} {
+		if v.got.Cmp(v.want) != 0 {
+			t.Errorf("%s = %x, want %x", v.name, v.got, v.want)
+		}
+	}
+	if err = key.Verify([]byte("yes"), signature); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
+
+func TestBlindSchnorr(t *testing.T) {
+	key := vectorSchnorrKey(t)
+	public := &key.SchnorrPublicKey
+	session, err := key.NewSession(rand.Reader)
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	request, e, err := public.Blind(rand.Reader, session.Commitment(), []byte("yes"))
+	if err != nil {
+		t.Fatalf("Blind() error = %v", err)
+	}
+	s, err := session.Respond(e)
+	if err != nil {
+		t.Fatalf("Respond() error = %v", err)
+	}
+	signature, err := request.Finalize(s)
+	if err != nil {
+		t.Fatalf("Finalize() error = %v", err)
+	}
+	// Подписывающий видел (R, e, s), а подпись (E', S') с ними не совпадает
+	if signature.E.Cmp(e) == 0 || signature.S.Cmp(s) == 0 {
+		t.Error("signature repeats the signer's view")
+	}
+	if _, err = session.Respond(e); !errors.Is(err, ErrSessionUsed) {
+		t.Errorf("second Respond() error = %v, want %v", err, ErrSessionUsed)
+	}
+
+	tests := []struct {
+		name      string
+		message   []byte
+		signature *SchnorrSignature
+	}{
+		{name: "другое сообщение", message: []byte("no"), signature: signature},
+		{name: "изменён E", message: []byte("yes"), signature: &SchnorrSignature{E: new(big.Int).Add(signature.E, big.NewInt(1)), S: signature.S}},
+		{name: "изменён S", message: []byte("yes"), signature: &SchnorrSignature{E: signature.E, S: new(big.Int).Add(signature.S, big.NewInt(1))}},
+		{name: "S вне [0, Q)", message: []byte("yes"), signature: &SchnorrSignature{E: signature.E, S: new(big.Int).Add(signature.S, key.Q)}},
+		{name: "нет подписи", message: []byte("yes")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := public.Verify(tt.message, tt.signature); !errors.Is(err, common.ErrVerification) {
+				t.Errorf("Verify() error = %v, want %v", err, common.ErrVerification)
+			}
+		})
+	}
+
+	// Ответ на чужой вызов не проходит проверку при снятии слепоты
+	other, _ := key.NewSession(rand.Reader)
+	request, e, err = public.Blind(rand.Reader, other.Commitment(), []byte("yes"))
+	if err != nil {
+		t.Fatalf("Blind() error = %v", err)
+	}
+	s, _ = other.Respond(new(big.Int).Sub(key.Q, e))
+	if _, err = request.Finalize(s); !errors.Is(err, common.ErrVerification) {
+		t.Errorf("Finalize() with a wrong response error = %v, want %v", err, common.ErrVerification)
+	}
+	if _, _, err = public.Blind(rand.Reader, big.NewInt(1), []byte("yes")); !errors.Is(err, common.ErrVerification) {
+		t.Errorf("Blind() with commitment 1 error = %v, want %v", err, common.ErrVerification)
+	}
+	if _, err = GenerateSchnorrKey(rand.Reader, key.P, key.Q, big.NewInt(1)); !errors.Is(err, common.ErrInvalidParameters) {
+		t.Errorf("GenerateSchnorrKey() with G = 1 error = %v, want %v", err, common.ErrInvalidParameters)
+	}
+}
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/Raimguzhinov/protect-information/common"
 	"github.com/Raimguzhinov/protect-information/elgamal"
+	"github.com/Raimguzhinov/protect-information/factor"
+	"github.com/Raimguzhinov/protect-information/gm"
 	"github.com/Raimguzhinov/protect-information/gost"
+	"github.com/Raimguzhinov/protect-information/rabin"
 	"github.com/Raimguzhinov/protect-information/rsa"
 	"github.com/Raimguzhinov/protect-information/shamir"
 	"github.com/Raimguzhinov/protect-information/vernam"
@@ -17,6 +24,7 @@ import (
 	"math/big"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -96,7 +104,8 @@ func promptForPrime() (int64, error) {
 	return p, nil
 }
 
-func promptForPrimeWithRoot() (int64, int64, error) {
+// Функция для запроса простого числа p и первообразного корня g; q выбирается из [minV, maxV)
+func promptForPrimeWithRoot(minV, maxV int64) (int64, int64, error) {
 	confirm := confirmation.New("Generate a random prime number?", confirmation.Yes)
 	confirmed, err := confirm.RunPrompt()
 	if err != nil {
@@ -104,9 +113,8 @@ func promptForPrimeWithRoot() (int64, int64, error) {
 	}
 	if confirmed {
 		var P, q int64
-		minV, maxV := 1_000_000_000, 1_000_000_0000
 		for {
-			q = common.GenPrime(int64(minV), int64(maxV))
+			q = common.GenPrime(minV, maxV)
 			P = 2*q + 1
 			if common.IsPrime(P) {
 				break
@@ -170,7 +178,7 @@ func promptForFileName() (string, error) {
 
 // Функция для выбора шифра
 func promptForCipher() (string, error) {
-	selectionPrompt := selection.New[string]("Select cipher:", []string{"shamir", "vernam", "elgamal", "rsa"})
+	selectionPrompt := selection.New[string]("Select cipher:", []string{"shamir", "vernam", "elgamal", "rsa", "rabin", "goldwasser-micali"})
 	cipher, err := selectionPrompt.RunPrompt()
 	if err != nil {
 		return "", err
@@ -188,11 +196,19 @@ func promptForSignature() (string, error) {
 	return signature, nil
 }
 
-func InteractiveEncryptAndDecrypt() error {
+// InteractiveEncryptAndDecrypt - шифрование и расшифрование; в текстовом режиме закрытый
+// ключ записывается в privateKeyFile, если он задан
+func InteractiveEncryptAndDecrypt(privateKeyFile string) error {
 	var (
 		input           io.ReadCloser
-		outputEncrypted io.WriteCloser
-		outputDecrypted io.WriteCloser
+		outputEncrypted io.Writer
+		outputDecrypted io.Writer
+		keyEncrypted    = io.Discard
+		keyDecrypted    = io.Discard
+		outputEncFile   string
+		outputDecFile   string
+		encryptedText   bytes.Buffer
+		decryptedText   bytes.Buffer
 		wg              sync.WaitGroup
 		cipher          common.Cipher
 	)
@@ -210,12 +226,12 @@ func InteractiveEncryptAndDecrypt() error {
 			return err
 		}
 		input = io.NopCloser(strings.NewReader(m))
-		outputEncrypted = os.Stdout
-		outputDecrypted = os.Stdout
+		outputEncrypted = &encryptedText
+		outputDecrypted = &decryptedText
 		wg.Done()
 	case "file":
 		wg.Add(2)
-		var inputFile, outputEncFile, outputDecFile string
+		var inputFile string
 		inputFile, err = promptForFileName()
 		if err != nil {
 			return fmt.Errorf("error selecting input file: %v", err)
@@ -236,15 +252,17 @@ func InteractiveEncryptAndDecrypt() error {
 		if err != nil {
 			return fmt.Errorf("error entering encrypted file name: %v", err)
 		}
+		var encFile, decFile *os.File
 		go func() {
 			defer wg.Done()
-			outputEncrypted, err = os.OpenFile(outputEncFile, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0600)
+			encFile, err = os.OpenFile(outputEncFile, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0600)
 			if err != nil {
 				log.Fatalf("Error: %v", err)
 			}
+			outputEncrypted = encFile
 		}()
 		defer func() {
-			_ = outputEncrypted.Close()
+			_ = encFile.Close()
 		}()
 		decPrompt := textinput.New("Enter name for the decrypted output file:")
 		decPrompt.Placeholder = "Example: decrypted_output.dat"
@@ -254,13 +272,14 @@ func InteractiveEncryptAndDecrypt() error {
 		}
 		go func() {
 			defer wg.Done()
-			outputDecrypted, err = os.OpenFile(outputDecFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+			decFile, err = os.OpenFile(outputDecFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
 			if err != nil {
 				log.Fatalf("Error: %v", err)
 			}
+			outputDecrypted = decFile
 		}()
 		defer func() {
-			_ = outputDecrypted.Close()
+			_ = decFile.Close()
 		}()
 	default:
 		return fmt.Errorf("invalid action: %s", mode)
@@ -281,12 +300,30 @@ func InteractiveEncryptAndDecrypt() error {
 			return err
 		}
 	case "vernam":
-		cipher, err = vernam.NewCipher(input, outputEncrypted, outputDecrypted)
+		p, g, err := promptForPrimeWithRoot(1_000_000, 1_000_000_000)
+		if err != nil {
+			return fmt.Errorf("error selecting prime number: %v", err)
+		}
+		if mode == "file" {
+			// Зашифрованный ключ сохраняется рядом, как и раньше
+			keyEncFile, err := os.OpenFile("enkey.dat", os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0600)
+			if err != nil {
+				return err
+			}
+			defer keyEncFile.Close()
+			keyDecFile, err := os.OpenFile("deckey.dat", os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0600)
+			if err != nil {
+				return err
+			}
+			defer keyDecFile.Close()
+			keyEncrypted, keyDecrypted = keyEncFile, keyDecFile
+		}
+		cipher, err = vernam.NewCipher(p, g, input, outputEncrypted, outputDecrypted, keyEncrypted, keyDecrypted)
 		if err != nil {
 			return err
 		}
 	case "elgamal":
-		p, g, err := promptForPrimeWithRoot()
+		p, g, err := promptForPrimeWithRoot(1_000_000_000, 1_000_000_0000)
 		if err != nil {
 			return fmt.Errorf("error selecting prime number: %v", err)
 		}
@@ -299,18 +336,53 @@ func InteractiveEncryptAndDecrypt() error {
 		if err != nil {
 			return err
 		}
+	case "rabin":
+		cipher, err = rabin.NewCipher(input, outputEncrypted, outputDecrypted)
+		if err != nil {
+			return err
+		}
+	case "goldwasser-micali":
+		cipher, err = gm.NewCipher(input, outputEncrypted, outputDecrypted)
+		if err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("invalid cipher: %s", cipherName)
 	}
-	return cipher.EncryptAndDecrypt()
+	if err = cipher.EncryptAndDecrypt(); err != nil {
+		return err
+	}
+	if mode != "text" {
+		fmt.Printf("Encrypted: %s\nDecrypted: %s\n", absPath(outputEncFile), absPath(outputDecFile))
+		return nil
+	}
+	// В текстовом режиме шифртекст выводится в броне, чтобы его можно было вставить в письмо
+	err = common.EncodeArmor(os.Stdout, &common.ArmorBlock{
+		Type:    common.ArmorCiphertext,
+		Headers: map[string]string{"Cipher": cipherName},
+		Data:    encryptedText.Bytes(),
+	})
+	if err != nil {
+		return err
+	}
+	if err = printPublicKey(cipher); err != nil {
+		return err
+	}
+	if err = exportPrivateKey(cipher, privateKeyFile); err != nil {
+		return err
+	}
+	fmt.Printf("Decrypted message: %s\n", decryptedText.String())
+	return nil
 }
 
 func InteractiveSignature() error {
 	var (
-		input     io.ReadCloser
-		output    io.ReadWriteCloser
-		wg        sync.WaitGroup
-		signature common.Signer
+		input          io.ReadCloser
+		output         io.ReadWriter
+		outputSignFile string
+		signedText     bytes.Buffer
+		wg             sync.WaitGroup
+		signature      common.Signer
 	)
 	mode, err := promptForMode()
 	if err != nil {
@@ -326,14 +398,11 @@ func InteractiveSignature() error {
 			return err
 		}
 		input = io.NopCloser(strings.NewReader(m))
-		output, err = os.OpenFile("/Users/yanakosteva/Study/protect_information/signature.dat", os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0600)
-		if err != nil {
-			log.Fatalf("Error: %v", err)
-		}
+		output = &signedText
 		wg.Done()
 	case "file":
 		wg.Add(1)
-		var inputFile, outputSignFile string
+		var inputFile string
 		inputFile, err = promptForFileName()
 		if err != nil {
 			return fmt.Errorf("error selecting input file: %v", err)
@@ -354,15 +423,17 @@ func InteractiveSignature() error {
 		if err != nil {
 			return fmt.Errorf("error entering signed file name: %v", err)
 		}
+		var signFile *os.File
 		go func() {
 			defer wg.Done()
-			output, err = os.OpenFile(outputSignFile, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0600)
+			signFile, err = os.OpenFile(outputSignFile, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0600)
 			if err != nil {
 				log.Fatalf("Error: %v", err)
 			}
+			output = signFile
 		}()
 		defer func() {
-			_ = output.Close()
+			_ = signFile.Close()
 		}()
 	default:
 		return fmt.Errorf("invalid action: %s", mode)
@@ -412,11 +483,323 @@ func InteractiveSignature() error {
 	default:
 		return fmt.Errorf("invalid cipher: %s", signatureName)
 	}
-	return signature.SignAndVerify()
+	if err = signature.SignAndVerify(); err != nil {
+		return err
+	}
+	if mode != "text" {
+		fmt.Printf("Signed: %s\nVerified: true\n", absPath(outputSignFile))
+		return nil
+	}
+	err = common.EncodeArmor(os.Stdout, &common.ArmorBlock{
+		Type:    common.ArmorSignature,
+		Headers: map[string]string{"Algorithm": signatureAlgorithm(signatureName)},
+		Data:    signedText.Bytes(),
+	})
+	if err != nil {
+		return err
+	}
+	if err = printPublicKey(signature); err != nil {
+		return err
+	}
+	fmt.Println("Verified: true")
+	return nil
+}
+
+// absPath - путь к выходному файлу для отчёта пользователю
+func absPath(name string) string {
+	if path, err := filepath.Abs(name); err == nil {
+		return path
+	}
+	return name
+}
+
+// signatureAlgorithm - имя алгоритма подписи для заголовков брони
+func signatureAlgorithm(name string) string {
+	if name == "ГОСТ" {
+		return "gost"
+	}
+	return name
+}
+
+// printPublicKey - вывод открытого ключа в броне, если алгоритм его предоставляет
+func printPublicKey(algorithm any) error {
+	exporter, ok := algorithm.(common.KeyExporter)
+	if !ok {
+		return nil
+	}
+	block, err := exporter.ExportPublicKey()
+	if err != nil {
+		return err
+	}
+	return common.EncodeArmor(os.Stdout, block)
+}
+
+// exportPrivateKey - запись закрытого ключа в броне в файл path с правами 0600, чтобы
+// шифртекст можно было расшифровать позже. В stdout ключ не выводится: без path он
+// не сохраняется вовсе.
+func exportPrivateKey(cipher any, path string) error {
+	exporter, ok := cipher.(common.PrivateKeyExporter)
+	if !ok {
+		return nil
+	}
+	if path == "" {
+		fmt.Println("The private key is not saved; run with -export-private-key FILE to decrypt this ciphertext later")
+		return nil
+	}
+	block, err := exporter.ExportPrivateKey()
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	// O_TRUNC сохраняет права существующего файла
+	if err = f.Chmod(0600); err == nil {
+		err = common.EncodeArmor(f, block)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Private key written to %s; keep it secret and paste it into dearmor after the ciphertext to decrypt\n", path)
+	return nil
+}
+
+// readArmor - один блок брони из общего ввода. DecodeArmor читает с упреждением,
+// поэтому строки до END отделяются здесь, и следующий блок остаётся во вводе.
+func readArmor(in *bufio.Reader) (*common.ArmorBlock, error) {
+	var text strings.Builder
+	for {
+		line, err := in.ReadString('\n')
+		text.WriteString(line)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "-----END ") {
+			break
+		}
+	}
+	return common.DecodeArmor(strings.NewReader(text.String()))
+}
+
+// InteractiveDearmor - разбор вставленного блока брони с проверкой контрольной суммы.
+// Шифртекст расшифровывается вставленным закрытым ключом, подпись проверяется вставленным
+// открытым ключом, у ключей выводятся параметры.
+func InteractiveDearmor() error {
+	in := bufio.NewReader(os.Stdin)
+	fmt.Println("Paste an armored block (input ends at the END line):")
+	block, err := readArmor(in)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Type: %s\n", block.Type)
+	keys := make([]string, 0, len(block.Headers))
+	for key := range block.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Printf("%s: %s\n", key, block.Headers[key])
+	}
+	switch block.Type {
+	case common.ArmorCiphertext:
+		return decryptArmored(in, block)
+	case common.ArmorSignature:
+		return verifyArmored(in, block)
+	case common.ArmorPublicKey, common.ArmorPrivateKey:
+		numbers, err := common.ReadBigNumbers(bytes.NewReader(block.Data))
+		if err != nil {
+			return err
+		}
+		names := strings.Split(block.Headers["Params"], ",")
+		for i, num := range numbers {
+			name := fmt.Sprintf("#%d", i)
+			if i < len(names) && names[i] != "" {
+				name = names[i]
+			}
+			fmt.Printf("%s = %s\n", name, num.String())
+		}
+	default:
+		fmt.Printf("Data: %x\n", block.Data)
+	}
+	return nil
+}
+
+// decryptArmored - расшифрование вставленного шифртекста вставленным закрытым ключом
+func decryptArmored(in *bufio.Reader, block *common.ArmorBlock) error {
+	decrypters := map[string]func(*common.ArmorBlock, []byte, io.Writer) error{
+		"elgamal":           elgamal.DecryptArmored,
+		"rsa":               rsa.DecryptArmored,
+		"rabin":             rabin.DecryptArmored,
+		"goldwasser-micali": gm.DecryptArmored,
+	}
+	cipherName := block.Headers["Cipher"]
+	decrypt, ok := decrypters[cipherName]
+	if !ok {
+		return fmt.Errorf("cipher %q has no exportable private key, its ciphertext cannot be decrypted from armor", cipherName)
+	}
+	fmt.Println("Paste the PRIVATE KEY block printed with the ciphertext:")
+	key, err := readArmor(in)
+	if err != nil {
+		return err
+	}
+	var decrypted bytes.Buffer
+	if err = decrypt(key, block.Data, &decrypted); err != nil {
+		return err
+	}
+	fmt.Printf("Decrypted message: %s\n", decrypted.String())
+	return nil
+}
+
+// verifyArmored - проверка вставленной подписи вставленным открытым ключом и сообщением
+func verifyArmored(in *bufio.Reader, block *common.ArmorBlock) error {
+	verifiers := map[string]func(*common.ArmorBlock, []byte, []byte) error{
+		"elgamal": elgamal.VerifyArmored,
+		"rsa":     rsa.VerifyArmored,
+		"gost":    gost.VerifyArmored,
+	}
+	algorithm := block.Headers["Algorithm"]
+	verify, ok := verifiers[algorithm]
+	if !ok {
+		return fmt.Errorf("unknown signature algorithm %q", algorithm)
+	}
+	fmt.Println("Paste the PUBLIC KEY block printed with the signature:")
+	key, err := readArmor(in)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Enter the signed message (one line):")
+	message, err := in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if err = verify(key, []byte(strings.TrimRight(message, "\r\n")), block.Data); err != nil {
+		return err
+	}
+	fmt.Println("Verified: true")
+	return nil
+}
+
+// InteractiveFactor - разложение модуля N; при известном открытом экспоненте D
+// восстанавливается закрытый экспонент RSA C = D^-1 mod φ(N)
+func InteractiveFactor() error {
+	prompt := textinput.New("Enter modulus N:")
+	prompt.Placeholder = "Example: the N of an rsa public key"
+	response, err := prompt.RunPrompt()
+	if err != nil {
+		return err
+	}
+	n, ok := new(big.Int).SetString(strings.TrimSpace(response), 10)
+	if !ok || n.Sign() <= 0 {
+		return &common.ParameterError{Name: "N", Reason: fmt.Sprintf("%q is not a positive integer", response)}
+	}
+	prompt = textinput.New("Enter public exponent D (empty to skip):")
+	prompt.Placeholder = "Example: the D of an rsa public key"
+	response, err = prompt.RunPrompt()
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	factors, err := factor.Factor(n)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Factored in %s:\n", time.Since(start))
+	for _, pp := range factors {
+		fmt.Printf("%s^%d\n", pp.P, pp.E)
+	}
+	if strings.TrimSpace(response) == "" {
+		return nil
+	}
+	d, ok := new(big.Int).SetString(strings.TrimSpace(response), 10)
+	if !ok {
+		return &common.ParameterError{Name: "D", Reason: fmt.Sprintf("%q is not an integer", response)}
+	}
+	c, err := common.ModInverseBig(d, common.EulerPhi(factors))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Private exponent C = %s\n", c)
+	return nil
+}
+
+// Функция для выбора действия
+func promptForAction() (string, error) {
+	selectionPrompt := selection.New[string]("Select action:", []string{"encrypt", "sign", "dearmor", "factor"})
+	action, err := selectionPrompt.RunPrompt()
+	if err != nil {
+		return "", err
+	}
+	return action, nil
 }
 
 func main() {
-	if err := InteractiveSignature(); err != nil {
+	traceFormat := flag.String("trace", "", "trace algorithm steps: text or json")
+	traceOut := flag.String("trace-out", "", "file for the trace (default stderr)")
+	privateKeyFile := flag.String("export-private-key", "", "file (mode 0600) for the armored private key of a text-mode encryption")
+	flag.Parse()
+	var traceWriter io.Writer = os.Stderr
+	if *traceOut != "" {
+		f, err := os.OpenFile(*traceOut, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		defer f.Close()
+		traceWriter = f
+	}
+	tracer, err := common.NewTracer(*traceFormat, traceWriter)
+	if err != nil {
+		log.Fatalf("Error: %s", describeError(err))
+	}
+	common.SetTracer(tracer)
+
+	action, err := promptForAction()
+	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
+	switch action {
+	case "encrypt":
+		err = InteractiveEncryptAndDecrypt(*privateKeyFile)
+	case "sign":
+		err = InteractiveSignature()
+	case "dearmor":
+		err = InteractiveDearmor()
+	case "factor":
+		err = InteractiveFactor()
+	}
+	if err != nil {
+		log.Fatalf("Error: %s", describeError(err))
+	}
+}
+
+// describeError - понятное пользователю описание ошибок из библиотечных пакетов
+func describeError(err error) string {
+	var (
+		tooLarge *common.MessageTooLargeError
+		param    *common.ParameterError
+		noInv    *common.NoInverseError
+	)
+	switch {
+	case errors.As(err, &tooLarge):
+		return fmt.Sprintf("message block %s does not fit the modulus %s; choose a larger prime", tooLarge.Value, tooLarge.Limit)
+	case errors.As(err, &param):
+		return fmt.Sprintf("parameter %s is not acceptable (%s); check the entered numbers", param.Name, param.Reason)
+	case errors.As(err, &noInv):
+		return fmt.Sprintf("%s has no inverse modulo %s; the key parameters are not coprime", noInv.A, noInv.M)
+	case errors.Is(err, common.ErrVerification):
+		return "signature verification failed: the signature does not match the message"
+	case errors.Is(err, common.ErrDiscreteLogNotFound):
+		return "discrete logarithm not found: the value is not in the subgroup generated by the base"
+	case errors.Is(err, common.ErrArmorChecksum):
+		return "armored block is corrupted: checksum mismatch"
+	case errors.Is(err, common.ErrArmorNotFound), errors.Is(err, common.ErrArmorMalformed):
+		return "input is not a valid armored block: " + err.Error()
+	}
+	return err.Error()
 }
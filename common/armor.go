@@ -0,0 +1,165 @@
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Типы блоков ASCII-брони
+const (
+	ArmorCiphertext = "CIPHERTEXT"
+	ArmorSignature  = "SIGNATURE"
+	ArmorPublicKey  = "PUBLIC KEY"
+	ArmorPrivateKey = "PRIVATE KEY"
+)
+
+const (
+	armorBegin      = "-----BEGIN "
+	armorEnd        = "-----END "
+	armorDashes     = "-----"
+	armorLineLength = 64
+	crc24Init       = 0xB704CE
+	crc24Poly       = 0x1864CFB
+	crc24Mask       = 0xFFFFFF
+)
+
+var (
+	ErrArmorNotFound  = errors.New("armor: begin line not found")
+	ErrArmorMalformed = errors.New("armor: malformed block")
+	ErrArmorChecksum  = errors.New("armor: checksum mismatch")
+)
+
+// ArmorBlock - двоичные данные в текстовой "броне" (base64 между строками BEGIN/END)
+type ArmorBlock struct {
+	Type    string            // Тип блока, например ArmorCiphertext
+	Headers map[string]string // Заголовки вида "Key: Value"
+	Data    []byte            // Полезная нагрузка
+}
+
+// crc24 - контрольная сумма CRC-24 из OpenPGP (RFC 4880, раздел 6.1)
+func crc24(data []byte) uint32 {
+	crc := uint32(crc24Init)
+	for _, b := range data {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= crc24Poly
+			}
+		}
+	}
+	return crc & crc24Mask
+}
+
+// EncodeArmor - запись блока в io.Writer в текстовом виде
+func EncodeArmor(w io.Writer, block *ArmorBlock) error {
+	var buf bytes.Buffer
+	buf.WriteString(armorBegin + block.Type + armorDashes + "\n")
+	if len(block.Headers) > 0 {
+		keys := make([]string, 0, len(block.Headers))
+		for k := range block.Headers {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			buf.WriteString(k + ": " + block.Headers[k] + "\n")
+		}
+		buf.WriteString("\n")
+	}
+	encoded := base64.StdEncoding.EncodeToString(block.Data)
+	for len(encoded) > armorLineLength {
+		buf.WriteString(encoded[:armorLineLength] + "\n")
+		encoded = encoded[armorLineLength:]
+	}
+	if len(encoded) > 0 {
+		buf.WriteString(encoded + "\n")
+	}
+	crc := crc24(block.Data)
+	sum := base64.StdEncoding.EncodeToString([]byte{byte(crc >> 16), byte(crc >> 8), byte(crc)})
+	buf.WriteString("=" + sum + "\n")
+	buf.WriteString(armorEnd + block.Type + armorDashes + "\n")
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("error writing armor: %v", err)
+	}
+	return nil
+}
+
+// DecodeArmor - чтение первого блока из io.Reader. Текст до строки BEGIN
+// пропускается, поэтому блок можно вставить вместе с окружающим письмом.
+func DecodeArmor(r io.Reader) (*ArmorBlock, error) {
+	scanner := bufio.NewScanner(r)
+	block := &ArmorBlock{Headers: map[string]string{}}
+	found := false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, armorBegin) && strings.HasSuffix(line, armorDashes) {
+			block.Type = strings.TrimSuffix(strings.TrimPrefix(line, armorBegin), armorDashes)
+			found = true
+			break
+		}
+	}
+	if !found {
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("error reading armor: %v", err)
+		}
+		return nil, ErrArmorNotFound
+	}
+	var (
+		body     strings.Builder
+		checksum string
+		closed   bool
+	)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, armorEnd):
+			if line != armorEnd+block.Type+armorDashes {
+				return nil, fmt.Errorf("%w: end line %q does not match %q", ErrArmorMalformed, line, block.Type)
+			}
+			closed = true
+		case strings.HasPrefix(line, "="):
+			checksum = line[1:]
+		case strings.Contains(line, ":"):
+			if body.Len() > 0 {
+				return nil, fmt.Errorf("%w: header %q after data", ErrArmorMalformed, line)
+			}
+			key, value, _ := strings.Cut(line, ":")
+			block.Headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		default:
+			body.WriteString(line)
+		}
+		if closed {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading armor: %v", err)
+	}
+	if !closed {
+		return nil, fmt.Errorf("%w: missing end line", ErrArmorMalformed)
+	}
+	data, err := base64.StdEncoding.DecodeString(body.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrArmorMalformed, err)
+	}
+	if checksum == "" {
+		return nil, fmt.Errorf("%w: missing checksum", ErrArmorMalformed)
+	}
+	sum, err := base64.StdEncoding.DecodeString(checksum)
+	if err != nil || len(sum) != 3 {
+		return nil, fmt.Errorf("%w: bad checksum line", ErrArmorMalformed)
+	}
+	if crc24(data) != uint32(sum[0])<<16|uint32(sum[1])<<8|uint32(sum[2]) {
+		return nil, ErrArmorChecksum
+	}
+	block.Data = data
+	return block, nil
+}
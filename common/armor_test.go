@@ -0,0 +1,172 @@
+package common
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestArmorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		block ArmorBlock
+	}{
+		{
+			name:  "пустые данные",
+			block: ArmorBlock{Type: ArmorCiphertext, Data: []byte{}},
+		},
+		{
+			name: "шифртекст с заголовком",
+			block: ArmorBlock{
+				Type:    ArmorCiphertext,
+				Headers: map[string]string{"Cipher": "rsa"},
+				Data:    []byte("hello, world"),
+			},
+		},
+		{
+			name:  "данные длиннее одной строки",
+			block: ArmorBlock{Type: ArmorSignature, Data: bytes.Repeat([]byte{0xAB, 0x00, 0x7F}, 100)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeArmor(&buf, &tt.block); err != nil {
+				t.Fatal(err)
+			}
+			got, err := DecodeArmor(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Type != tt.block.Type {
+				t.Errorf("DecodeArmor() type = %q, want %q", got.Type, tt.block.Type)
+			}
+			if !bytes.Equal(got.Data, tt.block.Data) {
+				t.Errorf("DecodeArmor() data = %x, want %x", got.Data, tt.block.Data)
+			}
+			for k, v := range tt.block.Headers {
+				if got.Headers[k] != v {
+					t.Errorf("DecodeArmor() header %s = %q, want %q", k, got.Headers[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeArmorPasted(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeArmor(&buf, &ArmorBlock{Type: ArmorCiphertext, Data: []byte("secret")}); err != nil {
+		t.Fatal(err)
+	}
+	// Блок, вставленный в письмо: текст вокруг, отступы и переводы строк CRLF
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	pasted := "Привет! Вот шифровка:\r\n\r\n  " + strings.Join(lines, "\r\n  ") + "\r\nПока."
+	got, err := DecodeArmor(strings.NewReader(pasted))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got.Data) != "secret" {
+		t.Errorf("DecodeArmor() data = %q, want %q", got.Data, "secret")
+	}
+}
+
+func TestDecodeArmorErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeArmor(&buf, &ArmorBlock{Type: ArmorSignature, Data: []byte("signature")}); err != nil {
+		t.Fatal(err)
+	}
+	valid := buf.String()
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{
+			name:    "нет блока",
+			input:   "просто текст",
+			wantErr: ErrArmorNotFound,
+		},
+		{
+			name:    "испорчены данные",
+			input:   strings.Replace(valid, "c2ln", "c2lo", 1),
+			wantErr: ErrArmorChecksum,
+		},
+		{
+			name:    "нет строки END",
+			input:   strings.Split(valid, armorEnd)[0],
+			wantErr: ErrArmorMalformed,
+		},
+		{
+			name:    "чужая строка END",
+			input:   strings.Replace(valid, armorEnd+ArmorSignature, armorEnd+ArmorCiphertext, 1),
+			wantErr: ErrArmorMalformed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeArmor(strings.NewReader(tt.input))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("DecodeArmor() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPublicKeyBlock(t *testing.T) {
+	values := []*big.Int{big.NewInt(3233), big.NewInt(17)}
+	block, err := PublicKeyBlock("rsa", []string{"N", "D"}, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Headers["Params"] != "N,D" {
+		t.Errorf("PublicKeyBlock() Params = %q, want %q", block.Headers["Params"], "N,D")
+	}
+	got, err := ReadBigNumbers(bytes.NewReader(block.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range values {
+		if got[i].Cmp(values[i]) != 0 {
+			t.Errorf("ReadBigNumbers()[%d] = %s, want %s", i, got[i], values[i])
+		}
+	}
+}
+
+func TestKeyNumbers(t *testing.T) {
+	values := []*big.Int{big.NewInt(61), big.NewInt(53)}
+	private, err := PrivateKeyBlock("rabin", []string{"P", "Q"}, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if private.Type != ArmorPrivateKey {
+		t.Errorf("PrivateKeyBlock() Type = %q, want %q", private.Type, ArmorPrivateKey)
+	}
+	got, err := KeyNumbers(private, ArmorPrivateKey, "rabin", 2)
+	if err != nil {
+		t.Fatalf("KeyNumbers() error = %v", err)
+	}
+	for i := range values {
+		if got[i].Cmp(values[i]) != 0 {
+			t.Errorf("KeyNumbers()[%d] = %s, want %s", i, got[i], values[i])
+		}
+	}
+	tests := []struct {
+		name      string
+		blockType string
+		algorithm string
+		count     int
+	}{
+		{name: "другой тип блока", blockType: ArmorPublicKey, algorithm: "rabin", count: 2},
+		{name: "другой алгоритм", blockType: ArmorPrivateKey, algorithm: "rsa", count: 2},
+		{name: "другое количество чисел", blockType: ArmorPrivateKey, algorithm: "rabin", count: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := KeyNumbers(private, tt.blockType, tt.algorithm, tt.count); !errors.Is(err, ErrArmorMalformed) {
+				t.Errorf("KeyNumbers() error = %v, want %v", err, ErrArmorMalformed)
+			}
+		})
+	}
+}
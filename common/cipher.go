@@ -1,9 +1,12 @@
 package common
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math/big"
+	"strings"
 )
 
 type Cipher interface {
@@ -12,6 +15,16 @@ type Cipher interface {
 	EncryptAndDecrypt() error
 }
 
+// KeyExporter - шифр или подпись, умеющие выгрузить свой открытый ключ
+type KeyExporter interface {
+	ExportPublicKey() (*ArmorBlock, error)
+}
+
+// PrivateKeyExporter - шифр, умеющий выгрузить закрытый ключ для расшифрования вставленного шифртекста
+type PrivateKeyExporter interface {
+	ExportPrivateKey() (*ArmorBlock, error)
+}
+
 // WriteNumbers Запись чисел в io.Writer
 func WriteNumbers(w io.Writer, data []int64) error {
 	for _, num := range data {
@@ -60,3 +73,82 @@ func ReadNumbers(r io.Reader) ([]int64, error) {
 	}
 	return numbers, nil
 }
+
+// WriteBigNumbers - запись больших чисел в io.Writer (длина uint32 + байты big-endian)
+func WriteBigNumbers(w io.Writer, data []*big.Int) error {
+	for _, num := range data {
+		b := num.Bytes()
+		if err := binary.Write(w, binary.BigEndian, uint32(len(b))); err != nil {
+			return fmt.Errorf("error writing number to output: %v", err)
+		}
+		if _, err := w.Write(b); err != nil {
+			return fmt.Errorf("error writing number to output: %v", err)
+		}
+	}
+	return nil
+}
+
+// ReadBigNumbers - чтение больших чисел, записанных WriteBigNumbers
+func ReadBigNumbers(r io.Reader) ([]*big.Int, error) {
+	var numbers []*big.Int
+	for {
+		var size uint32
+		err := binary.Read(r, binary.BigEndian, &size)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading number from input: %v", err)
+		}
+		b := make([]byte, size)
+		if _, err = io.ReadFull(r, b); err != nil {
+			return nil, fmt.Errorf("error reading number from input: %v", err)
+		}
+		numbers = append(numbers, new(big.Int).SetBytes(b))
+	}
+	return numbers, nil
+}
+
+// PublicKeyBlock - упаковка открытого ключа в блок брони.
+// Имена параметров сохраняются в заголовке Params в том же порядке, что и числа.
+func PublicKeyBlock(algorithm string, names []string, values []*big.Int) (*ArmorBlock, error) {
+	return keyBlock(ArmorPublicKey, algorithm, names, values)
+}
+
+// PrivateKeyBlock - упаковка закрытого ключа в блок брони, как PublicKeyBlock
+func PrivateKeyBlock(algorithm string, names []string, values []*big.Int) (*ArmorBlock, error) {
+	return keyBlock(ArmorPrivateKey, algorithm, names, values)
+}
+
+func keyBlock(blockType, algorithm string, names []string, values []*big.Int) (*ArmorBlock, error) {
+	if len(names) != len(values) {
+		return nil, fmt.Errorf("%s: %d names for %d values", strings.ToLower(blockType), len(names), len(values))
+	}
+	var buf bytes.Buffer
+	if err := WriteBigNumbers(&buf, values); err != nil {
+		return nil, err
+	}
+	return &ArmorBlock{
+		Type: blockType,
+		Headers: map[string]string{
+			"Algorithm": algorithm,
+			"Params":    strings.Join(names, ","),
+		},
+		Data: buf.Bytes(),
+	}, nil
+}
+
+// KeyNumbers - числа блока ключа типа blockType для алгоритма algorithm; их должно быть count
+func KeyNumbers(block *ArmorBlock, blockType, algorithm string, count int) ([]*big.Int, error) {
+	if block.Type != blockType || block.Headers["Algorithm"] != algorithm {
+		return nil, fmt.Errorf("%s: %s block for %q: %w", algorithm, block.Type, block.Headers["Algorithm"], ErrArmorMalformed)
+	}
+	numbers, err := ReadBigNumbers(bytes.NewReader(block.Data))
+	if err != nil {
+		return nil, err
+	}
+	if len(numbers) != count {
+		return nil, fmt.Errorf("%s: %d numbers in %s: %w", algorithm, len(numbers), blockType, ErrArmorMalformed)
+	}
+	return numbers, nil
+}
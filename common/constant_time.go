@@ -0,0 +1,356 @@
+package common
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+)
+
+// Операции этого файла предназначены для секретных значений (закрытых ключей,
+// одноразовых k): последовательность инструкций и обращений к памяти зависит только
+// от длины модуля и заданной длины показателя, но не от их битов. Числа хранятся
+// словами фиксированной ширины, выбор делается масками вместо ветвлений.
+// Преобразования на входе и выходе (big.Int) не скрывают длину самих значений.
+
+// toWords - x (0 <= x < 2^(W*n)) в виде ровно n слов little-endian
+func toWords(x *big.Int, n int) []uint {
+	z := make([]uint, n)
+	for i, w := range x.Bits() {
+		z[i] = uint(w)
+	}
+	return z
+}
+
+// fromWords - слова в big.Int
+func fromWords(x []uint) *big.Int {
+	words := make([]big.Word, len(x))
+	for i, w := range x {
+		words[i] = big.Word(w)
+	}
+	return new(big.Int).SetBits(words)
+}
+
+// ctEq - маска из единиц, если a == b, иначе 0
+func ctEq(a, b uint) uint {
+	x := a ^ b
+	return ((x | -x) >> (bits.UintSize - 1)) - 1
+}
+
+// ctSelect - z = x, если mask из единиц, z = y, если mask = 0
+func ctSelect(mask uint, z, x, y []uint) {
+	for i := range z {
+		z[i] = x[i]&mask | y[i]&^mask
+	}
+}
+
+// ctSwap - обмен x и y, если mask из единиц
+func ctSwap(mask uint, x, y []uint) {
+	for i := range x {
+		d := (x[i] ^ y[i]) & mask
+		x[i] ^= d
+		y[i] ^= d
+	}
+}
+
+// ctAdd - z = x + y, если mask из единиц (иначе z = x), возвращает перенос
+func ctAdd(mask uint, z, x, y []uint) (c uint) {
+	for i := range z {
+		z[i], c = bits.Add(x[i], y[i]&mask, c)
+	}
+	return c
+}
+
+// ctSub - z = x - y, если mask из единиц (иначе z = x), возвращает заём
+func ctSub(mask uint, z, x, y []uint) (b uint) {
+	for i := range z {
+		z[i], b = bits.Sub(x[i], y[i]&mask, b)
+	}
+	return b
+}
+
+// ctNeg - z = -x (дополнительный код), если mask из единиц
+func ctNeg(mask uint, z []uint) {
+	c := mask & 1
+	for i := range z {
+		z[i], c = bits.Add(z[i]^mask, 0, c)
+	}
+}
+
+// ctShr1 - z >>= 1 с вдвиганием бита hi сверху, возвращает выдвинутый младший бит
+func ctShr1(z []uint, hi uint) uint {
+	low := z[0] & 1
+	for i := 0; i < len(z)-1; i++ {
+		z[i] = z[i]>>1 | z[i+1]<<(bits.UintSize-1)
+	}
+	z[len(z)-1] = z[len(z)-1]>>1 | hi<<(bits.UintSize-1)
+	return low
+}
+
+// addMulVVW - z += x*y, возвращает перенос. len(x) >= len(z)
+func addMulVVW(z, x []uint, y uint) (c uint) {
+	x = x[:len(z)]
+	for i := range z {
+		hi, lo := bits.Mul(x[i], y)
+		lo, cc := bits.Add(lo, z[i], 0)
+		hi += cc
+		lo, cc = bits.Add(lo, c, 0)
+		z[i] = lo
+		c = hi + cc
+	}
+	return c
+}
+
+// montMulWords - z = x*y*R^-1 mod m над словами фиксированной ширины. t - буфер из 2n слов.
+// z может совпадать с x или y.
+func (mc *ModContext) montMulWords(z, x, y, t []uint) {
+	n, m := mc.n, mc.words
+	t = t[:2*n]
+	for i := range t {
+		t[i] = 0
+	}
+	var c uint
+	for i := 0; i < n; i++ {
+		// t += x*y[i]*2^(W*i), затем добавляем u*m так, чтобы слово t[i] обнулилось
+		w := t[i : n+i]
+		c2 := addMulVVW(w, x, y[i])
+		u := w[0] * mc.m0inv
+		c3 := addMulVVW(w, m, u)
+		cx, b1 := bits.Add(c, c2, 0)
+		cy, b2 := bits.Add(cx, c3, 0)
+		t[n+i] = cy
+		c = b1 | b2
+	}
+	// Результат (c, t[n:]) < 2m: вычитаем m и выбираем t или t-m маской
+	hi := t[n:]
+	z = z[:n]
+	b := ctSub(^uint(0), z, hi, m)
+	_, b = bits.Sub(c, 0, b)
+	ctSelect(-b, z, hi, z)
+}
+
+// ExpConstantTime - base^exp mod m лестницей Монтгомери за ровно expBits шагов.
+// На каждом шаге выполняются одно умножение и одно возведение в квадрат независимо
+// от бита показателя. Для отрицательного показателя или длиннее expBits бит - *ParameterError.
+func (mc *ModContext) ExpConstantTime(base, exp *big.Int, expBits int) (*big.Int, error) {
+	if err := checkExpBits(exp, expBits); err != nil {
+		return nil, err
+	}
+	n := mc.n
+	t := make([]uint, 2*n)
+	e := toWords(exp, (expBits+bits.UintSize-1)/bits.UintSize)
+	b := base
+	if b.Sign() < 0 || b.Cmp(mc.mod) >= 0 {
+		b = new(big.Int).Mod(b, mc.mod)
+	}
+	// r0 = 1, r1 = base в форме Монтгомери; инвариант r1 = r0 * base
+	r0 := make([]uint, n)
+	copy(r0, mc.oneWords)
+	r1 := toWords(b, n)
+	mc.montMulWords(r1, r1, mc.rrWords, t)
+	for i := expBits - 1; i >= 0; i-- {
+		mask := -(e[i/bits.UintSize] >> (i % bits.UintSize) & 1)
+		ctSwap(mask, r0, r1)
+		mc.montMulWords(r1, r0, r1, t)
+		mc.montMulWords(r0, r0, r0, t)
+		ctSwap(mask, r0, r1)
+	}
+	unit := make([]uint, n)
+	unit[0] = 1
+	mc.montMulWords(r0, r0, unit, t)
+	return fromWords(r0), nil
+}
+
+// ExpConstantTime - g^e mod m по таблице гребёнки: на каждом столбце читается вся
+// таблица, нужная запись выбирается маской, умножение выполняется всегда.
+// Для отрицательного показателя или длиннее maxBits - *ParameterError.
+func (fb *FixedBase) ExpConstantTime(e *big.Int) (*big.Int, error) {
+	if err := checkExpBits(e, fb.bits); err != nil {
+		return nil, err
+	}
+	mc := fb.mc
+	n := mc.n
+	t := make([]uint, 2*n)
+	ew := toWords(e, (fb.teeth*fb.span+bits.UintSize-1)/bits.UintSize)
+	acc := make([]uint, n)
+	copy(acc, mc.oneWords)
+	entry := make([]uint, n)
+	for col := fb.span - 1; col >= 0; col-- {
+		mc.montMulWords(acc, acc, acc, t)
+		var idx uint
+		for i := fb.teeth - 1; i >= 0; i-- {
+			pos := i*fb.span + col
+			idx = idx<<1 | ew[pos/bits.UintSize]>>(pos%bits.UintSize)&1
+		}
+		for j, w := range fb.words {
+			ctSelect(ctEq(uint(j), idx), entry, w, entry)
+		}
+		mc.montMulWords(acc, acc, entry, t)
+	}
+	unit := make([]uint, n)
+	unit[0] = 1
+	mc.montMulWords(acc, acc, unit, t)
+	return fromWords(acc), nil
+}
+
+// checkExpBits - показатель неотрицателен и умещается в expBits бит. Сам показатель
+// секретен и в ошибку не попадает.
+func checkExpBits(exp *big.Int, expBits int) error {
+	if exp.Sign() < 0 || exp.BitLen() > expBits {
+		return &ParameterError{Name: "exponent", Reason: fmt.Sprintf("negative or longer than %d bits", expBits)}
+	}
+	return nil
+}
+
+// ModInverseConstantTime - a^-1 mod m. Для нечётного m используется двоичный
+// алгоритм Мёллера с фиксированным числом итераций 2*W*n и маскированными
+// операциями. Чётный m (например, φ(n) в RSA или p - 1 в Эль-Гамале) сводится
+// к нечётному модулю a: a*x ≡ 1 (mod m) при x = (1 + m*(a - (m^-1 mod a))) / a.
+// Все шаги сведения - деление, умножение и алгоритм Мёллера по модулю a, дополненному
+// до n слов, - выполняются над словами фиксированной ширины за число итераций,
+// зависящее только от n. Чётность a не скрывается: чётное a по чётному модулю
+// необратимо, и ошибка возвращается сразу.
+func ModInverseConstantTime(a, m *big.Int) (*big.Int, error) {
+	if m.Cmp(big.NewInt(1)) <= 0 {
+		return nil, &ParameterError{Name: "modulus", Reason: fmt.Sprintf("%s is not greater than 1", m)}
+	}
+	n := len(m.Bits())
+	mw := toWords(m, n)
+	if a.Sign() < 0 {
+		a = new(big.Int).Mod(a, m)
+	}
+	width := len(a.Bits())
+	if width < n {
+		width = n
+	}
+	_, ar := ctDivMod(toWords(a, width), mw)
+	if m.Bit(0) == 1 {
+		x, ok := modInverseOdd(ar, mw)
+		if !ok {
+			return nil, &NoInverseError{A: new(big.Int).Set(a), M: new(big.Int).Set(m)}
+		}
+		return fromWords(x), nil
+	}
+	// Чётный модуль: обратимы только нечётные a
+	if ar[0]&1 == 0 {
+		return nil, &NoInverseError{A: new(big.Int).Set(a), M: new(big.Int).Set(m)}
+	}
+	_, ma := ctDivMod(mw, ar)
+	y, ok := modInverseOdd(ma, ar)
+	if !ok {
+		return nil, &NoInverseError{A: new(big.Int).Set(a), M: new(big.Int).Set(m)}
+	}
+	// m*(a - y) ≡ -1 (mod a), поэтому 1 + m*(a - y) делится на a
+	t := make([]uint, n)
+	ctSub(^uint(0), t, ar, y)
+	prod := ctMulWords(t, mw)
+	unit := make([]uint, 2*n)
+	unit[0] = 1
+	ctAdd(^uint(0), prod, prod, unit)
+	q, _ := ctDivMod(prod, ar)
+	// q <= m, и q = m + 1 - m при a = 1: одно маскированное вычитание
+	x := q[:n]
+	r := make([]uint, n)
+	b := ctSub(^uint(0), r, x, mw)
+	_, b = bits.Sub(q[n], 0, b)
+	ctSelect(-b, x, x, r)
+	return fromWords(x), nil
+}
+
+// ctShl1 - z <<= 1 с вдвиганием бита lo снизу, возвращает выдвинутый старший бит
+func ctShl1(z []uint, lo uint) uint {
+	high := z[len(z)-1] >> (bits.UintSize - 1)
+	for i := len(z) - 1; i > 0; i-- {
+		z[i] = z[i]<<1 | z[i-1]>>(bits.UintSize-1)
+	}
+	z[0] = z[0]<<1 | lo
+	return high
+}
+
+// ctMulWords - полное произведение x*y из len(x)+len(y) слов
+func ctMulWords(x, y []uint) []uint {
+	z := make([]uint, len(x)+len(y))
+	for i, w := range y {
+		z[len(x)+i] = addMulVVW(z[i:i+len(x)], x, w)
+	}
+	return z
+}
+
+// ctDivMod - частное (len(x) слов) и остаток (len(d) слов) от деления x на d > 0
+// восстанавливающим делением: W*len(x) шагов с маскированным вычитанием
+func ctDivMod(x, d []uint) (q, r []uint) {
+	n := len(d)
+	q = make([]uint, len(x))
+	rem := make([]uint, n+1) // остаток до вычитания меньше 2d
+	dw := make([]uint, n+1)
+	copy(dw, d)
+	t := make([]uint, n+1)
+	for i := len(x)*bits.UintSize - 1; i >= 0; i-- {
+		ctShl1(rem, x[i/bits.UintSize]>>(i%bits.UintSize)&1)
+		ge := ctSub(^uint(0), t, rem, dw) - 1 // маска из единиц, если rem >= d
+		ctSelect(ge, rem, t, rem)
+		q[i/bits.UintSize] |= (ge & 1) << (i % bits.UintSize)
+	}
+	return q, rem[:n]
+}
+
+// modInverseOdd - x^-1 mod m для нечётного m и 0 <= x < m, заданных n словами;
+// ok = false, если gcd(x, m) != 1. Старшие слова m могут быть нулевыми.
+// Инварианты: a ≡ u*x, b ≡ v*x (mod m); по окончании b = gcd, v = x^-1.
+func modInverseOdd(x, m []uint) ([]uint, bool) {
+	n := len(m)
+	a := append([]uint(nil), x...)
+	b := append([]uint(nil), m...)
+	u := make([]uint, n)
+	u[0] = 1
+	v := make([]uint, n)
+	// (m+1)/2 для деления на 2 по модулю m
+	half := make([]uint, n)
+	unit := make([]uint, n)
+	unit[0] = 1
+	c := ctAdd(^uint(0), half, m, unit)
+	ctShr1(half, c)
+	all := ^uint(0)
+	for i := 0; i < 2*n*bits.UintSize; i++ {
+		odd := -(a[0] & 1)
+		// a нечётно: a = a - b; если a < b, то a = b - a, b = старое a, u и v меняются местами
+		swap := -ctSub(odd, a, a, b)
+		ctAdd(swap, b, b, a)
+		ctNeg(swap, a)
+		ctSwap(swap, u, v)
+		// u = u - v mod m
+		borrow := -ctSub(odd, u, u, v)
+		ctAdd(borrow, u, u, m)
+		// a чётно: a = a/2, u = u/2 mod m
+		ctShr1(a, 0)
+		low := -ctShr1(u, 0)
+		ctAdd(low, u, u, half)
+	}
+	// b == 1 без ветвлений по словам
+	one := all
+	for i, w := range b {
+		if i == 0 {
+			one &= ctEq(w, 1)
+		} else {
+			one &= ctEq(w, 0)
+		}
+	}
+	return v, one == all
+}
+
+// ModularExponentiationConstantTime - a^x mod p для int64 лестницей Монтгомери:
+// 63 шага при любом x. Для чётного p или p <= 1 возвращается *ParameterError,
+// для отрицательного x - тоже.
+func ModularExponentiationConstantTime(a, x, p int64) (int64, error) {
+	if x < 0 {
+		return 0, &ParameterError{Name: "exponent", Reason: fmt.Sprintf("%d is negative", x)}
+	}
+	mc, err := NewModContext(big.NewInt(p))
+	if err != nil {
+		return 0, err
+	}
+	y, err := mc.ExpConstantTime(big.NewInt(a), big.NewInt(x), 63)
+	if err != nil {
+		return 0, err
+	}
+	return y.Int64(), nil
+}
@@ -0,0 +1,266 @@
+package common
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"os"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestExpConstantTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		modBits int
+		expBits int
+	}{
+		{name: "одно слово", modBits: 61, expBits: 61},
+		{name: "граница слова", modBits: 64, expBits: 64},
+		{name: "512 бит", modBits: 512, expBits: 512},
+		{name: "1024 бит, короткий показатель", modBits: 1024, expBits: 256},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := randomOdd(t, tt.modBits)
+			mc, err := NewModContext(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 10; i++ {
+				base := randomBelow(t, tt.modBits+8)
+				exp := randomBelow(t, tt.expBits)
+				want := new(big.Int).Exp(base, exp, m)
+				got, err := mc.ExpConstantTime(base, exp, tt.expBits)
+				if err != nil {
+					t.Fatalf("ExpConstantTime(%s, %s) error = %v", base, exp, err)
+				}
+				if got.Cmp(want) != 0 {
+					t.Fatalf("ExpConstantTime(%s, %s) = %s, want %s", base, exp, got, want)
+				}
+			}
+			if got, err := mc.ExpConstantTime(big.NewInt(7), big.NewInt(0), tt.expBits); err != nil || got.Cmp(big.NewInt(1)) != 0 {
+				t.Errorf("ExpConstantTime(7, 0) = %s, %v, want 1", got, err)
+			}
+			// Показатель длиннее expBits и отрицательный
+			long := new(big.Int).Lsh(big.NewInt(1), uint(tt.expBits))
+			for _, exp := range []*big.Int{long, big.NewInt(-1)} {
+				var pe *ParameterError
+				if _, err := mc.ExpConstantTime(big.NewInt(7), exp, tt.expBits); !errors.As(err, &pe) {
+					t.Errorf("ExpConstantTime(7, %s) error = %v, want *ParameterError", exp, err)
+				}
+			}
+		})
+	}
+}
+
+func TestFixedBaseExpConstantTime(t *testing.T) {
+	m := randomOdd(t, 1024)
+	mc, err := NewModContext(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := randomBelow(t, 1024)
+	fb := mc.NewFixedBase(g, 256)
+	for _, e := range []*big.Int{big.NewInt(0), big.NewInt(1), randomBelow(t, 256), randomBelow(t, 256)} {
+		want := new(big.Int).Exp(g, e, m)
+		got, err := fb.ExpConstantTime(e)
+		if err != nil {
+			t.Fatalf("FixedBase.ExpConstantTime(%s) error = %v", e, err)
+		}
+		if got.Cmp(want) != 0 {
+			t.Fatalf("FixedBase.ExpConstantTime(%s) = %s, want %s", e, got, want)
+		}
+	}
+	var pe *ParameterError
+	if _, err := fb.ExpConstantTime(new(big.Int).Lsh(big.NewInt(1), 256)); !errors.As(err, &pe) {
+		t.Errorf("FixedBase.ExpConstantTime(2^256) error = %v, want *ParameterError", err)
+	}
+}
+
+func TestModularExponentiationConstantTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, x, p int64
+		want    int64
+		wantErr error
+	}{
+		{name: "5^20 mod 7", a: 5, x: 20, p: 7, want: 4},
+		{name: "3^21 mod 11", a: 3, x: 21, p: 11, want: 3},
+		{name: "нулевой показатель", a: 9, x: 0, p: 11, want: 1},
+		{name: "чётный модуль", a: 3, x: 5, p: 10, wantErr: ErrInvalidParameters},
+		{name: "модуль 1", a: 3, x: 5, p: 1, wantErr: ErrInvalidParameters},
+		{name: "отрицательный показатель", a: 3, x: -1, p: 11, wantErr: ErrInvalidParameters},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ModularExponentiationConstantTime(tt.a, tt.x, tt.p)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ModularExponentiationConstantTime() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("ModularExponentiationConstantTime(%d, %d, %d) = %d, want %d", tt.a, tt.x, tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModInverseConstantTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, m    int64
+		want    int64
+		wantErr bool
+	}{
+		{name: "простой модуль", a: 3, m: 11, want: 4},
+		{name: "нечётный составной модуль", a: 7, m: 15, want: 13},
+		{name: "чётный модуль φ(n)", a: 7, m: 3120, want: 1783},
+		{name: "a больше модуля", a: 25, m: 11, want: 4},
+		{name: "единица по чётному модулю", a: 1, m: 10, want: 1},
+		{name: "a больше чётного модуля", a: 3127, m: 3120, want: 1783},
+		{name: "отрицательное a по чётному модулю", a: -3113, m: 3120, want: 1783},
+		{name: "нет обратного, нечётный модуль", a: 6, m: 9, wantErr: true},
+		{name: "нет обратного, чётный модуль", a: 4, m: 8, wantErr: true},
+		{name: "ноль", a: 0, m: 7, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ModInverseConstantTime(big.NewInt(tt.a), big.NewInt(tt.m))
+			if tt.wantErr {
+				if !errors.Is(err, ErrNoInverse) {
+					t.Fatalf("ModInverseConstantTime() error = %v, want ErrNoInverse", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Int64() != tt.want {
+				t.Errorf("ModInverseConstantTime(%d, %d) = %s, want %d", tt.a, tt.m, got, tt.want)
+			}
+		})
+	}
+	// Случайные проверки против math/big для нечётных и чётных модулей
+	for i := 0; i < 50; i++ {
+		m := randomBelow(t, 512)
+		m.SetBit(m, 511, 1)
+		if i%2 == 0 {
+			m.SetBit(m, 0, 0)
+		}
+		a := randomBelow(t, 600)
+		want := new(big.Int).ModInverse(a, m)
+		got, err := ModInverseConstantTime(a, m)
+		if want == nil {
+			if err == nil {
+				t.Fatalf("ModInverseConstantTime(%s, %s) = %s, want error", a, m, got)
+			}
+			continue
+		}
+		if err != nil || got.Cmp(want) != 0 {
+			t.Fatalf("ModInverseConstantTime(%s, %s) = %v, %v, want %s", a, m, got, err, want)
+		}
+	}
+}
+
+// medianDurations - медианы времени f(low) и f(high); замеры чередуются,
+// чтобы фоновая нагрузка влияла на оба набора одинаково
+func medianDurations(rounds int, f func(x *big.Int), low, high *big.Int) (time.Duration, time.Duration) {
+	lows := make([]time.Duration, rounds)
+	highs := make([]time.Duration, rounds)
+	for i := 0; i < rounds; i++ {
+		start := time.Now()
+		f(low)
+		lows[i] = time.Since(start)
+		start = time.Now()
+		f(high)
+		highs[i] = time.Since(start)
+	}
+	median := func(d []time.Duration) time.Duration {
+		sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+		return d[len(d)/2]
+	}
+	return median(lows), median(highs)
+}
+
+// relativeGap - |a - b| / min(a, b)
+func relativeGap(a, b time.Duration) float64 {
+	if a > b {
+		a, b = b, a
+	}
+	return float64(b-a) / float64(a)
+}
+
+// TestConstantTimeHammingWeight - показатели одной длины, но с весом Хэмминга 2 и 512.
+// Квадратично-мультипликативный алгоритм выдаёт вес временем, лестница - нет.
+// Замеры зависят от нагрузки машины, поэтому тест запускается только с
+// CONSTANT_TIME_TIMING=1 на свободной машине.
+func TestConstantTimeHammingWeight(t *testing.T) {
+	if testing.Short() || os.Getenv("CONSTANT_TIME_TIMING") != "1" {
+		t.Skip("замеры времени включаются переменной CONSTANT_TIME_TIMING=1")
+	}
+	const bits = 512
+	const rounds = 101
+	const maxGap = 0.1
+	m := randomOdd(t, bits)
+	mc, err := NewModContext(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := randomBelow(t, bits-1)
+	low := new(big.Int).SetBit(big.NewInt(1), bits-1, 1)
+	high := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), bits), big.NewInt(1))
+
+	l, h := medianDurations(rounds, func(e *big.Int) { ModularExponentiationBig(base, e, m) }, low, high)
+	t.Logf("ModularExponentiationBig: вес 2 - %v, вес %d - %v, разница %.0f%%", l, bits, h, 100*relativeGap(l, h))
+
+	l, h = medianDurations(rounds, func(e *big.Int) { _, _ = mc.ExpConstantTime(base, e, bits) }, low, high)
+	gap := relativeGap(l, h)
+	t.Logf("ExpConstantTime: вес 2 - %v, вес %d - %v, разница %.1f%%", l, bits, h, 100*gap)
+	if gap > maxGap {
+		t.Errorf("ExpConstantTime: разница во времени %.1f%% больше %.0f%%", 100*gap, 100*maxGap)
+	}
+
+	fb := mc.NewFixedBase(base, bits)
+	l, h = medianDurations(rounds, func(e *big.Int) { _, _ = fb.ExpConstantTime(e) }, low, high)
+	gap = relativeGap(l, h)
+	t.Logf("FixedBase.ExpConstantTime: вес 2 - %v, вес %d - %v, разница %.1f%%", l, bits, h, 100*gap)
+	if gap > maxGap {
+		t.Errorf("FixedBase.ExpConstantTime: разница во времени %.1f%% больше %.0f%%", 100*gap, 100*maxGap)
+	}
+
+	// Обращение: нечётный модуль и аргументы с разным весом Хэмминга
+	invLow := new(big.Int).SetBit(big.NewInt(3), bits-2, 1)
+	invHigh := new(big.Int).Rsh(high, 2)
+	l, h = medianDurations(rounds, func(a *big.Int) { _, _ = ModInverseConstantTime(a, m) }, invLow, invHigh)
+	gap = relativeGap(l, h)
+	t.Logf("ModInverseConstantTime: вес 3 - %v, вес %d - %v, разница %.1f%%", l, bits-2, h, 100*gap)
+	if gap > maxGap {
+		t.Errorf("ModInverseConstantTime: разница во времени %.1f%% больше %.0f%%", 100*gap, 100*maxGap)
+	}
+
+	// Чётный модуль, как φ(n) в RSA и p - 1 в Эль-Гамале: сначала аргументы с разным
+	// весом Хэмминга, затем секретные модули с разным весом Хэмминга
+	// φ-подобный модуль 2q с простым q, чтобы оба аргумента были обратимы
+	q, err := rand.Prime(rand.Reader, bits-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	even := new(big.Int).Lsh(q, 1)
+	l, h = medianDurations(rounds, func(a *big.Int) { _, _ = ModInverseConstantTime(a, even) }, invLow, invHigh)
+	gap = relativeGap(l, h)
+	t.Logf("ModInverseConstantTime, чётный модуль: вес 3 - %v, вес %d - %v, разница %.1f%%", l, bits-2, h, 100*gap)
+	if gap > maxGap {
+		t.Errorf("ModInverseConstantTime, чётный модуль: разница во времени %.1f%% больше %.0f%%", 100*gap, 100*maxGap)
+	}
+	// 2^511 + 2 и 2^512 - 2 взаимно просты с 65537
+	modLow := new(big.Int).SetBit(big.NewInt(2), bits-1, 1)
+	modHigh := new(big.Int).Sub(high, big.NewInt(1))
+	e := big.NewInt(65537)
+	l, h = medianDurations(rounds, func(mod *big.Int) { _, _ = ModInverseConstantTime(e, mod) }, modLow, modHigh)
+	gap = relativeGap(l, h)
+	t.Logf("ModInverseConstantTime, модули: вес 2 - %v, вес %d - %v, разница %.1f%%", l, bits-1, h, 100*gap)
+	if gap > maxGap {
+		t.Errorf("ModInverseConstantTime, модули: разница во времени %.1f%% больше %.0f%%", 100*gap, 100*maxGap)
+	}
+}
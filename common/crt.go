@@ -0,0 +1,55 @@
+package common
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// CRT - китайская теорема об остатках: x = residues[i] (mod moduli[i]),
+// 0 <= x < prod moduli[i]. Модули попарно взаимно просты, их произведение помещается в int64.
+func CRT(residues, moduli []int64) (int64, error) {
+	r := make([]*big.Int, len(residues))
+	for i, v := range residues {
+		r[i] = big.NewInt(v)
+	}
+	m := make([]*big.Int, len(moduli))
+	prod := big.NewInt(1)
+	for i, v := range moduli {
+		m[i] = big.NewInt(v)
+		prod.Mul(prod, m[i])
+	}
+	if !prod.IsInt64() {
+		return 0, &ParameterError{Name: "moduli", Reason: fmt.Sprintf("product of moduli %s overflows int64", prod)}
+	}
+	x, err := CRTBig(r, m)
+	if err != nil {
+		return 0, err
+	}
+	return x.Int64(), nil
+}
+
+// CRTBig - китайская теорема об остатках для big.Int (алгоритм Гарнера):
+// x = residues[i] (mod moduli[i]), 0 <= x < prod moduli[i]
+func CRTBig(residues, moduli []*big.Int) (*big.Int, error) {
+	if len(residues) != len(moduli) {
+		return nil, &ParameterError{Name: "moduli", Reason: fmt.Sprintf("%d residues for %d moduli", len(residues), len(moduli))}
+	}
+	x := big.NewInt(0)
+	m := big.NewInt(1)
+	for i, r := range residues {
+		if moduli[i].Sign() <= 0 {
+			return nil, &ParameterError{Name: "moduli", Reason: fmt.Sprintf("modulus %s is not positive", moduli[i])}
+		}
+		inv := new(big.Int).ModInverse(m, moduli[i])
+		if inv == nil {
+			return nil, &ParameterError{Name: "moduli", Reason: fmt.Sprintf("modulus %s is not coprime with the previous ones", moduli[i])}
+		}
+		// x + m*t = r (mod moduli[i]) => t = (r - x) * m^-1 (mod moduli[i])
+		t := new(big.Int).Sub(r, x)
+		t.Mul(t, inv)
+		t.Mod(t, moduli[i])
+		x.Add(x, t.Mul(t, m))
+		m.Mul(m, moduli[i])
+	}
+	return x.Mod(x, m), nil
+}
@@ -0,0 +1,61 @@
+package common
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestCRT(t *testing.T) {
+	tests := []struct {
+		name     string
+		residues []int64
+		moduli   []int64
+		want     int64
+		wantErr  error
+	}{
+		{name: "задача Сунь-цзы", residues: []int64{2, 3, 2}, moduli: []int64{3, 5, 7}, want: 23},
+		{name: "один модуль", residues: []int64{17}, moduli: []int64{5}, want: 2},
+		{name: "отрицательный остаток", residues: []int64{-1, 0}, moduli: []int64{4, 9}, want: 27},
+		{name: "большие модули", residues: []int64{1, 2}, moduli: []int64{1_000_000_007, 998_244_353}, want: 993328913953302350},
+		{name: "пустая система", want: 0},
+		{name: "модули не взаимно просты", residues: []int64{1, 2}, moduli: []int64{6, 9}, wantErr: ErrInvalidParameters},
+		{name: "разная длина", residues: []int64{1}, moduli: []int64{3, 5}, wantErr: ErrInvalidParameters},
+		{name: "переполнение int64", residues: []int64{1, 1, 1}, moduli: []int64{1 << 31, 3_000_000_019, 3_000_000_037}, wantErr: ErrInvalidParameters},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CRT(tt.residues, tt.moduli)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CRT() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("CRT() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCRTBig(t *testing.T) {
+	moduli := []*big.Int{randomOdd(t, 128), randomOdd(t, 96), randomOdd(t, 64)}
+	// Делаем модули попарно взаимно простыми
+	for i := 1; i < len(moduli); i++ {
+		for j := 0; j < i; j++ {
+			for new(big.Int).GCD(nil, nil, moduli[i], moduli[j]).Cmp(big.NewInt(1)) != 0 {
+				moduli[i].Add(moduli[i], big.NewInt(2))
+			}
+		}
+	}
+	x := randomBelow(t, 250)
+	residues := make([]*big.Int, len(moduli))
+	for i, m := range moduli {
+		residues[i] = new(big.Int).Mod(x, m)
+	}
+	got, err := CRTBig(residues, moduli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Cmp(x) != 0 {
+		t.Errorf("CRTBig() = %s, want %s", got, x)
+	}
+}
@@ -2,7 +2,6 @@ package common
 
 import (
 	"fmt"
-	"log"
 )
 
 // DiffieHellman - функция вычисления ключа шифрования Diffie-Hellman
@@ -17,17 +16,19 @@ func DiffieHellman() (int64, error) {
 			break
 		}
 	}
-	log.Printf("P = %d, g = %d", P, g)
-	Xa := Seed().Int63n(P-1) + 1 // private Alice key
-	Xb := Seed().Int63n(P-1) + 1 // private Bob key
-	log.Printf("Xa = %d, Xb = %d", Xa, Xb)
+	Xa := Seed().Int63n(P-1) + 1          // private Alice key
+	Xb := Seed().Int63n(P-1) + 1          // private Bob key
 	Ya := ModularExponentiation(g, Xa, P) // public Alice key
 	Yb := ModularExponentiation(g, Xb, P) // public Bob key
-	log.Printf("Ya = %d, Yb = %d", Ya, Yb)
 	Zab := ModularExponentiation(Yb, Xa, P)
 	Zba := ModularExponentiation(Ya, Xb, P)
+	if Tracing() {
+		Trace("dh", "exchange",
+			ValInt("P", P), ValInt("g", g), ValInt("Xa", Xa), ValInt("Xb", Xb),
+			ValInt("Ya", Ya), ValInt("Yb", Yb), ValInt("Zab", Zab), ValInt("Zba", Zba))
+	}
 	if Zab != Zba {
-		return -1, fmt.Errorf("z_ab must be equal to z_ba")
+		return -1, fmt.Errorf("%w: z_ab must be equal to z_ba", ErrVerification)
 	}
 	return Zab, nil
 }
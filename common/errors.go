@@ -0,0 +1,85 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Базовые ошибки. Конкретные ошибки ниже оборачивают их, поэтому
+// вызывающий код может проверять их через errors.Is, а детали получать через errors.As.
+var (
+	ErrNoInverse           = errors.New("no modular inverse exists")
+	ErrMessageTooLarge     = errors.New("message is too large for the modulus")
+	ErrInvalidParameters   = errors.New("invalid parameters")
+	ErrDiscreteLogNotFound = errors.New("discrete logarithm not found")
+	ErrVerification        = errors.New("verification failed")
+	ErrNoSquareRoot        = errors.New("no modular square root exists")
+	ErrDecryption          = errors.New("decryption failed")
+)
+
+// NoInverseError - число A необратимо по модулю M
+type NoInverseError struct {
+	A, M *big.Int
+}
+
+func (e *NoInverseError) Error() string {
+	return fmt.Sprintf("no modular inverse exists for %s mod %s", e.A, e.M)
+}
+
+func (e *NoInverseError) Unwrap() error {
+	return ErrNoInverse
+}
+
+// MessageTooLargeError - блок сообщения Value не меньше модуля Limit
+type MessageTooLargeError struct {
+	Value, Limit *big.Int
+}
+
+func (e *MessageTooLargeError) Error() string {
+	return fmt.Sprintf("message block %s is greater than or equal to %s", e.Value, e.Limit)
+}
+
+func (e *MessageTooLargeError) Unwrap() error {
+	return ErrMessageTooLarge
+}
+
+// ParameterError - недопустимое значение параметра Name
+type ParameterError struct {
+	Name   string
+	Reason string
+}
+
+func (e *ParameterError) Error() string {
+	return fmt.Sprintf("invalid parameter %s: %s", e.Name, e.Reason)
+}
+
+func (e *ParameterError) Unwrap() error {
+	return ErrInvalidParameters
+}
+
+// DiscreteLogError - не найден x, такой что Base^x = Target mod Modulus
+type DiscreteLogError struct {
+	Base, Target, Modulus *big.Int
+}
+
+func (e *DiscreteLogError) Error() string {
+	return fmt.Sprintf("discrete logarithm of %s to base %s mod %s not found", e.Target, e.Base, e.Modulus)
+}
+
+func (e *DiscreteLogError) Unwrap() error {
+	return ErrDiscreteLogNotFound
+}
+
+// NoSquareRootError - A не является квадратичным вычетом по модулю простого P
+type NoSquareRootError struct {
+	A, P *big.Int
+}
+
+func (e *NoSquareRootError) Error() string {
+	return fmt.Sprintf("%s is not a quadratic residue mod %s", e.A, e.P)
+}
+
+func (e *NoSquareRootError) Unwrap() error {
+	return ErrNoSquareRoot
+}
@@ -0,0 +1,70 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+func TestErrorsWrap(t *testing.T) {
+	_, errInv := ModInverse(6, 9)
+	_, errInvBig := ModInverseBig(big.NewInt(6), big.NewInt(9))
+	_, errDlog := GiantBabyStep(2, 7, 3) // 2 порождает подгруппу {1, 2, 4}
+	_, errRange := GenCoprimeBig(big.NewInt(10), big.NewInt(5), big.NewInt(5))
+	_, errSqrt := TonelliShanks(big.NewInt(3), big.NewInt(7))
+	tests := []struct {
+		name   string
+		err    error
+		target error
+	}{
+		{
+			name:   "ModInverse",
+			err:    errInv,
+			target: ErrNoInverse,
+		},
+		{
+			name:   "ModInverseBig",
+			err:    errInvBig,
+			target: ErrNoInverse,
+		},
+		{
+			name:   "GiantBabyStep",
+			err:    errDlog,
+			target: ErrDiscreteLogNotFound,
+		},
+		{
+			name:   "GenCoprimeBig",
+			err:    errRange,
+			target: ErrInvalidParameters,
+		},
+		{
+			name:   "TonelliShanks",
+			err:    errSqrt,
+			target: ErrNoSquareRoot,
+		},
+		{
+			name:   "обёрнутая ошибка",
+			err:    fmt.Errorf("keygen: %w", &MessageTooLargeError{Value: big.NewInt(300), Limit: big.NewInt(257)}),
+			target: ErrMessageTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !errors.Is(tt.err, tt.target) {
+				t.Errorf("errors.Is(%v, %v) = false", tt.err, tt.target)
+			}
+		})
+	}
+}
+
+func TestNoInverseErrorAs(t *testing.T) {
+	_, err := ModInverseBig(big.NewInt(4), big.NewInt(8))
+	var noInv *NoInverseError
+	if !errors.As(err, &noInv) {
+		t.Fatalf("errors.As(%v) = false", err)
+	}
+	if noInv.A.Int64() != 4 || noInv.M.Int64() != 8 {
+		t.Errorf("NoInverseError = %v, want 4 mod 8", noInv)
+	}
+}
@@ -1,7 +1,6 @@
 package common
 
 import (
-	"fmt"
 	"math/big"
 )
 
@@ -35,7 +34,7 @@ func ModInverse(a, p int64) (int64, error) {
 	}
 	gcd, _, y := GCDExtended(a, p)
 	if gcd != 1 {
-		return 0, fmt.Errorf("no modular inverse exists for %d mod %d", a, p)
+		return 0, &NoInverseError{A: big.NewInt(a), M: big.NewInt(p)}
 	}
 	if y < 0 {
 		y += p
@@ -78,7 +77,7 @@ func ModInverseBig(a, p *big.Int) (*big.Int, error) {
 	// Вычисляем GCD и коэффициенты с помощью GCDExtendedBig
 	gcd, x, _ := GCDExtendedBig(aMod, p)
 	if gcd.Cmp(big.NewInt(1)) != 0 {
-		return nil, fmt.Errorf("no modular inverse exists for %s mod %s", a.String(), p.String())
+		return nil, &NoInverseError{A: new(big.Int).Set(a), M: new(big.Int).Set(p)}
 	}
 	// Убедимся, что y положительное
 	if x.Cmp(big.NewInt(0)) < 0 {
@@ -1,8 +1,8 @@
 package common
 
 import (
-	"fmt"
 	"math"
+	"math/big"
 )
 
 func GiantBabyStep(a, p, y int64) (int64, error) {
@@ -16,15 +16,24 @@ func GiantBabyStep(a, p, y int64) (int64, error) {
 		mp[num] = i
 	}
 	num = ModularExponentiation(a, m, p)
+	if Tracing() {
+		Trace("bsgs", "baby steps", ValInt("m", m), ValInt("table", int64(len(mp))), ValInt("a^m", num))
+	}
 	if mm, ok := mp[num]; ok {
+		if Tracing() {
+			Trace("bsgs", "match", ValInt("i", 1), ValInt("j", mm), ValInt("x", m-mm))
+		}
 		return m - mm, nil
 	}
 	step := num
 	for i := int64(2); i <= k; i++ {
 		num = (num * step) % p
 		if mm, ok := mp[num]; ok {
+			if Tracing() {
+				Trace("bsgs", "match", ValInt("i", i), ValInt("j", mm), ValInt("x", i*m-mm))
+			}
 			return i*m - mm, nil
 		}
 	}
-	return -1, fmt.Errorf("not found")
+	return -1, &DiscreteLogError{Base: big.NewInt(a), Target: big.NewInt(y), Modulus: big.NewInt(p)}
 }
@@ -0,0 +1,310 @@
+package common
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+)
+
+// ModContext - арифметика по фиксированному нечётному модулю m в форме Монтгомери.
+// Контекст создаётся один раз на модуль (p в ГОСТ, n в голосовании, p в покере) и
+// переиспользуется: таблицы гребёнки FixedBase для постоянных оснований, одновременное
+// возведение MultiExp и операции с постоянным временем. Безопасен для конкурентного
+// использования: временные значения выделяются на вызов.
+type ModContext struct {
+	mod   *big.Int
+	n     int      // длина модуля в словах
+	shift uint     // W*n: R = 2^shift
+	minv  *big.Int // -m^-1 mod R
+	rr    *big.Int // R^2 mod m
+	one   *big.Int // R mod m - единица в форме Монтгомери
+
+	// Те же значения в словах фиксированной ширины для операций с постоянным временем
+	words    []uint // модуль, n слов little-endian
+	m0inv    uint   // -m^-1 mod 2^W
+	rrWords  []uint
+	oneWords []uint
+}
+
+// NewModContext - контекст для нечётного модуля m > 1
+func NewModContext(m *big.Int) (*ModContext, error) {
+	if m.Sign() <= 0 || m.Bit(0) == 0 || m.Cmp(big.NewInt(1)) == 0 {
+		return nil, &ParameterError{Name: "modulus", Reason: fmt.Sprintf("%s is not an odd number greater than 1", m)}
+	}
+	n := len(m.Bits())
+	mc := &ModContext{
+		mod:   new(big.Int).Set(m),
+		n:     n,
+		shift: uint(n * bits.UintSize),
+	}
+	r := new(big.Int).Lsh(big.NewInt(1), mc.shift)
+	mc.minv = new(big.Int).ModInverse(m, r)
+	mc.minv.Sub(r, mc.minv)
+	mc.one = new(big.Int).Mod(r, m)
+	mc.rr = new(big.Int).Mod(new(big.Int).Mul(r, r), m)
+	mc.words = toWords(m, n)
+	mc.m0inv = uint(mc.minv.Bits()[0])
+	mc.rrWords = toWords(mc.rr, n)
+	mc.oneWords = toWords(mc.one, n)
+	return mc, nil
+}
+
+// Modulus - модуль контекста
+func (mc *ModContext) Modulus() *big.Int {
+	return new(big.Int).Set(mc.mod)
+}
+
+// montScratch - временные значения одного вычисления
+type montScratch struct {
+	t, q, low big.Int
+}
+
+// lowWords - x mod R без копирования слов
+func (mc *ModContext) lowWords(z, x *big.Int) *big.Int {
+	w := x.Bits()
+	if len(w) > mc.n {
+		w = w[:mc.n]
+	}
+	return z.SetBits(w)
+}
+
+// montMul - z = x*y*R^-1 mod m (редукция Монтгомери). z может совпадать с x или y.
+func (mc *ModContext) montMul(z, x, y *big.Int, s *montScratch) *big.Int {
+	s.t.Mul(x, y)
+	// q = (t mod R) * (-m^-1) mod R, тогда t + q*m делится на R
+	s.q.Mul(mc.lowWords(&s.low, &s.t), mc.minv)
+	s.q.Mul(mc.lowWords(&s.low, &s.q), mc.mod)
+	s.t.Add(&s.t, &s.q)
+	z.Rsh(&s.t, mc.shift)
+	if z.Cmp(mc.mod) >= 0 {
+		z.Sub(z, mc.mod)
+	}
+	return z
+}
+
+// toMont - x*R mod m
+func (mc *ModContext) toMont(x *big.Int, s *montScratch) *big.Int {
+	z := new(big.Int).Set(x)
+	if x.Sign() < 0 || x.Cmp(mc.mod) >= 0 {
+		z.Mod(x, mc.mod)
+	}
+	return mc.montMul(z, z, mc.rr, s)
+}
+
+// fromMont - x*R^-1 mod m
+func (mc *ModContext) fromMont(x *big.Int, s *montScratch) *big.Int {
+	return mc.montMul(new(big.Int), x, big.NewInt(1), s)
+}
+
+// Mul - a*b mod m
+func (mc *ModContext) Mul(a, b *big.Int) *big.Int {
+	var s montScratch
+	x := mc.toMont(a, &s)
+	return mc.fromMont(mc.montMul(x, x, mc.toMont(b, &s), &s), &s)
+}
+
+// windowSize - ширина скользящего окна для показателя длины nbits
+func windowSize(nbits int) int {
+	switch {
+	case nbits > 768:
+		return 6
+	case nbits > 240:
+		return 5
+	case nbits > 80:
+		return 4
+	case nbits > 24:
+		return 3
+	case nbits > 6:
+		return 2
+	}
+	return 1
+}
+
+// slidingDigits - разложение e на окна: digits[l] = v означает слагаемое v*2^l, v нечётно
+func slidingDigits(e *big.Int, k int) []uint16 {
+	nbits := e.BitLen()
+	digits := make([]uint16, nbits)
+	for i := nbits - 1; i >= 0; {
+		if e.Bit(i) == 0 {
+			i--
+			continue
+		}
+		l := i - k + 1
+		if l < 0 {
+			l = 0
+		}
+		for e.Bit(l) == 0 {
+			l++
+		}
+		var v uint16
+		for j := i; j >= l; j-- {
+			v = v<<1 | uint16(e.Bit(j))
+		}
+		digits[l] = v
+		i = l - 1
+	}
+	return digits
+}
+
+// oddPowers - таблица x, x^3, x^5, ..., x^(2^k-1) в форме Монтгомери
+func (mc *ModContext) oddPowers(x *big.Int, k int, s *montScratch) []*big.Int {
+	tbl := make([]*big.Int, 1<<(k-1))
+	tbl[0] = x
+	if len(tbl) == 1 {
+		return tbl
+	}
+	x2 := mc.montMul(new(big.Int), x, x, s)
+	for i := 1; i < len(tbl); i++ {
+		tbl[i] = mc.montMul(new(big.Int), tbl[i-1], x2, s)
+	}
+	return tbl
+}
+
+// Exp - base^exp mod m. Собственного возведения одного основания у контекста нет:
+// его делает big.Int.Exp, который использует то же представление Монтгомери и
+// скользящее окно, но с ассемблерным ядром умножения, и на порядок длин модулей
+// этого репозитория быстрее реализации на montMul. Выигрыш у big.Int.Exp дают
+// только таблицы FixedBase.
+func (mc *ModContext) Exp(base, exp *big.Int) *big.Int {
+	return new(big.Int).Exp(base, exp, mc.mod)
+}
+
+// Exp2 - a^u1 * y^u2 mod m за один проход (трюк Шамира). Общие возведения в квадрат
+// не окупают montMul без ассемблера: Exp2 не быстрее двух big.Int.Exp. Для оснований,
+// которые повторяются от вызова к вызову, как A и Y в проверке подписи ГОСТ, быстрее FixedBase.
+func (mc *ModContext) Exp2(a, u1, y, u2 *big.Int) *big.Int {
+	return mc.multiExp([]*big.Int{a, y}, []*big.Int{u1, u2})
+}
+
+// MultiExp - произведение bases[i]^exps[i] mod m (алгоритм Штрауса): возведения в
+// квадрат общие для всех оснований, у каждого основания своё скользящее окно
+func (mc *ModContext) MultiExp(bases, exps []*big.Int) (*big.Int, error) {
+	if len(bases) != len(exps) {
+		return nil, &ParameterError{Name: "exps", Reason: fmt.Sprintf("got %d exponents for %d bases", len(exps), len(bases))}
+	}
+	return mc.multiExp(bases, exps), nil
+}
+
+// multiExp - MultiExp для срезов одной длины
+func (mc *ModContext) multiExp(bases, exps []*big.Int) *big.Int {
+	if len(bases) == 1 {
+		return mc.Exp(bases[0], exps[0])
+	}
+	for _, e := range exps {
+		if e.Sign() < 0 {
+			// Редкий случай: вычисляем каждое основание отдельно
+			res := big.NewInt(1)
+			for i := range bases {
+				res = mc.Mul(res, mc.Exp(bases[i], exps[i]))
+			}
+			return res
+		}
+	}
+	var s montScratch
+	nbits := 0
+	tables := make([][]*big.Int, len(bases))
+	digits := make([][]uint16, len(bases))
+	for i := range bases {
+		k := windowSize(exps[i].BitLen())
+		digits[i] = slidingDigits(exps[i], k)
+		tables[i] = mc.oddPowers(mc.toMont(bases[i], &s), k, &s)
+		if len(digits[i]) > nbits {
+			nbits = len(digits[i])
+		}
+	}
+	acc := new(big.Int).Set(mc.one)
+	started := false
+	for pos := nbits - 1; pos >= 0; pos-- {
+		if started {
+			mc.montMul(acc, acc, acc, &s)
+		}
+		for i := range bases {
+			if pos >= len(digits[i]) || digits[i][pos] == 0 {
+				continue
+			}
+			entry := tables[i][digits[i][pos]>>1]
+			if started {
+				mc.montMul(acc, acc, entry, &s)
+			} else {
+				acc.Set(entry)
+				started = true
+			}
+		}
+	}
+	return mc.fromMont(acc, &s)
+}
+
+// FixedBase - таблица гребёнки Лим-Ли для многократного возведения одного основания
+// (генераторов G, A) в степени длиной до maxBits бит
+type FixedBase struct {
+	mc    *ModContext
+	base  *big.Int
+	bits  int        // максимальная длина показателя
+	teeth int        // число зубьев гребёнки h
+	span  int        // a = ceil(bits / h)
+	table []*big.Int // table[mask] = prod g^(2^(i*a)) по битам mask, форма Монтгомери
+	words [][]uint   // та же таблица в словах фиксированной ширины
+}
+
+// NewFixedBase - предвычисление таблицы для основания g и показателей до maxBits бит
+func (mc *ModContext) NewFixedBase(g *big.Int, maxBits int) *FixedBase {
+	if maxBits < 1 {
+		maxBits = 1
+	}
+	h := 8
+	if maxBits < h {
+		h = maxBits
+	}
+	a := (maxBits + h - 1) / h
+	var s montScratch
+	// gi[i] = g^(2^(i*a))
+	gi := make([]*big.Int, h)
+	gi[0] = mc.toMont(g, &s)
+	for i := 1; i < h; i++ {
+		gi[i] = new(big.Int).Set(gi[i-1])
+		for j := 0; j < a; j++ {
+			mc.montMul(gi[i], gi[i], gi[i], &s)
+		}
+	}
+	table := make([]*big.Int, 1<<h)
+	table[0] = mc.one
+	for mask := 1; mask < len(table); mask++ {
+		low := bits.TrailingZeros(uint(mask))
+		table[mask] = mc.montMul(new(big.Int), table[mask&^(1<<low)], gi[low], &s)
+	}
+	words := make([][]uint, len(table))
+	for i, v := range table {
+		words[i] = toWords(v, mc.n)
+	}
+	return &FixedBase{mc: mc, base: new(big.Int).Set(g), bits: maxBits, teeth: h, span: a, table: table, words: words}
+}
+
+// Exp - g^e mod m: a возведений в квадрат и не более a умножений
+func (fb *FixedBase) Exp(e *big.Int) *big.Int {
+	if e.Sign() < 0 || e.BitLen() > fb.bits {
+		return fb.mc.Exp(fb.base, e)
+	}
+	mc := fb.mc
+	var s montScratch
+	acc := new(big.Int).Set(mc.one)
+	started := false
+	for col := fb.span - 1; col >= 0; col-- {
+		if started {
+			mc.montMul(acc, acc, acc, &s)
+		}
+		idx := 0
+		for i := fb.teeth - 1; i >= 0; i-- {
+			idx = idx<<1 | int(e.Bit(i*fb.span+col))
+		}
+		if idx == 0 {
+			continue
+		}
+		if started {
+			mc.montMul(acc, acc, fb.table[idx], &s)
+		} else {
+			acc.Set(fb.table[idx])
+			started = true
+		}
+	}
+	return mc.fromMont(acc, &s)
+}
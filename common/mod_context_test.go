@@ -0,0 +1,219 @@
+package common
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// randomOdd - случайное нечётное число заданной длины
+func randomOdd(t testing.TB, bits int) *big.Int {
+	t.Helper()
+	max := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.SetBit(n, bits-1, 1)
+	n.SetBit(n, 0, 1)
+	return n
+}
+
+func randomBelow(t testing.TB, bits int) *big.Int {
+	t.Helper()
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), uint(bits)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestNewModContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       *big.Int
+		wantErr bool
+	}{
+		{name: "нечётный модуль", m: big.NewInt(13)},
+		{name: "чётный модуль", m: big.NewInt(12), wantErr: true},
+		{name: "единица", m: big.NewInt(1), wantErr: true},
+		{name: "отрицательный", m: big.NewInt(-7), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewModContext(tt.m)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewModContext() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidParameters) {
+				t.Errorf("NewModContext() error = %v, want ErrInvalidParameters", err)
+			}
+		})
+	}
+}
+
+func TestModContextExp(t *testing.T) {
+	tests := []struct {
+		name    string
+		modBits int
+		expBits int
+	}{
+		{name: "одно слово", modBits: 61, expBits: 61},
+		{name: "граница слова", modBits: 64, expBits: 20},
+		{name: "256 бит", modBits: 256, expBits: 256},
+		{name: "1024 бит, короткий показатель", modBits: 1024, expBits: 160},
+		{name: "2048 бит", modBits: 2048, expBits: 2048},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := randomOdd(t, tt.modBits)
+			mc, err := NewModContext(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 10; i++ {
+				// Основание может быть больше модуля
+				base := randomBelow(t, tt.modBits+8)
+				exp := randomBelow(t, tt.expBits)
+				want := new(big.Int).Exp(base, exp, m)
+				if got := mc.Exp(base, exp); got.Cmp(want) != 0 {
+					t.Fatalf("Exp(%s, %s) = %s, want %s", base, exp, got, want)
+				}
+			}
+			if got := mc.Exp(big.NewInt(5), big.NewInt(0)); got.Cmp(big.NewInt(1)) != 0 {
+				t.Errorf("Exp(5, 0) = %s, want 1", got)
+			}
+			if got := mc.Exp(big.NewInt(0), big.NewInt(7)); got.Sign() != 0 {
+				t.Errorf("Exp(0, 7) = %s, want 0", got)
+			}
+		})
+	}
+}
+
+func TestModContextExp2(t *testing.T) {
+	m := randomOdd(t, 1024)
+	mc, err := NewModContext(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		a, y := randomBelow(t, 1024), randomBelow(t, 1024)
+		u1, u2 := randomBelow(t, 256), randomBelow(t, 200)
+		want := new(big.Int).Mul(new(big.Int).Exp(a, u1, m), new(big.Int).Exp(y, u2, m))
+		want.Mod(want, m)
+		if got := mc.Exp2(a, u1, y, u2); got.Cmp(want) != 0 {
+			t.Fatalf("Exp2() = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestModContextMultiExp(t *testing.T) {
+	m := randomOdd(t, 512)
+	mc, err := NewModContext(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bases := make([]*big.Int, 5)
+	exps := make([]*big.Int, 5)
+	want := big.NewInt(1)
+	for i := range bases {
+		bases[i] = randomBelow(t, 512)
+		exps[i] = randomBelow(t, 40*(i+1))
+		want.Mul(want, new(big.Int).Exp(bases[i], exps[i], m)).Mod(want, m)
+	}
+	got, err := mc.MultiExp(bases, exps)
+	if err != nil || got.Cmp(want) != 0 {
+		t.Fatalf("MultiExp() = %v, %v, want %s", got, err, want)
+	}
+	if _, err = mc.MultiExp(bases, exps[:4]); !errors.Is(err, ErrInvalidParameters) {
+		t.Errorf("MultiExp() with 4 exponents for 5 bases error = %v, want %v", err, ErrInvalidParameters)
+	}
+	if got := mc.Mul(bases[0], bases[1]); got.Cmp(new(big.Int).Mod(new(big.Int).Mul(bases[0], bases[1]), m)) != 0 {
+		t.Errorf("Mul() = %s", got)
+	}
+}
+
+func TestFixedBase(t *testing.T) {
+	m := randomOdd(t, 1024)
+	mc, err := NewModContext(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := randomBelow(t, 1024)
+	fb := mc.NewFixedBase(g, 256)
+	exps := []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(2), randomBelow(t, 256), randomBelow(t, 256), randomBelow(t, 300)}
+	for _, e := range exps {
+		want := new(big.Int).Exp(g, e, m)
+		if got := fb.Exp(e); got.Cmp(want) != 0 {
+			t.Fatalf("FixedBase.Exp(%s) = %s, want %s", e, got, want)
+		}
+	}
+	small := mc.NewFixedBase(big.NewInt(3), 5)
+	if got := small.Exp(big.NewInt(31)); got.Cmp(new(big.Int).Exp(big.NewInt(3), big.NewInt(31), m)) != 0 {
+		t.Errorf("FixedBase(maxBits=5).Exp(31) = %s", got)
+	}
+}
+
+func benchmarkParams(b *testing.B) (*big.Int, *big.Int, *big.Int) {
+	m := randomOdd(b, 1024)
+	return m, randomBelow(b, 1024), randomBelow(b, 256)
+}
+
+func BenchmarkExpBigInt(b *testing.B) {
+	m, base, exp := benchmarkParams(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		new(big.Int).Exp(base, exp, m)
+	}
+}
+
+func BenchmarkExpModularExponentiationBig(b *testing.B) {
+	m, base, exp := benchmarkParams(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ModularExponentiationBig(base, exp, m)
+	}
+}
+
+func BenchmarkExpFixedBase(b *testing.B) {
+	m, base, exp := benchmarkParams(b)
+	mc, _ := NewModContext(m)
+	fb := mc.NewFixedBase(base, 256)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fb.Exp(exp)
+	}
+}
+
+func BenchmarkExp2BigInt(b *testing.B) {
+	m, a, u1 := benchmarkParams(b)
+	y, u2 := randomBelow(b, 1024), randomBelow(b, 256)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v := new(big.Int).Mul(new(big.Int).Exp(a, u1, m), new(big.Int).Exp(y, u2, m))
+		v.Mod(v, m)
+	}
+}
+
+// BenchmarkExp2FixedBase - проверка подписи ГОСТ: оба основания (A и ключ y) известны заранее
+func BenchmarkExp2FixedBase(b *testing.B) {
+	m, a, u1 := benchmarkParams(b)
+	y, u2 := randomBelow(b, 1024), randomBelow(b, 256)
+	mc, _ := NewModContext(m)
+	fa, fy := mc.NewFixedBase(a, 256), mc.NewFixedBase(y, 256)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mc.Mul(fa.Exp(u1), fy.Exp(u2))
+	}
+}
+
+func BenchmarkExp2ModContext(b *testing.B) {
+	m, a, u1 := benchmarkParams(b)
+	y, u2 := randomBelow(b, 1024), randomBelow(b, 256)
+	mc, _ := NewModContext(m)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mc.Exp2(a, u1, y, u2)
+	}
+}
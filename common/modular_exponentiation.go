@@ -21,26 +21,22 @@ func ModularExponentiation(a, x, p int64) int64 {
 	return y
 }
 
-// ModularExponentiationBig выполняет возведения a^x mod p для больших чисел
+// ModularExponentiationBig выполняет возведения a^x mod p для больших чисел.
+// Для многократных возведений по одному модулю см. ModContext.
 func ModularExponentiationBig(a, x, p *big.Int) *big.Int {
 	// Инициализируем результат как 1
 	result := big.NewInt(1)
 	base := new(big.Int).Mod(a, p) // Приводим `a` к модулю `p`
-	exp := new(big.Int).Set(x)     // Копия `x` для модификации в цикле
-	// Используем цикл для быстрого возведения в степень
-	zero := big.NewInt(0)
-	one := big.NewInt(1)
-	for exp.Cmp(zero) > 0 {
+	// Проходим по битам экспоненты от младшего к старшему, не копируя её
+	for i := 0; i < x.BitLen(); i++ {
 		// Если текущий бит экспоненты равен 1, умножаем результат на базу
-		if new(big.Int).And(exp, one).Cmp(one) == 0 {
+		if x.Bit(i) == 1 {
 			result.Mul(result, base)
 			result.Mod(result, p)
 		}
 		// Возводим базу в квадрат и берем по модулю `p`
 		base.Mul(base, base)
 		base.Mod(base, p)
-		// Сдвигаем экспоненту вправо на 1 бит (деление на 2)
-		exp.Rsh(exp, 1)
 	}
 	return result
 }
@@ -1,6 +1,8 @@
 package common
 
 import (
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/factor"
 	"math/big"
 	"math/rand"
 	"runtime"
@@ -62,41 +64,49 @@ func GenCoprime(n int64, minV int64, maxV int64) int64 {
 	}
 }
 
-// PrimitiveRoot - находит примитивный корень для простого числа p
+// PrimitiveRoot - находит примитивный корень для простого числа p; -1, если p не простое.
+// Вычисления идут в big.Int: произведения в int64 переполняются уже для p порядка 10^10.
 func PrimitiveRoot(p int64) int64 {
-	if !IsPrime(p) {
+	g, err := PrimitiveRootBig(big.NewInt(p))
+	if err != nil {
 		return -1 // p должно быть простым числом
 	}
-	var factors []int64
-	phi := p - 1 // φ(p) = p-1 для простых чисел
-	// Разложение φ(p) на простые множители
-	n := phi
-	for i := int64(2); i*i <= n; i++ {
-		if n%i == 0 {
-			factors = append(factors, i)
-			for n%i == 0 {
-				n /= i
-			}
-		}
-	}
-	if n > 1 {
-		factors = append(factors, n)
-	}
-	// Ищем минимальное g, такое что g^((p-1)/f) mod p != 1 для каждого простого делителя f
-	for g := int64(2); g < p; g++ {
+	return g.Int64()
+}
+
+// PrimitiveRootBig - наименьший примитивный корень по модулю простого p.
+// Ошибка factor.ErrNoFactor - если не удалось разложить p-1.
+func PrimitiveRootBig(p *big.Int) (*big.Int, error) {
+	if p.Cmp(big.NewInt(2)) < 0 || !p.ProbablyPrime(20) {
+		return nil, &ParameterError{Name: "p", Reason: fmt.Sprintf("%s is not prime", p)}
+	}
+	if p.Cmp(big.NewInt(2)) == 0 {
+		return big.NewInt(1), nil
+	}
+	phi := new(big.Int).Sub(p, big.NewInt(1))
+	factors, err := factor.Factor(phi)
+	if err != nil {
+		return nil, err
+	}
+	// Показатели (p-1)/f для каждого простого делителя f
+	exps := make([]*big.Int, len(factors))
+	for i, f := range factors {
+		exps[i] = new(big.Int).Quo(phi, f.P)
+	}
+	for g := big.NewInt(2); g.Cmp(p) < 0; g.Add(g, big.NewInt(1)) {
 		isPrimitive := true
-		for _, factor := range factors {
-			// Проверяем, что g^((p-1)/factor) % p != 1
-			if ModularExponentiation(g, phi/factor, p) == 1 {
+		for _, e := range exps {
+			// g^((p-1)/f) = 1 - порядок g меньше p-1
+			if new(big.Int).Exp(g, e, p).Cmp(big.NewInt(1)) == 0 {
 				isPrimitive = false
 				break
 			}
 		}
 		if isPrimitive {
-			return g
+			return new(big.Int).Set(g), nil
 		}
 	}
-	return -1 // Если не найдено (теоретически не должно происходить)
+	return nil, &ParameterError{Name: "p", Reason: fmt.Sprintf("no primitive root modulo %s", p)}
 }
 
 func GenPrimeBig(minV, maxV *big.Int) *big.Int {
@@ -109,6 +119,29 @@ func GenPrimeBig(minV, maxV *big.Int) *big.Int {
 	}
 }
 
+// GenPrimeBitsBig - простое длиной ровно bits бит. Тест Ферма в GenPrimeBig пропускает
+// числа Кармайкла, поэтому кандидаты дополнительно проверяются тестом Миллера-Рабина.
+func GenPrimeBitsBig(bits int) *big.Int {
+	minV := new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+	maxV := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	for {
+		p := GenPrimeBig(minV, maxV)
+		if p.ProbablyPrime(20) {
+			return p
+		}
+	}
+}
+
+// GenBlumPrimeBig - простое длиной bits бит, сравнимое с 3 по модулю 4 (простое Блюма)
+func GenBlumPrimeBig(bits int) *big.Int {
+	for {
+		p := GenPrimeBitsBig(bits)
+		if p.Bit(0) == 1 && p.Bit(1) == 1 {
+			return p
+		}
+	}
+}
+
 // IsPrimeBig проверяет, является ли число простым (используется алгоритм Ферма)
 func IsPrimeBig(p *big.Int) bool {
 	one := big.NewInt(1)
@@ -152,10 +185,10 @@ func SeedBig() *rand.Rand {
 }
 
 // GenCoprimeBig генерирует случайное число num в диапазоне [minV, maxV] такое, что GCD(n, num) = 1
-func GenCoprimeBig(n, minV, maxV *big.Int) *big.Int {
+func GenCoprimeBig(n, minV, maxV *big.Int) (*big.Int, error) {
 	// Проверяем, что minV < maxV
 	if minV.Cmp(maxV) >= 0 {
-		return nil // Или можно выбросить ошибку
+		return nil, &ParameterError{Name: "range", Reason: fmt.Sprintf("minimum %s is not less than maximum %s", minV, maxV)}
 	}
 	// Разница maxV - minV для генерации значения в диапазоне
 	rangeSize := new(big.Int).Sub(maxV, minV)
@@ -165,7 +198,7 @@ func GenCoprimeBig(n, minV, maxV *big.Int) *big.Int {
 		num.Add(num, minV) // num = num + minV
 		// Проверяем, что num взаимно просто с n (GCD(n, num) == 1)
 		if new(big.Int).GCD(nil, nil, n, num).Cmp(big.NewInt(1)) == 0 {
-			return num
+			return num, nil
 		}
 	}
 }
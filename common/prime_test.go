@@ -0,0 +1,69 @@
+package common
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestPrimitiveRoot(t *testing.T) {
+	tests := []struct {
+		name string
+		p    int64
+		want int64
+	}{
+		{name: "p = 7", p: 7, want: 3},
+		{name: "p = 23", p: 23, want: 5},
+		{name: "p = 41", p: 41, want: 6},
+		{name: "p = 10^9+7", p: 1_000_000_007, want: 5},
+		{name: "p порядка 10^10", p: 10_000_000_019, want: 2},
+		{name: "составное p", p: 3 * 1_000_000_007, want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PrimitiveRoot(tt.p); got != tt.want {
+				t.Errorf("PrimitiveRoot(%d) = %d, want %d", tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrimitiveRootBig(t *testing.T) {
+	mersenne61 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 61), big.NewInt(1))
+	tests := []struct {
+		name    string
+		p       *big.Int
+		want    *big.Int
+		wantErr error
+	}{
+		{name: "p = 2", p: big.NewInt(2), want: big.NewInt(1)},
+		{name: "p = 11", p: big.NewInt(11), want: big.NewInt(2)},
+		{name: "p = 2^61-1", p: mersenne61, want: big.NewInt(37)},
+		{name: "составное p", p: big.NewInt(1_000_000_007 * 3), wantErr: ErrInvalidParameters},
+		{name: "p = 1", p: big.NewInt(1), wantErr: ErrInvalidParameters},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PrimitiveRootBig(tt.p)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PrimitiveRootBig(%s) error = %v, want %v", tt.p, err, tt.wantErr)
+			}
+			if tt.want != nil && got.Cmp(tt.want) != 0 {
+				t.Errorf("PrimitiveRootBig(%s) = %s, want %s", tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenPrimeBitsBig(t *testing.T) {
+	for _, bits := range []int{16, 64, 128} {
+		p := GenPrimeBitsBig(bits)
+		if p.BitLen() != bits || !p.ProbablyPrime(20) {
+			t.Errorf("GenPrimeBitsBig(%d) = %s, want a %d-bit prime", bits, p, bits)
+		}
+		b := GenBlumPrimeBig(bits)
+		if b.BitLen() != bits || !b.ProbablyPrime(20) || b.Bit(0) != 1 || b.Bit(1) != 1 {
+			t.Errorf("GenBlumPrimeBig(%d) = %s, want a %d-bit prime = 3 mod 4", bits, b, bits)
+		}
+	}
+}
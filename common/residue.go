@@ -0,0 +1,164 @@
+package common
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Jacobi - символ Якоби (a/n) для нечётного n > 0; для остальных n паникует, как big.Jacobi
+func Jacobi(a, n int64) int {
+	if n <= 0 || n%2 == 0 {
+		panic(fmt.Sprintf("common: Jacobi symbol for invalid n = %d", n))
+	}
+	a %= n
+	if a < 0 {
+		a += n
+	}
+	res := 1
+	for a != 0 {
+		// (2/n) = -1 при n = 3, 5 (mod 8)
+		for a%2 == 0 {
+			a /= 2
+			if r := n % 8; r == 3 || r == 5 {
+				res = -res
+			}
+		}
+		// Квадратичный закон взаимности: знак меняется, если a = n = 3 (mod 4)
+		a, n = n, a
+		if a%4 == 3 && n%4 == 3 {
+			res = -res
+		}
+		a %= n
+	}
+	if n != 1 {
+		return 0
+	}
+	return res
+}
+
+// JacobiBig - символ Якоби (a/n) для нечётного n > 0
+func JacobiBig(a, n *big.Int) int {
+	if n.Sign() <= 0 || n.Bit(0) == 0 {
+		panic(fmt.Sprintf("common: Jacobi symbol for invalid n = %s", n))
+	}
+	x := new(big.Int).Mod(a, n)
+	y := new(big.Int).Set(n)
+	res := 1
+	for x.Sign() != 0 {
+		s := x.TrailingZeroBits()
+		x.Rsh(x, s)
+		if r := y.Bits()[0] & 7; s%2 == 1 && (r == 3 || r == 5) {
+			res = -res
+		}
+		x, y = y, x
+		if x.Bits()[0]&3 == 3 && y.Bits()[0]&3 == 3 {
+			res = -res
+		}
+		x.Mod(x, y)
+	}
+	if y.Cmp(big.NewInt(1)) != 0 {
+		return 0
+	}
+	return res
+}
+
+// Legendre - символ Лежандра (a/p) для нечётного простого p: 1, -1 или 0
+func Legendre(a, p int64) int {
+	return Jacobi(a, p)
+}
+
+// LegendreBig - символ Лежандра (a/p) для нечётного простого p
+func LegendreBig(a, p *big.Int) int {
+	return JacobiBig(a, p)
+}
+
+// sqrtModCheck - a mod p для нечётного простого p; ошибка, если a - квадратичный невычет
+func sqrtModCheck(a, p *big.Int) (*big.Int, error) {
+	if p.Cmp(big.NewInt(3)) < 0 || p.Bit(0) == 0 || !p.ProbablyPrime(20) {
+		return nil, &ParameterError{Name: "p", Reason: fmt.Sprintf("%s is not an odd prime", p)}
+	}
+	x := new(big.Int).Mod(a, p)
+	if LegendreBig(x, p) == -1 {
+		return nil, &NoSquareRootError{A: new(big.Int).Set(a), P: new(big.Int).Set(p)}
+	}
+	return x, nil
+}
+
+// TonelliShanks - квадратный корень x из a по модулю нечётного простого p (x^2 = a),
+// алгоритм Тонелли-Шенкса. Второй корень равен p - x.
+func TonelliShanks(a, p *big.Int) (*big.Int, error) {
+	a, err := sqrtModCheck(a, p)
+	if err != nil {
+		return nil, err
+	}
+	if a.Sign() == 0 {
+		return a, nil
+	}
+	one := big.NewInt(1)
+	pm1 := new(big.Int).Sub(p, one)
+	// p - 1 = q * 2^s, q нечётное
+	s := pm1.TrailingZeroBits()
+	q := new(big.Int).Rsh(pm1, s)
+	// z - квадратичный невычет
+	z := big.NewInt(2)
+	for LegendreBig(z, p) != -1 {
+		z.Add(z, one)
+	}
+	m := s
+	c := new(big.Int).Exp(z, q, p)
+	t := new(big.Int).Exp(a, q, p)
+	r := new(big.Int).Exp(a, new(big.Int).Rsh(new(big.Int).Add(q, one), 1), p)
+	for t.Cmp(one) != 0 {
+		// Наименьшее i, при котором t^(2^i) = 1
+		i := uint(0)
+		for t2 := new(big.Int).Set(t); t2.Cmp(one) != 0; i++ {
+			t2.Mul(t2, t2).Mod(t2, p)
+		}
+		b := new(big.Int).Exp(c, new(big.Int).Lsh(one, m-i-1), p)
+		m = i
+		c.Mul(b, b).Mod(c, p)
+		t.Mul(t, c).Mod(t, p)
+		r.Mul(r, b).Mod(r, p)
+	}
+	return r, nil
+}
+
+// Cipolla - квадратный корень x из a по модулю нечётного простого p алгоритмом Чиполлы:
+// x = (t + w)^((p+1)/2) в F_p(w), w^2 = t^2 - a - невычет
+func Cipolla(a, p *big.Int) (*big.Int, error) {
+	a, err := sqrtModCheck(a, p)
+	if err != nil {
+		return nil, err
+	}
+	if a.Sign() == 0 {
+		return a, nil
+	}
+	one := big.NewInt(1)
+	t := big.NewInt(0)
+	w2 := new(big.Int)
+	for {
+		t.Add(t, one)
+		w2.Mul(t, t).Sub(w2, a).Mod(w2, p)
+		if LegendreBig(w2, p) == -1 {
+			break
+		}
+	}
+	// (x0 + x1*w) * (y0 + y1*w) = (x0*y0 + x1*y1*w^2) + (x0*y1 + x1*y0)*w
+	mul := func(x0, x1, y0, y1 *big.Int) (*big.Int, *big.Int) {
+		r0 := new(big.Int).Mul(x1, y1)
+		r0.Mul(r0, w2).Add(r0, new(big.Int).Mul(x0, y0)).Mod(r0, p)
+		r1 := new(big.Int).Mul(x0, y1)
+		r1.Add(r1, new(big.Int).Mul(x1, y0)).Mod(r1, p)
+		return r0, r1
+	}
+	e := new(big.Int).Rsh(new(big.Int).Add(p, one), 1)
+	r0, r1 := big.NewInt(1), big.NewInt(0)
+	b0, b1 := new(big.Int).Set(t), big.NewInt(1)
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		r0, r1 = mul(r0, r1, r0, r1)
+		if e.Bit(i) == 1 {
+			r0, r1 = mul(r0, r1, b0, b1)
+		}
+	}
+	return r0, nil
+}
@@ -0,0 +1,125 @@
+package common
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestJacobi(t *testing.T) {
+	tests := []struct {
+		name string
+		a, n int64
+		want int
+	}{
+		{name: "(1/1)", a: 1, n: 1, want: 1},
+		{name: "(2/7) = 1", a: 2, n: 7, want: 1},
+		{name: "(3/7) = -1", a: 3, n: 7, want: -1},
+		{name: "(1001/9907) = -1", a: 1001, n: 9907, want: -1},
+		{name: "(19/45) = 1", a: 19, n: 45, want: 1},
+		{name: "(8/21) = -1", a: 8, n: 21, want: -1},
+		{name: "общий делитель", a: 6, n: 15, want: 0},
+		{name: "отрицательное a", a: -1, n: 7, want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Jacobi(tt.a, tt.n); got != tt.want {
+				t.Errorf("Jacobi(%d, %d) = %d, want %d", tt.a, tt.n, got, tt.want)
+			}
+			if got := JacobiBig(big.NewInt(tt.a), big.NewInt(tt.n)); got != tt.want {
+				t.Errorf("JacobiBig(%d, %d) = %d, want %d", tt.a, tt.n, got, tt.want)
+			}
+		})
+	}
+	// Сверка с math/big на случайных числах
+	for i := 0; i < 200; i++ {
+		n := randomOdd(t, 16+i%200)
+		a := randomBelow(t, 256)
+		if i%2 == 1 {
+			a.Neg(a)
+		}
+		if got, want := JacobiBig(a, n), big.Jacobi(a, n); got != want {
+			t.Fatalf("JacobiBig(%s, %s) = %d, want %d", a, n, got, want)
+		}
+		if n.IsInt64() && a.IsInt64() {
+			if got, want := Jacobi(a.Int64(), n.Int64()), big.Jacobi(a, n); got != want {
+				t.Fatalf("Jacobi(%s, %s) = %d, want %d", a, n, got, want)
+			}
+		}
+	}
+}
+
+func TestLegendre(t *testing.T) {
+	// Квадратичные вычеты по модулю 11: 1, 3, 4, 5, 9
+	want := []int{0, 1, -1, 1, 1, 1, -1, -1, -1, 1, -1}
+	for a := int64(0); a < 11; a++ {
+		if got := Legendre(a, 11); got != want[a] {
+			t.Errorf("Legendre(%d, 11) = %d, want %d", a, got, want[a])
+		}
+		if got := LegendreBig(big.NewInt(a), big.NewInt(11)); got != want[a] {
+			t.Errorf("LegendreBig(%d, 11) = %d, want %d", a, got, want[a])
+		}
+	}
+}
+
+func TestSquareRoot(t *testing.T) {
+	p256, err := rand.Prime(rand.Reader, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		a, p    *big.Int
+		wantErr error
+	}{
+		{name: "p = 3 (mod 4)", a: big.NewInt(2), p: big.NewInt(7)},
+		{name: "p = 1 (mod 8)", a: big.NewInt(10), p: big.NewInt(13)},
+		{name: "p = 119*2^23+1", a: big.NewInt(123456789 * 123456789 % 998244353), p: big.NewInt(998244353)},
+		{name: "p = 3*2^30+1", a: big.NewInt(987654321 * 987654321 % 3221225473), p: big.NewInt(3221225473)},
+		{name: "ноль", a: big.NewInt(0), p: big.NewInt(13)},
+		{name: "a больше p", a: big.NewInt(40), p: big.NewInt(13)},
+		{name: "невычет", a: big.NewInt(3), p: big.NewInt(7), wantErr: ErrNoSquareRoot},
+		{name: "чётный модуль", a: big.NewInt(1), p: big.NewInt(8), wantErr: ErrInvalidParameters},
+		{name: "составной модуль 9", a: big.NewInt(4), p: big.NewInt(9), wantErr: ErrInvalidParameters},
+		{name: "составной модуль 15", a: big.NewInt(4), p: big.NewInt(15), wantErr: ErrInvalidParameters},
+	}
+	// Случайные квадраты по модулю 256-битного простого
+	for i := 0; i < 5; i++ {
+		x := randomBelow(t, 255)
+		tests = append(tests, struct {
+			name    string
+			a, p    *big.Int
+			wantErr error
+		}{name: "случайный квадрат", a: x.Mul(x, x).Mod(x, p256), p: p256})
+	}
+	methods := []struct {
+		name string
+		sqrt func(a, p *big.Int) (*big.Int, error)
+	}{
+		{name: "Тонелли-Шенкс", sqrt: TonelliShanks},
+		{name: "Чиполла", sqrt: Cipolla},
+	}
+	for _, m := range methods {
+		for _, tt := range tests {
+			t.Run(m.name+": "+tt.name, func(t *testing.T) {
+				got, err := m.sqrt(tt.a, tt.p)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				if err != nil {
+					return
+				}
+				sq := new(big.Int).Mul(got, got)
+				if sq.Mod(sq, tt.p).Cmp(new(big.Int).Mod(tt.a, tt.p)) != 0 {
+					t.Errorf("%s^2 != %s mod %s", got, tt.a, tt.p)
+				}
+				// math/big возвращает один из двух корней
+				want := new(big.Int).ModSqrt(tt.a, tt.p)
+				if got.Cmp(want) != 0 && new(big.Int).Sub(tt.p, got).Cmp(want) != 0 {
+					t.Errorf("root %s differs from math/big %s", got, want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package common
+
+import (
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/factor"
+	"math/big"
+)
+
+// EulerPhi - функция Эйлера φ(n) по разложению n = prod p^e: prod p^(e-1) * (p-1)
+func EulerPhi(factors []factor.PrimePower) *big.Int {
+	phi := big.NewInt(1)
+	for _, pp := range factors {
+		phi.Mul(phi, new(big.Int).Exp(pp.P, big.NewInt(int64(pp.E-1)), nil))
+		phi.Mul(phi, new(big.Int).Sub(pp.P, big.NewInt(1)))
+	}
+	return phi
+}
+
+// CarmichaelLambda - функция Кармайкла λ(n) по разложению n: НОК λ(p^e), где
+// λ(2) = 1, λ(4) = 2, λ(2^e) = 2^(e-2) при e >= 3, λ(p^e) = φ(p^e) для нечётных p
+func CarmichaelLambda(factors []factor.PrimePower) *big.Int {
+	lambda := big.NewInt(1)
+	for _, pp := range factors {
+		l := EulerPhi([]factor.PrimePower{pp})
+		if pp.P.Cmp(big.NewInt(2)) == 0 && pp.E >= 3 {
+			l.Rsh(l, 1)
+		}
+		// НОК(a, b) = a / НОД(a, b) * b
+		g := new(big.Int).GCD(nil, nil, lambda, l)
+		lambda.Quo(lambda, g).Mul(lambda, l)
+	}
+	return lambda
+}
+
+// MultiplicativeOrder - наименьшее k > 0, при котором a^k = 1 (mod n); a и n > 1 взаимно просты.
+// Порядок делит λ(n), поэтому из λ(n) убираются простые множители, пока a^k остаётся равным 1.
+func MultiplicativeOrder(a, n *big.Int) (*big.Int, error) {
+	if n.Cmp(big.NewInt(1)) <= 0 {
+		return nil, &ParameterError{Name: "n", Reason: fmt.Sprintf("modulus %s is not greater than 1", n)}
+	}
+	if new(big.Int).GCD(nil, nil, new(big.Int).Mod(a, n), n).Cmp(big.NewInt(1)) != 0 {
+		return nil, &NoInverseError{A: new(big.Int).Set(a), M: new(big.Int).Set(n)}
+	}
+	factors, err := factor.Factor(n)
+	if err != nil {
+		return nil, err
+	}
+	order := CarmichaelLambda(factors)
+	if factors, err = factor.Factor(order); err != nil {
+		return nil, err
+	}
+	for _, pp := range factors {
+		for i := 0; i < pp.E; i++ {
+			k := new(big.Int).Quo(order, pp.P)
+			if new(big.Int).Exp(a, k, n).Cmp(big.NewInt(1)) != 0 {
+				break
+			}
+			order = k
+		}
+	}
+	return order, nil
+}
+
+// IsPrimitiveRootBig - является ли g первообразным корнем по модулю n > 1,
+// т.е. порождает ли g всю мультипликативную группу: g^(φ(n)/q) != 1 для всех простых q | φ(n).
+// Ошибка - если n или φ(n) не удалось разложить.
+func IsPrimitiveRootBig(g, n *big.Int) (bool, error) {
+	if n.Cmp(big.NewInt(1)) <= 0 || new(big.Int).GCD(nil, nil, new(big.Int).Mod(g, n), n).Cmp(big.NewInt(1)) != 0 {
+		return false, nil
+	}
+	factors, err := factor.Factor(n)
+	if err != nil {
+		return false, err
+	}
+	phi := EulerPhi(factors)
+	if factors, err = factor.Factor(phi); err != nil {
+		return false, err
+	}
+	for _, pp := range factors {
+		if new(big.Int).Exp(g, new(big.Int).Quo(phi, pp.P), n).Cmp(big.NewInt(1)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
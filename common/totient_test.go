@@ -0,0 +1,154 @@
+package common
+
+import (
+	"errors"
+	"github.com/Raimguzhinov/protect-information/factor"
+	"math/big"
+	"testing"
+)
+
+// bruteOrder - порядок a по модулю n перебором; 0, если a необратимо
+func bruteOrder(a, n int64) int64 {
+	if GCD(a, n) != 1 {
+		return 0
+	}
+	x := a % n
+	for k := int64(1); ; k++ {
+		if x == 1%n {
+			return k
+		}
+		x = x * a % n
+	}
+}
+
+func TestEulerPhiAndCarmichaelLambda(t *testing.T) {
+	tests := []struct {
+		name       string
+		n          int64
+		wantPhi    int64
+		wantLambda int64
+	}{
+		{name: "n = 1", n: 1, wantPhi: 1, wantLambda: 1},
+		{name: "n = 8", n: 8, wantPhi: 4, wantLambda: 2},
+		{name: "n = 15", n: 15, wantPhi: 8, wantLambda: 4},
+		{name: "n = 561 (число Кармайкла)", n: 561, wantPhi: 320, wantLambda: 80},
+		{name: "n = 2^10*3^4", n: 1024 * 81, wantPhi: 512 * 54, wantLambda: 6912},
+		{name: "простое", n: 1_000_000_007, wantPhi: 1_000_000_006, wantLambda: 1_000_000_006},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			factors, err := factor.Factor(big.NewInt(tt.n))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := EulerPhi(factors); got.Int64() != tt.wantPhi {
+				t.Errorf("EulerPhi(%d) = %s, want %d", tt.n, got, tt.wantPhi)
+			}
+			if got := CarmichaelLambda(factors); got.Int64() != tt.wantLambda {
+				t.Errorf("CarmichaelLambda(%d) = %s, want %d", tt.n, got, tt.wantLambda)
+			}
+		})
+	}
+	// λ(n) - наибольший порядок элемента, φ(n) - число обратимых элементов
+	for n := int64(2); n < 300; n++ {
+		factors, err := factor.Factor(big.NewInt(n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var count, maxOrder int64
+		for a := int64(1); a < n; a++ {
+			if k := bruteOrder(a, n); k > 0 {
+				count++
+				if k > maxOrder {
+					maxOrder = k
+				}
+			}
+		}
+		if got := EulerPhi(factors).Int64(); got != count {
+			t.Fatalf("EulerPhi(%d) = %d, want %d", n, got, count)
+		}
+		if got := CarmichaelLambda(factors).Int64(); got != maxOrder {
+			t.Fatalf("CarmichaelLambda(%d) = %d, want %d", n, got, maxOrder)
+		}
+	}
+}
+
+func TestMultiplicativeOrder(t *testing.T) {
+	mersenne61 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 61), big.NewInt(1))
+	tests := []struct {
+		name    string
+		a, n    *big.Int
+		want    *big.Int
+		wantErr error
+	}{
+		{name: "2 mod 7", a: big.NewInt(2), n: big.NewInt(7), want: big.NewInt(3)},
+		{name: "первообразный корень 3 mod 7", a: big.NewInt(3), n: big.NewInt(7), want: big.NewInt(6)},
+		{name: "единица", a: big.NewInt(1), n: big.NewInt(100), want: big.NewInt(1)},
+		{name: "2 mod 2^61-1", a: big.NewInt(2), n: mersenne61, want: big.NewInt(61)},
+		{name: "-1 mod 1000", a: big.NewInt(-1), n: big.NewInt(1000), want: big.NewInt(2)},
+		{name: "необратимое a", a: big.NewInt(4), n: big.NewInt(10), wantErr: ErrNoInverse},
+		{name: "модуль 1", a: big.NewInt(1), n: big.NewInt(1), wantErr: ErrInvalidParameters},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MultiplicativeOrder(tt.a, tt.n)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MultiplicativeOrder() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got.Cmp(tt.want) != 0 {
+				t.Errorf("MultiplicativeOrder(%s, %s) = %s, want %s", tt.a, tt.n, got, tt.want)
+			}
+		})
+	}
+	for n := int64(2); n < 120; n++ {
+		for a := int64(1); a < n; a++ {
+			want := bruteOrder(a, n)
+			got, err := MultiplicativeOrder(big.NewInt(a), big.NewInt(n))
+			if (want == 0) != (err != nil) || (err == nil && got.Int64() != want) {
+				t.Fatalf("MultiplicativeOrder(%d, %d) = %v, %v, want %d", a, n, got, err, want)
+			}
+		}
+	}
+}
+
+func TestIsPrimitiveRootBig(t *testing.T) {
+	mersenne61 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 61), big.NewInt(1))
+	tests := []struct {
+		name string
+		g, n *big.Int
+		want bool
+	}{
+		{name: "3 mod 7", g: big.NewInt(3), n: big.NewInt(7), want: true},
+		{name: "2 mod 7", g: big.NewInt(2), n: big.NewInt(7), want: false},
+		{name: "37 mod 2^61-1", g: big.NewInt(37), n: mersenne61, want: true},
+		{name: "2 mod 2^61-1", g: big.NewInt(2), n: mersenne61, want: false},
+		{name: "2 mod 9 (степень простого)", g: big.NewInt(2), n: big.NewInt(9), want: true},
+		{name: "группа не циклическая", g: big.NewInt(3), n: big.NewInt(8), want: false},
+		{name: "необратимый g", g: big.NewInt(3), n: big.NewInt(9), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsPrimitiveRootBig(tt.g, tt.n)
+			if err != nil {
+				t.Fatalf("IsPrimitiveRootBig(%s, %s) error = %v", tt.g, tt.n, err)
+			}
+			if got != tt.want {
+				t.Errorf("IsPrimitiveRootBig(%s, %s) = %v, want %v", tt.g, tt.n, got, tt.want)
+			}
+		})
+	}
+	// Согласованность с PrimitiveRootBig: меньшие g не являются первообразными корнями
+	p := big.NewInt(1_000_000_007)
+	g, err := PrimitiveRootBig(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := IsPrimitiveRootBig(g, p); err != nil || !ok {
+		t.Errorf("PrimitiveRootBig(%s) = %s is not a primitive root, error = %v", p, g, err)
+	}
+	for x := big.NewInt(2); x.Cmp(g) < 0; x.Add(x, big.NewInt(1)) {
+		if ok, _ := IsPrimitiveRootBig(x, p); ok {
+			t.Errorf("%s is a smaller primitive root than %s", x, g)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TraceValue - именованное большое число, показываемое на шаге алгоритма
+type TraceValue struct {
+	Label string
+	Value *big.Int
+}
+
+// Val - значение для трассировки
+func Val(label string, v *big.Int) TraceValue {
+	return TraceValue{Label: label, Value: new(big.Int).Set(v)}
+}
+
+// ValInt - значение int64 для трассировки
+func ValInt(label string, v int64) TraceValue {
+	return TraceValue{Label: label, Value: big.NewInt(v)}
+}
+
+// Tracer - получатель шагов алгоритмов (для обучения и аудита)
+type Tracer interface {
+	Step(algorithm, step string, values ...TraceValue)
+}
+
+// NopTracer - трассировщик по умолчанию, ничего не делает
+type NopTracer struct{}
+
+func (NopTracer) Step(string, string, ...TraceValue) {}
+
+type tracerHolder struct {
+	Tracer
+	on bool // false для NopTracer
+}
+
+var currentTracer atomic.Pointer[tracerHolder]
+
+func init() {
+	currentTracer.Store(&tracerHolder{Tracer: NopTracer{}})
+}
+
+// SetTracer - установка глобального трассировщика, возвращает предыдущий.
+// nil восстанавливает NopTracer.
+func SetTracer(t Tracer) Tracer {
+	if t == nil {
+		t = NopTracer{}
+	}
+	_, nop := t.(NopTracer)
+	return currentTracer.Swap(&tracerHolder{Tracer: t, on: !nop}).Tracer
+}
+
+// Tracing - включена ли трассировка. Вызовы Trace оборачиваются в эту проверку,
+// чтобы при выключенной трассировке не копировать значения Val.
+func Tracing() bool {
+	return currentTracer.Load().on
+}
+
+// Trace - передача шага текущему трассировщику
+func Trace(algorithm, step string, values ...TraceValue) {
+	currentTracer.Load().Step(algorithm, step, values...)
+}
+
+// textTracer - человекочитаемый вывод: "[rsa] sign: h = 42, S = 1337"
+type textTracer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewTextTracer - трассировщик с человекочитаемым выводом
+func NewTextTracer(w io.Writer) Tracer {
+	return &textTracer{w: w}
+}
+
+func (t *textTracer) Step(algorithm, step string, values ...TraceValue) {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = v.Label + " = " + v.Value.String()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(parts) == 0 {
+		_, _ = fmt.Fprintf(t.w, "[%s] %s\n", algorithm, step)
+		return
+	}
+	_, _ = fmt.Fprintf(t.w, "[%s] %s: %s\n", algorithm, step, strings.Join(parts, ", "))
+}
+
+// TraceRecord - строка JSON-журнала трассировки
+type TraceRecord struct {
+	Time      time.Time         `json:"time"`
+	Algorithm string            `json:"algorithm"`
+	Step      string            `json:"step"`
+	Values    []TraceRecordPair `json:"values,omitempty"`
+}
+
+// TraceRecordPair - значение в записи; число хранится строкой, чтобы не терять точность
+type TraceRecordPair struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// jsonTracer - запись шагов в формате JSON Lines
+type jsonTracer struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONTracer - трассировщик, пишущий по одной JSON-записи TraceRecord на строку
+func NewJSONTracer(w io.Writer) Tracer {
+	return &jsonTracer{enc: json.NewEncoder(w)}
+}
+
+func (t *jsonTracer) Step(algorithm, step string, values ...TraceValue) {
+	rec := TraceRecord{Time: time.Now().UTC(), Algorithm: algorithm, Step: step}
+	for _, v := range values {
+		rec.Values = append(rec.Values, TraceRecordPair{Label: v.Label, Value: v.Value.String()})
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_ = t.enc.Encode(rec)
+}
+
+// NewTracer - трассировщик по имени формата: "text", "json" или "" (выключен)
+func NewTracer(format string, w io.Writer) (Tracer, error) {
+	switch format {
+	case "":
+		return NopTracer{}, nil
+	case "text":
+		return NewTextTracer(w), nil
+	case "json":
+		return NewJSONTracer(w), nil
+	}
+	return nil, &ParameterError{Name: "trace", Reason: fmt.Sprintf("unknown format %q, want text or json", format)}
+}
@@ -0,0 +1,91 @@
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestTextTracer(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTextTracer(&buf)
+	tracer.Step("rsa", "sign", ValInt("h", 42), Val("S", big.NewInt(1337)))
+	tracer.Step("bsgs", "start")
+	want := "[rsa] sign: h = 42, S = 1337\n[bsgs] start\n"
+	if buf.String() != want {
+		t.Errorf("textTracer output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestJSONTracerDiffieHellman(t *testing.T) {
+	var buf bytes.Buffer
+	prev := SetTracer(NewJSONTracer(&buf))
+	defer SetTracer(prev)
+	key, err := DiffieHellman()
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner := bufio.NewScanner(&buf)
+	found := false
+	for scanner.Scan() {
+		var rec TraceRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		if rec.Algorithm != "dh" || rec.Step != "exchange" {
+			continue
+		}
+		found = true
+		for _, v := range rec.Values {
+			if v.Label == "Zab" && v.Value != big.NewInt(key).String() {
+				t.Errorf("traced Zab = %s, want %d", v.Value, key)
+			}
+		}
+	}
+	if !found {
+		t.Error("dh exchange step was not traced")
+	}
+}
+
+func TestNewTracer(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		wantErr bool
+	}{
+		{name: "выключен", format: ""},
+		{name: "text", format: "text"},
+		{name: "json", format: "json"},
+		{name: "неизвестный формат", format: "xml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTracer(tt.format, &bytes.Buffer{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewTracer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidParameters) {
+				t.Errorf("NewTracer() error = %v, want ErrInvalidParameters", err)
+			}
+		})
+	}
+}
+
+func TestTracing(t *testing.T) {
+	prev := SetTracer(nil)
+	defer SetTracer(prev)
+	if Tracing() {
+		t.Error("Tracing() = true with NopTracer")
+	}
+	SetTracer(NewTextTracer(&bytes.Buffer{}))
+	if !Tracing() {
+		t.Error("Tracing() = false with text tracer")
+	}
+	SetTracer(NopTracer{})
+	if Tracing() {
+		t.Error("Tracing() = true after SetTracer(NopTracer{})")
+	}
+}
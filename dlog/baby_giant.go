@@ -0,0 +1,37 @@
+package dlog
+
+import (
+	"context"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+)
+
+// babyGiantMaxBits - common.GiantBabyStep считает в int64, произведения не должны переполняться
+const babyGiantMaxBits = 31
+
+// BabyGiant - адаптер к common.GiantBabyStep для модулей до 2^31
+type BabyGiant struct{}
+
+func (BabyGiant) Name() string { return "baby-step giant-step" }
+
+func (BabyGiant) Solve(ctx context.Context, pr Problem) (*big.Int, error) {
+	if err := pr.validate(); err != nil {
+		return nil, err
+	}
+	if pr.P.BitLen() > babyGiantMaxBits {
+		return nil, &common.ParameterError{Name: "P", Reason: fmt.Sprintf("%d-bit modulus is too large for int64 baby-step giant-step", pr.P.BitLen())}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	x, err := common.GiantBabyStep(pr.G.Int64(), pr.P.Int64(), pr.H.Int64())
+	if err != nil {
+		return nil, err
+	}
+	res := new(big.Int).Mod(big.NewInt(x), pr.order())
+	if !pr.check(res) {
+		return nil, pr.notFound()
+	}
+	return res, nil
+}
@@ -0,0 +1,180 @@
+package dlog
+
+import (
+	"context"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math"
+	"math/big"
+	"runtime"
+	"sync"
+)
+
+const (
+	bsgsDefaultTable  = 1 << 20 // записей в таблице по умолчанию (~40 МБ)
+	bsgsProgressChunk = 1 << 14 // шагов великана между проверками отмены и отчётами
+)
+
+// BSGS - шаги младенца и великана для *big.Int с известным порядком группы n.
+// Таблица из m = min(ceil(sqrt(n)), MaxTable) шагов младенца хранится в памяти,
+// шагов великана - ceil(n/m); они делятся между горутинами. MaxTable задаёт
+// компромисс: вдвое меньше памяти - вдвое больше шагов великана.
+type BSGS struct {
+	MaxTable int // Наибольшее число записей таблицы; 0 - 2^20
+	Workers  int // Число горутин для шагов великана; 0 - runtime.NumCPU()
+	// Progress вызывается после каждой порции шагов великана (не одновременно)
+	Progress func(done, total uint64)
+}
+
+func (b *BSGS) Name() string { return "baby-step giant-step (big)" }
+
+// bsgsTable - отображение младшего слова g^j в j. Совпадения младших слов
+// у разных элементов редки и хранятся отдельно.
+type bsgsTable struct {
+	first map[uint64]uint32
+	extra map[uint64][]uint32
+}
+
+func lowWord(x *big.Int) uint64 {
+	if words := x.Bits(); len(words) > 0 {
+		return uint64(words[0])
+	}
+	return 0
+}
+
+func (t *bsgsTable) add(x *big.Int, j uint32) {
+	key := lowWord(x)
+	if _, ok := t.first[key]; ok {
+		t.extra[key] = append(t.extra[key], j)
+		return
+	}
+	t.first[key] = j
+}
+
+// candidates - показатели j, у которых младшее слово g^j совпадает с x
+func (t *bsgsTable) candidates(x *big.Int) []uint32 {
+	key := lowWord(x)
+	j, ok := t.first[key]
+	if !ok {
+		return nil
+	}
+	return append([]uint32{j}, t.extra[key]...)
+}
+
+func (b *BSGS) Solve(ctx context.Context, pr Problem) (*big.Int, error) {
+	if err := pr.validate(); err != nil {
+		return nil, err
+	}
+	n := pr.order()
+	maxTable := b.MaxTable
+	if maxTable <= 0 {
+		maxTable = bsgsDefaultTable
+	}
+	if maxTable > math.MaxUint32 {
+		return nil, &common.ParameterError{Name: "MaxTable", Reason: fmt.Sprintf("%d entries do not fit uint32 indices", maxTable)}
+	}
+	m := new(big.Int).Sqrt(n)
+	if new(big.Int).Mul(m, m).Cmp(n) < 0 {
+		m.Add(m, big.NewInt(1))
+	}
+	if m.Cmp(big.NewInt(int64(maxTable))) > 0 {
+		m.SetInt64(int64(maxTable))
+	}
+	// ceil(n/m) шагов великана
+	steps := new(big.Int).Add(n, new(big.Int).Sub(m, big.NewInt(1)))
+	steps.Quo(steps, m)
+	if !steps.IsUint64() {
+		return nil, &common.ParameterError{Name: "MaxTable", Reason: fmt.Sprintf("%d entries leave more than 2^64 giant steps for order %s", maxTable, n)}
+	}
+	total := steps.Uint64()
+	// Шаги младенца: g^j для j < m
+	table := &bsgsTable{first: make(map[uint64]uint32, m.Int64()), extra: make(map[uint64][]uint32)}
+	cur := big.NewInt(1)
+	for j := int64(0); j < m.Int64(); j++ {
+		if j&0xffff == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		table.add(cur, uint32(j))
+		cur.Mul(cur, pr.G).Mod(cur, pr.P)
+	}
+	// cur = g^m; шаг великана - умножение на g^-m
+	stepInv := new(big.Int).ModInverse(cur, pr.P)
+	if common.Tracing() {
+		common.Trace("bsgs", "baby steps", common.Val("m", m), common.ValInt("table", int64(len(table.first))), common.Val("giant steps", steps))
+	}
+	workers := b.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if uint64(workers) > total {
+		workers = int(total)
+	}
+	// Горутина k проходит i = k, k+W, k+2W, ...: начинает с H*g^(-m*k), шагает на g^(-m*W)
+	stride := new(big.Int).Exp(stepInv, big.NewInt(int64(workers)), pr.P)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		done     uint64 // под reportMu, чтобы Progress видел неубывающие значения
+		reportMu sync.Mutex
+		found    = make(chan *big.Int, 1)
+		wg       sync.WaitGroup
+	)
+	report := func(k uint64) {
+		reportMu.Lock()
+		defer reportMu.Unlock()
+		done += k
+		if b.Progress != nil {
+			b.Progress(done, total)
+		}
+	}
+	start := new(big.Int).Set(pr.H)
+	for k := 0; k < workers; k++ {
+		gamma := new(big.Int).Set(start)
+		wg.Add(1)
+		go func(k uint64) {
+			defer wg.Done()
+			var pending uint64
+			for i := k; i < total; i += uint64(workers) {
+				for _, j := range table.candidates(gamma) {
+					// x = i*m + j; проверка отсекает совпадения только по младшему слову
+					x := new(big.Int).Mul(new(big.Int).SetUint64(i), m)
+					x.Add(x, big.NewInt(int64(j)))
+					if pr.check(x) {
+						select {
+						case found <- x.Mod(x, n):
+						default:
+						}
+						cancel()
+						return
+					}
+				}
+				gamma.Mul(gamma, stride).Mod(gamma, pr.P)
+				pending++
+				if pending == bsgsProgressChunk {
+					report(pending)
+					pending = 0
+					if ctx.Err() != nil {
+						return
+					}
+				}
+			}
+			report(pending)
+		}(uint64(k))
+		start.Mul(start, stepInv).Mod(start, pr.P)
+	}
+	wg.Wait()
+	select {
+	case x := <-found:
+		if common.Tracing() {
+			common.Trace("bsgs", "match", common.Val("x", x), common.ValInt("giant steps", int64(done)))
+		}
+		return x, nil
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, pr.notFound()
+}
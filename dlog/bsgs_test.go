@@ -0,0 +1,73 @@
+package dlog
+
+import (
+	"context"
+	"errors"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBSGS(t *testing.T) {
+	p, q := safePrime(t, 32)
+	tests := []struct {
+		name   string
+		solver *BSGS
+		order  *big.Int
+	}{
+		{name: "таблица sqrt(n)", solver: &BSGS{}, order: q},
+		{name: "одна горутина", solver: &BSGS{Workers: 1}, order: q},
+		{name: "урезанная таблица", solver: &BSGS{MaxTable: 1 << 12}, order: q},
+		{name: "порядок p-1", solver: &BSGS{MaxTable: 1 << 16}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr, _ := newProblem(t, big.NewInt(4), p, tt.order)
+			x, err := tt.solver.Solve(context.Background(), pr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !pr.check(x) {
+				t.Errorf("4^%s != %s mod %s", x, pr.H, p)
+			}
+		})
+	}
+}
+
+func TestBSGSNotFound(t *testing.T) {
+	p, q := safePrime(t, 24)
+	pr := Problem{G: big.NewInt(4), H: new(big.Int).Sub(p, big.NewInt(1)), P: p, Order: q}
+	if _, err := (&BSGS{}).Solve(context.Background(), pr); !errors.Is(err, common.ErrDiscreteLogNotFound) {
+		t.Errorf("Solve() error = %v, want ErrDiscreteLogNotFound", err)
+	}
+}
+
+func TestBSGSProgressAndCancel(t *testing.T) {
+	p, q := safePrime(t, 64)
+	pr, _ := newProblem(t, big.NewInt(4), p, q)
+	var mu sync.Mutex
+	var last, total uint64
+	solver := &BSGS{
+		MaxTable: 1 << 10,
+		Progress: func(d, tot uint64) {
+			mu.Lock()
+			defer mu.Unlock()
+			if d < last {
+				t.Errorf("progress went back: %d after %d", d, last)
+			}
+			last, total = d, tot
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := solver.Solve(ctx, pr); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Solve() error = %v, want context.DeadlineExceeded", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if last == 0 || total == 0 || last >= total {
+		t.Errorf("progress = %d of %d, want partial progress", last, total)
+	}
+}
@@ -0,0 +1,125 @@
+// Package dlog - алгоритмы дискретного логарифмирования: x такое, что G^x = H (mod P).
+// Используются для атак на слабые параметры Эль-Гамаля и Диффи-Хеллмана.
+package dlog
+
+import (
+	"context"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+)
+
+// Problem - задача G^x = H (mod P) для простого P
+type Problem struct {
+	G, H, P *big.Int
+	Order   *big.Int // Порядок G (или его кратное); nil - P-1
+}
+
+// Solver - алгоритм дискретного логарифмирования. Решение приводится по модулю порядка.
+type Solver interface {
+	Name() string
+	Solve(ctx context.Context, pr Problem) (*big.Int, error)
+}
+
+// order - порядок группы, в которой ведутся вычисления с показателями
+func (pr Problem) order() *big.Int {
+	if pr.Order != nil {
+		return pr.Order
+	}
+	return new(big.Int).Sub(pr.P, big.NewInt(1))
+}
+
+// validate - проверка параметров задачи
+func (pr Problem) validate() error {
+	if pr.P == nil || pr.P.Cmp(big.NewInt(3)) < 0 {
+		return &common.ParameterError{Name: "P", Reason: "modulus must be a prime greater than 2"}
+	}
+	if pr.G == nil || pr.G.Sign() <= 0 || pr.G.Cmp(pr.P) >= 0 {
+		return &common.ParameterError{Name: "G", Reason: fmt.Sprintf("%v is not in [1, P-1]", pr.G)}
+	}
+	if pr.H == nil || pr.H.Sign() <= 0 || pr.H.Cmp(pr.P) >= 0 {
+		return &common.ParameterError{Name: "H", Reason: fmt.Sprintf("%v is not in [1, P-1]", pr.H)}
+	}
+	if pr.Order != nil && pr.Order.Sign() <= 0 {
+		return &common.ParameterError{Name: "Order", Reason: fmt.Sprintf("%s is not positive", pr.Order)}
+	}
+	return nil
+}
+
+// check - G^x = H (mod P)
+func (pr Problem) check(x *big.Int) bool {
+	return new(big.Int).Exp(pr.G, x, pr.P).Cmp(pr.H) == 0
+}
+
+// notFound - ошибка для задачи без решения
+func (pr Problem) notFound() error {
+	return &common.DiscreteLogError{Base: pr.G, Target: pr.H, Modulus: pr.P}
+}
+
+// bsgsBestBits - до такого размера простого порядка таблица шагов младенца
+// помещается в память по умолчанию и BSGS быстрее ро-метода
+const bsgsBestBits = 40
+
+// Best - подходящий алгоритм: Полиг-Хеллман, если порядок составной, для простого
+// порядка - шаги младенца и великана при небольшом порядке и ро-метод при большом
+func Best(pr Problem) Solver {
+	n := pr.order()
+	switch {
+	case !n.ProbablyPrime(20):
+		return &PohligHellman{}
+	case n.BitLen() <= bsgsBestBits:
+		return &BSGS{}
+	}
+	return &Rho{}
+}
+
+// Solve - решение задачи алгоритмом, выбранным Best
+func Solve(ctx context.Context, pr Problem) (*big.Int, error) {
+	if err := pr.validate(); err != nil {
+		return nil, err
+	}
+	return Best(pr).Solve(ctx, pr)
+}
+
+// bruteForce - перебор показателей 0..n-1 для очень малых порядков
+func bruteForce(ctx context.Context, pr Problem, n *big.Int) (*big.Int, error) {
+	cur := big.NewInt(1)
+	for x := big.NewInt(0); x.Cmp(n) < 0; x.Add(x, big.NewInt(1)) {
+		if cur.Cmp(pr.H) == 0 {
+			return x, nil
+		}
+		if x.Int64()&0xff == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		cur.Mul(cur, pr.G).Mod(cur, pr.P)
+	}
+	return nil, pr.notFound()
+}
+
+// solveLinear - решения a*x = b (mod n), не больше limit штук
+func solveLinear(a, b, n *big.Int, limit int64) []*big.Int {
+	a = new(big.Int).Mod(a, n)
+	b = new(big.Int).Mod(b, n)
+	g := new(big.Int).GCD(nil, nil, a, n)
+	if g.Sign() == 0 || new(big.Int).Mod(b, g).Sign() != 0 || !g.IsInt64() || g.Int64() > limit {
+		return nil
+	}
+	// a/g * x = b/g (mod n/g) имеет единственное решение x0, остальные - x0 + k*n/g
+	ng := new(big.Int).Quo(n, g)
+	inv := new(big.Int).ModInverse(new(big.Int).Quo(a, g), ng)
+	if inv == nil {
+		if ng.Cmp(big.NewInt(1)) != 0 {
+			return nil
+		}
+		inv = big.NewInt(0)
+	}
+	x0 := new(big.Int).Mul(inv, new(big.Int).Quo(b, g))
+	x0.Mod(x0, ng)
+	res := make([]*big.Int, 0, g.Int64())
+	for k := int64(0); k < g.Int64(); k++ {
+		res = append(res, new(big.Int).Add(x0, new(big.Int).Mul(big.NewInt(k), ng)))
+	}
+	return res
+}
@@ -0,0 +1,204 @@
+package dlog
+
+import (
+	"context"
+	"errors"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// safePrime - простое p = 2q+1 с простым q длиной bits бит
+func safePrime(t *testing.T, bits int) (*big.Int, *big.Int) {
+	t.Helper()
+	for {
+		q, err := randomBelow(new(big.Int).Lsh(big.NewInt(1), uint(bits)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		q.SetBit(q, bits-1, 1)
+		if !q.ProbablyPrime(20) {
+			continue
+		}
+		p := new(big.Int).Lsh(q, 1)
+		p.Add(p, big.NewInt(1))
+		if p.ProbablyPrime(20) {
+			return p, q
+		}
+	}
+}
+
+// smoothPrime - простое p, у которого p-1 раскладывается на множители меньше 2^12
+func smoothPrime(t *testing.T, bits int) *big.Int {
+	t.Helper()
+	for {
+		m := big.NewInt(2)
+		for m.BitLen() < bits {
+			q, err := randomBelow(big.NewInt(1 << 12))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q.ProbablyPrime(20) {
+				m.Mul(m, q)
+			}
+		}
+		p := new(big.Int).Add(m, big.NewInt(1))
+		if p.ProbablyPrime(20) {
+			return p
+		}
+	}
+}
+
+// newProblem - задача с известным ответом x для основания g
+func newProblem(t *testing.T, g, p, order *big.Int) (Problem, *big.Int) {
+	t.Helper()
+	n := order
+	if n == nil {
+		n = new(big.Int).Sub(p, big.NewInt(1))
+	}
+	x, err := randomBelow(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Problem{G: g, H: new(big.Int).Exp(g, x, p), P: p, Order: order}, x
+}
+
+func TestSolvers(t *testing.T) {
+	safeP, safeQ := safePrime(t, 36)
+	smooth := smoothPrime(t, 128)
+	small := big.NewInt(1_000_003)
+	tests := []struct {
+		name   string
+		solver Solver
+		g, p   *big.Int
+		order  *big.Int
+	}{
+		{name: "шаги младенца и великана", solver: BabyGiant{}, g: big.NewInt(2), p: small},
+		{name: "ро-метод в подгруппе простого порядка", solver: &Rho{}, g: big.NewInt(4), p: safeP, order: safeQ},
+		{name: "ро-метод в одной горутине", solver: &Rho{Workers: 1}, g: big.NewInt(4), p: safeP, order: safeQ},
+		{name: "ро-метод, составной порядок p-1", solver: &Rho{}, g: big.NewInt(3), p: small},
+		{name: "Полиг-Хеллман, гладкий p-1", solver: &PohligHellman{}, g: big.NewInt(3), p: smooth},
+		{name: "Полиг-Хеллман, безопасное простое", solver: &PohligHellman{}, g: big.NewInt(3), p: safeP},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr, _ := newProblem(t, tt.g, tt.p, tt.order)
+			x, err := tt.solver.Solve(context.Background(), pr)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.solver.Name(), err)
+			}
+			// Основание может не быть первообразным корнем, поэтому сравниваем степени
+			if !pr.check(x) {
+				t.Errorf("%s: %s^%s != %s mod %s", tt.solver.Name(), pr.G, x, pr.H, pr.P)
+			}
+		})
+	}
+}
+
+// TestNonGenerator - основание не порождает группу, а порядок по умолчанию P-1
+// лишь кратен его порядку
+func TestNonGenerator(t *testing.T) {
+	smooth := smoothPrime(t, 64)
+	// g = 3^(2*3) - не порождающий, если 2 и 3 делят P-1
+	g := new(big.Int).Exp(big.NewInt(3), big.NewInt(6), smooth)
+	random, _ := newProblem(t, g, smooth, nil)
+	tests := []struct {
+		name string
+		pr   Problem
+	}{
+		{name: "4^x = 13 mod 17", pr: Problem{G: big.NewInt(4), H: big.NewInt(13), P: big.NewInt(17)}},
+		{name: "основание 1", pr: Problem{G: big.NewInt(1), H: big.NewInt(1), P: big.NewInt(17)}},
+		{name: "гладкий P-1", pr: random},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := (&PohligHellman{}).Solve(context.Background(), tt.pr)
+			if err != nil {
+				t.Fatalf("Solve() error = %v", err)
+			}
+			if !tt.pr.check(x) {
+				t.Errorf("Solve() = %s: %s^%s != %s mod %s", x, tt.pr.G, x, tt.pr.H, tt.pr.P)
+			}
+		})
+	}
+}
+
+func TestNotInSubgroup(t *testing.T) {
+	p, q := safePrime(t, 24)
+	// 4 порождает квадраты, а -1 - не квадрат по модулю p = 2q+1
+	pr := Problem{G: big.NewInt(4), H: new(big.Int).Sub(p, big.NewInt(1)), P: p}
+	tests := []struct {
+		name   string
+		solver Solver
+		pr     Problem
+	}{
+		{name: "Полиг-Хеллман", solver: &PohligHellman{}, pr: pr},
+		{name: "ро-метод", solver: &Rho{}, pr: Problem{G: pr.G, H: pr.H, P: p, Order: q}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.solver.Solve(context.Background(), tt.pr)
+			if !errors.Is(err, common.ErrDiscreteLogNotFound) {
+				t.Errorf("%s: error = %v, want ErrDiscreteLogNotFound", tt.solver.Name(), err)
+			}
+		})
+	}
+}
+
+func TestRhoCancel(t *testing.T) {
+	p, q := safePrime(t, 64)
+	pr, _ := newProblem(t, big.NewInt(4), p, q)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := (&Rho{}).Solve(ctx, pr)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Solve() error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestBest(t *testing.T) {
+	smallP, smallQ := safePrime(t, 30)
+	bigP, bigQ := safePrime(t, 50)
+	tests := []struct {
+		name string
+		pr   Problem
+		want string
+	}{
+		{name: "составной порядок", pr: Problem{G: big.NewInt(2), H: big.NewInt(5), P: big.NewInt(1_000_003)}, want: (&PohligHellman{}).Name()},
+		{name: "малый простой порядок", pr: Problem{G: big.NewInt(4), H: big.NewInt(5), P: smallP, Order: smallQ}, want: (&BSGS{}).Name()},
+		{name: "большой простой порядок", pr: Problem{G: big.NewInt(4), H: big.NewInt(5), P: bigP, Order: bigQ}, want: (&Rho{}).Name()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Best(tt.pr).Name(); got != tt.want {
+				t.Errorf("Best() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSolveLinear(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b, n int64
+		want    []int64
+	}{
+		{name: "единственное решение", a: 3, b: 1, n: 7, want: []int64{5}},
+		{name: "несколько решений", a: 4, b: 2, n: 10, want: []int64{3, 8}},
+		{name: "нет решений", a: 4, b: 3, n: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := solveLinear(big.NewInt(tt.a), big.NewInt(tt.b), big.NewInt(tt.n), 100)
+			if len(got) != len(tt.want) {
+				t.Fatalf("solveLinear() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Int64() != tt.want[i] {
+					t.Errorf("solveLinear() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
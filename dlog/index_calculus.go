@@ -0,0 +1,538 @@
+package dlog
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/factor"
+	"math"
+	"math/big"
+	"math/bits"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+const (
+	indexCalculusMaxBits = 64      // арифметика ведётся в uint64
+	indexCalculusSmall   = 1 << 20 // множители q^e порядка не больше этого решаются методом Полига-Хеллмана
+	indexCalculusUnknown = 4       // допустимая доля неизвестных логарифмов базы - 1/4
+)
+
+// IndexCalculus - метод исчисления индексов в F_p* для P до 64 бит.
+//
+//  1. База множителей - простые числа до Bound.
+//  2. Соотношения: для случайных k число g^k mod p записывается дробью r/t с
+//     |r|, |t| < sqrt(p) (обрывок алгоритма Евклида), и если r и t раскладываются
+//     по базе, получаем k = sum e_i*log(p_i) (mod p-1). Поиск идёт в нескольких горутинах.
+//  3. Логарифмы элементов базы находятся структурированным исключением Гаусса
+//     по модулю каждого большого множителя q^e порядка p-1; малые множители
+//     обрабатываются методом Полига-Хеллмана.
+//  4. Спуск: для цели y ищется s, при котором y*g^s раскладывается по базе.
+type IndexCalculus struct {
+	Bound   uint64 // Граница базы множителей; 0 - exp(sqrt(ln h * ln ln h)), h = sqrt(P)
+	Workers int    // Число горутин поиска соотношений; 0 - runtime.NumCPU()
+	Extra   int    // Соотношений сверх размера базы; 0 - 10% базы + 10
+}
+
+func (ic *IndexCalculus) Name() string { return "index calculus" }
+
+// smoothRep - y*g^k = ±r/t, где r и t раскладываются по базе: exps[i] - степень i-го простого
+type smoothRep struct {
+	k    uint64
+	neg  bool
+	idx  []int32
+	exps []int32
+}
+
+// icField - арифметика по модулю p и база множителей
+type icField struct {
+	p, n  uint64
+	sqrtP uint64
+	gen   uint64 // первообразный корень
+	base  []uint64
+	index map[uint64]int32
+}
+
+func mulMod(a, b, m uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return bits.Rem64(hi, lo, m)
+}
+
+func addMod(a, b, m uint64) uint64 {
+	s, c := bits.Add64(a, b, 0)
+	if c != 0 || s >= m {
+		s -= m
+	}
+	return s
+}
+
+func subMod(a, b, m uint64) uint64 {
+	if a >= b {
+		return a - b
+	}
+	return m - (b - a)
+}
+
+func powMod(a, e, m uint64) uint64 {
+	r := uint64(1) % m
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			r = mulMod(r, a, m)
+		}
+		a = mulMod(a, a, m)
+	}
+	return r
+}
+
+// defaultBound - граница базы exp(sqrt(ln h * ln ln h)) для h = sqrt(p):
+// по базе раскладываются половинные числа r и t, а не g^k целиком
+func defaultBound(p uint64) uint64 {
+	lnh := math.Log(float64(p)) / 2
+	b := math.Exp(math.Sqrt(lnh * math.Log(lnh)))
+	if b < 50 {
+		b = 50
+	}
+	if b > 1<<22 {
+		b = 1 << 22
+	}
+	return uint64(b)
+}
+
+// primesUpTo - решето Эратосфена
+func primesUpTo(b uint64) []uint64 {
+	composite := make([]bool, b+1)
+	var res []uint64
+	for i := uint64(2); i <= b; i++ {
+		if composite[i] {
+			continue
+		}
+		res = append(res, i)
+		for j := i * i; j <= b; j += i {
+			composite[j] = true
+		}
+	}
+	return res
+}
+
+// split - разложение v по базе; false, если есть простой множитель больше границы
+func (f *icField) split(v uint64, sign int32, idx []int32, exps []int32) ([]int32, []int32, bool) {
+	for i, q := range f.base {
+		if v == 1 {
+			break
+		}
+		if q*q > v {
+			// Остаток простой
+			j, ok := f.index[v]
+			if !ok {
+				return idx, exps, false
+			}
+			return append(idx, j), append(exps, sign), true
+		}
+		var e int32
+		for v%q == 0 {
+			v /= q
+			e++
+		}
+		if e != 0 {
+			idx = append(idx, int32(i))
+			exps = append(exps, sign*e)
+		}
+	}
+	return idx, exps, v == 1
+}
+
+// represent - v = ±r/t с |r|, |t| < sqrt(p) и разложение r и t по базе
+func (f *icField) represent(v uint64, k uint64) (smoothRep, bool) {
+	// Инвариант алгоритма Евклида для (p, v): r_i = t_i * v (mod p)
+	r0, r1 := f.p, v
+	var t0, t1 int64 = 0, 1
+	for r1 >= f.sqrtP {
+		q := r0 / r1
+		r0, r1 = r1, r0-q*r1
+		t0, t1 = t1, t0-int64(q)*t1
+	}
+	rep := smoothRep{k: k, neg: t1 < 0}
+	t := uint64(t1)
+	if t1 < 0 {
+		t = uint64(-t1)
+	}
+	var ok bool
+	if rep.idx, rep.exps, ok = f.split(r1, 1, nil, nil); !ok {
+		return rep, false
+	}
+	rep.idx, rep.exps, ok = f.split(t, -1, rep.idx, rep.exps)
+	return rep, ok
+}
+
+// search - параллельный поиск want разложимых значений y*g^k для последовательных k
+// от случайной точки в каждой горутине
+func (f *icField) search(ctx context.Context, workers, want int, y uint64, accept func(smoothRep) bool) ([]smoothRep, uint64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mu     sync.Mutex
+		res    []smoothRep
+		trials uint64
+		wg     sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		var seed [8]byte
+		if _, err := crand.Read(seed[:]); err != nil {
+			return nil, 0, err
+		}
+		rng := rand.New(rand.NewSource(int64(binary.LittleEndian.Uint64(seed[:]))))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			k := rng.Uint64() % f.n
+			v := mulMod(y, powMod(f.gen, k, f.p), f.p)
+			var local uint64
+			for ; ; local++ {
+				if local&0xff == 0 && ctx.Err() != nil {
+					break
+				}
+				if rep, ok := f.represent(v, k); ok && accept(rep) {
+					mu.Lock()
+					if len(res) < want {
+						res = append(res, rep)
+					}
+					full := len(res) >= want
+					mu.Unlock()
+					if full {
+						cancel()
+					}
+				}
+				k = addMod(k, 1, f.n)
+				v = mulMod(v, f.gen, f.p)
+			}
+			mu.Lock()
+			trials += local
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if len(res) < want {
+		return nil, trials, ctx.Err()
+	}
+	return res, trials, nil
+}
+
+// modInverse64 - a^-1 mod m для обратимого a
+func modInverse64(a, m uint64) uint64 {
+	inv := new(big.Int).ModInverse(new(big.Int).SetUint64(a), new(big.Int).SetUint64(m))
+	return inv.Uint64()
+}
+
+// solveRelations - логарифмы элементов базы по модулю m = q^e структурированным
+// исключением Гаусса-Жордана: столбцы обрабатываются от самых лёгких (сначала
+// одиночные, без заполнения), ведущей берётся самая короткая строка с обратимым
+// коэффициентом. known[i] = false для логарифмов, которые система не определяет.
+func solveRelations(rels []smoothRep, nvars int, m, q, negLog uint64) ([]uint64, []bool) {
+	rows := make([]map[int32]uint64, len(rels))
+	rhs := make([]uint64, len(rels))
+	colRows := make([]map[int]struct{}, nvars)
+	for c := range colRows {
+		colRows[c] = make(map[int]struct{})
+	}
+	for r, rel := range rels {
+		row := make(map[int32]uint64)
+		for i, c := range rel.idx {
+			e := int64(rel.exps[i])
+			var v uint64
+			if e >= 0 {
+				v = uint64(e) % m
+			} else {
+				v = subMod(0, uint64(-e)%m, m)
+			}
+			row[c] = addMod(row[c], v, m)
+			if row[c] == 0 {
+				delete(row, c)
+			}
+		}
+		for c := range row {
+			colRows[c][r] = struct{}{}
+		}
+		rows[r] = row
+		rhs[r] = rel.k % m
+		if rel.neg {
+			rhs[r] = subMod(rhs[r], negLog, m)
+		}
+	}
+	pivotRow := make([]int, nvars)
+	for c := range pivotRow {
+		pivotRow[c] = -1
+	}
+	usedRow := make([]bool, len(rows))
+	for {
+		// Самый лёгкий столбец без ведущей строки, у которого есть обратимый коэффициент
+		best, bestRow := -1, -1
+		for c := 0; c < nvars; c++ {
+			if pivotRow[c] >= 0 || len(colRows[c]) == 0 || (best >= 0 && len(colRows[c]) >= len(colRows[best])) {
+				continue
+			}
+			cand := -1
+			for r := range colRows[c] {
+				if !usedRow[r] && rows[r][int32(c)]%q != 0 && (cand < 0 || len(rows[r]) < len(rows[cand])) {
+					cand = r
+				}
+			}
+			if cand >= 0 {
+				best, bestRow = c, cand
+			}
+		}
+		if best < 0 {
+			break
+		}
+		c32 := int32(best)
+		pr := rows[bestRow]
+		// Нормируем ведущую строку
+		inv := modInverse64(pr[c32], m)
+		for c, v := range pr {
+			pr[c] = mulMod(v, inv, m)
+		}
+		rhs[bestRow] = mulMod(rhs[bestRow], inv, m)
+		pivotRow[best] = bestRow
+		usedRow[bestRow] = true
+		// Исключаем столбец из всех остальных строк
+		for r := range colRows[best] {
+			if r == bestRow {
+				continue
+			}
+			row := rows[r]
+			factor := row[c32]
+			for c, v := range pr {
+				nv := subMod(row[c], mulMod(factor, v, m), m)
+				if nv == 0 {
+					delete(row, c)
+					delete(colRows[c], r)
+				} else {
+					if _, ok := row[c]; !ok {
+						colRows[c][r] = struct{}{}
+					}
+					row[c] = nv
+				}
+			}
+			rhs[r] = subMod(rhs[r], mulMod(factor, rhs[bestRow], m), m)
+		}
+	}
+	// После Гаусса-Жордана ведущая строка содержит только свою переменную и свободные;
+	// значение определено, если свободных переменных в строке нет
+	vals := make([]uint64, nvars)
+	known := make([]bool, nvars)
+	for c, r := range pivotRow {
+		if r >= 0 && len(rows[r]) == 1 {
+			vals[c], known[c] = rhs[r], true
+		}
+	}
+	return vals, known
+}
+
+func (ic *IndexCalculus) Solve(ctx context.Context, pr Problem) (*big.Int, error) {
+	if err := pr.validate(); err != nil {
+		return nil, err
+	}
+	if pr.P.BitLen() > indexCalculusMaxBits {
+		return nil, &common.ParameterError{Name: "P", Reason: fmt.Sprintf("%d-bit modulus is larger than %d bits", pr.P.BitLen(), indexCalculusMaxBits)}
+	}
+	p := pr.P.Uint64()
+	nBig := new(big.Int).Sub(pr.P, big.NewInt(1))
+	factors, err := factor.FactorContext(ctx, nBig)
+	if err != nil {
+		return nil, err
+	}
+	f := &icField{p: p, n: p - 1, sqrtP: uint64(math.Sqrt(float64(p))) + 1}
+	f.gen = primitiveRoot(p, factors)
+	// Логарифмы G и H по основанию первообразного корня gen
+	targets := []*big.Int{pr.G, pr.H}
+	residues := [][]*big.Int{nil, nil}
+	var moduli []*big.Int
+	var large []factor.PrimePower
+	ph := &PohligHellman{}
+	for _, pp := range factors {
+		qe := new(big.Int).Exp(pp.P, big.NewInt(int64(pp.E)), nil)
+		if qe.Cmp(big.NewInt(indexCalculusSmall)) > 0 {
+			large = append(large, pp)
+			continue
+		}
+		for i, y := range targets {
+			sub := Problem{G: new(big.Int).SetUint64(f.gen), H: y, P: pr.P}
+			// gen - первообразный корень, поэтому q^t = q^e
+			x, _, err := ph.solvePrimePower(ctx, sub, nBig, pp)
+			if err != nil {
+				return nil, err
+			}
+			residues[i] = append(residues[i], x)
+		}
+		moduli = append(moduli, qe)
+	}
+	if len(large) > 0 {
+		logs, err := ic.largeLogs(ctx, f, large, targets)
+		if err != nil {
+			return nil, err
+		}
+		for _, pp := range large {
+			moduli = append(moduli, new(big.Int).Exp(pp.P, big.NewInt(int64(pp.E)), nil))
+		}
+		for i := range targets {
+			residues[i] = append(residues[i], logs[i]...)
+		}
+	}
+	a, err := common.CRTBig(residues[0], moduli)
+	if err != nil {
+		return nil, err
+	}
+	b, err := common.CRTBig(residues[1], moduli)
+	if err != nil {
+		return nil, err
+	}
+	// G = gen^a, H = gen^b: x*a = b (mod p-1)
+	for _, x := range solveLinear(a, b, nBig, rhoMaxSolutions) {
+		if pr.check(x) {
+			return x.Mod(x, pr.order()), nil
+		}
+	}
+	return nil, pr.notFound()
+}
+
+// largeLogs - логарифмы targets по модулю каждого большого множителя q^e порядка
+func (ic *IndexCalculus) largeLogs(ctx context.Context, f *icField, large []factor.PrimePower, targets []*big.Int) ([][]*big.Int, error) {
+	bound := ic.Bound
+	if bound == 0 {
+		bound = defaultBound(f.p)
+	}
+	f.base = primesUpTo(bound)
+	f.index = make(map[uint64]int32, len(f.base))
+	for i, q := range f.base {
+		f.index[q] = int32(i)
+	}
+	workers := ic.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	extra := ic.Extra
+	if extra <= 0 {
+		extra = len(f.base)/10 + 10
+	}
+	if common.Tracing() {
+		common.Trace("index calculus", "factor base", common.ValInt("bound", int64(bound)), common.ValInt("size", int64(len(f.base))))
+	}
+	var (
+		rels   []smoothRep
+		vals   [][]uint64
+		known  []bool
+		trials uint64
+	)
+	// Простые, встретившиеся в одном соотношении, и свободные переменные делают систему
+	// неполного ранга, поэтому соотношения добираются партиями, пока неизвестных
+	// логарифмов не останется меньше maxUnknown
+	want := len(f.base) + extra
+	maxUnknown := len(f.base) / indexCalculusUnknown
+	for {
+		more, n, err := f.search(ctx, workers, want-len(rels), 1, func(smoothRep) bool { return true })
+		if err != nil {
+			return nil, err
+		}
+		rels = append(rels, more...)
+		trials += n
+		if common.Tracing() {
+			common.Trace("index calculus", "relations", common.ValInt("count", int64(len(rels))), common.ValInt("trials", int64(trials)))
+		}
+		// Порядок соотношений не должен зависеть от планировщика горутин
+		sort.Slice(rels, func(i, j int) bool { return rels[i].k < rels[j].k })
+		vals, known = ic.linearAlgebra(f, large, rels)
+		unknown := 0
+		for _, ok := range known {
+			if !ok {
+				unknown++
+			}
+		}
+		if common.Tracing() {
+			common.Trace("index calculus", "linear algebra", common.ValInt("unknown logs", int64(unknown)))
+		}
+		if unknown <= maxUnknown {
+			break
+		}
+		want += extra
+	}
+	res := make([][]*big.Int, len(targets))
+	for t, y := range targets {
+		// Спуск: y*gen^s = ±r/t, все логарифмы множителей r и t известны
+		reps, _, err := f.search(ctx, workers, 1, y.Uint64(), func(rep smoothRep) bool {
+			for _, c := range rep.idx {
+				if !known[c] {
+					return false
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		rep := reps[0]
+		if common.Tracing() {
+			common.Trace("index calculus", "descent", common.Val("y", y), common.ValInt("s", int64(rep.k)))
+		}
+		for i, pp := range large {
+			m := new(big.Int).Exp(pp.P, big.NewInt(int64(pp.E)), nil).Uint64()
+			// log y = sum e_i*log(p_i) + [знак]*log(-1) - s
+			v := subMod(0, rep.k%m, m)
+			if rep.neg {
+				v = addMod(v, (f.n/2)%m, m)
+			}
+			for j, c := range rep.idx {
+				e := int64(rep.exps[j])
+				if e >= 0 {
+					v = addMod(v, mulMod(uint64(e)%m, vals[i][c], m), m)
+				} else {
+					v = subMod(v, mulMod(uint64(-e)%m, vals[i][c], m), m)
+				}
+			}
+			res[t] = append(res[t], new(big.Int).SetUint64(v))
+		}
+	}
+	return res, nil
+}
+
+// linearAlgebra - логарифмы базы по модулю каждого большого q^e; логарифм известен,
+// если он определён по всем модулям
+func (ic *IndexCalculus) linearAlgebra(f *icField, large []factor.PrimePower, rels []smoothRep) ([][]uint64, []bool) {
+	vals := make([][]uint64, len(large))
+	known := make([]bool, len(f.base))
+	for i := range known {
+		known[i] = true
+	}
+	for i, pp := range large {
+		m := new(big.Int).Exp(pp.P, big.NewInt(int64(pp.E)), nil).Uint64()
+		// log(-1) = (p-1)/2 для первообразного корня
+		negLog := (f.n / 2) % m
+		var kn []bool
+		vals[i], kn = solveRelations(rels, len(f.base), m, pp.P.Uint64(), negLog)
+		for j, ok := range kn {
+			if !ok {
+				known[j] = false
+			}
+		}
+	}
+	return vals, known
+}
+
+// primitiveRoot - наименьший первообразный корень по модулю простого p
+func primitiveRoot(p uint64, factors []factor.PrimePower) uint64 {
+	n := p - 1
+	for g := uint64(2); ; g++ {
+		ok := true
+		for _, pp := range factors {
+			if powMod(g, n/pp.P.Uint64(), p) == 1 {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return g
+		}
+	}
+}
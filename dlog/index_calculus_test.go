@@ -0,0 +1,82 @@
+package dlog
+
+import (
+	"context"
+	"errors"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+	"testing"
+)
+
+func TestIndexCalculus(t *testing.T) {
+	p40, _ := safePrime(t, 40)
+	p56, _ := safePrime(t, 56)
+	p64, _ := safePrime(t, 63)
+	tests := []struct {
+		name   string
+		solver *IndexCalculus
+		g, p   *big.Int
+	}{
+		{name: "40 бит", solver: &IndexCalculus{}, g: big.NewInt(3), p: p40},
+		{name: "56 бит", solver: &IndexCalculus{}, g: big.NewInt(5), p: p56},
+		{name: "64 бита", solver: &IndexCalculus{}, g: big.NewInt(3), p: p64},
+		{name: "своя граница базы", solver: &IndexCalculus{Bound: 500, Workers: 2}, g: big.NewInt(7), p: p40},
+		{name: "гладкий p-1", solver: &IndexCalculus{}, g: big.NewInt(3), p: smoothPrime(t, 48)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr, _ := newProblem(t, tt.g, tt.p, nil)
+			x, err := tt.solver.Solve(context.Background(), pr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !pr.check(x) {
+				t.Errorf("%s^%s != %s mod %s", pr.G, x, pr.H, pr.P)
+			}
+		})
+	}
+}
+
+func TestIndexCalculusErrors(t *testing.T) {
+	p, _ := safePrime(t, 32)
+	tests := []struct {
+		name   string
+		pr     Problem
+		target error
+	}{
+		{
+			name:   "модуль больше 64 бит",
+			pr:     Problem{G: big.NewInt(3), H: big.NewInt(5), P: new(big.Int).Lsh(big.NewInt(1), 70)},
+			target: common.ErrInvalidParameters,
+		},
+		{
+			// 4 - квадрат, а p-1 = -1 по модулю безопасного простого - нет
+			name:   "нет решения",
+			pr:     Problem{G: big.NewInt(4), H: new(big.Int).Sub(p, big.NewInt(1)), P: p},
+			target: common.ErrDiscreteLogNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&IndexCalculus{}).Solve(context.Background(), tt.pr)
+			if !errors.Is(err, tt.target) {
+				t.Errorf("Solve() error = %v, want %v", err, tt.target)
+			}
+		})
+	}
+}
+
+func TestSolveRelations(t *testing.T) {
+	// 2*L0 + L1 = 7, L0 + L1 = 5, L2 не встречается (mod 11)
+	rels := []smoothRep{
+		{k: 7, idx: []int32{0, 1}, exps: []int32{2, 1}},
+		{k: 5, idx: []int32{0, 1}, exps: []int32{1, 1}},
+	}
+	vals, known := solveRelations(rels, 3, 11, 11, 0)
+	if !known[0] || !known[1] || known[2] {
+		t.Fatalf("known = %v, want [true true false]", known)
+	}
+	if vals[0] != 2 || vals[1] != 3 {
+		t.Errorf("vals = %v, want [2 3 _]", vals)
+	}
+}
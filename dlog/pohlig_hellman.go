@@ -0,0 +1,107 @@
+package dlog
+
+import (
+	"context"
+	"errors"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/factor"
+	"math/big"
+)
+
+// PohligHellman - сведение к подгруппам простого порядка: порядок n = prod q^e
+// раскладывается на множители, x mod q^e находится по цифрам в системе счисления
+// с основанием q, результаты объединяются по китайской теореме об остатках.
+// Сложность определяется наибольшим простым делителем порядка.
+type PohligHellman struct {
+	Sub Solver // Решатель в подгруппах простого порядка; nil - выбор Best для каждой подгруппы
+}
+
+func (ph *PohligHellman) Name() string { return "Pohlig-Hellman" }
+
+func (ph *PohligHellman) Solve(ctx context.Context, pr Problem) (*big.Int, error) {
+	if err := pr.validate(); err != nil {
+		return nil, err
+	}
+	n := pr.order()
+	factors, err := factor.FactorContext(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	residues := make([]*big.Int, 0, len(factors))
+	moduli := make([]*big.Int, 0, len(factors))
+	for _, f := range factors {
+		x, qt, err := ph.solvePrimePower(ctx, pr, n, f)
+		if err != nil {
+			return nil, err
+		}
+		if qt.Cmp(big.NewInt(1)) == 0 {
+			continue // q не делит порядок G
+		}
+		if common.Tracing() {
+			common.Trace("pohlig-hellman", "prime power", common.Val("q", f.P), common.Val("q^t", qt), common.Val("x mod q^t", x))
+		}
+		residues = append(residues, x)
+		moduli = append(moduli, qt)
+	}
+	if len(moduli) == 0 {
+		// G = 1
+		residues, moduli = []*big.Int{big.NewInt(0)}, []*big.Int{big.NewInt(1)}
+	}
+	x, err := common.CRTBig(residues, moduli)
+	if err != nil {
+		return nil, err
+	}
+	if !pr.check(x) {
+		return nil, pr.notFound()
+	}
+	return x, nil
+}
+
+// solvePrimePower - x mod q^t, где q^t - наибольшая степень q, делящая порядок G
+// (t <= e: G может не быть порождающим, а n - лишь кратным его порядку). В подгруппе
+// порядка q^t цифры x_k находятся по одной задачей в подгруппе порядка q.
+func (ph *PohligHellman) solvePrimePower(ctx context.Context, pr Problem, n *big.Int, f factor.PrimePower) (x, qt *big.Int, err error) {
+	qe := new(big.Int).Exp(f.P, big.NewInt(int64(f.E)), nil)
+	cofactor := new(big.Int).Quo(n, qe)
+	g := new(big.Int).Exp(pr.G, cofactor, pr.P)
+	h := new(big.Int).Exp(pr.H, cofactor, pr.P)
+	// Порядок g равен q^t: t - число возведений в степень q до единицы
+	t, qt := 0, big.NewInt(1)
+	for gq := new(big.Int).Set(g); gq.Cmp(big.NewInt(1)) != 0 && t < f.E; t++ {
+		gq.Exp(gq, f.P, pr.P)
+		qt.Mul(qt, f.P)
+	}
+	if t == 0 {
+		return big.NewInt(0), qt, nil
+	}
+	// gamma = g^(q^(t-1)) имеет порядок q
+	qt1 := new(big.Int).Quo(qt, f.P)
+	gamma := new(big.Int).Exp(g, qt1, pr.P)
+	gInv := new(big.Int).ModInverse(g, pr.P)
+	x = big.NewInt(0)
+	qk := big.NewInt(1) // q^k
+	for k := 0; k < t; k++ {
+		// h_k = (g^-x * h)^(q^(t-1-k))
+		hk := new(big.Int).Exp(gInv, x, pr.P)
+		hk.Mul(hk, h).Mod(hk, pr.P)
+		hk.Exp(hk, new(big.Int).Quo(qt1, qk), pr.P)
+		d := big.NewInt(0)
+		if hk.Cmp(big.NewInt(1)) != 0 {
+			subPr := Problem{G: gamma, H: hk, P: pr.P, Order: f.P}
+			sub := ph.Sub
+			if sub == nil {
+				sub = Best(subPr)
+			}
+			d, err = sub.Solve(ctx, subPr)
+			if errors.Is(err, common.ErrDiscreteLogNotFound) {
+				return nil, nil, pr.notFound()
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		x.Add(x, new(big.Int).Mul(d, qk))
+		qk.Mul(qk, f.P)
+	}
+	return x, qt, nil
+}
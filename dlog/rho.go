@@ -0,0 +1,227 @@
+package dlog
+
+import (
+	"context"
+	"crypto/rand"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+	"runtime"
+	"sync"
+)
+
+const (
+	rhoPartitions   = 32   // число множителей r-аддитивного блуждания
+	rhoBruteForce   = 1024 // порядки меньше этого перебираются напрямую
+	rhoMaxSolutions = 1 << 16
+	rhoMaxFailures  = 64 // столько бесполезных столкновений подряд означает, что H не лежит в <G>
+)
+
+// Rho - ро-метод Полларда с r-аддитивным блужданием. Память постоянна: хранятся
+// только выделенные точки (с нулевыми младшими битами хеша), поэтому несколько
+// блужданий в разных горутинах находят столкновения друг с другом.
+// Работает за ~sqrt(n) шагов, где n - порядок; лучше всего для простого порядка.
+type Rho struct {
+	Workers           int // Число параллельных блужданий; 0 - runtime.NumCPU()
+	DistinguishedBits int // Число нулевых бит выделенной точки; 0 - по размеру порядка
+}
+
+func (r *Rho) Name() string { return "Pollard rho" }
+
+// rhoPoint - точка блуждания x = G^a * H^b
+type rhoPoint struct {
+	a, b *big.Int
+}
+
+// rhoWalk - общие для всех блужданий параметры
+type rhoWalk struct {
+	pr          Problem
+	n           *big.Int
+	mult        [rhoPartitions]*big.Int // M_j = G^A_j * H^B_j
+	multA       [rhoPartitions]*big.Int
+	multB       [rhoPartitions]*big.Int
+	mask        uint64
+	maxSteps    int
+	mu          sync.Mutex
+	seen        map[string]rhoPoint
+	collisions  int
+	failures    int
+	distinguish int
+}
+
+// hash - перемешивание младшего слова x (хеш Фибоначчи)
+func hash(x *big.Int) uint64 {
+	var w uint64
+	if words := x.Bits(); len(words) > 0 {
+		w = uint64(words[0])
+	}
+	return w * 0x9E3779B97F4A7C15
+}
+
+func randomBelow(n *big.Int) (*big.Int, error) {
+	return rand.Int(rand.Reader, n)
+}
+
+func (r *Rho) Solve(ctx context.Context, pr Problem) (*big.Int, error) {
+	if err := pr.validate(); err != nil {
+		return nil, err
+	}
+	n := pr.order()
+	if n.Cmp(big.NewInt(rhoBruteForce)) < 0 {
+		return bruteForce(ctx, pr, n)
+	}
+	bitsD := r.DistinguishedBits
+	if bitsD <= 0 {
+		bitsD = n.BitLen()/4 - 2
+		if bitsD < 0 {
+			bitsD = 0
+		}
+	}
+	w := &rhoWalk{
+		pr:       pr,
+		n:        n,
+		mask:     1<<uint(bitsD) - 1,
+		maxSteps: 20 << uint(bitsD),
+		seen:     make(map[string]rhoPoint),
+	}
+	for j := range w.mult {
+		a, err := randomBelow(n)
+		if err != nil {
+			return nil, err
+		}
+		b, err := randomBelow(n)
+		if err != nil {
+			return nil, err
+		}
+		w.multA[j], w.multB[j] = a, b
+		w.mult[j] = new(big.Int).Mul(new(big.Int).Exp(pr.G, a, pr.P), new(big.Int).Exp(pr.H, b, pr.P))
+		w.mult[j].Mod(w.mult[j], pr.P)
+	}
+	workers := r.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	found := make(chan *big.Int, 1)
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			x, err := w.run(ctx)
+			switch {
+			case err != nil:
+				errs <- err
+			case x != nil:
+				select {
+				case found <- x:
+				default:
+				}
+			default:
+				return
+			}
+			cancel()
+		}()
+	}
+	wg.Wait()
+	select {
+	case x := <-found:
+		if common.Tracing() {
+			common.Trace("rho", "solved", common.Val("x", x),
+				common.ValInt("distinguished", int64(w.distinguish)), common.ValInt("collisions", int64(w.collisions)))
+		}
+		return x, nil
+	default:
+	}
+	select {
+	case err := <-errs:
+		return nil, err
+	default:
+	}
+	// Ни решения, ни ошибки: блуждания остановлены отменой контекста вызывающего
+	return nil, ctx.Err()
+}
+
+// start - случайная стартовая точка
+func (w *rhoWalk) start() (*big.Int, *big.Int, *big.Int, error) {
+	a, err := randomBelow(w.n)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	b, err := randomBelow(w.n)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	x := new(big.Int).Mul(new(big.Int).Exp(w.pr.G, a, w.pr.P), new(big.Int).Exp(w.pr.H, b, w.pr.P))
+	return x.Mod(x, w.pr.P), a, b, nil
+}
+
+// run - одно блуждание с перезапусками; возвращает решение или nil после отмены
+func (w *rhoWalk) run(ctx context.Context) (*big.Int, error) {
+	for {
+		x, a, b, err := w.start()
+		if err != nil {
+			return nil, err
+		}
+		for step := 0; step < w.maxSteps; step++ {
+			if step&0xfff == 0 && ctx.Err() != nil {
+				return nil, nil
+			}
+			h := hash(x)
+			if h&w.mask == 0 {
+				res, restart, err := w.report(x, a, b)
+				if res != nil || err != nil {
+					return res, err
+				}
+				if restart {
+					break
+				}
+			}
+			j := h >> 59 // старшие 5 бит: номер множителя
+			x.Mul(x, w.mult[j]).Mod(x, w.pr.P)
+			a.Add(a, w.multA[j])
+			if a.Cmp(w.n) >= 0 {
+				a.Sub(a, w.n)
+			}
+			b.Add(b, w.multB[j])
+			if b.Cmp(w.n) >= 0 {
+				b.Sub(b, w.n)
+			}
+		}
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+	}
+}
+
+// report - запись выделенной точки. При столкновении G^a1 H^b1 = G^a2 H^b2
+// решается (b1 - b2) x = a2 - a1 (mod n). restart - блуждание слилось с уже
+// пройденным без полезного соотношения, его нужно начать заново.
+func (w *rhoWalk) report(x, a, b *big.Int) (*big.Int, bool, error) {
+	key := string(x.Bytes())
+	w.mu.Lock()
+	prev, ok := w.seen[key]
+	if !ok {
+		w.seen[key] = rhoPoint{a: new(big.Int).Set(a), b: new(big.Int).Set(b)}
+		w.distinguish++
+		w.mu.Unlock()
+		return nil, false, nil
+	}
+	w.collisions++
+	w.mu.Unlock()
+	db := new(big.Int).Sub(b, prev.b)
+	da := new(big.Int).Sub(prev.a, a)
+	for _, cand := range solveLinear(db, da, w.n, rhoMaxSolutions) {
+		if w.pr.check(cand) {
+			return cand, false, nil
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.failures++
+	if w.failures > rhoMaxFailures {
+		return nil, false, w.pr.notFound()
+	}
+	return nil, true, nil
+}
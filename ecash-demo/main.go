@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/blindsig"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/ecash"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// serveLoopback - HTTP API банка на свободном порту 127.0.0.1
+func serveLoopback(bank *ecash.Bank) (*http.Server, string, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, "", err
+	}
+	srv := &http.Server{Handler: ecash.NewHandler(bank, log.Printf), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Банк: %v", err)
+		}
+	}()
+	return srv, "http://" + ln.Addr().String(), nil
+}
+
+// customer - покупатель: кошелёк, сумма снятия и покупка
+type customer struct {
+	name     string
+	balance  int64
+	withdraw int64
+	merchant string
+	amount   int64
+}
+
+func main() {
+	bits := flag.Int("bits", 2048, "длина модуля ключа банка в битах")
+	overHTTP := flag.Bool("http", false, "банк доступен кошелькам и продавцам по HTTP на 127.0.0.1")
+	offline := flag.Bool("offline", false, "оплата offline-монетами с внесением в банк после покупок")
+	cheat := flag.Bool("cheat", false, "покупатель alice тратит свои монеты второй раз")
+	spentPath := flag.String("spent", "", "файл базы потраченных монет (JSON Lines); пусто - в памяти")
+	traceFormat := flag.String("trace", "", "трассировка шагов алгоритма: text или json")
+	flag.Parse()
+	tracer, err := common.NewTracer(*traceFormat, os.Stderr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	common.SetTracer(tracer)
+	ctx := context.Background()
+
+	start := time.Now()
+	key, err := blindsig.GenerateKey(rand.Reader, *bits)
+	if err != nil {
+		log.Fatalf("Ошибка генерации ключа: %v", err)
+	}
+	var spent *ecash.SpentDB
+	if *spentPath != "" {
+		if spent, err = ecash.OpenSpentDB(*spentPath); err != nil {
+			log.Fatal(err)
+		}
+		defer spent.Close()
+	}
+	bank, err := ecash.NewBank(key, spent)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Банк: ключ %d бит за %s, номиналы %v\n", key.N.BitLen(), time.Since(start), ecash.Denominations)
+
+	var api ecash.BankAPI = bank
+	if *overHTTP {
+		srv, url, err := serveLoopback(bank)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer srv.Close()
+		api = &ecash.Client{BaseURL: url}
+		fmt.Printf("Банк: HTTP API на %s\n", url)
+	}
+
+	customers := []customer{
+		{name: "alice", balance: 300, withdraw: 137, merchant: "shop", amount: 37},
+		{name: "bob", balance: 150, withdraw: 75, merchant: "cafe", amount: 25},
+	}
+	tokens := make(map[string]string)
+	for _, c := range customers {
+		if tokens[c.name], err = bank.OpenAccount(c.name, c.balance); err != nil {
+			log.Fatal(err)
+		}
+	}
+	merchants := make(map[string]*ecash.Merchant)
+	for _, name := range []string{"shop", "cafe"} {
+		if tokens[name], err = bank.OpenAccount(name, 0); err != nil {
+			log.Fatal(err)
+		}
+		if merchants[name], err = ecash.NewMerchant(ctx, api, name, tokens[name]); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	wallets := make(map[string]*ecash.Wallet)
+	for _, c := range customers {
+		w, err := ecash.NewWallet(ctx, api, c.name, tokens[c.name])
+		if err != nil {
+			log.Fatal(err)
+		}
+		start = time.Now()
+		if *offline {
+			err = w.WithdrawOffline(ctx, c.withdraw)
+		} else {
+			err = w.Withdraw(ctx, c.withdraw)
+		}
+		if err != nil {
+			log.Fatalf("%s: ошибка снятия: %v", c.name, err)
+		}
+		wallets[c.name] = w
+		fmt.Printf("%s: снято %d за %s\n", c.name, c.withdraw, time.Since(start))
+	}
+
+	// Копия кошелька alice до покупки: её монеты будут потрачены второй раз
+	var copied *ecash.Wallet
+	if *cheat {
+		copied = wallets["alice"].Clone()
+	}
+	pay := func(w *ecash.Wallet, merchant string, amount int64) error {
+		m := merchants[merchant]
+		if *offline {
+			return w.PayOffline(m, amount)
+		}
+		return w.Pay(ctx, m, amount)
+	}
+	for _, c := range customers {
+		if err = pay(wallets[c.name], c.merchant, c.amount); err != nil {
+			log.Fatalf("%s: ошибка оплаты: %v", c.name, err)
+		}
+		fmt.Printf("%s: оплачено %d продавцу %s\n", c.name, c.amount, c.merchant)
+	}
+	detected := false
+	if *cheat {
+		err = pay(copied, "cafe", customers[0].amount)
+		var ds *ecash.DoubleSpendError
+		switch {
+		case errors.As(err, &ds):
+			detected = true
+			fmt.Printf("alice: повторная трата отвергнута банком: монета %.16s…\n", ds.Serial)
+		case err != nil:
+			log.Fatalf("alice: ошибка повторной оплаты: %v", err)
+		default:
+			fmt.Printf("alice: продавец cafe принял %d теми же монетами без связи с банком\n", customers[0].amount)
+		}
+	}
+
+	if *offline {
+		for _, name := range []string{"shop", "cafe"} {
+			deposited, err := merchants[name].DepositPending(ctx)
+			fmt.Printf("%s: внесено %d\n", name, deposited)
+			var ds *ecash.DoubleSpendError
+			for _, e := range unwrapAll(err) {
+				if errors.As(e, &ds) {
+					detected = true
+					fmt.Printf("%s: банк отверг повторную трату монеты %.16s…, владелец: %q\n", name, ds.Serial, ds.Identity)
+				} else {
+					log.Printf("%s: платёж отвергнут: %v", name, e)
+				}
+			}
+		}
+	}
+
+	fmt.Println("Счета:")
+	for _, name := range []string{"alice", "bob", "shop", "cafe"} {
+		balance, err := api.Balance(ctx, name, tokens[name])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("  %-6s %d\n", name, balance)
+	}
+	if *cheat && !detected {
+		fmt.Println("Повторная трата не обнаружена")
+		os.Exit(1)
+	}
+}
+
+// unwrapAll - ошибки, объединённые errors.Join
+func unwrapAll(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
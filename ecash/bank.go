@@ -0,0 +1,236 @@
+package ecash
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/blindsig"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+	"sync"
+)
+
+var (
+	// ErrUnknownDenomination - номинала нет в Denominations
+	ErrUnknownDenomination = errors.New("ecash: unknown denomination")
+	// ErrUnauthorized - счёта нет или токен не подходит
+	ErrUnauthorized = errors.New("ecash: unknown account or wrong token")
+	// ErrInsufficientFunds - на счёте меньше номинала монеты
+	ErrInsufficientFunds = errors.New("ecash: insufficient funds")
+	// ErrDoubleSpent - монета уже внесена в банк
+	ErrDoubleSpent = errors.New("ecash: coin already spent")
+	// ErrDoubleDeposit - продавец повторно вносит тот же платёж: это не повторная
+	// трата, личность покупателя не раскрывается
+	ErrDoubleDeposit = errors.New("ecash: payment already deposited")
+	// ErrCheating - раскрытый при снятии кандидат не соответствует ослеплённому
+	// или содержит чужую личность
+	ErrCheating = errors.New("ecash: withdrawal candidate does not match its opening")
+	// ErrUnknownSession - сеанса снятия нет или он уже завершён
+	ErrUnknownSession = errors.New("ecash: unknown withdrawal session")
+)
+
+// DoubleSpendError - монета Serial потрачена повторно. Для offline-монеты по двум
+// платежам с разными вызовами раскрывается имя счёта владельца Identity.
+type DoubleSpendError struct {
+	Serial   string
+	Identity string // пусто, если владелец не установлен
+}
+
+func (e *DoubleSpendError) Error() string {
+	if e.Identity == "" {
+		return fmt.Sprintf("coin %s: %v", e.Serial, ErrDoubleSpent)
+	}
+	return fmt.Sprintf("coin %s: %v by %q", e.Serial, ErrDoubleSpent, e.Identity)
+}
+
+func (e *DoubleSpendError) Unwrap() error { return ErrDoubleSpent }
+
+// BankAPI - операции банка, доступные кошельку и продавцу: сам Bank или Client
+// того же банка по HTTP
+type BankAPI interface {
+	Key(ctx context.Context) (*KeyInfo, error)
+	Balance(ctx context.Context, account, token string) (int64, error)
+	Withdraw(ctx context.Context, account, token string, denomination int64, blinded *big.Int) (*big.Int, error)
+	Deposit(ctx context.Context, account, token string, coin *Coin) (int64, error)
+	BeginOfflineWithdrawal(ctx context.Context, account, token string, denomination int64, blinded []*big.Int) (*OfflineWithdrawal, error)
+	FinishOfflineWithdrawal(ctx context.Context, account, token, session string, openings []CandidateOpening) (*big.Int, error)
+	DepositOffline(ctx context.Context, account, token string, payment *Payment) (int64, error)
+}
+
+// account - счёт: токен доступа и остаток
+type account struct {
+	token   string
+	balance int64
+}
+
+// Bank - банк: счета в памяти, ключи номиналов и база потраченных монет
+type Bank struct {
+	info     KeyInfo
+	keys     map[string]*blindsig.PrivateKey // denominationInfo -> ключ номинала
+	spent    *SpentDB
+	accounts map[string]*account
+	sessions map[string]*withdrawal // незавершённые снятия offline-монет
+	mu       sync.Mutex
+}
+
+var _ BankAPI = (*Bank)(nil)
+
+// NewBank - банк с ключом key. spent - база потраченных монет; nil - база в памяти.
+func NewBank(key *blindsig.PrivateKey, spent *SpentDB) (*Bank, error) {
+	if key == nil {
+		return nil, &common.ParameterError{Name: "key", Reason: "missing bank key"}
+	}
+	if spent == nil {
+		spent = NewSpentDB()
+	}
+	b := &Bank{
+		info:     KeyInfo{N: key.N, Denominations: append([]int64(nil), Denominations...)},
+		keys:     make(map[string]*blindsig.PrivateKey),
+		spent:    spent,
+		accounts: make(map[string]*account),
+		sessions: make(map[string]*withdrawal),
+	}
+	for _, d := range Denominations {
+		for _, offline := range []bool{false, true} {
+			info := denominationInfo(d, offline)
+			k, err := key.Derive(info)
+			if err != nil {
+				return nil, err
+			}
+			b.keys[string(info)] = k
+		}
+	}
+	return b, nil
+}
+
+// OpenAccount - новый счёт name с начальным остатком balance; возвращается токен
+// доступа. Имя записывается в offline-монеты владельца, поэтому ограничено IdentitySize байтами.
+func (b *Bank) OpenAccount(name string, balance int64) (string, error) {
+	if _, err := identityOf(name); err != nil {
+		return "", err
+	}
+	if balance < 0 {
+		return "", &common.ParameterError{Name: "balance", Reason: "must not be negative"}
+	}
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.accounts[name]; ok {
+		return "", &common.ParameterError{Name: "account", Reason: fmt.Sprintf("%q already exists", name)}
+	}
+	b.accounts[name] = &account{token: token, balance: balance}
+	return token, nil
+}
+
+// authorize - счёт name с токеном token; вызывается под b.mu
+func (b *Bank) authorize(name, token string) (*account, error) {
+	acc, ok := b.accounts[name]
+	if !ok || subtle.ConstantTimeCompare([]byte(acc.token), []byte(token)) != 1 {
+		return nil, ErrUnauthorized
+	}
+	return acc, nil
+}
+
+// debit - списание amount со счёта до выдачи подписи
+func (b *Bank) debit(name, token string, amount int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	acc, err := b.authorize(name, token)
+	if err != nil {
+		return err
+	}
+	if acc.balance < amount {
+		return fmt.Errorf("%q has %d, needs %d: %w", name, acc.balance, amount, ErrInsufficientFunds)
+	}
+	acc.balance -= amount
+	return nil
+}
+
+// credit - зачисление amount; возвращается новый остаток
+func (b *Bank) credit(name string, amount int64) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	acc := b.accounts[name]
+	acc.balance += amount
+	return acc.balance
+}
+
+// denominationKey - закрытый ключ номинала
+func (b *Bank) denominationKey(denomination int64, offline bool) (*blindsig.PrivateKey, error) {
+	if err := validDenomination(denomination); err != nil {
+		return nil, err
+	}
+	return b.keys[string(denominationInfo(denomination, offline))], nil
+}
+
+// Key - открытый ключ банка
+func (b *Bank) Key(context.Context) (*KeyInfo, error) {
+	info := b.info
+	info.Denominations = append([]int64(nil), info.Denominations...)
+	return &info, nil
+}
+
+// Balance - остаток на счёте
+func (b *Bank) Balance(_ context.Context, name, token string) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	acc, err := b.authorize(name, token)
+	if err != nil {
+		return 0, err
+	}
+	return acc.balance, nil
+}
+
+// Withdraw - снятие монеты: со счёта списывается номинал, и банк вслепую подписывает
+// сообщение blinded ключом номинала. Серийного номера монеты банк не видит.
+func (b *Bank) Withdraw(_ context.Context, name, token string, denomination int64, blinded *big.Int) (*big.Int, error) {
+	key, err := b.denominationKey(denomination, false)
+	if err != nil {
+		return nil, err
+	}
+	if blinded == nil || blinded.Sign() <= 0 || blinded.Cmp(key.N) >= 0 {
+		return nil, &common.ParameterError{Name: "blinded", Reason: "blinded message is not in [1, N)"}
+	}
+	if err = b.debit(name, token, denomination); err != nil {
+		return nil, err
+	}
+	sig, err := key.BlindSign(blinded)
+	if err != nil {
+		b.credit(name, denomination)
+		return nil, err
+	}
+	if common.Tracing() {
+		common.Trace("ecash", "withdraw", common.ValInt("denomination", denomination), common.Val("blinded", blinded))
+	}
+	return sig, nil
+}
+
+// Deposit - внесение монеты на счёт продавца: проверка подписи, затем серийный номер
+// записывается в базу потраченных. Повторная трата даёт DoubleSpendError.
+func (b *Bank) Deposit(_ context.Context, name, token string, coin *Coin) (int64, error) {
+	b.mu.Lock()
+	_, err := b.authorize(name, token)
+	b.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	if coin == nil {
+		return 0, &common.ParameterError{Name: "coin", Reason: "missing"}
+	}
+	if err = coin.Verify(&b.info); err != nil {
+		return 0, err
+	}
+	if _, fresh, err := b.spent.Record(SpentRecord{Serial: coin.Serial, Account: name}); err != nil {
+		return 0, err
+	} else if !fresh {
+		return 0, &DoubleSpendError{Serial: coin.Serial}
+	}
+	return b.credit(name, coin.Denomination), nil
+}
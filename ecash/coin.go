@@ -0,0 +1,143 @@
+// Package ecash - электронные деньги Чаума на слепых подписях: банк выпускает монеты
+// фиксированных номиналов, подписывая их вслепую, пользователь снимает монеты со счёта
+// и платит ими продавцу, продавец вносит монеты на свой счёт. Повторная трата
+// обнаруживается по базе потраченных серийных номеров. Монеты для оплаты без связи
+// с банком (offline) содержат зашифрованную личность владельца: она раскрывается,
+// только если одну монету потратили дважды (Чаум, Фиат, Наор).
+package ecash
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/blindsig"
+	"github.com/Raimguzhinov/protect-information/common"
+	"io"
+	"math/big"
+)
+
+// Denominations - номиналы монет по убыванию
+var Denominations = []int64{100, 50, 20, 10, 5, 2, 1}
+
+// SerialSize - длина серийного номера монеты в байтах
+const SerialSize = 32
+
+// Coin - монета для оплаты со связью с банком: случайный серийный номер и подпись
+// банка ключом номинала под сообщением coinMessage
+type Coin struct {
+	Denomination int64    `json:"denomination"`
+	Serial       string   `json:"serial"`
+	Signature    *big.Int `json:"signature"`
+}
+
+// KeyInfo - открытый ключ банка: модуль и номиналы. Показатели ключей номиналов
+// выводятся из номинала (Denomination), поэтому банк не может пометить монету
+// пользователя особым ключом.
+type KeyInfo struct {
+	N             *big.Int `json:"n"`
+	Denominations []int64  `json:"denominations"`
+}
+
+// validDenomination - номинал есть в списке Denominations
+func validDenomination(denomination int64) error {
+	for _, d := range Denominations {
+		if d == denomination {
+			return nil
+		}
+	}
+	return fmt.Errorf("%d: %w", denomination, ErrUnknownDenomination)
+}
+
+// Denomination - ключ частично слепой подписи монет номинала denomination:
+// открытая информация - номинал и вид монеты, сообщение банк не видит
+func (k *KeyInfo) Denomination(denomination int64, offline bool) *blindsig.PublicKey {
+	return (&blindsig.PublicKey{N: k.N}).Derive(denominationInfo(denomination, offline))
+}
+
+// validate - модуль задан и номиналы совпадают с Denominations
+func (k *KeyInfo) validate() error {
+	if k == nil || k.N == nil || k.N.Sign() <= 0 {
+		return &common.ParameterError{Name: "bank key", Reason: "missing modulus"}
+	}
+	if len(k.Denominations) != len(Denominations) {
+		return &common.ParameterError{Name: "bank key", Reason: "unexpected denominations"}
+	}
+	for i, d := range k.Denominations {
+		if d != Denominations[i] {
+			return &common.ParameterError{Name: "bank key", Reason: "unexpected denominations"}
+		}
+	}
+	return nil
+}
+
+// denominationInfo - открытая информация ключа номинала
+func denominationInfo(denomination int64, offline bool) []byte {
+	if offline {
+		return []byte(fmt.Sprintf("ecash offline denomination %d", denomination))
+	}
+	return []byte(fmt.Sprintf("ecash denomination %d", denomination))
+}
+
+// coinMessage - сообщение, которое подписывает банк: серийный номер монеты
+func coinMessage(serial string) []byte {
+	return []byte("ecash coin\n" + serial)
+}
+
+// newSerial - случайный серийный номер в шестнадцатеричной записи
+func newSerial() (string, error) {
+	b := make([]byte, SerialSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Verify - подпись банка под монетой ключом её номинала
+func (c *Coin) Verify(key *KeyInfo) error {
+	if err := validDenomination(c.Denomination); err != nil {
+		return err
+	}
+	if b, err := hex.DecodeString(c.Serial); err != nil || len(b) != SerialSize {
+		return &common.ParameterError{Name: "serial", Reason: fmt.Sprintf("must be %d hex bytes", SerialSize)}
+	}
+	if err := key.Denomination(c.Denomination, false).Verify(coinMessage(c.Serial), c.Signature); err != nil {
+		return fmt.Errorf("coin %s: %w", c.Serial, err)
+	}
+	return nil
+}
+
+// seedReader - детерминированный поток SHA-256(seed || счётчик). Кошелёк ослепляет
+// кандидатов при снятии offline-монет случайностью из такого потока, а при проверке
+// раскрывает seed, и банк повторяет ослепление.
+type seedReader struct {
+	seed    []byte
+	counter uint32
+	buf     []byte
+}
+
+func (r *seedReader) Read(p []byte) (int, error) {
+	for len(r.buf) < len(p) {
+		var c [4]byte
+		binary.BigEndian.PutUint32(c[:], r.counter)
+		r.counter++
+		h := sha256.New()
+		h.Write([]byte("ecash blinding\n"))
+		h.Write(r.seed)
+		h.Write(c[:])
+		r.buf = h.Sum(r.buf)
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// randomBytes - n случайных байт
+func randomBytes(random io.Reader, n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(random, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,281 @@
+package ecash
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"github.com/Raimguzhinov/protect-information/blindsig"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+var (
+	testKeyOnce sync.Once
+	testKey     *blindsig.PrivateKey
+	testKeyErr  error
+)
+
+// newTestBank - банк с общим для тестов 1024-битным ключом
+func newTestBank(t *testing.T, spent *SpentDB) *Bank {
+	t.Helper()
+	testKeyOnce.Do(func() { testKey, testKeyErr = blindsig.GenerateKey(rand.Reader, 1024) })
+	if testKeyErr != nil {
+		t.Fatalf("GenerateKey() error = %v", testKeyErr)
+	}
+	bank, err := NewBank(testKey, spent)
+	if err != nil {
+		t.Fatalf("NewBank() error = %v", err)
+	}
+	return bank
+}
+
+// transports - обращение к банку в том же процессе и по HTTP
+var transports = []struct {
+	name string
+	http bool
+}{
+	{"в памяти", false},
+	{"по HTTP", true},
+}
+
+// connect - сам банк или клиент его HTTP-сервера на loopback
+func connect(t *testing.T, bank *Bank, overHTTP bool) BankAPI {
+	if !overHTTP {
+		return bank
+	}
+	srv := httptest.NewServer(NewHandler(bank, t.Logf))
+	t.Cleanup(srv.Close)
+	return &Client{BaseURL: srv.URL, HTTP: srv.Client()}
+}
+
+// openAccount - счёт с начальным остатком
+func openAccount(t *testing.T, bank *Bank, name string, balance int64) string {
+	t.Helper()
+	token, err := bank.OpenAccount(name, balance)
+	if err != nil {
+		t.Fatalf("OpenAccount(%q) error = %v", name, err)
+	}
+	return token
+}
+
+// wantBalance - остаток на счёте
+func wantBalance(t *testing.T, api BankAPI, name, token string, want int64) {
+	t.Helper()
+	got, err := api.Balance(context.Background(), name, token)
+	if err != nil {
+		t.Fatalf("Balance(%q) error = %v", name, err)
+	}
+	if got != want {
+		t.Errorf("Balance(%q) = %d, want %d", name, got, want)
+	}
+}
+
+func TestOnline(t *testing.T) {
+	for _, tr := range transports {
+		t.Run(tr.name, func(t *testing.T) {
+			ctx := context.Background()
+			bank := newTestBank(t, nil)
+			api := connect(t, bank, tr.http)
+			aliceToken := openAccount(t, bank, "alice", 200)
+			shopToken := openAccount(t, bank, "shop", 0)
+			wallet, err := NewWallet(ctx, api, "alice", aliceToken)
+			if err != nil {
+				t.Fatalf("NewWallet() error = %v", err)
+			}
+			shop, err := NewMerchant(ctx, api, "shop", shopToken)
+			if err != nil {
+				t.Fatalf("NewMerchant() error = %v", err)
+			}
+
+			if err = wallet.Withdraw(ctx, 137); err != nil {
+				t.Fatalf("Withdraw() error = %v", err)
+			}
+			wantBalance(t, api, "alice", aliceToken, 63)
+			if online, _ := wallet.Balance(); online != 137 {
+				t.Errorf("wallet.Balance() = %d, want 137", online)
+			}
+			if err = wallet.Withdraw(ctx, 100); !errors.Is(err, ErrInsufficientFunds) {
+				t.Errorf("Withdraw() beyond balance error = %v, want %v", err, ErrInsufficientFunds)
+			}
+			wantBalance(t, api, "alice", aliceToken, 63)
+
+			// 100 + 20 + 10 + 5 + 2: 37 = 20 + 10 + 5 + 2
+			cheater := wallet.Clone()
+			if err = wallet.Pay(ctx, shop, 37); err != nil {
+				t.Fatalf("Pay() error = %v", err)
+			}
+			wantBalance(t, api, "shop", shopToken, 37)
+			if online, _ := wallet.Balance(); online != 100 {
+				t.Errorf("wallet.Balance() after Pay = %d, want 100", online)
+			}
+			var ds *DoubleSpendError
+			if err = cheater.Pay(ctx, shop, 37); !errors.As(err, &ds) || !errors.Is(err, ErrDoubleSpent) {
+				t.Fatalf("Pay() with spent coins error = %v, want %T", err, ds)
+			}
+			wantBalance(t, api, "shop", shopToken, 37)
+			if err = wallet.Pay(ctx, shop, 3); !errors.Is(err, ErrNoChange) {
+				t.Errorf("Pay() without change error = %v, want %v", err, ErrNoChange)
+			}
+			if _, err = api.Balance(ctx, "alice", shopToken); !errors.Is(err, ErrUnauthorized) {
+				t.Errorf("Balance() with a foreign token error = %v, want %v", err, ErrUnauthorized)
+			}
+			if _, err = api.Withdraw(ctx, "alice", aliceToken, 3, big.NewInt(2)); !errors.Is(err, ErrUnknownDenomination) {
+				t.Errorf("Withdraw() of 3 error = %v, want %v", err, ErrUnknownDenomination)
+			}
+		})
+	}
+}
+
+// TestCoinRejected - монета без верной подписи ключом своего номинала не принимается
+func TestCoinRejected(t *testing.T) {
+	ctx := context.Background()
+	bank := newTestBank(t, nil)
+	token := openAccount(t, bank, "alice", 10)
+	shopToken := openAccount(t, bank, "shop", 0)
+	wallet, err := NewWallet(ctx, bank, "alice", token)
+	if err != nil {
+		t.Fatalf("NewWallet() error = %v", err)
+	}
+	if err = wallet.Withdraw(ctx, 1); err != nil {
+		t.Fatalf("Withdraw() error = %v", err)
+	}
+	coin := wallet.Coins()[0]
+	key, _ := bank.Key(ctx)
+	tests := []struct {
+		name   string
+		change func(c *Coin)
+		want   error
+	}{
+		{"другой номинал", func(c *Coin) { c.Denomination = 100 }, common.ErrVerification},
+		{"неизвестный номинал", func(c *Coin) { c.Denomination = 3 }, ErrUnknownDenomination},
+		{"другой серийный номер", func(c *Coin) { c.Serial = c.Serial[2:] + "00" }, common.ErrVerification},
+		{"короткий серийный номер", func(c *Coin) { c.Serial = "00" }, common.ErrInvalidParameters},
+		{"подпись s + N", func(c *Coin) { c.Signature = new(big.Int).Add(c.Signature, key.N) }, common.ErrVerification},
+		{"нет подписи", func(c *Coin) { c.Signature = nil }, common.ErrVerification},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := coin
+			tt.change(&c)
+			if err := c.Verify(key); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+			if _, err := bank.Deposit(ctx, "shop", shopToken, &c); !errors.Is(err, tt.want) {
+				t.Errorf("Deposit() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if err = coin.Verify(key); err != nil {
+		t.Errorf("Verify() of the original coin error = %v", err)
+	}
+}
+
+func TestSelectCoins(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []int64
+		amount int64
+		want   []int64 // номиналы выбранных монет по убыванию
+		err    error
+	}{
+		{"жадный выбор", []int64{50, 20, 10, 2}, 30, []int64{20, 10}, nil},
+		{"жадный выбор не подходит", []int64{5, 2, 2, 2}, 6, []int64{2, 2, 2}, nil},
+		{"наименьшее число монет", []int64{1, 1, 1, 1, 2, 2}, 4, []int64{2, 2}, nil},
+		{"все монеты", []int64{100, 5}, 105, []int64{100, 5}, nil},
+		{"не хватает", []int64{10, 5}, 20, nil, ErrInsufficientFunds},
+		{"без сдачи нельзя", []int64{10, 5}, 7, nil, ErrNoChange},
+		{"нулевая сумма", []int64{10}, 0, nil, common.ErrInvalidParameters},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			picked, err := selectCoins(tt.values, tt.amount)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("selectCoins() error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			var got []int64
+			seen := make(map[int]bool)
+			for _, i := range picked {
+				if seen[i] {
+					t.Fatalf("selectCoins() = %v picks coin %d twice", picked, i)
+				}
+				seen[i] = true
+				got = append(got, tt.values[i])
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i] > got[j] })
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectCoins() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSpentDB - база в файле переживает перезапуск банка, оборванная строка отбрасывается
+func TestSpentDB(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "spent.jsonl")
+	db, err := OpenSpentDB(path)
+	if err != nil {
+		t.Fatalf("OpenSpentDB() error = %v", err)
+	}
+	bank := newTestBank(t, db)
+	token := openAccount(t, bank, "alice", 20)
+	shopToken := openAccount(t, bank, "shop", 0)
+	wallet, err := NewWallet(ctx, bank, "alice", token)
+	if err != nil {
+		t.Fatalf("NewWallet() error = %v", err)
+	}
+	if err = wallet.Withdraw(ctx, 20); err != nil {
+		t.Fatalf("Withdraw() error = %v", err)
+	}
+	coin := wallet.Coins()[0]
+	if _, err = bank.Deposit(ctx, "shop", shopToken, &coin); err != nil {
+		t.Fatalf("Deposit() error = %v", err)
+	}
+	if err = db.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Запись, оборванная при сбое
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.WriteString(`{"serial":"ab`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	db, err = OpenSpentDB(path)
+	if err != nil {
+		t.Fatalf("OpenSpentDB() after restart error = %v", err)
+	}
+	defer db.Close()
+	if db.Len() != 1 {
+		t.Fatalf("Len() = %d, want 1", db.Len())
+	}
+	if rec, ok := db.Lookup(coin.Serial); !ok || rec.Account != "shop" {
+		t.Errorf("Lookup() = %+v, %v, want the shop deposit", rec, ok)
+	}
+	bank = newTestBank(t, db)
+	shopToken = openAccount(t, bank, "shop", 0)
+	if _, err = bank.Deposit(ctx, "shop", shopToken, &coin); !errors.Is(err, ErrDoubleSpent) {
+		t.Errorf("Deposit() after restart error = %v, want %v", err, ErrDoubleSpent)
+	}
+
+	if err = os.WriteFile(path, []byte("{}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = OpenSpentDB(path); !errors.Is(err, ErrCorruptSpentDB) {
+		t.Errorf("OpenSpentDB() of a corrupt file error = %v, want %v", err, ErrCorruptSpentDB)
+	}
+}
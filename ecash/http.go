@@ -0,0 +1,375 @@
+package ecash
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/jsonio"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+// Пути HTTP API банка
+const (
+	PathKey            = "/key"
+	PathBalance        = "/balance"
+	PathWithdraw       = "/withdraw"
+	PathOfflineBegin   = "/withdraw/offline"
+	PathOfflineFinish  = "/withdraw/offline/open"
+	PathDeposit        = "/deposit"
+	PathDepositOffline = "/deposit/offline"
+)
+
+// maxRequestBody - предел размера JSON-запроса: раскрытия семи кандидатов занимают ~60 КБ
+const maxRequestBody = 256 << 10
+
+// AccountRequest - счёт и токен доступа
+type AccountRequest struct {
+	Account string `json:"account"`
+	Token   string `json:"token"`
+}
+
+// WithdrawRequest - запрос слепой подписи монеты
+type WithdrawRequest struct {
+	AccountRequest
+	Denomination int64    `json:"denomination"`
+	Blinded      *big.Int `json:"blinded"`
+}
+
+// OfflineBeginRequest - ослеплённые кандидаты offline-монеты
+type OfflineBeginRequest struct {
+	AccountRequest
+	Denomination int64      `json:"denomination"`
+	Blinded      []*big.Int `json:"blinded"`
+}
+
+// OfflineFinishRequest - раскрытие кандидатов offline-монеты
+type OfflineFinishRequest struct {
+	AccountRequest
+	Session  string             `json:"session"`
+	Openings []CandidateOpening `json:"openings"`
+}
+
+// DepositRequest - внесение монеты
+type DepositRequest struct {
+	AccountRequest
+	Coin *Coin `json:"coin"`
+}
+
+// OfflineDepositRequest - внесение offline-платежа
+type OfflineDepositRequest struct {
+	AccountRequest
+	Payment *Payment `json:"payment"`
+}
+
+// SignatureResponse - слепая подпись банка
+type SignatureResponse struct {
+	Signature *big.Int `json:"signature"`
+}
+
+// BalanceResponse - остаток на счёте
+type BalanceResponse struct {
+	Balance int64 `json:"balance"`
+}
+
+// ErrorResponse - тело ответа с ошибкой. Code позволяет клиенту восстановить
+// ошибку-признак, Serial и Identity заполняются при повторной трате.
+type ErrorResponse struct {
+	Error    string `json:"error"`
+	Code     string `json:"code,omitempty"`
+	Serial   string `json:"serial,omitempty"`
+	Identity string `json:"identity,omitempty"`
+}
+
+// errorCodes - ошибки-признаки, их коды и коды ответа HTTP
+var errorCodes = []struct {
+	err    error
+	code   string
+	status int
+}{
+	{ErrUnauthorized, "unauthorized", http.StatusForbidden},
+	{ErrInsufficientFunds, "insufficient-funds", http.StatusPaymentRequired},
+	{ErrUnknownDenomination, "unknown-denomination", http.StatusBadRequest},
+	{ErrUnknownSession, "unknown-session", http.StatusNotFound},
+	{ErrCheating, "cheating", http.StatusForbidden},
+	{ErrDoubleSpent, "double-spent", http.StatusConflict},
+	{ErrDoubleDeposit, "double-deposit", http.StatusConflict},
+	{common.ErrVerification, "verification", http.StatusBadRequest},
+	{common.ErrInvalidParameters, "invalid-parameters", http.StatusBadRequest},
+}
+
+// bankService - HTTP-обработчики банка
+type bankService struct {
+	bank *Bank
+	logf func(format string, args ...any)
+}
+
+// NewHandler - HTTP API банка b. В logf уходят внутренние ошибки банка и ошибки записи
+// ответа; nil - без журнала.
+func NewHandler(b *Bank, logf func(format string, args ...any)) http.Handler {
+	svc := &bankService{bank: b, logf: logf}
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathKey, svc.method(http.MethodGet, svc.key))
+	mux.HandleFunc(PathBalance, svc.method(http.MethodPost, svc.balance))
+	mux.HandleFunc(PathWithdraw, svc.method(http.MethodPost, svc.withdraw))
+	mux.HandleFunc(PathOfflineBegin, svc.method(http.MethodPost, svc.offlineBegin))
+	mux.HandleFunc(PathOfflineFinish, svc.method(http.MethodPost, svc.offlineFinish))
+	mux.HandleFunc(PathDeposit, svc.method(http.MethodPost, svc.deposit))
+	mux.HandleFunc(PathDepositOffline, svc.method(http.MethodPost, svc.depositOffline))
+	return mux
+}
+
+// writeJSON - ответ с кодом status и телом v
+func (svc *bankService) writeJSON(w http.ResponseWriter, status int, v any) {
+	if err := jsonio.WriteJSON(w, status, v); err != nil && svc.logf != nil {
+		svc.logf("Банк: ошибка записи ответа: %v", err)
+	}
+}
+
+// writeError - ответ с ошибкой: код HTTP и Code по первой подходящей ошибке-признаку
+func (svc *bankService) writeError(w http.ResponseWriter, err error) {
+	resp := ErrorResponse{Error: err.Error()}
+	status := http.StatusInternalServerError
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			resp.Code, status = c.code, c.status
+			break
+		}
+	}
+	var ds *DoubleSpendError
+	if errors.As(err, &ds) {
+		resp.Serial, resp.Identity = ds.Serial, ds.Identity
+	}
+	if status == http.StatusInternalServerError {
+		if svc.logf != nil {
+			svc.logf("Банк: %v", err)
+		}
+		resp.Error = "internal error"
+	}
+	svc.writeJSON(w, status, resp)
+}
+
+// writeMessage - ответ с ошибкой запроса без ошибки-признака
+func (svc *bankService) writeMessage(w http.ResponseWriter, status int, msg string) {
+	svc.writeJSON(w, status, ErrorResponse{Error: msg})
+}
+
+// method - обработчик только для указанного метода
+func (svc *bankService) method(method string, h http.HandlerFunc) http.HandlerFunc {
+	return jsonio.Method(method, svc.writeMessage, h)
+}
+
+// readJSON - разбор тела запроса; при ошибке ответ уже отправлен
+func (svc *bankService) readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	return jsonio.ReadJSON(w, r, maxRequestBody, v, svc.writeMessage)
+}
+
+func (svc *bankService) key(w http.ResponseWriter, r *http.Request) {
+	key, err := svc.bank.Key(r.Context())
+	if err != nil {
+		svc.writeError(w, err)
+		return
+	}
+	svc.writeJSON(w, http.StatusOK, key)
+}
+
+func (svc *bankService) balance(w http.ResponseWriter, r *http.Request) {
+	var req AccountRequest
+	if !svc.readJSON(w, r, &req) {
+		return
+	}
+	balance, err := svc.bank.Balance(r.Context(), req.Account, req.Token)
+	if err != nil {
+		svc.writeError(w, err)
+		return
+	}
+	svc.writeJSON(w, http.StatusOK, BalanceResponse{Balance: balance})
+}
+
+func (svc *bankService) withdraw(w http.ResponseWriter, r *http.Request) {
+	var req WithdrawRequest
+	if !svc.readJSON(w, r, &req) {
+		return
+	}
+	sig, err := svc.bank.Withdraw(r.Context(), req.Account, req.Token, req.Denomination, req.Blinded)
+	if err != nil {
+		svc.writeError(w, err)
+		return
+	}
+	svc.writeJSON(w, http.StatusOK, SignatureResponse{Signature: sig})
+}
+
+func (svc *bankService) offlineBegin(w http.ResponseWriter, r *http.Request) {
+	var req OfflineBeginRequest
+	if !svc.readJSON(w, r, &req) {
+		return
+	}
+	session, err := svc.bank.BeginOfflineWithdrawal(r.Context(), req.Account, req.Token, req.Denomination, req.Blinded)
+	if err != nil {
+		svc.writeError(w, err)
+		return
+	}
+	svc.writeJSON(w, http.StatusOK, session)
+}
+
+func (svc *bankService) offlineFinish(w http.ResponseWriter, r *http.Request) {
+	var req OfflineFinishRequest
+	if !svc.readJSON(w, r, &req) {
+		return
+	}
+	sig, err := svc.bank.FinishOfflineWithdrawal(r.Context(), req.Account, req.Token, req.Session, req.Openings)
+	if err != nil {
+		svc.writeError(w, err)
+		return
+	}
+	svc.writeJSON(w, http.StatusOK, SignatureResponse{Signature: sig})
+}
+
+func (svc *bankService) deposit(w http.ResponseWriter, r *http.Request) {
+	var req DepositRequest
+	if !svc.readJSON(w, r, &req) {
+		return
+	}
+	balance, err := svc.bank.Deposit(r.Context(), req.Account, req.Token, req.Coin)
+	if err != nil {
+		svc.writeError(w, err)
+		return
+	}
+	svc.writeJSON(w, http.StatusOK, BalanceResponse{Balance: balance})
+}
+
+func (svc *bankService) depositOffline(w http.ResponseWriter, r *http.Request) {
+	var req OfflineDepositRequest
+	if !svc.readJSON(w, r, &req) {
+		return
+	}
+	balance, err := svc.bank.DepositOffline(r.Context(), req.Account, req.Token, req.Payment)
+	if err != nil {
+		svc.writeError(w, err)
+		return
+	}
+	svc.writeJSON(w, http.StatusOK, BalanceResponse{Balance: balance})
+}
+
+// Client - клиент HTTP API банка; реализует BankAPI
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client // nil - http.DefaultClient
+}
+
+var _ BankAPI = (*Client)(nil)
+
+// do - JSON-запрос; ответ с ошибкой превращается в ошибку-признак по ErrorResponse.Code
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseURL, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var e ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		return responseError(method+" "+path, resp.Status, &e)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// responseError - ошибка по ответу банка
+func responseError(op, status string, e *ErrorResponse) error {
+	if e.Code == "double-spent" {
+		return &DoubleSpendError{Serial: e.Serial, Identity: e.Identity}
+	}
+	for _, c := range errorCodes {
+		if c.code == e.Code {
+			return fmt.Errorf("%s: %s: %w", op, e.Error, c.err)
+		}
+	}
+	return fmt.Errorf("%s: %s: %s", op, status, e.Error)
+}
+
+// Key - открытый ключ банка
+func (c *Client) Key(ctx context.Context) (*KeyInfo, error) {
+	var key KeyInfo
+	if err := c.do(ctx, http.MethodGet, PathKey, nil, &key); err != nil {
+		return nil, err
+	}
+	if err := key.validate(); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// Balance - остаток на счёте
+func (c *Client) Balance(ctx context.Context, account, token string) (int64, error) {
+	var resp BalanceResponse
+	err := c.do(ctx, http.MethodPost, PathBalance, AccountRequest{Account: account, Token: token}, &resp)
+	return resp.Balance, err
+}
+
+// Withdraw - слепая подпись монеты номинала denomination
+func (c *Client) Withdraw(ctx context.Context, account, token string, denomination int64, blinded *big.Int) (*big.Int, error) {
+	var resp SignatureResponse
+	req := WithdrawRequest{AccountRequest: AccountRequest{Account: account, Token: token}, Denomination: denomination, Blinded: blinded}
+	if err := c.do(ctx, http.MethodPost, PathWithdraw, req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Signature, nil
+}
+
+// Deposit - внесение монеты; возвращается новый остаток
+func (c *Client) Deposit(ctx context.Context, account, token string, coin *Coin) (int64, error) {
+	var resp BalanceResponse
+	req := DepositRequest{AccountRequest: AccountRequest{Account: account, Token: token}, Coin: coin}
+	err := c.do(ctx, http.MethodPost, PathDeposit, req, &resp)
+	return resp.Balance, err
+}
+
+// BeginOfflineWithdrawal - отправка ослеплённых кандидатов offline-монеты
+func (c *Client) BeginOfflineWithdrawal(ctx context.Context, account, token string, denomination int64, blinded []*big.Int) (*OfflineWithdrawal, error) {
+	var resp OfflineWithdrawal
+	req := OfflineBeginRequest{AccountRequest: AccountRequest{Account: account, Token: token}, Denomination: denomination, Blinded: blinded}
+	if err := c.do(ctx, http.MethodPost, PathOfflineBegin, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// FinishOfflineWithdrawal - раскрытие кандидатов и слепая подпись выбранного
+func (c *Client) FinishOfflineWithdrawal(ctx context.Context, account, token, session string, openings []CandidateOpening) (*big.Int, error) {
+	var resp SignatureResponse
+	req := OfflineFinishRequest{AccountRequest: AccountRequest{Account: account, Token: token}, Session: session, Openings: openings}
+	if err := c.do(ctx, http.MethodPost, PathOfflineFinish, req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Signature, nil
+}
+
+// DepositOffline - внесение offline-платежа; возвращается новый остаток
+func (c *Client) DepositOffline(ctx context.Context, account, token string, payment *Payment) (int64, error) {
+	var resp BalanceResponse
+	req := OfflineDepositRequest{AccountRequest: AccountRequest{Account: account, Token: token}, Payment: payment}
+	err := c.do(ctx, http.MethodPost, PathDepositOffline, req, &resp)
+	return resp.Balance, err
+}
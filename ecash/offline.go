@@ -0,0 +1,370 @@
+package ecash
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+	"strings"
+)
+
+const (
+	// IdentitySize - длина личности владельца в offline-монете: имя счёта, дополненное нулями
+	IdentitySize = 32
+	// OfflinePairs - число пар в монете; вызов продавца раскрывает по половине каждой пары
+	OfflinePairs = 32
+	// OfflineCandidates - кандидатов при снятии; банк подписывает один, остальные раскрываются.
+	// Неверный кандидат проходит проверку с вероятностью 1/OfflineCandidates.
+	OfflineCandidates = 8
+	// NonceSize - длина случайного вызова продавца в байтах
+	NonceSize = 16
+	// saltSize - длина соли обязательства в байтах
+	saltSize = 16
+)
+
+// Opening - раскрытие половины пары: значение и соль обязательства
+type Opening struct {
+	Value []byte `json:"value"`
+	Salt  []byte `json:"salt"`
+}
+
+// OfflineCoin - монета для оплаты без связи с банком. Пара i - обязательства
+// к a_i и a_i ⊕ u, где u - личность владельца: одна половина ничего не говорит
+// о u, обе половины одной пары раскрывают её.
+type OfflineCoin struct {
+	Denomination int64       `json:"denomination"`
+	Commitments  [][2][]byte `json:"commitments"`
+	Signature    *big.Int    `json:"signature"`
+}
+
+// CandidateOpening - раскрытие кандидата при снятии: seed ослепления и обе половины всех пар
+type CandidateOpening struct {
+	Index int          `json:"index"`
+	Seed  []byte       `json:"seed"`
+	Pairs [][2]Opening `json:"pairs"`
+}
+
+// OfflineWithdrawal - первый шаг снятия offline-монеты: сеанс и номер кандидата,
+// который банк подпишет; остальные кандидаты кошелёк раскрывает
+type OfflineWithdrawal struct {
+	Session string `json:"session"`
+	Keep    int    `json:"keep"`
+}
+
+// Payment - оплата offline-монетой: вызов задаётся продавцом (Merchant, Nonce),
+// Responses - раскрытые по битам вызова половины пар
+type Payment struct {
+	Coin      OfflineCoin `json:"coin"`
+	Merchant  string      `json:"merchant"`
+	Nonce     []byte      `json:"nonce"`
+	Responses []Opening   `json:"responses"`
+}
+
+// withdrawal - незавершённое снятие offline-монеты
+type withdrawal struct {
+	account      string
+	denomination int64
+	blinded      []*big.Int
+	keep         int
+}
+
+// identityOf - имя счёта, дополненное нулями до IdentitySize байт
+func identityOf(name string) ([]byte, error) {
+	if name == "" || len(name) > IdentitySize || strings.IndexByte(name, 0) >= 0 {
+		return nil, &common.ParameterError{Name: "account", Reason: fmt.Sprintf("name must be 1..%d bytes without NUL", IdentitySize)}
+	}
+	id := make([]byte, IdentitySize)
+	copy(id, name)
+	return id, nil
+}
+
+// xorBytes - a ⊕ b для срезов одной длины
+func xorBytes(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
+
+// commit - обязательство к половине side пары i
+func commit(i, side int, o Opening) []byte {
+	var idx [4]byte
+	binary.BigEndian.PutUint32(idx[:], uint32(i))
+	h := sha256.New()
+	h.Write([]byte("ecash pair\n"))
+	h.Write(idx[:])
+	h.Write([]byte{byte(side)})
+	h.Write(o.Value)
+	h.Write(o.Salt)
+	return h.Sum(nil)
+}
+
+// newPairs - пары (a_i, a_i ⊕ identity) со случайными a_i и солями
+func newPairs(identity []byte) ([][2]Opening, error) {
+	pairs := make([][2]Opening, OfflinePairs)
+	for i := range pairs {
+		a, err := randomBytes(rand.Reader, IdentitySize)
+		if err != nil {
+			return nil, err
+		}
+		for side, value := range [][]byte{a, xorBytes(a, identity)} {
+			salt, err := randomBytes(rand.Reader, saltSize)
+			if err != nil {
+				return nil, err
+			}
+			pairs[i][side] = Opening{Value: value, Salt: salt}
+		}
+	}
+	return pairs, nil
+}
+
+// checkPairs - все пары правильного размера и раскрывают личность identity
+func checkPairs(pairs [][2]Opening, identity []byte) error {
+	if len(pairs) != OfflinePairs {
+		return fmt.Errorf("%d pairs, want %d: %w", len(pairs), OfflinePairs, ErrCheating)
+	}
+	for i, p := range pairs {
+		for _, o := range p {
+			if len(o.Value) != IdentitySize || len(o.Salt) != saltSize {
+				return fmt.Errorf("pair %d is malformed: %w", i, ErrCheating)
+			}
+		}
+		if !bytes.Equal(xorBytes(p[0].Value, p[1].Value), identity) {
+			return fmt.Errorf("pair %d hides another identity: %w", i, ErrCheating)
+		}
+	}
+	return nil
+}
+
+// newOfflineCoin - неподписанная монета по раскрытым парам
+func newOfflineCoin(denomination int64, pairs [][2]Opening) *OfflineCoin {
+	c := &OfflineCoin{Denomination: denomination, Commitments: make([][2][]byte, len(pairs))}
+	for i, p := range pairs {
+		c.Commitments[i] = [2][]byte{commit(i, 0, p[0]), commit(i, 1, p[1])}
+	}
+	return c
+}
+
+// message - сообщение, которое подписывает банк: все обязательства монеты
+func (c *OfflineCoin) message() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("ecash offline coin\n")
+	for _, p := range c.Commitments {
+		fmt.Fprintf(&buf, "%x %x\n", p[0], p[1])
+	}
+	return buf.Bytes()
+}
+
+// Serial - серийный номер монеты: SHA-256 подписанного сообщения
+func (c *OfflineCoin) Serial() string {
+	sum := sha256.Sum256(c.message())
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify - форма монеты и подпись банка offline-ключом номинала
+func (c *OfflineCoin) Verify(key *KeyInfo) error {
+	if err := validDenomination(c.Denomination); err != nil {
+		return err
+	}
+	if len(c.Commitments) != OfflinePairs {
+		return &common.ParameterError{Name: "coin", Reason: fmt.Sprintf("must have %d pairs", OfflinePairs)}
+	}
+	for _, p := range c.Commitments {
+		if len(p[0]) != sha256.Size || len(p[1]) != sha256.Size {
+			return &common.ParameterError{Name: "coin", Reason: "commitment must be a SHA-256 hash"}
+		}
+	}
+	if err := key.Denomination(c.Denomination, true).Verify(c.message(), c.Signature); err != nil {
+		return fmt.Errorf("coin %s: %w", c.Serial(), err)
+	}
+	return nil
+}
+
+// challengeBits - биты вызова: SHA-256 серийного номера, продавца и его случайного числа.
+// Продавец не может повторить чужой вызов, а покупатель - выбрать его.
+func challengeBits(serial, merchant string, nonce []byte) []int {
+	h := sha256.New()
+	h.Write([]byte("ecash challenge\n" + serial + "\n" + merchant + "\n"))
+	h.Write(nonce)
+	sum := h.Sum(nil)
+	bits := make([]int, OfflinePairs)
+	for i := range bits {
+		bits[i] = int(sum[i/8]>>(7-i%8)) & 1
+	}
+	return bits
+}
+
+// Verify - монета подписана банком, и каждое раскрытие соответствует обязательству,
+// выбранному битом вызова
+func (p *Payment) Verify(key *KeyInfo) error {
+	if err := p.Coin.Verify(key); err != nil {
+		return err
+	}
+	if len(p.Nonce) != NonceSize || len(p.Responses) != OfflinePairs {
+		return &common.ParameterError{Name: "payment", Reason: "malformed challenge or responses"}
+	}
+	serial := p.Coin.Serial()
+	for i, bit := range challengeBits(serial, p.Merchant, p.Nonce) {
+		r := p.Responses[i]
+		if len(r.Value) != IdentitySize || !bytes.Equal(commit(i, bit, r), p.Coin.Commitments[i][bit]) {
+			return fmt.Errorf("coin %s: response %d: %w", serial, i, common.ErrVerification)
+		}
+	}
+	return nil
+}
+
+// identify - владелец монеты по двум проверенным платежам: в паре, где биты вызовов
+// различны, раскрыты a_i и a_i ⊕ u. Пусто, если вызовы совпали.
+func identify(a, b *Payment) string {
+	bitsA := challengeBits(a.Coin.Serial(), a.Merchant, a.Nonce)
+	bitsB := challengeBits(b.Coin.Serial(), b.Merchant, b.Nonce)
+	for i := range bitsA {
+		if bitsA[i] != bitsB[i] {
+			return strings.TrimRight(string(xorBytes(a.Responses[i].Value, b.Responses[i].Value)), "\x00")
+		}
+	}
+	return ""
+}
+
+// BeginOfflineWithdrawal - первый шаг снятия offline-монеты (разрежь и выбери): кошелёк
+// присылает OfflineCandidates ослеплённых кандидатов, банк списывает номинал и случайно
+// выбирает кандидата для подписи.
+func (b *Bank) BeginOfflineWithdrawal(_ context.Context, name, token string, denomination int64, blinded []*big.Int) (*OfflineWithdrawal, error) {
+	key, err := b.denominationKey(denomination, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(blinded) != OfflineCandidates {
+		return nil, &common.ParameterError{Name: "blinded", Reason: fmt.Sprintf("need %d candidates", OfflineCandidates)}
+	}
+	for _, m := range blinded {
+		if m == nil || m.Sign() <= 0 || m.Cmp(key.N) >= 0 {
+			return nil, &common.ParameterError{Name: "blinded", Reason: "blinded message is not in [1, N)"}
+		}
+	}
+	keep, err := rand.Int(rand.Reader, big.NewInt(OfflineCandidates))
+	if err != nil {
+		return nil, err
+	}
+	raw, err := randomBytes(rand.Reader, 16)
+	if err != nil {
+		return nil, err
+	}
+	if err = b.debit(name, token, denomination); err != nil {
+		return nil, err
+	}
+	w := &OfflineWithdrawal{Session: hex.EncodeToString(raw), Keep: int(keep.Int64())}
+	b.mu.Lock()
+	b.sessions[w.Session] = &withdrawal{account: name, denomination: denomination, blinded: blinded, keep: w.Keep}
+	b.mu.Unlock()
+	return w, nil
+}
+
+// FinishOfflineWithdrawal - второй шаг: кошелёк раскрывает всех кандидатов, кроме
+// выбранного. Банк повторяет их построение и ослепление; если все они содержат личность
+// владельца счёта, выбранный кандидат подписывается вслепую. Неверное раскрытие
+// завершает сеанс с ErrCheating, и списанный номинал не возвращается - иначе кошелёк
+// повторял бы снятие, пока банк не выберет поддельного кандидата.
+func (b *Bank) FinishOfflineWithdrawal(_ context.Context, name, token, session string, openings []CandidateOpening) (*big.Int, error) {
+	identity, err := identityOf(name)
+	if err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	_, err = b.authorize(name, token)
+	w, ok := b.sessions[session]
+	if err == nil && (!ok || w.account != name) {
+		err = ErrUnknownSession
+	}
+	if err == nil {
+		err = checkOpenings(openings, w.keep)
+	}
+	if err == nil {
+		delete(b.sessions, session)
+	}
+	b.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	key := b.keys[string(denominationInfo(w.denomination, true))]
+	for _, o := range openings {
+		if err = checkPairs(o.Pairs, identity); err != nil {
+			return nil, fmt.Errorf("candidate %d: %w", o.Index, err)
+		}
+		coin := newOfflineCoin(w.denomination, o.Pairs)
+		m, _, err := key.Blind(&seedReader{seed: o.Seed}, coin.message())
+		if err != nil {
+			return nil, err
+		}
+		if m.Cmp(w.blinded[o.Index]) != 0 {
+			return nil, fmt.Errorf("candidate %d is blinded differently: %w", o.Index, ErrCheating)
+		}
+	}
+	sig, err := key.BlindSign(w.blinded[w.keep])
+	if err != nil {
+		b.credit(name, w.denomination)
+		return nil, err
+	}
+	if common.Tracing() {
+		common.Trace("ecash", "offline withdraw", common.ValInt("denomination", w.denomination), common.ValInt("kept", int64(w.keep)))
+	}
+	return sig, nil
+}
+
+// checkOpenings - раскрыты все кандидаты, кроме keep, каждый ровно один раз.
+// Ошибка формы не завершает сеанс.
+func checkOpenings(openings []CandidateOpening, keep int) error {
+	if len(openings) != OfflineCandidates-1 {
+		return &common.ParameterError{Name: "openings", Reason: fmt.Sprintf("need %d openings", OfflineCandidates-1)}
+	}
+	seen := make(map[int]bool, len(openings))
+	for _, o := range openings {
+		if o.Index < 0 || o.Index >= OfflineCandidates || o.Index == keep || seen[o.Index] {
+			return &common.ParameterError{Name: "openings", Reason: fmt.Sprintf("unexpected candidate %d", o.Index)}
+		}
+		seen[o.Index] = true
+	}
+	return nil
+}
+
+// DepositOffline - внесение платежа offline-монетой на счёт продавца. Повтор того же
+// платежа даёт ErrDoubleDeposit; второй платёж той же монетой с другим вызовом -
+// DoubleSpendError с именем владельца.
+func (b *Bank) DepositOffline(_ context.Context, name, token string, p *Payment) (int64, error) {
+	b.mu.Lock()
+	_, err := b.authorize(name, token)
+	b.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	if p == nil {
+		return 0, &common.ParameterError{Name: "payment", Reason: "missing"}
+	}
+	if p.Merchant != name {
+		return 0, &common.ParameterError{Name: "payment", Reason: fmt.Sprintf("payment was made to %q", p.Merchant)}
+	}
+	if err = p.Verify(&b.info); err != nil {
+		return 0, err
+	}
+	serial := p.Coin.Serial()
+	prev, fresh, err := b.spent.Record(SpentRecord{Serial: serial, Account: name, Payment: p})
+	if err != nil {
+		return 0, err
+	}
+	if !fresh {
+		if prev.Payment == nil {
+			return 0, &DoubleSpendError{Serial: serial}
+		}
+		if prev.Payment.Merchant == p.Merchant && bytes.Equal(prev.Payment.Nonce, p.Nonce) {
+			return 0, fmt.Errorf("coin %s: %w", serial, ErrDoubleDeposit)
+		}
+		return 0, &DoubleSpendError{Serial: serial, Identity: identify(prev.Payment, p)}
+	}
+	return b.credit(name, p.Coin.Denomination), nil
+}
@@ -0,0 +1,250 @@
+package ecash
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"github.com/Raimguzhinov/protect-information/blindsig"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+	"testing"
+)
+
+func TestOffline(t *testing.T) {
+	for _, tr := range transports {
+		t.Run(tr.name, func(t *testing.T) {
+			ctx := context.Background()
+			bank := newTestBank(t, nil)
+			api := connect(t, bank, tr.http)
+			aliceToken := openAccount(t, bank, "alice", 50)
+			cafeToken := openAccount(t, bank, "cafe", 0)
+			kioskToken := openAccount(t, bank, "kiosk", 0)
+			wallet, err := NewWallet(ctx, api, "alice", aliceToken)
+			if err != nil {
+				t.Fatalf("NewWallet() error = %v", err)
+			}
+			cafe, err := NewMerchant(ctx, api, "cafe", cafeToken)
+			if err != nil {
+				t.Fatalf("NewMerchant() error = %v", err)
+			}
+			kiosk, err := NewMerchant(ctx, api, "kiosk", kioskToken)
+			if err != nil {
+				t.Fatalf("NewMerchant() error = %v", err)
+			}
+
+			if err = wallet.WithdrawOffline(ctx, 30); err != nil {
+				t.Fatalf("WithdrawOffline() error = %v", err)
+			}
+			wantBalance(t, api, "alice", aliceToken, 20)
+			if _, offline := wallet.Balance(); offline != 30 {
+				t.Errorf("wallet.Balance() offline = %d, want 30", offline)
+			}
+
+			// Честный платёж и повторная трата той же монеты у другого продавца
+			cheater := wallet.Clone()
+			if err = wallet.PayOffline(cafe, 20); err != nil {
+				t.Fatalf("PayOffline() error = %v", err)
+			}
+			if err = cheater.PayOffline(kiosk, 20); err != nil {
+				t.Fatalf("PayOffline() of a spent coin error = %v, want acceptance without the bank", err)
+			}
+			payment := cafe.pending[0]
+			if got, err := cafe.DepositPending(ctx); err != nil || got != 20 {
+				t.Fatalf("DepositPending() = %d, %v, want 20", got, err)
+			}
+			var ds *DoubleSpendError
+			if got, err := kiosk.DepositPending(ctx); !errors.As(err, &ds) || got != 0 {
+				t.Fatalf("DepositPending() of a double-spent coin = %d, %v, want %T", got, err, ds)
+			}
+			if ds.Identity != "alice" || ds.Serial != payment.Coin.Serial() {
+				t.Errorf("DoubleSpendError = %+v, want alice and serial %s", ds, payment.Coin.Serial())
+			}
+			wantBalance(t, api, "cafe", cafeToken, 20)
+			wantBalance(t, api, "kiosk", kioskToken, 0)
+
+			// Продавец повторно вносит тот же платёж: личность не раскрывается
+			if _, err = api.DepositOffline(ctx, "cafe", cafeToken, payment); !errors.Is(err, ErrDoubleDeposit) {
+				t.Errorf("DepositOffline() of the same payment error = %v, want %v", err, ErrDoubleDeposit)
+			}
+			if _, err = api.DepositOffline(ctx, "kiosk", kioskToken, payment); !errors.Is(err, common.ErrInvalidParameters) {
+				t.Errorf("DepositOffline() of a payment to another merchant error = %v, want %v", err, common.ErrInvalidParameters)
+			}
+			if err = wallet.PayOffline(cafe, 5); !errors.Is(err, ErrNoChange) {
+				t.Errorf("PayOffline() without change error = %v, want %v", err, ErrNoChange)
+			}
+		})
+	}
+}
+
+// TestPaymentRejected - продавец проверяет платёж без связи с банком
+func TestPaymentRejected(t *testing.T) {
+	ctx := context.Background()
+	bank := newTestBank(t, nil)
+	token := openAccount(t, bank, "alice", 10)
+	shopToken := openAccount(t, bank, "shop", 0)
+	wallet, err := NewWallet(ctx, bank, "alice", token)
+	if err != nil {
+		t.Fatalf("NewWallet() error = %v", err)
+	}
+	shop, err := NewMerchant(ctx, bank, "shop", shopToken)
+	if err != nil {
+		t.Fatalf("NewMerchant() error = %v", err)
+	}
+	if err = wallet.WithdrawOffline(ctx, 10); err != nil {
+		t.Fatalf("WithdrawOffline() error = %v", err)
+	}
+	coin := wallet.offline[0]
+	tests := []struct {
+		name   string
+		change func(p *Payment, bits []int)
+		want   error
+	}{
+		{"чужой вызов", func(p *Payment, _ []int) { p.Nonce = make([]byte, NonceSize) }, common.ErrInvalidParameters},
+		{"платёж другому продавцу", func(p *Payment, _ []int) { p.Merchant = "other" }, common.ErrInvalidParameters},
+		{"раскрыта другая половина", func(p *Payment, bits []int) { p.Responses[5] = coin.pairs[5][1-bits[5]] }, common.ErrVerification},
+		{"подменённое значение", func(p *Payment, _ []int) { p.Responses[0].Value = make([]byte, IdentitySize) }, common.ErrVerification},
+		{"нет раскрытия", func(p *Payment, _ []int) { p.Responses = p.Responses[1:] }, common.ErrInvalidParameters},
+		{"другой номинал", func(p *Payment, _ []int) { p.Coin.Denomination = 20 }, common.ErrVerification},
+		{"подменено обязательство", func(p *Payment, _ []int) { p.Coin.Commitments[3][0] = make([]byte, 32) }, common.ErrVerification},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nonce, err := shop.Challenge()
+			if err != nil {
+				t.Fatalf("Challenge() error = %v", err)
+			}
+			c := coin
+			c.coin.Commitments = append([][2][]byte(nil), coin.coin.Commitments...)
+			p := c.respond("shop", nonce)
+			tt.change(p, challengeBits(c.coin.Serial(), "shop", nonce))
+			if err := shop.AcceptOffline(p); !errors.Is(err, tt.want) {
+				t.Errorf("AcceptOffline() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if len(shop.pending) != 0 {
+		t.Errorf("merchant accepted %d bad payments", len(shop.pending))
+	}
+}
+
+// candidates - кандидаты offline-монеты с личностью identity, ослеплённые своими seed
+func candidates(t *testing.T, pk *blindsig.PublicKey, denomination int64, identity string) ([]CandidateOpening, []*big.Int) {
+	t.Helper()
+	id := make([]byte, IdentitySize)
+	copy(id, identity)
+	openings := make([]CandidateOpening, OfflineCandidates)
+	blinded := make([]*big.Int, OfflineCandidates)
+	for i := range openings {
+		pairs, err := newPairs(id)
+		if err != nil {
+			t.Fatalf("newPairs() error = %v", err)
+		}
+		seed, err := randomBytes(rand.Reader, seedSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if blinded[i], _, err = pk.Blind(&seedReader{seed: seed}, newOfflineCoin(denomination, pairs).message()); err != nil {
+			t.Fatalf("Blind() error = %v", err)
+		}
+		openings[i] = CandidateOpening{Index: i, Seed: seed, Pairs: pairs}
+	}
+	return openings, blinded
+}
+
+// TestOfflineCheating - банк отвергает снятие, если раскрытый кандидат построен нечестно,
+// и не возвращает списанный номинал
+func TestOfflineCheating(t *testing.T) {
+	ctx := context.Background()
+	bank := newTestBank(t, nil)
+	key, _ := bank.Key(ctx)
+	pk := key.Denomination(10, true)
+	tests := []struct {
+		name     string
+		account  string
+		identity string                     // личность в кандидатах; пусто - имя счёта
+		change   func(o []CandidateOpening) // порча раскрытий
+		want     error
+	}{
+		{name: "честное снятие", account: "honest"},
+		{name: "чужая личность", account: "mallory", identity: "alice", want: ErrCheating},
+		{
+			name:    "другой seed ослепления",
+			account: "seed",
+			change:  func(o []CandidateOpening) { o[0].Seed = make([]byte, seedSize) },
+			want:    ErrCheating,
+		},
+		{
+			name:    "пара не раскрывает личность",
+			account: "pair",
+			change:  func(o []CandidateOpening) { o[1].Pairs[7][1].Value = make([]byte, IdentitySize) },
+			want:    ErrCheating,
+		},
+		{
+			name:    "раскрыт не каждый кандидат",
+			account: "partial",
+			change:  func(o []CandidateOpening) { o[2] = o[3] },
+			want:    common.ErrInvalidParameters,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := openAccount(t, bank, tt.account, 10)
+			identity := tt.identity
+			if identity == "" {
+				identity = tt.account
+			}
+			openings, blinded := candidates(t, pk, 10, identity)
+			session, err := bank.BeginOfflineWithdrawal(ctx, tt.account, token, 10, blinded)
+			if err != nil {
+				t.Fatalf("BeginOfflineWithdrawal() error = %v", err)
+			}
+			wantBalance(t, bank, tt.account, token, 0)
+			// opened - все кандидаты, кроме выбранного банком
+			opened := func() []CandidateOpening {
+				return append(append([]CandidateOpening(nil), openings[:session.Keep]...), openings[session.Keep+1:]...)
+			}
+			o := opened()
+			if tt.change != nil {
+				tt.change(o)
+			}
+			if _, err = bank.FinishOfflineWithdrawal(ctx, tt.account, token, session.Session, o); !errors.Is(err, tt.want) {
+				t.Fatalf("FinishOfflineWithdrawal() error = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil {
+				return
+			}
+			// Ошибка формы оставляет сеанс открытым, обман его завершает
+			_, err = bank.FinishOfflineWithdrawal(ctx, tt.account, token, session.Session, opened())
+			if errors.Is(tt.want, ErrCheating) && !errors.Is(err, ErrUnknownSession) {
+				t.Errorf("FinishOfflineWithdrawal() after cheating error = %v, want %v", err, ErrUnknownSession)
+			}
+			if !errors.Is(tt.want, ErrCheating) && err != nil {
+				t.Errorf("FinishOfflineWithdrawal() after a malformed request error = %v", err)
+			}
+			wantBalance(t, bank, tt.account, token, 0)
+		})
+	}
+}
+
+// TestIdentify - два платежа с разными вызовами раскрывают владельца, одинаковые - нет
+func TestIdentify(t *testing.T) {
+	identity, err := identityOf("bob")
+	if err != nil {
+		t.Fatalf("identityOf() error = %v", err)
+	}
+	pairs, err := newPairs(identity)
+	if err != nil {
+		t.Fatalf("newPairs() error = %v", err)
+	}
+	coin := &offlineCoin{coin: *newOfflineCoin(5, pairs), pairs: pairs}
+	first := coin.respond("cafe", make([]byte, NonceSize))
+	if got := identify(first, coin.respond("kiosk", make([]byte, NonceSize))); got != "bob" {
+		t.Errorf("identify() = %q, want %q", got, "bob")
+	}
+	if got := identify(first, first); got != "" {
+		t.Errorf("identify() of the same payment = %q, want empty", got)
+	}
+	if _, err = identityOf("имя длиннее тридцати двух байт"); !errors.Is(err, common.ErrInvalidParameters) {
+		t.Errorf("identityOf() of a long name error = %v, want %v", err, common.ErrInvalidParameters)
+	}
+}
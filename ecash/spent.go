@@ -0,0 +1,101 @@
+package ecash
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/jsonio"
+	"sync"
+	"time"
+)
+
+// ErrCorruptSpentDB - строка базы потраченных монет не разбирается
+var ErrCorruptSpentDB = errors.New("ecash: spent coin database is corrupt")
+
+// SpentRecord - внесённая монета: серийный номер, счёт продавца и, для offline-монеты,
+// платёж целиком - по второму платежу с той же монетой раскрывается владелец
+type SpentRecord struct {
+	Serial  string    `json:"serial"`
+	Account string    `json:"account"`
+	Payment *Payment  `json:"payment,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// SpentDB - база потраченных серийных номеров с атомарной проверкой и добавлением.
+// Может храниться в файле JSON Lines: запись сбрасывается на диск до зачисления
+// денег продавцу, оборванная при сбое последняя строка отбрасывается при открытии.
+type SpentDB struct {
+	records map[string]SpentRecord
+	file    *jsonio.Log // nil - база только в памяти
+	mu      sync.Mutex
+}
+
+// NewSpentDB - пустая база в памяти
+func NewSpentDB() *SpentDB {
+	return &SpentDB{records: make(map[string]SpentRecord)}
+}
+
+// OpenSpentDB - база в файле path: новый создаётся, существующий читается
+func OpenSpentDB(path string) (*SpentDB, error) {
+	db := NewSpentDB()
+	file, err := jsonio.OpenLog(path, db.decode)
+	if err != nil {
+		return nil, err
+	}
+	db.file = file
+	return db, nil
+}
+
+// decode - запись строки line файла
+func (db *SpentDB) decode(line int, dec *json.Decoder) error {
+	var rec SpentRecord
+	if err := dec.Decode(&rec); err != nil || rec.Serial == "" {
+		return fmt.Errorf("line %d: %w", line+1, ErrCorruptSpentDB)
+	}
+	if _, ok := db.records[rec.Serial]; ok {
+		return fmt.Errorf("line %d: coin %s recorded twice: %w", line+1, rec.Serial, ErrCorruptSpentDB)
+	}
+	db.records[rec.Serial] = rec
+	return nil
+}
+
+// Record - атомарная проверка и добавление. Если монета уже внесена, возвращается
+// прежняя запись и false. Запись файловой базы сброшена на диск до возврата.
+func (db *SpentDB) Record(rec SpentRecord) (SpentRecord, bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if prev, ok := db.records[rec.Serial]; ok {
+		return prev, false, nil
+	}
+	rec.Time = time.Now().UTC()
+	if db.file != nil {
+		if err := db.file.Append(rec); err != nil {
+			return SpentRecord{}, false, err
+		}
+	}
+	db.records[rec.Serial] = rec
+	return rec, true, nil
+}
+
+// Lookup - запись о монете serial
+func (db *SpentDB) Lookup(serial string) (SpentRecord, bool) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	rec, ok := db.records[serial]
+	return rec, ok
+}
+
+// Len - число потраченных монет
+func (db *SpentDB) Len() int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return len(db.records)
+}
+
+// Close - закрытие файла базы
+func (db *SpentDB) Close() error {
+	if db.file == nil {
+		return nil
+	}
+	return db.file.Close()
+}
@@ -0,0 +1,421 @@
+package ecash
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/blindsig"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math"
+	"math/big"
+	"sync"
+)
+
+// ErrNoChange - из монет кошелька нельзя составить сумму без сдачи
+var ErrNoChange = errors.New("ecash: wallet coins do not add up to the amount")
+
+// seedSize - длина seed ослепления кандидата offline-монеты
+const seedSize = 32
+
+// offlineCoin - offline-монета кошелька вместе с раскрытиями всех пар
+type offlineCoin struct {
+	coin  OfflineCoin
+	pairs [][2]Opening
+}
+
+// Wallet - кошелёк владельца счёта: снятые монеты. Не предназначен для одновременного
+// использования из нескольких горутин.
+type Wallet struct {
+	bank    BankAPI
+	account string
+	token   string
+	key     *KeyInfo
+	coins   []Coin
+	offline []offlineCoin
+}
+
+// NewWallet - кошелёк счёта account в банке bank
+func NewWallet(ctx context.Context, bank BankAPI, account, token string) (*Wallet, error) {
+	if _, err := identityOf(account); err != nil {
+		return nil, err
+	}
+	key, err := bank.Key(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = key.validate(); err != nil {
+		return nil, err
+	}
+	return &Wallet{bank: bank, account: account, token: token, key: key}, nil
+}
+
+// split - разложение суммы на номиналы жадным выбором от большего
+func split(amount int64) ([]int64, error) {
+	if amount <= 0 {
+		return nil, &common.ParameterError{Name: "amount", Reason: "must be positive"}
+	}
+	var out []int64
+	for _, d := range Denominations {
+		for ; amount >= d; amount -= d {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// Withdraw - снятие суммы amount монетами для оплаты со связью с банком
+func (w *Wallet) Withdraw(ctx context.Context, amount int64) error {
+	denominations, err := split(amount)
+	if err != nil {
+		return err
+	}
+	for _, d := range denominations {
+		if err = w.withdrawCoin(ctx, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withdrawCoin - снятие одной монеты: случайный серийный номер ослепляется, банк
+// подписывает его вслепую, кошелёк снимает слепоту и проверяет подпись
+func (w *Wallet) withdrawCoin(ctx context.Context, denomination int64) error {
+	serial, err := newSerial()
+	if err != nil {
+		return err
+	}
+	pk := w.key.Denomination(denomination, false)
+	blinded, state, err := pk.Blind(rand.Reader, coinMessage(serial))
+	if err != nil {
+		return err
+	}
+	bs, err := w.bank.Withdraw(ctx, w.account, w.token, denomination, blinded)
+	if err != nil {
+		return err
+	}
+	sig, err := pk.Finalize(state, bs)
+	if err != nil {
+		return fmt.Errorf("withdraw %d: %w", denomination, err)
+	}
+	w.coins = append(w.coins, Coin{Denomination: denomination, Serial: serial, Signature: sig})
+	return nil
+}
+
+// WithdrawOffline - снятие суммы amount offline-монетами
+func (w *Wallet) WithdrawOffline(ctx context.Context, amount int64) error {
+	denominations, err := split(amount)
+	if err != nil {
+		return err
+	}
+	for _, d := range denominations {
+		if err = w.withdrawOfflineCoin(ctx, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withdrawOfflineCoin - снятие offline-монеты: OfflineCandidates кандидатов со своей
+// личностью, ослеплённых случайностью из seed; банк выбирает одного, остальные раскрываются
+func (w *Wallet) withdrawOfflineCoin(ctx context.Context, denomination int64) error {
+	identity, err := identityOf(w.account)
+	if err != nil {
+		return err
+	}
+	pk := w.key.Denomination(denomination, true)
+	candidates := make([]CandidateOpening, OfflineCandidates)
+	coins := make([]*OfflineCoin, OfflineCandidates)
+	states := make([]*blindsig.BlindingState, OfflineCandidates)
+	blinded := make([]*big.Int, OfflineCandidates)
+	for i := range candidates {
+		pairs, err := newPairs(identity)
+		if err != nil {
+			return err
+		}
+		seed, err := randomBytes(rand.Reader, seedSize)
+		if err != nil {
+			return err
+		}
+		coins[i] = newOfflineCoin(denomination, pairs)
+		m, state, err := pk.Blind(&seedReader{seed: seed}, coins[i].message())
+		if err != nil {
+			return err
+		}
+		candidates[i] = CandidateOpening{Index: i, Seed: seed, Pairs: pairs}
+		states[i], blinded[i] = state, m
+	}
+	session, err := w.bank.BeginOfflineWithdrawal(ctx, w.account, w.token, denomination, blinded)
+	if err != nil {
+		return err
+	}
+	keep := session.Keep
+	if keep < 0 || keep >= OfflineCandidates {
+		return &common.ParameterError{Name: "keep", Reason: "bank chose a nonexistent candidate"}
+	}
+	openings := make([]CandidateOpening, 0, OfflineCandidates-1)
+	for i, c := range candidates {
+		if i != keep {
+			openings = append(openings, c)
+		}
+	}
+	bs, err := w.bank.FinishOfflineWithdrawal(ctx, w.account, w.token, session.Session, openings)
+	if err != nil {
+		return err
+	}
+	sig, err := pk.Finalize(states[keep], bs)
+	if err != nil {
+		return fmt.Errorf("withdraw offline %d: %w", denomination, err)
+	}
+	coin := *coins[keep]
+	coin.Signature = sig
+	w.offline = append(w.offline, offlineCoin{coin: coin, pairs: candidates[keep].Pairs})
+	return nil
+}
+
+// Balance - сумма монет кошелька: для оплаты со связью с банком и offline
+func (w *Wallet) Balance() (online, offline int64) {
+	for _, c := range w.coins {
+		online += c.Denomination
+	}
+	for _, c := range w.offline {
+		offline += c.coin.Denomination
+	}
+	return online, offline
+}
+
+// Coins - монеты для оплаты со связью с банком
+func (w *Wallet) Coins() []Coin {
+	return append([]Coin(nil), w.coins...)
+}
+
+// Clone - копия кошелька с теми же монетами. Трата монет из обеих копий - повторная
+// трата; копия нужна, чтобы показать, как банк её обнаруживает.
+func (w *Wallet) Clone() *Wallet {
+	c := *w
+	c.coins = append([]Coin(nil), w.coins...)
+	c.offline = append([]offlineCoin(nil), w.offline...)
+	return &c
+}
+
+// selectCoins - номера монет с суммой ровно amount, наименьшее их число
+// (рюкзак по сумме: cnt[i][s] - наименьшее число монет из первых i с суммой s)
+func selectCoins(values []int64, amount int64) ([]int, error) {
+	if amount <= 0 {
+		return nil, &common.ParameterError{Name: "amount", Reason: "must be positive"}
+	}
+	var total int64
+	for _, v := range values {
+		total += v
+	}
+	if total < amount {
+		return nil, fmt.Errorf("wallet holds %d, needs %d: %w", total, amount, ErrInsufficientFunds)
+	}
+	const none = math.MaxInt32
+	cnt := make([][]int, len(values)+1)
+	cnt[0] = make([]int, amount+1)
+	for s := int64(1); s <= amount; s++ {
+		cnt[0][s] = none
+	}
+	for i, v := range values {
+		row := append([]int(nil), cnt[i]...)
+		for s := v; s <= amount; s++ {
+			if prev := cnt[i][s-v]; prev != none && prev+1 < row[s] {
+				row[s] = prev + 1
+			}
+		}
+		cnt[i+1] = row
+	}
+	if cnt[len(values)][amount] == none {
+		return nil, fmt.Errorf("%d: %w", amount, ErrNoChange)
+	}
+	var picked []int
+	for i, s := len(values), amount; s > 0; i-- {
+		if cnt[i][s] != cnt[i-1][s] {
+			picked = append(picked, i-1)
+			s -= values[i-1]
+		}
+	}
+	return picked, nil
+}
+
+// Pay - оплата суммы amount продавцу m со связью с банком: продавец вносит каждую
+// монету сразу. Переданная продавцу монета удаляется из кошелька, даже если он её отверг.
+func (w *Wallet) Pay(ctx context.Context, m *Merchant, amount int64) error {
+	values := make([]int64, len(w.coins))
+	for i, c := range w.coins {
+		values[i] = c.Denomination
+	}
+	picked, err := selectCoins(values, amount)
+	if err != nil {
+		return err
+	}
+	coins := make([]Coin, len(picked))
+	for i, idx := range picked {
+		coins[i] = w.coins[idx]
+	}
+	for _, c := range coins {
+		w.removeCoin(c.Serial)
+		if err = m.Accept(ctx, &c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeCoin - удаление монеты с серийным номером serial
+func (w *Wallet) removeCoin(serial string) {
+	for i, c := range w.coins {
+		if c.Serial == serial {
+			w.coins = append(w.coins[:i], w.coins[i+1:]...)
+			return
+		}
+	}
+}
+
+// PayOffline - оплата суммы amount продавцу m offline-монетами: на каждую монету
+// продавец задаёт вызов, кошелёк раскрывает выбранные им половины пар
+func (w *Wallet) PayOffline(m *Merchant, amount int64) error {
+	values := make([]int64, len(w.offline))
+	for i, c := range w.offline {
+		values[i] = c.coin.Denomination
+	}
+	picked, err := selectCoins(values, amount)
+	if err != nil {
+		return err
+	}
+	coins := make([]offlineCoin, len(picked))
+	for i, idx := range picked {
+		coins[i] = w.offline[idx]
+	}
+	for _, c := range coins {
+		w.removeOffline(c.coin.Serial())
+		nonce, err := m.Challenge()
+		if err != nil {
+			return err
+		}
+		if err = m.AcceptOffline(c.respond(m.Name(), nonce)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeOffline - удаление offline-монеты с серийным номером serial
+func (w *Wallet) removeOffline(serial string) {
+	for i, c := range w.offline {
+		if c.coin.Serial() == serial {
+			w.offline = append(w.offline[:i], w.offline[i+1:]...)
+			return
+		}
+	}
+}
+
+// respond - платёж монетой c: половины пар по битам вызова продавца
+func (c *offlineCoin) respond(merchant string, nonce []byte) *Payment {
+	p := &Payment{Coin: c.coin, Merchant: merchant, Nonce: nonce, Responses: make([]Opening, OfflinePairs)}
+	for i, bit := range challengeBits(c.coin.Serial(), merchant, nonce) {
+		p.Responses[i] = c.pairs[i][bit]
+	}
+	return p
+}
+
+// Merchant - продавец со счётом в банке. Монеты для оплаты со связью с банком вносятся
+// сразу, offline-платежи копятся до DepositPending.
+type Merchant struct {
+	bank    BankAPI
+	account string
+	token   string
+	key     *KeyInfo
+	nonces  map[string]bool // выданные и ещё не использованные вызовы
+	pending []*Payment
+	mu      sync.Mutex
+}
+
+// NewMerchant - продавец со счётом account в банке bank
+func NewMerchant(ctx context.Context, bank BankAPI, account, token string) (*Merchant, error) {
+	key, err := bank.Key(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = key.validate(); err != nil {
+		return nil, err
+	}
+	return &Merchant{bank: bank, account: account, token: token, key: key, nonces: make(map[string]bool)}, nil
+}
+
+// Name - счёт продавца; входит в вызов offline-платежа
+func (m *Merchant) Name() string {
+	return m.account
+}
+
+// Accept - приём монеты: проверка подписи и немедленное внесение в банк,
+// который отвергает повторную трату
+func (m *Merchant) Accept(ctx context.Context, coin *Coin) error {
+	if err := coin.Verify(m.key); err != nil {
+		return err
+	}
+	_, err := m.bank.Deposit(ctx, m.account, m.token, coin)
+	return err
+}
+
+// Challenge - случайный вызов для очередного offline-платежа
+func (m *Merchant) Challenge() ([]byte, error) {
+	nonce, err := randomBytes(rand.Reader, NonceSize)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	m.nonces[string(nonce)] = true
+	m.mu.Unlock()
+	return nonce, nil
+}
+
+// issued - вызов nonce выдан и ещё не использован
+func (m *Merchant) issued(nonce []byte) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.nonces[string(nonce)]
+}
+
+// AcceptOffline - приём offline-платежа без связи с банком: вызов выдан этим продавцом
+// и ещё не использован, монета подписана банком, раскрытия верны. Повторная трата
+// обнаружится только при внесении.
+func (m *Merchant) AcceptOffline(p *Payment) error {
+	if p.Merchant != m.account {
+		return &common.ParameterError{Name: "payment", Reason: fmt.Sprintf("payment was made to %q", p.Merchant)}
+	}
+	if !m.issued(p.Nonce) {
+		return &common.ParameterError{Name: "payment", Reason: "challenge was not issued by this merchant"}
+	}
+	if err := p.Verify(m.key); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.nonces[string(p.Nonce)] {
+		return &common.ParameterError{Name: "payment", Reason: "challenge already used"}
+	}
+	delete(m.nonces, string(p.Nonce))
+	m.pending = append(m.pending, p)
+	return nil
+}
+
+// DepositPending - внесение накопленных offline-платежей. Возвращается внесённая сумма
+// и ошибки отвергнутых платежей; отвергнутые платежи из очереди удаляются.
+func (m *Merchant) DepositPending(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	pending := m.pending
+	m.pending = nil
+	m.mu.Unlock()
+	var deposited int64
+	var errs []error
+	for _, p := range pending {
+		if _, err := m.bank.DepositOffline(ctx, m.account, m.token, p); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		deposited += p.Coin.Denomination
+	}
+	return deposited, errors.Join(errs...)
+}
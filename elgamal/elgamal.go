@@ -1,12 +1,12 @@
 package elgamal
 
 import (
+	"bytes"
 	"crypto/md5"
 	"fmt"
 	"github.com/Raimguzhinov/protect-information/common"
 	"io"
 	"math/big"
-	"os"
 )
 
 type ElgamalCipher struct {
@@ -24,6 +24,12 @@ type ElgamalCipher struct {
 }
 
 func newElgamalAlgorithm(p, g int64) (*ElgamalCipher, error) {
+	if p < 3 || !big.NewInt(p).ProbablyPrime(20) {
+		return nil, &common.ParameterError{Name: "p", Reason: fmt.Sprintf("%d is not a prime greater than 2", p)}
+	}
+	if g < 2 || g >= p {
+		return nil, &common.ParameterError{Name: "g", Reason: fmt.Sprintf("%d is not in [2, p-1]", g)}
+	}
 	c := &ElgamalCipher{
 		P: p,
 		G: g,
@@ -34,6 +40,9 @@ func newElgamalAlgorithm(p, g int64) (*ElgamalCipher, error) {
 	if err != nil {
 		return nil, err
 	}
+	if common.Tracing() {
+		common.Trace("elgamal", "keygen", common.ValInt("P", c.P), common.ValInt("G", c.G), common.ValInt("X", c.X), common.ValInt("Y", c.Y))
+	}
 	return c, nil
 }
 
@@ -51,8 +60,11 @@ func NewCipher(p, g int64, input io.Reader, encOut, decOut io.Writer) (common.Ci
 
 // Генерация ключевой пары (X, Y), где Y = G^X mod P
 func generateKeyPair(p, g int64) (int64, int64, error) {
-	x := common.Seed().Int63n(p-1) + 1         // Приватный ключ X
-	y := common.ModularExponentiation(g, x, p) // Публичный ключ Y = G^X mod P
+	x := common.Seed().Int63n(p-1) + 1                          // Приватный ключ X
+	y, err := common.ModularExponentiationConstantTime(g, x, p) // Публичный ключ Y = G^X mod P
+	if err != nil {
+		return 0, 0, err
+	}
 	return x, y, nil
 }
 
@@ -64,14 +76,25 @@ func (ec *ElgamalCipher) Encrypt() error {
 	encryptedMessage := make([][2]int64, len(message))
 	for i, byteVal := range message {
 		if int64(byteVal) >= ec.P {
-			return fmt.Errorf("byte %d is greater than or equal to p", byteVal)
+			return &common.MessageTooLargeError{Value: big.NewInt(int64(byteVal)), Limit: big.NewInt(ec.P)}
 		}
 		// Случайное значение k
 		k := common.Seed().Int63n(ec.P-1) + 1
 		// Шифрование: r = G^k mod P, e = M * Y^k mod P
-		r := common.ModularExponentiation(ec.G, k, ec.P)
-		e := (int64(byteVal) * common.ModularExponentiation(ec.Y, k, ec.P)) % ec.P
+		r, err := common.ModularExponentiationConstantTime(ec.G, k, ec.P)
+		if err != nil {
+			return err
+		}
+		yk, err := common.ModularExponentiationConstantTime(ec.Y, k, ec.P)
+		if err != nil {
+			return err
+		}
+		e := (int64(byteVal) * yk) % ec.P
 		encryptedMessage[i] = [2]int64{r, e}
+		if common.Tracing() {
+			common.Trace("elgamal", "encrypt block",
+				common.ValInt("m", int64(byteVal)), common.ValInt("k", k), common.ValInt("r", r), common.ValInt("e", e))
+		}
 	}
 	ec.buffer = encryptedMessage
 	return common.WritePair(ec.OutputEncrypted, encryptedMessage)
@@ -82,8 +105,14 @@ func (ec *ElgamalCipher) Decrypt() error {
 	for i, pair := range ec.buffer {
 		r, e := pair[0], pair[1]
 		// Дешифрование: M = e * (r^(P-1-X) mod P)
-		s := common.ModularExponentiation(r, ec.P-1-ec.X, ec.P)
+		s, err := common.ModularExponentiationConstantTime(r, ec.P-1-ec.X, ec.P)
+		if err != nil {
+			return err
+		}
 		m := (e * s) % ec.P
+		if common.Tracing() {
+			common.Trace("elgamal", "decrypt block", common.ValInt("r", r), common.ValInt("e", e), common.ValInt("m", m))
+		}
 		decryptedMessage[i] = byte(m)
 	}
 	ec.msg = decryptedMessage
@@ -92,25 +121,9 @@ func (ec *ElgamalCipher) Decrypt() error {
 
 // EncryptAndDecrypt - объединяет шифрование и дешифрование
 func (ec *ElgamalCipher) EncryptAndDecrypt() error {
-	pwd, err := os.Getwd()
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
-	fmt.Print("Encrypted: ")
-	if err = ec.Encrypt(); err != nil {
+	if err := ec.Encrypt(); err != nil {
 		return err
 	}
-	if _, ok := ec.Input.(*os.File); ok {
-		fmt.Print(pwd + "/" + ec.OutputEncrypted.(*os.File).Name())
-	}
-	fmt.Print("\nDecrypted: ")
-	defer func() {
-		if _, ok := ec.Input.(*os.File); ok {
-			fmt.Print(pwd + "/" + ec.OutputDecrypted.(*os.File).Name())
-		}
-		fmt.Print("\n")
-	}()
 	return ec.Decrypt()
 }
 
@@ -118,6 +131,48 @@ func (ec *ElgamalCipher) GetDecryptMsg() []byte {
 	return ec.msg
 }
 
+// ExportPublicKey - открытый ключ (P, G, Y) в виде блока брони
+func (ec *ElgamalCipher) ExportPublicKey() (*common.ArmorBlock, error) {
+	return common.PublicKeyBlock("elgamal", []string{"P", "G", "Y"}, []*big.Int{big.NewInt(ec.P), big.NewInt(ec.G), big.NewInt(ec.Y)})
+}
+
+// ExportPrivateKey - закрытый ключ (P, G, Y, X) в виде блока брони
+func (ec *ElgamalCipher) ExportPrivateKey() (*common.ArmorBlock, error) {
+	return common.PrivateKeyBlock("elgamal", []string{"P", "G", "Y", "X"},
+		[]*big.Int{big.NewInt(ec.P), big.NewInt(ec.G), big.NewInt(ec.Y), big.NewInt(ec.X)})
+}
+
+// DecryptArmored - расшифрование пар (r, e) из блока CIPHERTEXT закрытым ключом из ExportPrivateKey
+func DecryptArmored(key *common.ArmorBlock, ciphertext []byte, decOut io.Writer) error {
+	numbers, err := common.KeyNumbers(key, common.ArmorPrivateKey, "elgamal", 4)
+	if err != nil {
+		return err
+	}
+	for _, num := range numbers {
+		if num.Sign() <= 0 || !num.IsInt64() {
+			return &common.ParameterError{Name: "elgamal key", Reason: fmt.Sprintf("%s is not a positive 64-bit integer", num)}
+		}
+	}
+	ec := &ElgamalCipher{P: numbers[0].Int64(), G: numbers[1].Int64(), Y: numbers[2].Int64(), X: numbers[3].Int64(), OutputDecrypted: decOut}
+	if ec.X >= ec.P-1 {
+		return &common.ParameterError{Name: "elgamal key", Reason: "X must lie in [1, P-1)"}
+	}
+	values, err := common.ReadNumbers(bytes.NewReader(ciphertext))
+	if err != nil {
+		return err
+	}
+	if len(values)%2 != 0 {
+		return fmt.Errorf("elgamal: %d numbers do not form (r, e) pairs: %w", len(values), common.ErrDecryption)
+	}
+	for i := 0; i < len(values); i += 2 {
+		if values[i] <= 0 || values[i] >= ec.P || values[i+1] < 0 || values[i+1] >= ec.P {
+			return fmt.Errorf("elgamal: pair %d is not reduced modulo P: %w", i/2, common.ErrDecryption)
+		}
+		ec.buffer = append(ec.buffer, [2]int64{values[i], values[i+1]})
+	}
+	return ec.Decrypt()
+}
+
 // elgamalSignature содержит параметры и результаты подписи
 type elgamalSignature struct {
 	P            *big.Int // Простое число (модуль)
@@ -129,20 +184,66 @@ type elgamalSignature struct {
 	Input        io.Reader
 	OutputSigned io.Writer
 	Message      []byte // Сообщение для подписи
+	mc           *common.ModContext
 }
 
 func NewSignature(p, g *big.Int, input io.Reader, output io.ReadWriter) (common.Signer, error) {
+	if p.Cmp(big.NewInt(5)) < 0 {
+		return nil, &common.ParameterError{Name: "p", Reason: fmt.Sprintf("%s is too small", p)}
+	}
 	es := elgamalSignature{
 		P: p,
 		G: g,
 		X: GenerateX(p),
 	}
-	es.Y = common.ModularExponentiationBig(g, es.X, p)
+	var err error
+	if es.mc, err = common.NewModContext(p); err != nil {
+		return nil, err
+	}
+	// X и k секретны: возведения в их степени выполняются за постоянное время
+	if es.Y, err = es.mc.ExpConstantTime(g, es.X, p.BitLen()); err != nil {
+		return nil, err
+	}
+	if common.Tracing() {
+		common.Trace("elgamal", "keygen", common.Val("P", es.P), common.Val("G", es.G), common.Val("X", es.X), common.Val("Y", es.Y))
+	}
 	es.Input = input
 	es.OutputSigned = output
 	return &es, nil
 }
 
+// ExportPublicKey - открытый ключ подписи (P, G, Y) в виде блока брони
+func (es *elgamalSignature) ExportPublicKey() (*common.ArmorBlock, error) {
+	return common.PublicKeyBlock("elgamal", []string{"P", "G", "Y"}, []*big.Int{es.P, es.G, es.Y})
+}
+
+// VerifyArmored - проверка подписи (R, S) из блока SIGNATURE открытым ключом из блока PUBLIC KEY
+func VerifyArmored(key *common.ArmorBlock, message, signature []byte) error {
+	numbers, err := common.KeyNumbers(key, common.ArmorPublicKey, "elgamal", 3)
+	if err != nil {
+		return err
+	}
+	rs, err := common.ReadBigNumbers(bytes.NewReader(signature))
+	if err != nil {
+		return err
+	}
+	if len(rs) != 2 {
+		return fmt.Errorf("elgamal: %d numbers in signature: %w", len(rs), common.ErrArmorMalformed)
+	}
+	if numbers[0].Cmp(big.NewInt(5)) < 0 {
+		return &common.ParameterError{Name: "p", Reason: fmt.Sprintf("%s is too small", numbers[0])}
+	}
+	es := &elgamalSignature{P: numbers[0], G: numbers[1], Y: numbers[2], R: rs[0], Signature: rs[1], Message: message}
+	ok, err := es.Verify()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return common.ErrVerification
+	}
+	return nil
+}
+
 func GenerateX(p *big.Int) *big.Int {
 	one := big.NewInt(1)
 	maximum := new(big.Int).Sub(p, one) // P - 1
@@ -163,11 +264,15 @@ func (es *elgamalSignature) Sign() error {
 	hashInt := new(big.Int).SetBytes(hash[:])
 	// Приводим хеш к модулю (P - 1), чтобы h < P - 1
 	hashInt.Mod(hashInt, new(big.Int).Sub(es.P, big.NewInt(1)))
-	fmt.Printf("Message hash (as int): %s\n", hashInt.String())
 	// k ∈ [2, P-2], gcd(k, P - 1) = 1
-	k := common.GenCoprimeBig(new(big.Int).Sub(es.P, big.NewInt(1)), big.NewInt(2), new(big.Int).Sub(es.P, big.NewInt(2)))
+	k, err := common.GenCoprimeBig(new(big.Int).Sub(es.P, big.NewInt(1)), big.NewInt(2), new(big.Int).Sub(es.P, big.NewInt(2)))
+	if err != nil {
+		return err
+	}
 	// R = G^k mod P
-	es.R = common.ModularExponentiationBig(es.G, k, es.P)
+	if es.R, err = es.mc.ExpConstantTime(es.G, k, es.P.BitLen()); err != nil {
+		return err
+	}
 	// u = (h - x*R) mod (P - 1)
 	u := new(big.Int).Sub(hashInt, new(big.Int).Mul(es.X, es.R))
 	u.Mod(u, new(big.Int).Sub(es.P, big.NewInt(1)))
@@ -175,21 +280,19 @@ func (es *elgamalSignature) Sign() error {
 		u.Add(u, new(big.Int).Sub(es.P, big.NewInt(1)))
 	}
 	// gcd(k, P-1) = 1, находим k1 = k^(-1) mod (P - 1)
-	gcd, k1, _ := common.GCDExtendedBig(k, new(big.Int).Sub(es.P, big.NewInt(1)))
-	if gcd.Cmp(big.NewInt(1)) != 0 {
-		return fmt.Errorf("gcd(%s, %s) != 1", k.String(), new(big.Int).Sub(es.P, big.NewInt(1)).String())
-	}
-	// Приводим k1 к модулю (P - 1)
-	//k1.Mod(k1, new(big.Int).Sub(es.P, big.NewInt(1)))
-	//if k1.Cmp(big.NewInt(0)) < 0 {
-	//	k1.Add(k1, new(big.Int).Sub(es.P, big.NewInt(1)))
-	//}
+	k1, err := common.ModInverseConstantTime(k, new(big.Int).Sub(es.P, big.NewInt(1)))
+	if err != nil {
+		return err
+	}
 	// S = (u * k^(-1)) mod (P - 1)
 	es.Signature = new(big.Int).Mul(u, k1)
 	es.Signature.Mod(es.Signature, new(big.Int).Sub(es.P, big.NewInt(1)))
-	fmt.Printf("Подпись создана: S = %s", es.Signature.String())
+	if common.Tracing() {
+		common.Trace("elgamal", "sign",
+			common.Val("h", hashInt), common.Val("k", k), common.Val("R", es.R), common.Val("u", u), common.Val("S", es.Signature))
+	}
 	es.Message = message
-	return common.WriteNumbers(es.OutputSigned, []int64{es.Signature.Int64()})
+	return common.WriteBigNumbers(es.OutputSigned, []*big.Int{es.R, es.Signature})
 }
 
 func (es *elgamalSignature) Verify() (bool, error) {
@@ -199,7 +302,6 @@ func (es *elgamalSignature) Verify() (bool, error) {
 	// Приводим хеш к модулю (P - 1), чтобы h < P - 1
 	// hashInt = h mod (P - 1)
 	hashInt.Mod(hashInt, new(big.Int).Sub(es.P, big.NewInt(1)))
-	fmt.Printf("Message hash (as int): %s\n", hashInt.String())
 	// yr = Y^R * R^S mod P
 	yr := new(big.Int).Mul(
 		common.ModularExponentiationBig(es.Y, es.R, es.P),         // Y^R mod P
@@ -208,40 +310,24 @@ func (es *elgamalSignature) Verify() (bool, error) {
 	yr.Mod(yr, es.P) // Приводим к модулю P
 	// g = G^h mod P
 	g := common.ModularExponentiationBig(es.G, hashInt, es.P)
+	if common.Tracing() {
+		common.Trace("elgamal", "verify", common.Val("h", hashInt), common.Val("Y^R*R^S", yr), common.Val("G^h", g))
+	}
 	// Подпись верна, если yr == g
 	return yr.Cmp(g) == 0, nil
 }
 
+// SignAndVerify - подписывает сообщение и сразу проверяет подпись
 func (es *elgamalSignature) SignAndVerify() error {
-	pwd, err := os.Getwd()
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
-	fmt.Print("Signed: ")
-	if err = es.Sign(); err != nil {
+	if err := es.Sign(); err != nil {
 		return err
 	}
-	if _, ok := es.Input.(*os.File); ok {
-		fmt.Print(pwd + "/" + es.OutputSigned.(*os.File).Name())
-	}
-	if out, ok := es.OutputSigned.(*os.File); ok {
-		_ = out.Sync()
-		defer out.Close()
-		es.OutputSigned, err = os.OpenFile(out.Name(), os.O_RDONLY, 0600)
-		if err != nil {
-			return err
-		}
-	}
-	fmt.Print("\nVerified: ")
 	ok, err := es.Verify()
 	if err != nil {
 		return err
 	}
-	if ok {
-		fmt.Println("true")
-	} else {
-		fmt.Println("false")
+	if !ok {
+		return common.ErrVerification
 	}
 	return nil
 }
@@ -0,0 +1,156 @@
+package factor
+
+import (
+	"context"
+	crand "crypto/rand"
+	"math/big"
+	"math/bits"
+)
+
+const (
+	ecmStage2Ratio = 25      // B2 = 25*B1
+	ecmStage2Max   = 1 << 23 // верхняя граница B2
+)
+
+// ecmPoint - точка кривой Монтгомери в проективных координатах (X : Z)
+type ecmPoint struct {
+	x, z *big.Int
+}
+
+// ecmCurve - кривая Монтгомери B*y^2 = x^3 + A*x^2 + x по модулю n; a24 = (A+2)/4
+type ecmCurve struct {
+	n, a24 *big.Int
+}
+
+func (c *ecmCurve) double(p ecmPoint) ecmPoint {
+	s := new(big.Int).Add(p.x, p.z)
+	s.Mul(s, s).Mod(s, c.n)
+	d := new(big.Int).Sub(p.x, p.z)
+	d.Mul(d, d).Mod(d, c.n)
+	t := new(big.Int).Sub(s, d)
+	x := new(big.Int).Mul(s, d)
+	x.Mod(x, c.n)
+	z := new(big.Int).Mul(c.a24, t)
+	z.Add(z, d).Mul(z, t).Mod(z, c.n)
+	return ecmPoint{x, z}
+}
+
+// add - p + q по известной разности diff = p - q
+func (c *ecmCurve) add(p, q, diff ecmPoint) ecmPoint {
+	u := new(big.Int).Sub(p.x, p.z)
+	u.Mul(u, new(big.Int).Add(q.x, q.z))
+	v := new(big.Int).Add(p.x, p.z)
+	v.Mul(v, new(big.Int).Sub(q.x, q.z))
+	x := new(big.Int).Add(u, v)
+	x.Mul(x, x).Mod(x, c.n).Mul(x, diff.z).Mod(x, c.n)
+	z := new(big.Int).Sub(u, v)
+	z.Mul(z, z).Mod(z, c.n).Mul(z, diff.x).Mod(z, c.n)
+	return ecmPoint{x, z}
+}
+
+// mul - [k]p лестницей Монтгомери, k >= 1
+func (c *ecmCurve) mul(p ecmPoint, k uint64) ecmPoint {
+	r0, r1 := p, c.double(p)
+	for i := 62 - bits.LeadingZeros64(k); i >= 0; i-- {
+		if k>>uint(i)&1 == 1 {
+			r0, r1 = c.add(r1, r0, p), c.double(r1)
+		} else {
+			r0, r1 = c.double(r0), c.add(r1, r0, p)
+		}
+	}
+	return r0
+}
+
+// suyama - кривая и точка по параметризации Суямы для sigma; если при построении
+// знаменатель необратим, вместо кривой возвращается делитель n
+func suyama(n, sigma *big.Int) (*ecmCurve, ecmPoint, *big.Int) {
+	u := new(big.Int).Mul(sigma, sigma)
+	u.Sub(u, big.NewInt(5)).Mod(u, n)
+	v := new(big.Int).Lsh(sigma, 2)
+	v.Mod(v, n)
+	u3 := new(big.Int).Exp(u, big.NewInt(3), n)
+	p := ecmPoint{x: u3, z: new(big.Int).Exp(v, big.NewInt(3), n)}
+	// a24 = (v-u)^3 * (3u+v) / (16 * u^3 * v)
+	num := new(big.Int).Sub(v, u)
+	num.Exp(num.Mod(num, n), big.NewInt(3), n)
+	num.Mul(num, new(big.Int).Add(new(big.Int).Mul(u, big.NewInt(3)), v)).Mod(num, n)
+	den := new(big.Int).Mul(u3, v)
+	den.Lsh(den, 4).Mod(den, n)
+	inv := new(big.Int).ModInverse(den, n)
+	if inv == nil {
+		return nil, p, new(big.Int).GCD(nil, nil, den, n)
+	}
+	return &ecmCurve{n: n, a24: num.Mul(num, inv).Mod(num, n)}, p, nil
+}
+
+// ECM - нетривиальный делитель n методом эллиптических кривых Ленстры: curves случайных
+// кривых Монтгомери, первая стадия с границей bound, вторая - до 25*bound.
+// Время поиска делителя p зависит от p, а не от n, поэтому метод хорош для несбалансированных n.
+func ECM(ctx context.Context, n *big.Int, bound uint64, curves int) (*big.Int, error) {
+	if n.Bit(0) == 0 {
+		return big.NewInt(2), nil
+	}
+	bound2 := bound * ecmStage2Ratio
+	if bound2 > ecmStage2Max {
+		bound2 = ecmStage2Max
+	}
+	if bound2 < bound {
+		bound2 = bound
+	}
+	ps := primes(bound)
+	isPrime2 := sieve(bound2)
+	sigmaRange := new(big.Int).Sub(n, big.NewInt(7))
+	g := new(big.Int)
+	for i := 0; i < curves; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		sigma, err := crand.Int(crand.Reader, sigmaRange)
+		if err != nil {
+			return nil, err
+		}
+		sigma.Add(sigma, big.NewInt(6))
+		c, p, d := suyama(n, sigma)
+		if d != nil {
+			if d.Cmp(one) > 0 && d.Cmp(n) < 0 {
+				return d, nil
+			}
+			continue
+		}
+		// Первая стадия: p <- [k]p, k - произведение наибольших степеней простых до bound
+		for _, q := range ps {
+			qk := q
+			for qk <= bound/q {
+				qk *= q
+			}
+			p = c.mul(p, qk)
+		}
+		g.GCD(nil, nil, p.z, n)
+		if g.Cmp(one) > 0 {
+			if g.Cmp(n) < 0 {
+				return new(big.Int).Set(g), nil
+			}
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// Вторая стадия: [m]p для нечётных m из (bound, bound2] шагом [2]p; для простых m
+		// накапливается произведение Z-координат
+		m := bound + 1 | 1
+		p2 := c.double(p)
+		prev, cur := c.mul(p, m-2), c.mul(p, m)
+		acc := big.NewInt(1)
+		for ; m <= bound2; m += 2 {
+			if isPrime2[m] {
+				acc.Mul(acc, cur.z).Mod(acc, c.n)
+			}
+			prev, cur = cur, c.add(cur, p2, prev)
+		}
+		g.GCD(nil, nil, acc, n)
+		if g.Cmp(one) > 0 && g.Cmp(n) < 0 {
+			return new(big.Int).Set(g), nil
+		}
+	}
+	return nil, ErrNoFactor
+}
@@ -0,0 +1,161 @@
+// Package factor - разложение целых чисел на простые множители.
+//
+// Пакет не зависит от common, чтобы common мог использовать его для поиска
+// первообразных корней.
+package factor
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sort"
+)
+
+var (
+	ErrNoFactor    = errors.New("no factor found") // метод не нашёл делителя в отведённых пределах
+	ErrNotPositive = errors.New("number to factor must be positive")
+)
+
+// PrimePower - множитель P^E разложения
+type PrimePower struct {
+	P *big.Int
+	E int
+}
+
+const (
+	trialBound = 1 << 16   // граница пробного деления
+	rhoSteps   = 1 << 18   // шагов ро-метода для чисел больше 64 бит
+	pm1Bound   = 100_000   // граница B1 метода p-1
+	ecmBound   = 2_000     // начальная граница B1 метода Ленстры
+	ecmCurves  = 25        // кривых на одну границу B1
+	ecmMaxB1   = 1_250_000 // наибольшая граница B1 ECM, после неё - ErrNoFactor
+	qsMaxBits  = 160       // квадратичное решето применяется к числам до этой длины
+)
+
+var one = big.NewInt(1)
+
+// Factor - разложение n > 0 на простые множители в порядке возрастания; для n = 1 - пустое.
+// ErrNotPositive при n <= 0, ErrNoFactor, если у составного множителя не нашлось делителя.
+func Factor(n *big.Int) ([]PrimePower, error) {
+	return FactorContext(context.Background(), n)
+}
+
+// FactorContext - Factor с возможностью отмены.
+//
+// После пробного деления до 2^16 каждый составной остаток проверяется на точную
+// степень, числа до 64 бит раскладываются ро-методом Полларда-Брента, большие -
+// ограниченными по времени ро-методом, методом p-1 и ECM, затем квадратичным
+// решетом (до 160 бит) и ECM с растущей до 1 250 000 границей B1. Если и она не
+// нашла делителя (у числа больше 160 бит два больших простых множителя), - ErrNoFactor.
+func FactorContext(ctx context.Context, n *big.Int) ([]PrimePower, error) {
+	if n.Sign() <= 0 {
+		return nil, ErrNotPositive
+	}
+	found, rest := TrialDivision(n, trialBound)
+	counts := make(map[string]*PrimePower, len(found))
+	add := func(p *big.Int, e int) {
+		key := p.String()
+		if pp, ok := counts[key]; ok {
+			pp.E += e
+			return
+		}
+		counts[key] = &PrimePower{P: new(big.Int).Set(p), E: e}
+	}
+	for _, pp := range found {
+		add(pp.P, pp.E)
+	}
+	type item struct {
+		m *big.Int
+		e int
+	}
+	stack := []item{{rest, 1}}
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if it.m.Cmp(one) == 0 {
+			continue
+		}
+		if it.m.ProbablyPrime(20) {
+			add(it.m, it.e)
+			continue
+		}
+		if r, k := perfectPower(it.m); k > 1 {
+			stack = append(stack, item{r, it.e * k})
+			continue
+		}
+		d, err := split(ctx, it.m)
+		if err != nil {
+			return nil, err
+		}
+		stack = append(stack, item{d, it.e}, item{new(big.Int).Quo(it.m, d), it.e})
+	}
+	res := make([]PrimePower, 0, len(counts))
+	for _, pp := range counts {
+		res = append(res, *pp)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].P.Cmp(res[j].P) < 0 })
+	return res, nil
+}
+
+// split - нетривиальный делитель составного n без малых множителей, не являющегося степенью
+func split(ctx context.Context, n *big.Int) (*big.Int, error) {
+	if n.BitLen() <= 64 {
+		return Brent(ctx, n, 0)
+	}
+	attempts := []func() (*big.Int, error){
+		func() (*big.Int, error) { return Brent(ctx, n, rhoSteps) },
+		func() (*big.Int, error) { return PollardPM1(ctx, n, pm1Bound) },
+		func() (*big.Int, error) { return ECM(ctx, n, ecmBound, ecmCurves) },
+	}
+	if n.BitLen() <= qsMaxBits {
+		attempts = append(attempts, func() (*big.Int, error) { return QuadraticSieve(ctx, n) })
+	}
+	for _, attempt := range attempts {
+		d, err := attempt()
+		if err == nil {
+			return d, nil
+		}
+		if !errors.Is(err, ErrNoFactor) {
+			return nil, err
+		}
+	}
+	// Остаётся ECM с растущей границей: время и память на первую стадию растут с B1,
+	// поэтому граница ограничена ecmMaxB1
+	for b1 := uint64(ecmBound) * 5; b1 <= ecmMaxB1; b1 *= 5 {
+		d, err := ECM(ctx, n, b1, ecmCurves)
+		if !errors.Is(err, ErrNoFactor) {
+			return d, err
+		}
+	}
+	return nil, ErrNoFactor
+}
+
+// perfectPower - n = r^k с наибольшим k; k = 1, если n не является точной степенью
+func perfectPower(n *big.Int) (*big.Int, int) {
+	// Множители меньше 2^16 уже отделены, поэтому r >= 2^16
+	for k := n.BitLen() / 16; k >= 2; k-- {
+		r := iroot(n, k)
+		if new(big.Int).Exp(r, big.NewInt(int64(k)), nil).Cmp(n) == 0 {
+			return r, k
+		}
+	}
+	return n, 1
+}
+
+// iroot - целая часть корня k-й степени из n > 0 (метод Ньютона)
+func iroot(n *big.Int, k int) *big.Int {
+	bk := big.NewInt(int64(k))
+	k1 := big.NewInt(int64(k - 1))
+	x := new(big.Int).Lsh(one, uint((n.BitLen()+k-1)/k))
+	for {
+		// y = ((k-1)*x + n/x^(k-1)) / k
+		y := new(big.Int).Exp(x, k1, nil)
+		y.Quo(n, y)
+		y.Add(y, new(big.Int).Mul(k1, x))
+		y.Quo(y, bk)
+		if y.Cmp(x) >= 0 {
+			return x
+		}
+		x = y
+	}
+}
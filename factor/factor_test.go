@@ -0,0 +1,236 @@
+package factor
+
+import (
+	"context"
+	crand "crypto/rand"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// randomPrime - случайное простое длиной bits бит
+func randomPrime(t *testing.T, bits int) *big.Int {
+	t.Helper()
+	p, err := crand.Prime(crand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// smoothPrime - простое p, у которого p-1 раскладывается на множители меньше 2^10
+func smoothPrime(t *testing.T, bits int) *big.Int {
+	t.Helper()
+	small := primesUpTo(1 << 10)
+	for {
+		m := big.NewInt(2)
+		for m.BitLen() < bits {
+			i, err := crand.Int(crand.Reader, big.NewInt(int64(len(small))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			m.Mul(m, new(big.Int).SetUint64(small[i.Int64()]))
+		}
+		p := new(big.Int).Add(m, one)
+		if p.ProbablyPrime(20) {
+			return p
+		}
+	}
+}
+
+// product - n = prod P^E
+func product(factors []PrimePower) *big.Int {
+	n := big.NewInt(1)
+	for _, pp := range factors {
+		n.Mul(n, new(big.Int).Exp(pp.P, big.NewInt(int64(pp.E)), nil))
+	}
+	return n
+}
+
+func TestFactor(t *testing.T) {
+	p48 := randomPrime(t, 48)
+	tests := []struct {
+		name string
+		want []PrimePower
+	}{
+		{name: "единица", want: nil},
+		{name: "малое простое", want: []PrimePower{{big.NewInt(65537), 1}}},
+		{name: "степени малых простых", want: []PrimePower{{big.NewInt(2), 10}, {big.NewInt(3), 5}, {big.NewInt(65521), 2}}},
+		{name: "64 бита", want: []PrimePower{{big.NewInt(4294967291), 1}, {big.NewInt(4294967311), 1}}},
+		{name: "куб большого простого", want: []PrimePower{{p48, 3}}},
+		{name: "сбалансированные 50 бит", want: sorted(randomPrime(t, 50), randomPrime(t, 50))},
+		{name: "несбалансированные 32 и 90 бит", want: sorted(randomPrime(t, 32), randomPrime(t, 90))},
+		{name: "смешанное", want: []PrimePower{{big.NewInt(3), 2}, {big.NewInt(7919), 1}, {p48, 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := product(tt.want)
+			got, err := Factor(n)
+			if err != nil {
+				t.Fatalf("Factor(%s) error = %v", n, err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Factor(%s) = %v, want %v", n, got, tt.want)
+			}
+			for i := range got {
+				if got[i].P.Cmp(tt.want[i].P) != 0 || got[i].E != tt.want[i].E {
+					t.Fatalf("Factor(%s) = %v, want %v", n, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// sorted - разложение p*q в порядке возрастания
+func sorted(p, q *big.Int) []PrimePower {
+	if p.Cmp(q) > 0 {
+		p, q = q, p
+	}
+	if p.Cmp(q) == 0 {
+		return []PrimePower{{p, 2}}
+	}
+	return []PrimePower{{p, 1}, {q, 1}}
+}
+
+func TestMethods(t *testing.T) {
+	balanced := new(big.Int).Mul(randomPrime(t, 36), randomPrime(t, 36))
+	tests := []struct {
+		name   string
+		n      *big.Int
+		method func(ctx context.Context, n *big.Int) (*big.Int, error)
+	}{
+		{
+			name:   "ро-метод Брента",
+			n:      balanced,
+			method: func(ctx context.Context, n *big.Int) (*big.Int, error) { return Brent(ctx, n, 0) },
+		},
+		{
+			name: "метод p-1 при гладком p-1",
+			n:    new(big.Int).Mul(smoothPrime(t, 60), randomPrime(t, 60)),
+			method: func(ctx context.Context, n *big.Int) (*big.Int, error) {
+				return PollardPM1(ctx, n, 1<<20)
+			},
+		},
+		{
+			name: "ECM",
+			n:    new(big.Int).Mul(randomPrime(t, 40), randomPrime(t, 100)),
+			method: func(ctx context.Context, n *big.Int) (*big.Int, error) {
+				return ECM(ctx, n, 2000, 500)
+			},
+		},
+		{
+			name:   "квадратичное решето",
+			n:      new(big.Int).Mul(randomPrime(t, 50), randomPrime(t, 50)),
+			method: QuadraticSieve,
+		},
+		{
+			name:   "чётное число",
+			n:      big.NewInt(2 * 1000003),
+			method: QuadraticSieve,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := tt.method(context.Background(), tt.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Cmp(one) <= 0 || d.Cmp(tt.n) >= 0 || new(big.Int).Mod(tt.n, d).Sign() != 0 {
+				t.Errorf("%s is not a proper divisor of %s", d, tt.n)
+			}
+		})
+	}
+}
+
+func TestMethodErrors(t *testing.T) {
+	n := new(big.Int).Mul(randomPrime(t, 64), randomPrime(t, 64))
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method func(ctx context.Context, n *big.Int) (*big.Int, error)
+		target error
+	}{
+		{
+			name:   "ро-метод с малым числом шагов",
+			ctx:    context.Background(),
+			method: func(ctx context.Context, n *big.Int) (*big.Int, error) { return Brent(ctx, n, 1000) },
+			target: ErrNoFactor,
+		},
+		{
+			name:   "метод p-1 с малой границей",
+			ctx:    context.Background(),
+			method: func(ctx context.Context, n *big.Int) (*big.Int, error) { return PollardPM1(ctx, n, 100) },
+			target: ErrNoFactor,
+		},
+		{
+			name:   "отмена ECM",
+			ctx:    cancelled,
+			method: func(ctx context.Context, n *big.Int) (*big.Int, error) { return ECM(ctx, n, 1000, 10) },
+			target: context.Canceled,
+		},
+		{
+			name:   "отмена квадратичного решета",
+			ctx:    cancelled,
+			method: QuadraticSieve,
+			target: context.Canceled,
+		},
+		{
+			name: "отрицательное число",
+			ctx:  context.Background(),
+			method: func(ctx context.Context, n *big.Int) (*big.Int, error) {
+				_, err := FactorContext(ctx, new(big.Int).Neg(n))
+				return nil, err
+			},
+			target: ErrNotPositive,
+		},
+		{
+			name: "ноль",
+			ctx:  context.Background(),
+			method: func(ctx context.Context, n *big.Int) (*big.Int, error) {
+				_, err := Factor(new(big.Int))
+				return nil, err
+			},
+			target: ErrNotPositive,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.method(tt.ctx, n)
+			if !errors.Is(err, tt.target) {
+				t.Errorf("error = %v, want %v", err, tt.target)
+			}
+		})
+	}
+}
+
+func TestTrialDivision(t *testing.T) {
+	tests := []struct {
+		name     string
+		n        *big.Int
+		bound    uint64
+		want     []PrimePower
+		wantRest int64
+	}{
+		{name: "полное разложение", n: big.NewInt(2 * 2 * 3 * 97), bound: 100, want: []PrimePower{{big.NewInt(2), 2}, {big.NewInt(3), 1}, {big.NewInt(97), 1}}, wantRest: 1},
+		{name: "остаток больше границы", n: big.NewInt(8 * 1009 * 1013), bound: 100, want: []PrimePower{{big.NewInt(2), 3}}, wantRest: 1009 * 1013},
+		{name: "простой остаток", n: big.NewInt(5 * 1_000_003), bound: 1 << 10, want: []PrimePower{{big.NewInt(5), 1}}, wantRest: 1_000_003},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest := TrialDivision(tt.n, tt.bound)
+			if rest.Cmp(big.NewInt(tt.wantRest)) != 0 {
+				t.Errorf("rest = %s, want %d", rest, tt.wantRest)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("factors = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].P.Cmp(tt.want[i].P) != 0 || got[i].E != tt.want[i].E {
+					t.Fatalf("factors = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
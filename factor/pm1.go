@@ -0,0 +1,65 @@
+package factor
+
+import (
+	"context"
+	"math/big"
+)
+
+// pm1Batch - простых между проверками НОД в методе p-1
+const pm1Batch = 64
+
+// PollardPM1 - нетривиальный делитель n методом p-1 Полларда (первая стадия).
+// Находит простой делитель p, если p-1 раскладывается на степени простых не больше bound.
+func PollardPM1(ctx context.Context, n *big.Int, bound uint64) (*big.Int, error) {
+	if n.Bit(0) == 0 {
+		return big.NewInt(2), nil
+	}
+	ps := primes(bound)
+	a := big.NewInt(2)
+	saved := new(big.Int)
+	g := new(big.Int)
+	t := new(big.Int)
+	am1 := new(big.Int)
+	// power - наибольшая степень q, не превосходящая bound
+	power := func(q uint64) *big.Int {
+		pq := q
+		for pq <= bound/q {
+			pq *= q
+		}
+		return t.SetUint64(pq)
+	}
+	for start := 0; start < len(ps); start += pm1Batch {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := start + pm1Batch
+		if end > len(ps) {
+			end = len(ps)
+		}
+		saved.Set(a)
+		for _, q := range ps[start:end] {
+			a.Exp(a, power(q), n)
+		}
+		g.GCD(nil, nil, am1.Sub(a, one), n)
+		if g.Cmp(one) == 0 {
+			continue
+		}
+		if g.Cmp(n) != 0 {
+			return g, nil
+		}
+		// Все простые делители найдены одновременно: повторяем пачку по одному простому
+		a.Set(saved)
+		for _, q := range ps[start:end] {
+			a.Exp(a, power(q), n)
+			g.GCD(nil, nil, am1.Sub(a, one), n)
+			if g.Cmp(one) > 0 {
+				break
+			}
+		}
+		if g.Cmp(n) != 0 {
+			return g, nil
+		}
+		return nil, ErrNoFactor
+	}
+	return nil, ErrNoFactor
+}
@@ -0,0 +1,301 @@
+package factor
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"math/bits"
+)
+
+const (
+	qsBlock = 1 << 16 // длина интервала просеивания
+	qsExtra = 16      // соотношений сверх числа столбцов матрицы
+	qsSlack = 2.0     // порог: log2|Q(x)| - qsSlack*log2(B), запас на степени и погрешность логарифмов
+)
+
+// qsPrime - простое базы множителей и корни t^2 = n (mod p)
+type qsPrime struct {
+	p     uint64
+	logp  uint8
+	roots []uint64
+}
+
+// qsRelation - x^2 - n = ±prod p_j^e_j: idx - номера столбцов (0 - знак), exps - степени
+type qsRelation struct {
+	x    *big.Int
+	idx  []int32
+	exps []uint16
+}
+
+// qsBound - граница базы exp(sqrt(ln n * ln ln n) / 2)
+func qsBound(n *big.Int) uint64 {
+	ln := float64(n.BitLen()) * math.Ln2
+	b := math.Exp(math.Sqrt(ln*math.Log(ln)) / 2)
+	if b < 200 {
+		b = 200
+	}
+	if b > 1<<22 {
+		b = 1 << 22
+	}
+	return uint64(b)
+}
+
+// powMod32 - a^e mod p для p < 2^32
+func powMod32(a, e, p uint64) uint64 {
+	r := uint64(1) % p
+	a %= p
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			r = r * a % p
+		}
+		a = a * a % p
+	}
+	return r
+}
+
+// sqrtMod32 - корень из квадратичного вычета a по модулю простого p < 2^32 (Тонелли-Шенкс)
+func sqrtMod32(a, p uint64) uint64 {
+	a %= p
+	if p == 2 || a == 0 {
+		return a
+	}
+	// p-1 = q * 2^s
+	q, s := p-1, 0
+	for q%2 == 0 {
+		q /= 2
+		s++
+	}
+	z := uint64(2)
+	for powMod32(z, (p-1)/2, p) != p-1 {
+		z++
+	}
+	m, c, t, r := s, powMod32(z, q, p), powMod32(a, q, p), powMod32(a, (q+1)/2, p)
+	for t != 1 {
+		i, t2 := 0, t
+		for t2 != 1 {
+			t2 = t2 * t2 % p
+			i++
+		}
+		b := c
+		for j := 0; j < m-i-1; j++ {
+			b = b * b % p
+		}
+		m, c = i, b*b%p
+		t, r = t*c%p, r*b%p
+	}
+	return r
+}
+
+// QuadraticSieve - нетривиальный делитель нечётного составного n, не являющегося
+// точной степенью, квадратичным решетом с одним многочленом Q(x) = x^2 - n.
+// Соотношения собираются просеиванием интервалов вокруг sqrt(n), зависимости по модулю 2
+// ищутся исключением Гаусса, и каждая зависимость даёт X^2 = Y^2 (mod n).
+func QuadraticSieve(ctx context.Context, n *big.Int) (*big.Int, error) {
+	if n.Bit(0) == 0 {
+		return big.NewInt(2), nil
+	}
+	root := new(big.Int).Sqrt(n)
+	if new(big.Int).Mul(root, root).Cmp(n) == 0 {
+		return root, nil
+	}
+	bound := qsBound(n)
+	var base []qsPrime
+	for _, p := range primesUpTo(bound) {
+		a := new(big.Int).Mod(n, new(big.Int).SetUint64(p)).Uint64()
+		if a == 0 {
+			if new(big.Int).SetUint64(p).Cmp(n) == 0 {
+				return nil, ErrNoFactor
+			}
+			return new(big.Int).SetUint64(p), nil
+		}
+		if p != 2 && powMod32(a, (p-1)/2, p) != 1 {
+			continue
+		}
+		t := sqrtMod32(a, p)
+		qp := qsPrime{p: p, logp: uint8(math.Round(math.Log2(float64(p)))), roots: []uint64{t}}
+		if p-t != t {
+			qp.roots = append(qp.roots, p-t)
+		}
+		base = append(base, qp)
+	}
+	cols := len(base) + 1
+	want := cols + qsExtra
+	// x = m + i, m = ceil(sqrt(n))
+	m := root.Add(root, one)
+	mMod := make([]uint64, len(base))
+	for j, qp := range base {
+		mMod[j] = new(big.Int).Mod(m, new(big.Int).SetUint64(qp.p)).Uint64()
+	}
+	thresholdBase := float64(n.BitLen())/2 + 1 - qsSlack*math.Log2(float64(bound))
+	var rels []qsRelation
+	sieveArr := make([]uint8, qsBlock)
+	// Интервалы просеивания чередуются: [0, L), [-L, 0), [L, 2L), ...
+	for block := 0; len(rels) < want; block++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		start := int64(block/2) * qsBlock
+		if block%2 == 1 {
+			start = -start - qsBlock
+		}
+		for i := range sieveArr {
+			sieveArr[i] = 0
+		}
+		for j, qp := range base {
+			s := uint64(((start % int64(qp.p)) + int64(qp.p)) % int64(qp.p))
+			for _, r := range qp.roots {
+				// (m + start + off) = r (mod p)
+				off := (r + 2*qp.p - mMod[j] - s) % qp.p
+				for ; off < qsBlock; off += qp.p {
+					sieveArr[off] += qp.logp
+				}
+			}
+		}
+		far := start
+		if far < 0 {
+			far = -far
+		} else {
+			far += qsBlock
+		}
+		threshold := thresholdBase + math.Log2(float64(far))
+		for off, v := range sieveArr {
+			if float64(v) < threshold {
+				continue
+			}
+			if rel, ok := qsSmooth(n, m, start+int64(off), base, mMod); ok {
+				rels = append(rels, rel)
+				if len(rels) >= want {
+					break
+				}
+			}
+		}
+	}
+	for _, dep := range qsDependencies(rels, cols) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if d := qsCombine(n, rels, dep, base); d != nil {
+			return d, nil
+		}
+	}
+	return nil, ErrNoFactor
+}
+
+// qsSmooth - разложение Q(m+i) по базе; false, если остаётся множитель вне базы
+func qsSmooth(n, m *big.Int, i int64, base []qsPrime, mMod []uint64) (qsRelation, bool) {
+	x := new(big.Int).Add(m, big.NewInt(i))
+	q := new(big.Int).Mul(x, x)
+	q.Sub(q, n)
+	rel := qsRelation{x: x}
+	if q.Sign() < 0 {
+		rel.idx = append(rel.idx, 0)
+		rel.exps = append(rel.exps, 1)
+		q.Neg(q)
+	}
+	quo, rem, bp := new(big.Int), new(big.Int), new(big.Int)
+	for j, qp := range base {
+		// Делим только на простые, для которых x - корень Q по модулю p
+		xm := (mMod[j] + uint64((i%int64(qp.p)+int64(qp.p))%int64(qp.p))) % qp.p
+		if xm != qp.roots[0] && (len(qp.roots) == 1 || xm != qp.roots[1]) {
+			continue
+		}
+		bp.SetUint64(qp.p)
+		var e uint16
+		for {
+			quo.QuoRem(q, bp, rem)
+			if rem.Sign() != 0 {
+				break
+			}
+			q.Set(quo)
+			e++
+		}
+		if e > 0 {
+			rel.idx = append(rel.idx, int32(j+1))
+			rel.exps = append(rel.exps, e)
+		}
+	}
+	return rel, q.Cmp(one) == 0
+}
+
+// qsDependencies - наборы соотношений, сумма векторов степеней которых чётна
+// (исключение Гаусса над GF(2) с историей строк)
+func qsDependencies(rels []qsRelation, cols int) [][]int {
+	words, hwords := (cols+63)/64, (len(rels)+63)/64
+	rows := make([][]uint64, len(rels))
+	hist := make([][]uint64, len(rels))
+	for r, rel := range rels {
+		rows[r] = make([]uint64, words)
+		hist[r] = make([]uint64, hwords)
+		hist[r][r/64] = 1 << uint(r%64)
+		for k, c := range rel.idx {
+			if rel.exps[k]&1 == 1 {
+				rows[r][c/64] ^= 1 << uint(c%64)
+			}
+		}
+	}
+	used := make([]bool, len(rels))
+	for c := 0; c < cols; c++ {
+		w, bit := c/64, uint64(1)<<uint(c%64)
+		pivot := -1
+		for r := range rows {
+			if !used[r] && rows[r][w]&bit != 0 {
+				pivot = r
+				break
+			}
+		}
+		if pivot < 0 {
+			continue
+		}
+		used[pivot] = true
+		for r := range rows {
+			if r != pivot && rows[r][w]&bit != 0 {
+				for k := range rows[r] {
+					rows[r][k] ^= rows[pivot][k]
+				}
+				for k := range hist[r] {
+					hist[r][k] ^= hist[pivot][k]
+				}
+			}
+		}
+	}
+	var deps [][]int
+	for r := range rows {
+		if used[r] {
+			continue
+		}
+		var dep []int
+		for k, word := range hist[r] {
+			for word != 0 {
+				b := bits.TrailingZeros64(word)
+				dep = append(dep, k*64+b)
+				word &= word - 1
+			}
+		}
+		deps = append(deps, dep)
+	}
+	return deps
+}
+
+// qsCombine - делитель НОД(X - Y, n) для зависимости: X = prod x_i, Y = sqrt(prod Q(x_i)) (mod n)
+func qsCombine(n *big.Int, rels []qsRelation, dep []int, base []qsPrime) *big.Int {
+	x := big.NewInt(1)
+	exps := make([]uint64, len(base)+1)
+	for _, r := range dep {
+		x.Mul(x, rels[r].x).Mod(x, n)
+		for k, c := range rels[r].idx {
+			exps[c] += uint64(rels[r].exps[k])
+		}
+	}
+	y := big.NewInt(1)
+	for j, qp := range base {
+		if e := exps[j+1] / 2; e > 0 {
+			pe := new(big.Int).Exp(new(big.Int).SetUint64(qp.p), new(big.Int).SetUint64(e), n)
+			y.Mul(y, pe).Mod(y, n)
+		}
+	}
+	d := new(big.Int).GCD(nil, nil, x.Sub(x, y), n)
+	if d.Cmp(one) > 0 && d.Cmp(n) < 0 {
+		return d
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package factor
+
+import (
+	"context"
+	"math/big"
+)
+
+// Brent - нетривиальный делитель составного n ро-методом Полларда в варианте Брента.
+// maxSteps ограничивает общее число шагов по всем многочленам x^2 + c; 0 - без ограничения.
+// Ожидаемое число шагов - порядка sqrt(p) для наименьшего простого делителя p.
+func Brent(ctx context.Context, n *big.Int, maxSteps int) (*big.Int, error) {
+	if n.Bit(0) == 0 {
+		return big.NewInt(2), nil
+	}
+	steps := 0
+	for c := int64(1); ; c++ {
+		y := big.NewInt(2)
+		x := new(big.Int)
+		ys := new(big.Int)
+		g := big.NewInt(1)
+		q := big.NewInt(1)
+		d := new(big.Int)
+		bc := big.NewInt(c)
+		f := func(v *big.Int) { v.Mul(v, v).Add(v, bc).Mod(v, n) }
+		const m = 128
+		for r := 1; g.Cmp(one) == 0; r <<= 1 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if maxSteps > 0 && steps > maxSteps {
+				return nil, ErrNoFactor
+			}
+			x.Set(y)
+			for i := 0; i < r; i++ {
+				f(y)
+			}
+			for k := 0; k < r && g.Cmp(one) == 0; k += m {
+				ys.Set(y)
+				for i := 0; i < m && i < r-k; i++ {
+					f(y)
+					q.Mul(q, d.Sub(x, y).Abs(d)).Mod(q, n)
+				}
+				g.GCD(nil, nil, q, n)
+			}
+			steps += 2 * r
+		}
+		if g.Cmp(n) == 0 {
+			// Произведение обнулилось: повторяем шаги по одному от сохранённой точки
+			for {
+				f(ys)
+				g.GCD(nil, nil, d.Sub(x, ys).Abs(d), n)
+				if g.Cmp(one) > 0 {
+					break
+				}
+			}
+		}
+		if g.Cmp(n) != 0 {
+			return g, nil
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package factor
+
+import (
+	"math/big"
+	"sync"
+)
+
+var (
+	primesOnce  sync.Once
+	smallPrimes []uint64 // простые до trialBound
+)
+
+// sieve - решето Эратосфена: isPrime[i] для i <= b
+func sieve(b uint64) []bool {
+	isPrime := make([]bool, b+1)
+	for i := uint64(2); i <= b; i++ {
+		isPrime[i] = true
+	}
+	for i := uint64(2); i*i <= b; i++ {
+		if isPrime[i] {
+			for j := i * i; j <= b; j += i {
+				isPrime[j] = false
+			}
+		}
+	}
+	return isPrime
+}
+
+// primesUpTo - простые числа до b
+func primesUpTo(b uint64) []uint64 {
+	var res []uint64
+	for i, ok := range sieve(b) {
+		if ok {
+			res = append(res, uint64(i))
+		}
+	}
+	return res
+}
+
+// primes - простые до bound; до trialBound берутся из общей таблицы
+func primes(bound uint64) []uint64 {
+	if bound > trialBound {
+		return primesUpTo(bound)
+	}
+	primesOnce.Do(func() { smallPrimes = primesUpTo(trialBound) })
+	i := len(smallPrimes)
+	for i > 0 && smallPrimes[i-1] > bound {
+		i--
+	}
+	return smallPrimes[:i]
+}
+
+// TrialDivision - пробное деление n > 0 на простые до bound: найденные множители
+// и остаток без них. Если остаток меньше квадрата границы, он равен 1 или простой.
+func TrialDivision(n *big.Int, bound uint64) ([]PrimePower, *big.Int) {
+	var res []PrimePower
+	rest := new(big.Int).Set(n)
+	q, r, bp := new(big.Int), new(big.Int), new(big.Int)
+	ps := primes(bound)
+	for i, p := range ps {
+		if rest.IsUint64() {
+			// Остаток помещается в машинное слово: дальше без big.Int
+			v := rest.Uint64()
+			for _, p := range ps[i:] {
+				if p*p > v {
+					break
+				}
+				e := 0
+				for v%p == 0 {
+					v /= p
+					e++
+				}
+				if e > 0 {
+					res = append(res, PrimePower{P: new(big.Int).SetUint64(p), E: e})
+				}
+			}
+			if v > 1 && v <= bound {
+				res = append(res, PrimePower{P: new(big.Int).SetUint64(v), E: 1})
+				v = 1
+			}
+			return res, rest.SetUint64(v)
+		}
+		bp.SetUint64(p)
+		e := 0
+		for {
+			q.QuoRem(rest, bp, r)
+			if r.Sign() != 0 {
+				break
+			}
+			rest.Set(q)
+			e++
+		}
+		if e > 0 {
+			res = append(res, PrimePower{P: new(big.Int).SetUint64(p), E: e})
+		}
+	}
+	return res, rest
+}
@@ -0,0 +1,166 @@
+// Package gm - вероятностная криптосистема Гольдвассер-Микали: каждый бит шифруется
+// отдельно как y^2 * x^b mod N, где x - псевдоквадрат (символ Якоби 1, но невычет).
+// Взлом равносилен задаче распознавания квадратичных вычетов по модулю N = P*Q.
+package gm
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"io"
+	"math/big"
+)
+
+const defaultBits = 1024 // длина модуля N по умолчанию
+
+type gmCipher struct {
+	P, Q, N         *big.Int // Закрытые P, Q и открытый модуль N = P*Q
+	X               *big.Int // Открытый псевдоквадрат
+	Input           io.Reader
+	OutputEncrypted io.Writer
+	OutputDecrypted io.Writer
+	buffer          []*big.Int
+}
+
+func newGMAlgorithm(bits int) (*gmCipher, error) {
+	if bits < 16 {
+		return nil, &common.ParameterError{Name: "bits", Reason: fmt.Sprintf("%d-bit modulus is too small", bits)}
+	}
+	// При P = Q = 3 (mod 4) число N-1 = -1 является невычетом по обоим модулям,
+	// поэтому его символ Якоби равен 1 и оно служит псевдоквадратом
+	p := common.GenBlumPrimeBig(bits / 2)
+	q := common.GenBlumPrimeBig(bits - bits/2)
+	for p.Cmp(q) == 0 {
+		q = common.GenBlumPrimeBig(bits - bits/2)
+	}
+	n := new(big.Int).Mul(p, q)
+	c := &gmCipher{P: p, Q: q, N: n, X: new(big.Int).Sub(n, big.NewInt(1))}
+	if common.Tracing() {
+		common.Trace("gm", "keygen", common.Val("P", c.P), common.Val("Q", c.Q), common.Val("N", c.N), common.Val("x", c.X))
+	}
+	return c, nil
+}
+
+func NewCipher(input io.Reader, encOut, decOut io.Writer) (common.Cipher, error) {
+	c, err := newGMAlgorithm(defaultBits)
+	if err != nil {
+		return nil, err
+	}
+	c.Input = input
+	c.OutputEncrypted = encOut
+	c.OutputDecrypted = decOut
+	return c, nil
+}
+
+// encryptBit - c = y^2 * x^b mod N со случайным y, взаимно простым с N.
+// Стойкость держится на непредсказуемости y, поэтому он берётся из crypto/rand.
+func (gc *gmCipher) encryptBit(b uint) (*big.Int, error) {
+	var y *big.Int
+	for y == nil || y.Sign() == 0 || common.GCDBig(y, gc.N).Cmp(big.NewInt(1)) != 0 {
+		var err error
+		if y, err = rand.Int(rand.Reader, gc.N); err != nil {
+			return nil, err
+		}
+	}
+	c := new(big.Int).Mul(y, y)
+	if b == 1 {
+		c.Mul(c, gc.X)
+	}
+	return c.Mod(c, gc.N), nil
+}
+
+// decryptBit - бит равен 0, если c - квадратичный вычет по модулю P
+func (gc *gmCipher) decryptBit(c *big.Int) (uint, error) {
+	switch common.LegendreBig(c, gc.P) {
+	case 1:
+		return 0, nil
+	case -1:
+		return 1, nil
+	}
+	return 0, fmt.Errorf("gm: ciphertext %s is not coprime with N: %w", c, common.ErrDecryption)
+}
+
+func (gc *gmCipher) Encrypt() error {
+	message, err := io.ReadAll(gc.Input)
+	if err != nil {
+		return err
+	}
+	// Восемь шифртекстов на байт, старший бит первым
+	encrypted := make([]*big.Int, 0, 8*len(message))
+	for _, byteVal := range message {
+		for i := 7; i >= 0; i-- {
+			b := uint(byteVal>>i) & 1
+			e, err := gc.encryptBit(b)
+			if err != nil {
+				return err
+			}
+			if common.Tracing() {
+				common.Trace("gm", "encrypt bit", common.ValInt("b", int64(b)), common.Val("c", e))
+			}
+			encrypted = append(encrypted, e)
+		}
+	}
+	gc.buffer = encrypted
+	return common.WriteBigNumbers(gc.OutputEncrypted, encrypted)
+}
+
+func (gc *gmCipher) Decrypt() error {
+	if len(gc.buffer)%8 != 0 {
+		return fmt.Errorf("gm: %d ciphertexts do not form whole bytes: %w", len(gc.buffer), common.ErrDecryption)
+	}
+	var decrypted bytes.Buffer
+	for i := 0; i < len(gc.buffer); i += 8 {
+		var byteVal byte
+		for _, e := range gc.buffer[i : i+8] {
+			b, err := gc.decryptBit(e)
+			if err != nil {
+				return err
+			}
+			if common.Tracing() {
+				common.Trace("gm", "decrypt bit", common.Val("c", e), common.ValInt("b", int64(b)))
+			}
+			byteVal = byteVal<<1 | byte(b)
+		}
+		decrypted.WriteByte(byteVal)
+	}
+	return common.WriteData(gc.OutputDecrypted, decrypted.Bytes())
+}
+
+// EncryptAndDecrypt - объединяет шифрование и дешифрование
+func (gc *gmCipher) EncryptAndDecrypt() error {
+	if err := gc.Encrypt(); err != nil {
+		return err
+	}
+	return gc.Decrypt()
+}
+
+// ExportPublicKey - открытый ключ (N, x) в виде блока брони
+func (gc *gmCipher) ExportPublicKey() (*common.ArmorBlock, error) {
+	return common.PublicKeyBlock("goldwasser-micali", []string{"N", "X"}, []*big.Int{gc.N, gc.X})
+}
+
+// ExportPrivateKey - закрытый ключ (P, Q) в виде блока брони
+func (gc *gmCipher) ExportPrivateKey() (*common.ArmorBlock, error) {
+	return common.PrivateKeyBlock("goldwasser-micali", []string{"P", "Q"}, []*big.Int{gc.P, gc.Q})
+}
+
+// DecryptArmored - расшифрование битов CIPHERTEXT закрытым ключом из ExportPrivateKey
+func DecryptArmored(key *common.ArmorBlock, ciphertext []byte, decOut io.Writer) error {
+	numbers, err := common.KeyNumbers(key, common.ArmorPrivateKey, "goldwasser-micali", 2)
+	if err != nil {
+		return err
+	}
+	p, q := numbers[0], numbers[1]
+	for _, prime := range numbers {
+		if prime.Cmp(big.NewInt(2)) <= 0 || !prime.ProbablyPrime(20) {
+			return &common.ParameterError{Name: "goldwasser-micali key", Reason: fmt.Sprintf("%s is not an odd prime", prime)}
+		}
+	}
+	n := new(big.Int).Mul(p, q)
+	gc := &gmCipher{P: p, Q: q, N: n, X: new(big.Int).Sub(n, big.NewInt(1)), OutputDecrypted: decOut}
+	if gc.buffer, err = common.ReadBigNumbers(bytes.NewReader(ciphertext)); err != nil {
+		return err
+	}
+	return gc.Decrypt()
+}
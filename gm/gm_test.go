@@ -0,0 +1,175 @@
+package gm
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/factor"
+	"math/big"
+	"testing"
+)
+
+func newTestCipher(t *testing.T, bits int, message []byte) (*gmCipher, *bytes.Buffer) {
+	t.Helper()
+	gc, err := newGMAlgorithm(bits)
+	if err != nil {
+		t.Fatalf("newGMAlgorithm(%d) error = %v", bits, err)
+	}
+	var enc, dec bytes.Buffer
+	gc.Input = bytes.NewReader(message)
+	gc.OutputEncrypted = &enc
+	gc.OutputDecrypted = &dec
+	return gc, &dec
+}
+
+func TestEncryptAndDecrypt(t *testing.T) {
+	tests := []struct {
+		name    string
+		bits    int
+		message []byte
+	}{
+		{name: "пустое сообщение", bits: 128, message: nil},
+		{name: "текст", bits: 128, message: []byte("Goldwasser-Micali")},
+		{name: "все значения байта", bits: 64, message: []byte{0x00, 0x01, 0x7f, 0x80, 0xff}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gc, dec := newTestCipher(t, tt.bits, tt.message)
+			if err := gc.EncryptAndDecrypt(); err != nil {
+				t.Fatalf("EncryptAndDecrypt() error = %v", err)
+			}
+			if !bytes.Equal(dec.Bytes(), tt.message) {
+				t.Errorf("decrypted %q, want %q", dec.Bytes(), tt.message)
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	gc, _ := newTestCipher(t, 128, nil)
+	// x - псевдоквадрат: символ Якоби равен 1, но по модулю P это невычет
+	if j := common.JacobiBig(gc.X, gc.N); j != 1 {
+		t.Errorf("Jacobi(x, N) = %d, want 1", j)
+	}
+	if l := common.LegendreBig(gc.X, gc.P); l != -1 {
+		t.Errorf("Legendre(x, P) = %d, want -1", l)
+	}
+	if _, err := newGMAlgorithm(8); !errors.Is(err, common.ErrInvalidParameters) {
+		t.Errorf("newGMAlgorithm(8) error = %v, want %v", err, common.ErrInvalidParameters)
+	}
+}
+
+// TestProbabilistic - один и тот же бит даёт разные шифртексты, а произведение
+// шифртекстов расшифровывается в XOR битов
+func TestProbabilistic(t *testing.T) {
+	gc, _ := newTestCipher(t, 128, nil)
+	seen := make(map[string]bool)
+	for i := 0; i < 16; i++ {
+		c, err := gc.encryptBit(1)
+		if err != nil {
+			t.Fatalf("encryptBit(1) error = %v", err)
+		}
+		if seen[c.String()] {
+			t.Fatalf("ciphertext %s repeated", c)
+		}
+		seen[c.String()] = true
+	}
+	for _, b0 := range []uint{0, 1} {
+		for _, b1 := range []uint{0, 1} {
+			c0, _ := gc.encryptBit(b0)
+			c1, _ := gc.encryptBit(b1)
+			got, err := gc.decryptBit(new(big.Int).Mod(new(big.Int).Mul(c0, c1), gc.N))
+			if err != nil {
+				t.Fatalf("decryptBit() error = %v", err)
+			}
+			if got != b0^b1 {
+				t.Errorf("E(%d) * E(%d) decrypts to %d, want %d", b0, b1, got, b0^b1)
+			}
+		}
+	}
+	if _, err := gc.decryptBit(new(big.Int).Set(gc.P)); !errors.Is(err, common.ErrDecryption) {
+		t.Errorf("decryptBit(P) error = %v, want %v", err, common.ErrDecryption)
+	}
+}
+
+// TestDecryptionOracleDecidesResiduosity - оракул расшифрования решает задачу
+// квадратичной вычетности: для a с символом Якоби 1 бит 0 означает, что a - квадрат
+func TestDecryptionOracleDecidesResiduosity(t *testing.T) {
+	gc, _ := newTestCipher(t, 128, nil)
+	oracle := func(c *big.Int) uint {
+		b, err := gc.decryptBit(c)
+		if err != nil {
+			t.Fatalf("decryptBit(%s) error = %v", c, err)
+		}
+		return b
+	}
+	counts := map[bool]int{}
+	for len(counts) < 2 || counts[true]+counts[false] < 64 {
+		a, _ := rand.Int(rand.Reader, gc.N)
+		if common.JacobiBig(a, gc.N) != 1 {
+			continue
+		}
+		// Истина известна только владельцу P и Q
+		isSquare := common.LegendreBig(a, gc.P) == 1 && common.LegendreBig(a, gc.Q) == 1
+		counts[isSquare]++
+		if got := oracle(a) == 0; got != isSquare {
+			t.Fatalf("oracle says %s is a square: %v, want %v", a, got, isSquare)
+		}
+	}
+}
+
+// TestFactoringBreaksGM - разложив N, противник вычисляет символ Лежандра и читает биты
+func TestFactoringBreaksGM(t *testing.T) {
+	message := []byte("secret")
+	gc, _ := newTestCipher(t, 64, message)
+	if err := gc.Encrypt(); err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	factors, err := factor.Factor(gc.N)
+	if err != nil {
+		t.Fatalf("Factor(N) error = %v", err)
+	}
+	if len(factors) != 2 {
+		t.Fatalf("Factor(N) = %v, want two primes", factors)
+	}
+	var dec bytes.Buffer
+	attacker := &gmCipher{P: factors[0].P, Q: factors[1].P, N: gc.N, OutputDecrypted: &dec, buffer: gc.buffer}
+	if err := attacker.Decrypt(); err != nil {
+		t.Fatalf("Decrypt() with recovered factors error = %v", err)
+	}
+	if !bytes.Equal(dec.Bytes(), message) {
+		t.Errorf("attacker decrypted %q, want %q", dec.Bytes(), message)
+	}
+}
+
+func TestDecryptArmored(t *testing.T) {
+	message := []byte("pasted ciphertext")
+	gc, _ := newTestCipher(t, 128, message)
+	var enc bytes.Buffer
+	gc.OutputEncrypted = &enc
+	if err := gc.Encrypt(); err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	key, err := gc.ExportPrivateKey()
+	if err != nil {
+		t.Fatalf("ExportPrivateKey() error = %v", err)
+	}
+	var dec bytes.Buffer
+	if err = DecryptArmored(key, enc.Bytes(), &dec); err != nil {
+		t.Fatalf("DecryptArmored() error = %v", err)
+	}
+	if !bytes.Equal(dec.Bytes(), message) {
+		t.Errorf("DecryptArmored() = %q, want %q", dec.Bytes(), message)
+	}
+	public, err := gc.ExportPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = DecryptArmored(public, enc.Bytes(), &dec); !errors.Is(err, common.ErrArmorMalformed) {
+		t.Errorf("DecryptArmored() with a public key error = %v, want %v", err, common.ErrArmorMalformed)
+	}
+	if err = DecryptArmored(key, enc.Bytes()[:enc.Len()-1], &dec); err == nil {
+		t.Error("DecryptArmored() of a truncated ciphertext error = nil")
+	}
+}
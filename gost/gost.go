@@ -7,7 +7,6 @@ import (
 	"github.com/Raimguzhinov/protect-information/common"
 	"io"
 	"math/big"
-	"os"
 )
 
 // gostSignature содержит параметры и результаты подписи
@@ -19,7 +18,9 @@ type gostSignature struct {
 	SignatureS   *big.Int // Часть подписи S
 	Input        io.Reader
 	OutputSigned io.Writer
-	Message      []byte // Сообщение для подписи
+	Message      []byte             // Сообщение для подписи
+	mc           *common.ModContext // Арифметика по модулю P
+	fbA, fbY     *common.FixedBase  // Таблицы для степеней A и открытого ключа (показатели < Q)
 }
 
 // Генерация случайного числа в диапазоне [min, max)
@@ -83,19 +84,41 @@ func generateGOSTParams() (*big.Int, *big.Int, *big.Int, error) {
 func NewSignature(input io.Reader, output io.ReadWriter) (common.Signer, error) {
 	p, q, a, err := generateGOSTParams()
 	if err != nil {
-		fmt.Printf("Ошибка генерации параметров: %v\n", err)
-		return nil, err
+		return nil, fmt.Errorf("ошибка генерации параметров: %v", err)
 	}
 	gs := &gostSignature{P: p, Q: q, A: a, Input: input, OutputSigned: output}
-	gs.GenerateKeys()
+	if gs.mc, err = common.NewModContext(p); err != nil {
+		return nil, err
+	}
+	gs.fbA = gs.mc.NewFixedBase(a, q.BitLen())
+	if err = gs.GenerateKeys(); err != nil {
+		return nil, err
+	}
 	return gs, nil
 }
 
-func (gs *gostSignature) GenerateKeys() {
+func (gs *gostSignature) GenerateKeys() error {
 	// Приватный ключ x — случайное число в диапазоне [1, q-1)
-	gs.PrivateKey = common.GenCoprimeBig(gs.Q, big.NewInt(1), new(big.Int).Sub(gs.Q, big.NewInt(1)))
+	var err error
+	gs.PrivateKey, err = common.GenCoprimeBig(gs.Q, big.NewInt(1), new(big.Int).Sub(gs.Q, big.NewInt(1)))
+	if err != nil {
+		return err
+	}
 	// Публичный ключ y = a^x mod p
-	gs.PublicKey = common.ModularExponentiationBig(gs.A, gs.PrivateKey, gs.P)
+	if gs.PublicKey, err = gs.fbA.ExpConstantTime(gs.PrivateKey); err != nil {
+		return err
+	}
+	gs.fbY = gs.mc.NewFixedBase(gs.PublicKey, gs.Q.BitLen())
+	if common.Tracing() {
+		common.Trace("gost", "keygen",
+			common.Val("P", gs.P), common.Val("Q", gs.Q), common.Val("A", gs.A), common.Val("x", gs.PrivateKey), common.Val("y", gs.PublicKey))
+	}
+	return nil
+}
+
+// ExportPublicKey - параметры (P, Q, A) и открытый ключ Y в виде блока брони
+func (gs *gostSignature) ExportPublicKey() (*common.ArmorBlock, error) {
+	return common.PublicKeyBlock("gost", []string{"P", "Q", "A", "Y"}, []*big.Int{gs.P, gs.Q, gs.A, gs.PublicKey})
 }
 
 // Подпись сообщения
@@ -105,101 +128,100 @@ func (gs *gostSignature) Sign() error {
 	if err != nil {
 		return err
 	}
-	// Хешируем сообщение
+	hashInt := hashMessage(message, gs.Q)
+	// k секретно, поэтому степень считается за постоянное время
+	gs.SignatureR, gs.SignatureS, err = sign(gs.fbA.ExpConstantTime, gs.Q, gs.PrivateKey, hashInt)
+	if err != nil {
+		return err
+	}
+	return common.WriteBigNumbers(gs.OutputSigned, []*big.Int{gs.SignatureR, gs.SignatureS})
+}
+
+// Проверка подписи
+func (gs *gostSignature) Verify() (bool, error) {
+	hashInt := hashMessage(gs.Message, gs.Q)
+	exp2 := func(u1, u2 *big.Int) *big.Int { return gs.mc.Mul(gs.fbA.Exp(u1), gs.fbY.Exp(u2)) }
+	return verify(exp2, gs.Q, hashInt, gs.SignatureR, gs.SignatureS), nil
+}
+
+// hashMessage - h = SHA-256(message) mod q
+func hashMessage(message []byte, q *big.Int) *big.Int {
 	hash := sha256.Sum256(message)
 	hashInt := new(big.Int).SetBytes(hash[:])
 	// Убедимся, что hashInt лежит в диапазоне [0, q)
-	if hashInt.Cmp(gs.Q) >= 0 {
-		hashInt.Mod(hashInt, gs.Q)
+	if hashInt.Cmp(q) >= 0 {
+		hashInt.Mod(hashInt, q)
 	}
-	fmt.Printf("Message hash (as int): %s\n", hashInt.String())
+	return hashInt
+}
+
+// sign - подпись (r, s) хэша h закрытым ключом x; expA(k) = a^k mod p
+func sign(expA func(k *big.Int) (*big.Int, error), q, x, hashInt *big.Int) (*big.Int, *big.Int, error) {
 	for {
-		k := common.GenCoprimeBig(gs.Q, big.NewInt(1), new(big.Int).Sub(gs.Q, big.NewInt(1)))
-		r := common.ModularExponentiationBig(gs.A, k, gs.P)
-		r.Mod(r, gs.Q)
+		k, err := common.GenCoprimeBig(q, big.NewInt(1), new(big.Int).Sub(q, big.NewInt(1)))
+		if err != nil {
+			return nil, nil, err
+		}
+		r, err := expA(k)
+		if err != nil {
+			return nil, nil, err
+		}
+		r.Mod(r, q)
 		if r.Cmp(big.NewInt(0)) == 0 {
 			continue // Если R = 0, снова выбираем k
 		}
 		// Вычисляем s = (k*h + x*r) mod q
 		s := new(big.Int).Mul(k, hashInt)
-		s.Add(s, new(big.Int).Mul(gs.PrivateKey, r))
-		s.Mod(s, gs.Q)
+		s.Add(s, new(big.Int).Mul(x, r))
+		s.Mod(s, q)
 		if s.Cmp(big.NewInt(0)) == 0 {
 			continue // Если S = 0, снова выбираем k
 		}
-		// Успешная подпись, сохраняем R и S
-		gs.SignatureR = r
-		gs.SignatureS = s
-		break
+		if common.Tracing() {
+			common.Trace("gost", "sign", common.Val("h", hashInt), common.Val("k", k), common.Val("r", r), common.Val("s", s))
+		}
+		return r, s, nil
 	}
-	fmt.Printf("Подпись создана: R = %s, S = %s", gs.SignatureR.String(), gs.SignatureS.String())
-	return nil
 }
 
-// Проверка подписи
-func (gs *gostSignature) Verify() (bool, error) {
-	// Хешируем сообщение
-	hash := sha256.Sum256(gs.Message)
-	hashInt := new(big.Int).SetBytes(hash[:])
-	fmt.Printf("Message hash (as int): %s\n", hashInt.String())
+// verify - проверка подписи (r, s) хэша h; exp2(u1, u2) = a^u1 * y^u2 mod p
+func verify(exp2 func(u1, u2 *big.Int) *big.Int, q, hashInt, r, s *big.Int) bool {
 	// Проверка неравенств для R и S
-	if gs.SignatureR.Cmp(big.NewInt(0)) <= 0 || gs.SignatureR.Cmp(gs.Q) >= 0 {
-		return false, nil
-	}
-	if gs.SignatureS.Cmp(big.NewInt(0)) <= 0 || gs.SignatureS.Cmp(gs.Q) >= 0 {
-		return false, nil
+	if r == nil || s == nil || r.Sign() <= 0 || r.Cmp(q) >= 0 || s.Sign() <= 0 || s.Cmp(q) >= 0 {
+		return false
 	}
 	// Вычисляем h^(-1) mod q с помощью common.GCDExtendedBig
-	gcd, hInv, _ := common.GCDExtendedBig(hashInt, gs.Q)
+	gcd, hInv, _ := common.GCDExtendedBig(hashInt, q)
 	if gcd.Cmp(big.NewInt(1)) != 0 {
-		return false, nil // Обратного элемента не существует
+		return false // Обратного элемента не существует
 	}
-	hInv.Mod(hInv, gs.Q)
+	hInv.Mod(hInv, q)
 	// Вычисляем u1 и u2
-	u1 := new(big.Int).Mul(gs.SignatureS, hInv)
-	u1.Mod(u1, gs.Q)
-	u2 := new(big.Int).Mul(new(big.Int).Neg(gs.SignatureR), hInv)
-	u2.Mod(u2, gs.Q)
+	u1 := new(big.Int).Mul(s, hInv)
+	u1.Mod(u1, q)
+	u2 := new(big.Int).Mul(new(big.Int).Neg(r), hInv)
+	u2.Mod(u2, q)
 	// Вычисляем v = (a^u1 * y^u2 mod p) mod q
-	v1 := common.ModularExponentiationBig(gs.A, u1, gs.P)
-	v2 := common.ModularExponentiationBig(gs.PublicKey, u2, gs.P)
-	v := new(big.Int).Mul(v1, v2)
-	v.Mod(v, gs.P)
-	v.Mod(v, gs.Q)
+	v := exp2(u1, u2)
+	v.Mod(v, q)
+	if common.Tracing() {
+		common.Trace("gost", "verify", common.Val("h", hashInt), common.Val("u1", u1), common.Val("u2", u2), common.Val("v", v))
+	}
 	// Сравниваем v и R
-	return v.Cmp(gs.SignatureR) == 0, nil
+	return v.Cmp(r) == 0
 }
 
+// SignAndVerify - подписывает сообщение и сразу проверяет подпись
 func (gs *gostSignature) SignAndVerify() error {
-	pwd, err := os.Getwd()
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
-	fmt.Print("Signed: ")
-	if err = gs.Sign(); err != nil {
+	if err := gs.Sign(); err != nil {
 		return err
 	}
-	if _, ok := gs.Input.(*os.File); ok {
-		fmt.Print(pwd + "/" + gs.OutputSigned.(*os.File).Name())
-	}
-	if out, ok := gs.OutputSigned.(*os.File); ok {
-		_ = out.Sync()
-		defer out.Close()
-		gs.OutputSigned, err = os.OpenFile(out.Name(), os.O_RDONLY, 0600)
-		if err != nil {
-			return err
-		}
-	}
-	fmt.Print("\nVerified: ")
 	ok, err := gs.Verify()
 	if err != nil {
 		return err
 	}
-	if ok {
-		fmt.Println("true")
-	} else {
-		fmt.Println("false")
+	if !ok {
+		return common.ErrVerification
 	}
 	return nil
 }
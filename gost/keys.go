@@ -0,0 +1,209 @@
+package gost
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"io"
+	"math/big"
+	"sync"
+)
+
+// algorithm - значение заголовка Algorithm в блоках брони ключей
+const algorithm = "gost"
+
+// PublicKey - параметры (P, Q, A) и открытый ключ Y = A^X mod P. Таблицы гребёнки
+// для A и Y строятся при первой подписи или проверке и переиспользуются, поэтому
+// после этого поля ключа менять нельзя.
+type PublicKey struct {
+	P, Q, A *big.Int
+	Y       *big.Int
+
+	tables *keyTables
+}
+
+// keyTables - контекст модуля P и таблицы гребёнки для постоянных оснований A и Y
+type keyTables struct {
+	mc   *common.ModContext
+	a, y *common.FixedBase
+}
+
+// tablesMu - построение PublicKey.tables; ключи читаются из многих горутин (квитанции lab5)
+var tablesMu sync.Mutex
+
+// PrivateKey - долговременный ключ подписи; X - секретный показатель из [1, Q)
+type PrivateKey struct {
+	PublicKey
+	X *big.Int
+}
+
+// GenerateParams - новые параметры: 1024-битное P = b*Q + 1, 256-битное Q и A порядка Q.
+// Параметры можно разделить между многими ключами.
+func GenerateParams() (p, q, a *big.Int, err error) {
+	return generateGOSTParams()
+}
+
+// GenerateKey - ключ подписи для параметров (p, q, a)
+func GenerateKey(p, q, a *big.Int) (*PrivateKey, error) {
+	if err := (&PublicKey{P: p, Q: q, A: a, Y: big.NewInt(1)}).validateParams(); err != nil {
+		return nil, err
+	}
+	x, err := common.GenCoprimeBig(q, big.NewInt(1), new(big.Int).Sub(q, big.NewInt(1)))
+	if err != nil {
+		return nil, err
+	}
+	return NewPrivateKey(p, q, a, x)
+}
+
+// NewPrivateKey - ключ с известным показателем x из [1, q); Y вычисляется
+func NewPrivateKey(p, q, a, x *big.Int) (*PrivateKey, error) {
+	pk := &PublicKey{P: p, Q: q, A: a, Y: big.NewInt(1)}
+	if err := pk.validateParams(); err != nil {
+		return nil, err
+	}
+	if x == nil || x.Sign() <= 0 || x.Cmp(q) >= 0 {
+		return nil, &common.ParameterError{Name: "gost key", Reason: "X must lie in [1, Q)"}
+	}
+	mc, err := common.NewModContext(p)
+	if err != nil {
+		return nil, err
+	}
+	fbA := mc.NewFixedBase(a, q.BitLen())
+	if pk.Y, err = fbA.ExpConstantTime(x); err != nil {
+		return nil, err
+	}
+	pk.tables = &keyTables{mc: mc, a: fbA, y: mc.NewFixedBase(pk.Y, q.BitLen())}
+	return &PrivateKey{PublicKey: *pk, X: x}, nil
+}
+
+// precomputed - таблицы ключа; строятся при первом вызове
+func (pk *PublicKey) precomputed() (*keyTables, error) {
+	tablesMu.Lock()
+	defer tablesMu.Unlock()
+	if pk.tables == nil {
+		mc, err := common.NewModContext(pk.P)
+		if err != nil {
+			return nil, err
+		}
+		bits := pk.Q.BitLen()
+		pk.tables = &keyTables{mc: mc, a: mc.NewFixedBase(pk.A, bits), y: mc.NewFixedBase(pk.Y, bits)}
+	}
+	return pk.tables, nil
+}
+
+// validateParams - A > 1 и A^Q = 1 mod P, то есть A порождает подгруппу порядка Q
+func (pk *PublicKey) validateParams() error {
+	if pk.P == nil || pk.Q == nil || pk.A == nil || pk.Y == nil {
+		return &common.ParameterError{Name: "gost key", Reason: "missing parameter"}
+	}
+	if pk.P.Bit(0) == 0 || pk.Q.Sign() <= 0 || pk.Q.Cmp(pk.P) >= 0 {
+		return &common.ParameterError{Name: "gost key", Reason: "P must be odd and Q must lie in (0, P)"}
+	}
+	if pk.A.Cmp(big.NewInt(1)) <= 0 || pk.A.Cmp(pk.P) >= 0 || new(big.Int).Exp(pk.A, pk.Q, pk.P).Cmp(big.NewInt(1)) != 0 {
+		return &common.ParameterError{Name: "gost key", Reason: "A is not of order Q modulo P"}
+	}
+	return nil
+}
+
+// Validate - проверка параметров и того, что Y лежит в подгруппе, порождённой A
+func (pk *PublicKey) Validate() error {
+	if err := pk.validateParams(); err != nil {
+		return err
+	}
+	if pk.Y.Cmp(big.NewInt(1)) <= 0 || pk.Y.Cmp(pk.P) >= 0 || new(big.Int).Exp(pk.Y, pk.Q, pk.P).Cmp(big.NewInt(1)) != 0 {
+		return &common.ParameterError{Name: "gost key", Reason: "Y is not in the subgroup of order Q"}
+	}
+	return nil
+}
+
+// Sign - подпись (r, s) сообщения: h = SHA-256(message) mod Q, s = (k*h + x*r) mod Q
+func (k *PrivateKey) Sign(message []byte) (r, s *big.Int, err error) {
+	t, err := k.precomputed()
+	if err != nil {
+		return nil, nil, err
+	}
+	return sign(t.a.ExpConstantTime, k.Q, k.X, hashMessage(message, k.Q))
+}
+
+// Verify - проверка подписи (r, s) под сообщением
+func (pk *PublicKey) Verify(message []byte, r, s *big.Int) bool {
+	t, err := pk.precomputed()
+	if err != nil {
+		return false
+	}
+	// u1 и u2 открыты: переменное время гребёнки допустимо
+	exp2 := func(u1, u2 *big.Int) *big.Int { return t.mc.Mul(t.a.Exp(u1), t.y.Exp(u2)) }
+	return verify(exp2, pk.Q, hashMessage(message, pk.Q), r, s)
+}
+
+// Equal - совпадение параметров и открытого ключа
+func (pk *PublicKey) Equal(other *PublicKey) bool {
+	return pk.P.Cmp(other.P) == 0 && pk.Q.Cmp(other.Q) == 0 && pk.A.Cmp(other.A) == 0 && pk.Y.Cmp(other.Y) == 0
+}
+
+// ExportPublicKey - параметры (P, Q, A) и Y в виде блока брони, как у подписи по потоку
+func (pk *PublicKey) ExportPublicKey() (*common.ArmorBlock, error) {
+	return common.PublicKeyBlock(algorithm, []string{"P", "Q", "A", "Y"}, []*big.Int{pk.P, pk.Q, pk.A, pk.Y})
+}
+
+// ExportPrivateKey - параметры, Y и X в виде блока брони PRIVATE KEY
+func (k *PrivateKey) ExportPrivateKey() (*common.ArmorBlock, error) {
+	return common.PrivateKeyBlock(algorithm, []string{"P", "Q", "A", "Y", "X"}, []*big.Int{k.P, k.Q, k.A, k.Y, k.X})
+}
+
+// ImportPublicKey - открытый ключ из блока ExportPublicKey с проверкой параметров
+func ImportPublicKey(block *common.ArmorBlock) (*PublicKey, error) {
+	numbers, err := common.KeyNumbers(block, common.ArmorPublicKey, algorithm, 4)
+	if err != nil {
+		return nil, err
+	}
+	pk := &PublicKey{P: numbers[0], Q: numbers[1], A: numbers[2], Y: numbers[3]}
+	if err = pk.Validate(); err != nil {
+		return nil, err
+	}
+	return pk, nil
+}
+
+// ImportPrivateKey - закрытый ключ из блока ExportPrivateKey; Y должен соответствовать X
+func ImportPrivateKey(block *common.ArmorBlock) (*PrivateKey, error) {
+	numbers, err := common.KeyNumbers(block, common.ArmorPrivateKey, algorithm, 5)
+	if err != nil {
+		return nil, err
+	}
+	k, err := NewPrivateKey(numbers[0], numbers[1], numbers[2], numbers[4])
+	if err != nil {
+		return nil, err
+	}
+	if k.Y.Cmp(numbers[3]) != 0 {
+		return nil, &common.ParameterError{Name: "gost key", Reason: "X does not match Y"}
+	}
+	return k, nil
+}
+
+// ReadPrivateKey - закрытый ключ из текстовой брони
+func ReadPrivateKey(r io.Reader) (*PrivateKey, error) {
+	block, err := common.DecodeArmor(r)
+	if err != nil {
+		return nil, err
+	}
+	return ImportPrivateKey(block)
+}
+
+// VerifyArmored - проверка подписи (r, s) из блока SIGNATURE открытым ключом из блока PUBLIC KEY
+func VerifyArmored(key *common.ArmorBlock, message, signature []byte) error {
+	pk, err := ImportPublicKey(key)
+	if err != nil {
+		return err
+	}
+	rs, err := common.ReadBigNumbers(bytes.NewReader(signature))
+	if err != nil {
+		return err
+	}
+	if len(rs) != 2 {
+		return fmt.Errorf("gost: %d numbers in signature: %w", len(rs), common.ErrArmorMalformed)
+	}
+	if !pk.Verify(message, rs[0], rs[1]) {
+		return common.ErrVerification
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package gost
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+)
+
+var (
+	testKeyOnce sync.Once
+	testKey     *PrivateKey
+	testKeyErr  error
+)
+
+// newTestKey - один ключ на все тесты пакета: параметры 1024/256 бит строятся долго
+func newTestKey(tb testing.TB) *PrivateKey {
+	tb.Helper()
+	testKeyOnce.Do(func() {
+		var p, q, a *big.Int
+		if p, q, a, testKeyErr = GenerateParams(); testKeyErr == nil {
+			testKey, testKeyErr = GenerateKey(p, q, a)
+		}
+	})
+	if testKeyErr != nil {
+		tb.Fatalf("GenerateKey() error = %v", testKeyErr)
+	}
+	return testKey
+}
+
+func TestSignVerify(t *testing.T) {
+	key := newTestKey(t)
+	message := []byte("бюллетень")
+	r, s, err := key.Sign(message)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	// Ключ без таблиц, как после разбора JSON
+	public := &PublicKey{P: key.P, Q: key.Q, A: key.A, Y: key.Y}
+	tests := []struct {
+		name    string
+		message []byte
+		r, s    *big.Int
+		want    bool
+	}{
+		{name: "верная подпись", message: message, r: r, s: s, want: true},
+		{name: "другое сообщение", message: []byte("другой бюллетень"), r: r, s: s, want: false},
+		{name: "изменённое s", message: message, r: r, s: new(big.Int).Add(s, big.NewInt(1)), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, pk := range []*PublicKey{&key.PublicKey, public} {
+				if got := pk.Verify(tt.message, tt.r, tt.s); got != tt.want {
+					t.Errorf("Verify() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkVerify - проверка подписи гребёнками A и Y, построенными один раз на ключ
+func BenchmarkVerify(b *testing.B) {
+	key := newTestKey(b)
+	message := []byte("бюллетень")
+	r, s, err := key.Sign(message)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !key.Verify(message, r, s) {
+			b.Fatal("Verify() = false")
+		}
+	}
+}
+
+// BenchmarkVerifyBigInt - та же проверка двумя big.Int.Exp
+func BenchmarkVerifyBigInt(b *testing.B) {
+	key := newTestKey(b)
+	message := []byte("бюллетень")
+	r, s, err := key.Sign(message)
+	if err != nil {
+		b.Fatal(err)
+	}
+	exp2 := func(u1, u2 *big.Int) *big.Int {
+		v := new(big.Int).Mul(new(big.Int).Exp(key.A, u1, key.P), new(big.Int).Exp(key.Y, u2, key.P))
+		return v.Mod(v, key.P)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !verify(exp2, key.Q, hashMessage(message, key.Q), r, s) {
+			b.Fatal("verify() = false")
+		}
+	}
+}
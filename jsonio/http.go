@@ -0,0 +1,47 @@
+package jsonio
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ErrorWriter - ответ с ошибкой msg и кодом status в формате конкретного API
+type ErrorWriter func(w http.ResponseWriter, status int, msg string)
+
+// WriteJSON - ответ с кодом status и телом v. Заголовок к этому моменту уже отправлен,
+// поэтому ошибку записи тела можно только записать в журнал.
+func WriteJSON(w http.ResponseWriter, status int, v any) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(v)
+}
+
+// Method - обработчик только для указанного метода; на остальные отвечает 405
+func Method(method string, writeError ErrorWriter, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		h(w, r)
+	}
+}
+
+// ReadJSON - разбор тела запроса не длиннее limit байт без неизвестных полей;
+// при ошибке ответ уже отправлен через writeError
+func ReadJSON(w http.ResponseWriter, r *http.Request, limit int64, v any, writeError ErrorWriter) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, limit))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+			return false
+		}
+		writeError(w, http.StatusBadRequest, "malformed request: "+err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package jsonio
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	writeError := func(w http.ResponseWriter, status int, msg string) {
+		WriteJSON(w, status, map[string]string{"error": msg})
+	}
+	h := Method(http.MethodPost, writeError, func(w http.ResponseWriter, r *http.Request) {
+		var req entry
+		if !ReadJSON(w, r, 16, &req, writeError) {
+			return
+		}
+		WriteJSON(w, http.StatusOK, req)
+	})
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "верный запрос", method: http.MethodPost, body: `{"n":7}`, wantStatus: http.StatusOK, wantBody: `{"n":7}`},
+		{name: "другой метод", method: http.MethodGet, wantStatus: http.StatusMethodNotAllowed, wantBody: "method not allowed"},
+		{name: "неизвестное поле", method: http.MethodPost, body: `{"m":7}`, wantStatus: http.StatusBadRequest, wantBody: "malformed request"},
+		{name: "слишком длинное тело", method: http.MethodPost, body: `{"n":1000000000000000}`, wantStatus: http.StatusRequestEntityTooLarge, wantBody: "too large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h(rec, httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("response %d %q, want %d with %q", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// Package jsonio - общие для lab5 и ecash журналы JSON Lines, в которые записи только
+// добавляются, и помощники HTTP-обработчиков с телами JSON.
+package jsonio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ReadLines - разбор целых строк r: каждая передаётся в decode с номером (с нуля) и
+// декодером, запрещающим неизвестные поля. Остаток без перевода строки - запись,
+// оборванная при сбое, - пропускается; size - длина целых строк.
+func ReadLines(r io.Reader, decode func(line int, dec *json.Decoder) error) (size int64, err error) {
+	br := bufio.NewReader(r)
+	for line := 0; ; line++ {
+		data, err := br.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return size, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err = decode(line, dec); err != nil {
+			return 0, err
+		}
+		size += int64(len(data))
+	}
+}
+
+// Log - файл JSON Lines только для добавления. Каждая строка сбрасывается на диск до
+// возврата из Append, поэтому при сбое может оборваться лишь последняя строка.
+type Log struct {
+	file *os.File
+	size int64 // длина файла до конца последней целой строки
+	mu   sync.Mutex
+}
+
+// OpenLog - журнал в файле path: новый создаётся, строки существующего разбираются
+// ReadLines, а оборванная последняя строка отрезается
+func OpenLog(path string, decode func(line int, dec *json.Decoder) error) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	l := &Log{file: f}
+	if l.size, err = ReadLines(f, decode); err == nil {
+		if err = l.rewind(); err == nil {
+			err = syncDir(path)
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Append - запись v строкой JSON со сбросом на диск
+func (l *Log) Append(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n, err := l.file.Write(append(data, '\n'))
+	if err == nil {
+		err = l.file.Sync()
+	}
+	if err != nil {
+		// Недописанная строка отрезается, чтобы следующая запись не легла после мусора
+		_ = l.rewind()
+		return err
+	}
+	l.size += int64(n)
+	return nil
+}
+
+// rewind - обрезка файла до целых строк и переход в его конец
+func (l *Log) rewind() error {
+	if err := l.file.Truncate(l.size); err != nil {
+		return err
+	}
+	if _, err := l.file.Seek(l.size, io.SeekStart); err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// Close - закрытие файла; после него Append возвращает ошибку
+func (l *Log) Close() error {
+	return l.file.Close()
+}
+
+// syncDir - сброс каталога, чтобы созданный файл пережил сбой
+func syncDir(path string) error {
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
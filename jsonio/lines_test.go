@@ -0,0 +1,105 @@
+package jsonio
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type entry struct {
+	N int `json:"n"`
+}
+
+// collect - decode, собирающий записи в *got
+func collect(got *[]entry) func(int, *json.Decoder) error {
+	return func(line int, dec *json.Decoder) error {
+		var e entry
+		if err := dec.Decode(&e); err != nil {
+			return err
+		}
+		*got = append(*got, e)
+		return nil
+	}
+}
+
+func TestReadLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		want     []entry
+		wantSize int64
+		wantErr  bool
+	}{
+		{name: "пустой ввод", input: "", wantSize: 0},
+		{name: "целые строки", input: "{\"n\":1}\n{\"n\":2}\n", want: []entry{{1}, {2}}, wantSize: 16},
+		{name: "оборванный хвост", input: "{\"n\":1}\n{\"n\":", want: []entry{{1}}, wantSize: 8},
+		{name: "неизвестное поле", input: "{\"n\":1,\"x\":2}\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []entry
+			size, err := ReadLines(strings.NewReader(tt.input), collect(&got))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadLines() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if size != tt.wantSize || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadLines() = %v, %d, want %v, %d", got, size, tt.want, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestOpenLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+	var got []entry
+	l, err := OpenLog(path, collect(&got))
+	if err != nil {
+		t.Fatalf("OpenLog() error = %v", err)
+	}
+	for n := 1; n <= 2; n++ {
+		if err = l.Append(entry{n}); err != nil {
+			t.Fatalf("Append(%d) error = %v", n, err)
+		}
+	}
+	l.Close()
+
+	// Сбой посреди записи: оборванная строка отрезается при открытии
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("{\"n\":")
+	f.Close()
+	if l, err = OpenLog(path, collect(&got)); err != nil {
+		t.Fatalf("OpenLog() after a crash error = %v", err)
+	}
+	if err = l.Append(entry{3}); err != nil {
+		t.Fatalf("Append(3) error = %v", err)
+	}
+	l.Close()
+	if err = l.Append(entry{4}); err == nil {
+		t.Error("Append() after Close() error = nil")
+	}
+
+	got = nil
+	if l, err = OpenLog(path, collect(&got)); err != nil {
+		t.Fatalf("OpenLog() error = %v", err)
+	}
+	l.Close()
+	if want := []entry{{1}, {2}, {3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OpenLog() read %v, want %v", got, want)
+	}
+
+	errBad := errors.New("bad line")
+	_, err = OpenLog(path, func(int, *json.Decoder) error { return errBad })
+	if !errors.Is(err, errBad) || !strings.Contains(err.Error(), path) {
+		t.Errorf("OpenLog() error = %v, want %v with the path", err, errBad)
+	}
+}
@@ -1,27 +1,91 @@
 package main
 
 import (
+	"context"
+	crand "crypto/rand"
+	"errors"
+	"flag"
 	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/dlog"
 	"log"
+	"math/big"
+	"time"
 )
 
 func main() {
+	bits := flag.Int("bits", 0, "длина модуля в битах для сравнения методов (40-64); 0 - одна задача подходящим методом")
+	timeout := flag.Duration("timeout", time.Minute, "ограничение времени на каждый метод при сравнении")
+	flag.Parse()
+	if *bits != 0 {
+		if err := compare(*bits, *timeout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	minV, maxV := int64(1_000_000), int64(1_000_000_00)
 	p := common.GenPrime(minV, maxV) //common.Seed().Int63n(maxV-minV) + minV
 	a := common.Seed().Int63n(maxV-minV) + minV
 	x := common.Seed().Int63n(p-2-1) + 1 // 1, p-2
 	y := common.ModularExponentiation(a, x, p)
 	log.Println(y)
-	var xVzlom int64
-	var err error
-	for {
-		xVzlom, err = common.GiantBabyStep(a, p, y)
-		if err != nil {
-			log.Fatal(err)
+	pr := dlog.Problem{G: new(big.Int).Mod(big.NewInt(a), big.NewInt(p)), H: big.NewInt(y), P: big.NewInt(p)}
+	solver := dlog.Best(pr)
+	xVzlom, err := solver.Solve(context.Background(), pr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// a может не быть первообразным корнем: тогда подходит любой x' = x mod ord(a),
+	// поэтому проверяем a^x' = y, а не совпадение с x
+	log.Println(solver.Name(), xVzlom, new(big.Int).Exp(pr.G, xVzlom, pr.P).Cmp(pr.H) == 0)
+}
+
+// compare - одна задача на случайном простом модуле длиной bits бит, решаемая
+// исчислением индексов и шагом младенца-великана, с замером времени каждого метода
+func compare(bits int, timeout time.Duration) error {
+	if bits < 8 || bits > 64 {
+		return &common.ParameterError{Name: "bits", Reason: "expected 8..64"}
+	}
+	p, err := crand.Prime(crand.Reader, bits)
+	if err != nil {
+		return err
+	}
+	n := new(big.Int).Sub(p, big.NewInt(1))
+	x, err := crand.Int(crand.Reader, n)
+	if err != nil {
+		return err
+	}
+	g := big.NewInt(3)
+	pr := dlog.Problem{G: g, H: new(big.Int).Exp(g, x, p), P: p}
+	log.Printf("p = %s (%d бит), g = %s, y = %s", p, p.BitLen(), pr.G, pr.H)
+
+	// Базовая линия сравнения - шаг младенца-великана: dlog.BSGS на big.Int работает
+	// на всём диапазоне 8..64 бит, int64-версия common.GiantBabyStep - только до 31 бита
+	baseline := &dlog.BSGS{}
+	solvers := []dlog.Solver{&dlog.IndexCalculus{}, baseline}
+	if bits <= 31 {
+		solvers = append(solvers, dlog.BabyGiant{})
+	} else {
+		log.Printf("%-34s пропущен: int64-версия ограничена модулями до 31 бита, базовая линия - %s", dlog.BabyGiant{}.Name(), baseline.Name())
+	}
+	for _, solver := range solvers {
+		name := solver.Name()
+		if solver == dlog.Solver(baseline) {
+			name += " [база]"
 		}
-		if xVzlom == x {
-			break
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		start := time.Now()
+		res, err := solver.Solve(ctx, pr)
+		elapsed := time.Since(start)
+		cancel()
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			log.Printf("%-34s не уложился в %s", name, timeout)
+		case err != nil:
+			log.Printf("%-34s ошибка: %v (%s)", name, err, elapsed)
+		default:
+			log.Printf("%-34s x = %s, %s", name, res, elapsed)
 		}
 	}
-	log.Println(xVzlom == x)
+	return nil
 }
@@ -0,0 +1,113 @@
+// Команда audit - независимая проверка журнала бюллетеней lab5: цепочка хэшей,
+// слепая подпись сервера под каждым бюллетенем и пересчёт итогов референдума или выборов
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/lab5/election"
+	"github.com/Raimguzhinov/protect-information/lab5/ledger"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+func main() {
+	ledgerPath := flag.String("ledger", "", "файл журнала бюллетеней")
+	keyPath := flag.String("key", "", "открытый ключ сервера (<файл ключей>.pub); пусто - ключ из заголовка журнала")
+	electionsPath := flag.String("elections", "", "файл определений выборов, по которому пересчитывается журнал выборов")
+	electionID := flag.String("election", "", "идентификатор выборов журнала; пусто - журнал референдума")
+	flag.Parse()
+	if *ledgerPath == "" {
+		log.Fatal("Не задан файл журнала (-ledger)")
+	}
+	var def *election.Election
+	if *electionID != "" {
+		if *electionsPath == "" {
+			log.Fatal("Для журнала выборов нужен файл определений (-elections)")
+		}
+		elections, err := election.Load(*electionsPath)
+		if err != nil {
+			log.Fatalf("Ошибка чтения определений выборов: %v", err)
+		}
+		for i := range elections {
+			if elections[i].ID == *electionID {
+				def = &elections[i]
+			}
+		}
+		if def == nil {
+			log.Fatalf("В %s нет выборов %s", *electionsPath, *electionID)
+		}
+	}
+
+	var key *ledger.PublicKey
+	if *keyPath != "" {
+		f, err := os.Open(*keyPath)
+		if err != nil {
+			log.Fatalf("Ошибка открытия ключа: %v", err)
+		}
+		block, err := common.DecodeArmor(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("Ошибка чтения ключа: %v", err)
+		}
+		k, err := ledger.ImportPublicKey(block)
+		if err != nil {
+			log.Fatalf("Ошибка чтения ключа: %v", err)
+		}
+		if def != nil {
+			// Модуль у выборов общий с ключом сервера, показатель выводится из идентификатора
+			k.D = election.Exponent(def.ID)
+		}
+		key = &k
+	}
+
+	f, err := os.Open(*ledgerPath)
+	if err != nil {
+		log.Fatalf("Ошибка открытия журнала: %v", err)
+	}
+	defer f.Close()
+	var report *ledger.Report
+	if def != nil {
+		report, err = ledger.AuditElection(f, key, def)
+	} else {
+		report, err = ledger.Audit(f, key)
+	}
+	if err != nil {
+		log.Fatalf("Журнал не прошёл проверку: %v", err)
+	}
+
+	if key == nil {
+		fmt.Println("Внимание: ключ сервера взят из заголовка журнала; сверьте его с опубликованным (-key)")
+	}
+	fmt.Printf("Журнал создан: %s\n", report.Header.Created.Format("2006-01-02 15:04:05 MST"))
+	fmt.Printf("Записей: %d, цепочка хэшей и подписи сервера верны\n", report.Records)
+	fmt.Printf("Хэш последней записи: %s\n", report.Head)
+	if report.Truncated {
+		fmt.Println("В конце файла оборванная запись: сервер отбросит её при следующем запуске")
+	}
+	if report.Result != nil {
+		fmt.Printf("Итоги выборов %s по журналу:\n", report.Result.Election)
+		for _, q := range report.Result.Questions {
+			fmt.Printf("%s [%s], воздержались: %d, победитель: %s\n", q.ID, q.Method, q.Blank, strings.Join(q.Winners, ", "))
+			printVotes(q.Votes, "  ")
+		}
+		return
+	}
+	fmt.Println("Итоги по журналу:")
+	printVotes(report.Votes, "")
+}
+
+// printVotes - голоса по названиям в алфавитном порядке
+func printVotes(votes map[string]int, indent string) {
+	names := make([]string, 0, len(votes))
+	for name := range votes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s%s: %d\n", indent, name, votes[name])
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/blindsig"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/lab5/voteapi"
+	"io"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotEligible - избирателя нет в списке
+	ErrNotEligible = errors.New("voter is not on the roll")
+	// ErrAlreadySigned - избиратель уже получил подпись под другим бюллетенем
+	ErrAlreadySigned = errors.New("blind signature already issued to this voter")
+)
+
+// AuditOutcome - решение сервера по запросу слепой подписи
+type AuditOutcome string
+
+const (
+	AuditSigned          AuditOutcome = "signed"          // подпись выдана
+	AuditDuplicate       AuditOutcome = "duplicate"       // повтор того же запроса: выдана прежняя подпись
+	AuditAlreadySigned   AuditOutcome = "already-signed"  // второй бюллетень того же избирателя
+	AuditNotEligible     AuditOutcome = "not-eligible"    // имени нет в списке
+	AuditBadSignature    AuditOutcome = "bad-signature"   // запрос подписан не ключом избирателя
+	AuditMalformed       AuditOutcome = "malformed"       // слепой хэш вне [1, n)
+	AuditUnauthenticated AuditOutcome = "unauthenticated" // запрос без подписи при списке избирателей
+)
+
+// AuditEntry - строка журнала аудита. Слепой хэш записывается только отпечатком
+// SHA-256: этого достаточно, чтобы сопоставить повторы, и журнал не растёт от чисел по 2048 бит.
+// Election пуст для запросов подписи бюллетеня референдума.
+type AuditEntry struct {
+	Time     time.Time    `json:"time"`
+	Election string       `json:"election,omitempty"`
+	Username string       `json:"username"`
+	Blinded  string       `json:"blinded,omitempty"`
+	Outcome  AuditOutcome `json:"outcome"`
+}
+
+// AuditLog - журнал решений по запросам подписи в формате JSON Lines
+type AuditLog struct {
+	enc *json.Encoder
+	now func() time.Time
+	mu  sync.Mutex
+}
+
+// NewAuditLog - журнал, записываемый в w
+func NewAuditLog(w io.Writer) *AuditLog {
+	return &AuditLog{enc: json.NewEncoder(w), now: time.Now}
+}
+
+// Record - запись решения; журнал nil ничего не записывает
+func (l *AuditLog) Record(username string, blinded *big.Int, outcome AuditOutcome) {
+	l.record("", username, blinded, outcome)
+}
+
+// record - запись решения по запросу подписи для выборов electionID
+func (l *AuditLog) record(electionID, username string, blinded *big.Int, outcome AuditOutcome) {
+	if l == nil {
+		return
+	}
+	entry := AuditEntry{Election: electionID, Username: username, Outcome: outcome}
+	if blinded != nil {
+		sum := sha256.Sum256(blinded.Bytes())
+		entry.Blinded = hex.EncodeToString(sum[:])
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry.Time = l.now().UTC()
+	if err := l.enc.Encode(entry); err != nil {
+		log.Printf("Сервер: ошибка записи журнала аудита: %v", err)
+	}
+}
+
+// issuedSignature - выданная избирателю подпись; хранится, чтобы повтор запроса
+// (например, после обрыва соединения) получил тот же ответ
+type issuedSignature struct {
+	blinded   *big.Int
+	signature *big.Int
+}
+
+// EnableAuthentication - подпись выдаётся только избирателям из roll по запросам,
+// подписанным их ключами; решения записываются в audit (nil - без журнала).
+// Вызывается до начала приёма запросов.
+func (s *Server) EnableAuthentication(roll *VoterRoll, audit *AuditLog) {
+	s.roll = roll
+	s.audit = audit
+	s.issued = make(map[string]issuedSignature)
+}
+
+// GetAuthenticatedBlindSignature - слепая подпись по запросу, подписанному ключом
+// избирателя (sigR, sigS) над voteapi.SignRequestMessage("", username, blindedHash).
+// Допуск, подпись запроса и повторы проверяются до вычисления подписи сервера.
+func (s *Server) GetAuthenticatedBlindSignature(username string, blindedHash, sigR, sigS *big.Int) (*big.Int, error) {
+	if s.roll == nil {
+		return nil, &common.ParameterError{Name: "server", Reason: "voter roll is not loaded"}
+	}
+	if err := s.authenticate("", username, blindedHash, sigR, sigS); err != nil {
+		return nil, err
+	}
+	return s.issue("", s.issued, s.key, username, blindedHash)
+}
+
+// authenticate - избиратель есть в списке, слепой хэш лежит в [1, n),
+// запрос для выборов electionID подписан ключом избирателя
+func (s *Server) authenticate(electionID, username string, blindedHash, sigR, sigS *big.Int) error {
+	key, ok := s.roll.Key(username)
+	if !ok {
+		s.audit.record(electionID, username, blindedHash, AuditNotEligible)
+		return fmt.Errorf("%q: %w", username, ErrNotEligible)
+	}
+	if blindedHash == nil || blindedHash.Sign() <= 0 || blindedHash.Cmp(s.key.N) >= 0 {
+		s.audit.record(electionID, username, nil, AuditMalformed)
+		return &common.ParameterError{Name: "blinded", Reason: "blinded hash is not in [1, n)"}
+	}
+	if !key.Verify(voteapi.SignRequestMessage(electionID, username, blindedHash), sigR, sigS) {
+		s.audit.record(electionID, username, blindedHash, AuditBadSignature)
+		return fmt.Errorf("request of %q: %w", username, common.ErrVerification)
+	}
+	return nil
+}
+
+// issue - подпись blindedHash ключом key, не более одной на избирателя в issued
+// (выданные подписи референдума или выборов electionID)
+func (s *Server) issue(electionID string, issued map[string]issuedSignature, key *blindsig.PrivateKey, username string, blindedHash *big.Int) (*big.Int, error) {
+	if signature, err := s.issuedTo(electionID, issued, username, blindedHash); signature != nil || err != nil {
+		return signature, err
+	}
+	// Подпись считается вне блокировки; одновременный запрос того же избирателя
+	// может успеть раньше, поэтому выдача ещё раз проверяется под блокировкой
+	signature, err := key.BlindSign(blindedHash)
+	if err != nil {
+		s.audit.record(electionID, username, blindedHash, AuditMalformed)
+		return nil, err
+	}
+	s.mu.Lock()
+	prev, seen := issued[username]
+	if !seen {
+		issued[username] = issuedSignature{blinded: blindedHash, signature: signature}
+	}
+	s.mu.Unlock()
+	if seen {
+		return s.repeated(electionID, username, blindedHash, prev)
+	}
+	s.audit.record(electionID, username, blindedHash, AuditSigned)
+	return signature, nil
+}
+
+// issuedTo - ответ на запрос избирателя, уже получившего подпись (см. repeated);
+// (nil, nil), если подпись ещё не выдавалась
+func (s *Server) issuedTo(electionID string, issued map[string]issuedSignature, username string, blindedHash *big.Int) (*big.Int, error) {
+	s.mu.Lock()
+	prev, seen := issued[username]
+	s.mu.Unlock()
+	if !seen {
+		return nil, nil
+	}
+	return s.repeated(electionID, username, blindedHash, prev)
+}
+
+// repeated - прежняя подпись при повторе того же запроса, ErrAlreadySigned при другом слепом хэше
+func (s *Server) repeated(electionID, username string, blindedHash *big.Int, prev issuedSignature) (*big.Int, error) {
+	if prev.blinded.Cmp(blindedHash) == 0 {
+		s.audit.record(electionID, username, blindedHash, AuditDuplicate)
+		return prev.signature, nil
+	}
+	s.audit.record(electionID, username, blindedHash, AuditAlreadySigned)
+	return nil, fmt.Errorf("%q: %w", username, ErrAlreadySigned)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/gost"
+	"github.com/Raimguzhinov/protect-information/lab5/voteapi"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var (
+	testRollOnce sync.Once
+	testRoll     *VoterRoll
+	testKeys     map[string]*gost.PrivateKey
+	testRollErr  error
+)
+
+// newTestRoll - список из alice, bob и carol; параметры ГОСТ генерируются один раз на пакет
+func newTestRoll(t *testing.T) (*VoterRoll, map[string]*gost.PrivateKey) {
+	t.Helper()
+	testRollOnce.Do(func() {
+		testRoll, testKeys, testRollErr = EnrollVoters([]string{"alice", "bob", "carol"})
+	})
+	if testRollErr != nil {
+		t.Fatalf("EnrollVoters() error = %v", testRollErr)
+	}
+	return testRoll, testKeys
+}
+
+func TestVoterRoll(t *testing.T) {
+	roll, keys := newTestRoll(t)
+	dir := t.TempDir()
+	for _, name := range []string{"roll.csv", "roll.json"} {
+		path := filepath.Join(dir, name)
+		if err := roll.Save(path); err != nil {
+			t.Fatalf("Save(%s) error = %v", name, err)
+		}
+		got, err := LoadRoll(path)
+		if err != nil {
+			t.Fatalf("LoadRoll(%s) error = %v", name, err)
+		}
+		if got.Len() != roll.Len() {
+			t.Errorf("LoadRoll(%s).Len() = %d, want %d", name, got.Len(), roll.Len())
+		}
+		for username, key := range keys {
+			if pk, ok := got.Key(username); !ok || !pk.Equal(&key.PublicKey) {
+				t.Errorf("LoadRoll(%s).Key(%s) = %v, %v, want the enrolled key", name, username, pk, ok)
+			}
+		}
+	}
+
+	var csvRoll bytes.Buffer
+	if err := roll.WriteCSV(&csvRoll); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(csvRoll.String()), "\n")
+	alice := keys["alice"]
+	badY := strings.Join([]string{"mallory", alice.P.String(), alice.Q.String(), alice.A.String(), "2"}, ",")
+	tests := []struct {
+		name string
+		read func(string) (*VoterRoll, error)
+		data string
+	}{
+		{name: "неверный заголовок CSV", read: readRollCSV, data: "name,p,q,a,y\n" + lines[1]},
+		{name: "повтор имени", read: readRollCSV, data: lines[0] + "\n" + lines[1] + "\n" + lines[1]},
+		{name: "не число", read: readRollCSV, data: lines[0] + "\nmallory,1,2,3,x"},
+		{name: "ключ вне подгруппы", read: readRollCSV, data: lines[0] + "\n" + badY},
+		{name: "пустое имя", read: readRollCSV, data: lines[0] + "\n" + strings.TrimPrefix(lines[1], "alice")},
+		{name: "не хватает столбца", read: readRollCSV, data: lines[0] + "\nmallory,1,2,3"},
+		{name: "неизвестное поле JSON", read: readRollJSON, data: `[{"username": "mallory", "key": 1}]`},
+		{name: "нет параметров JSON", read: readRollJSON, data: `[{"username": "mallory", "y": 2}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.read(tt.data); err == nil {
+				t.Error("error = nil, want a malformed roll error")
+			}
+		})
+	}
+	if _, err := LoadRoll(filepath.Join(dir, "roll.txt")); !errors.Is(err, common.ErrInvalidParameters) {
+		t.Errorf("LoadRoll(roll.txt) error = %v, want %v", err, common.ErrInvalidParameters)
+	}
+}
+
+func readRollCSV(data string) (*VoterRoll, error)  { return ReadRollCSV(strings.NewReader(data)) }
+func readRollJSON(data string) (*VoterRoll, error) { return ReadRollJSON(strings.NewReader(data)) }
+
+func TestAuthenticatedBlindSignature(t *testing.T) {
+	roll, keys := newTestRoll(t)
+	server := NewServer()
+	var audit bytes.Buffer
+	server.EnableAuthentication(roll, NewAuditLog(&audit))
+
+	// request - слепой хэш и подпись запроса ключом signer от имени username
+	request := func(username string, signer *gost.PrivateKey, blinded, signed *big.Int) (*big.Int, error) {
+		r, s, err := signer.Sign(voteapi.SignRequestMessage("", username, signed))
+		if err != nil {
+			t.Fatalf("Sign() error = %v", err)
+		}
+		return server.GetAuthenticatedBlindSignature(username, blinded, r, s)
+	}
+	first, second := big.NewInt(123456789), big.NewInt(987654321)
+	mallory, err := gost.GenerateKey(keys["alice"].P, keys["alice"].Q, keys["alice"].A)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		run     func() (*big.Int, error)
+		wantErr error
+		outcome AuditOutcome
+	}{
+		{
+			name:    "избиратель из списка",
+			run:     func() (*big.Int, error) { return request("alice", keys["alice"], first, first) },
+			outcome: AuditSigned,
+		},
+		{
+			name:    "повтор того же запроса",
+			run:     func() (*big.Int, error) { return request("alice", keys["alice"], first, first) },
+			outcome: AuditDuplicate,
+		},
+		{
+			name:    "второй бюллетень",
+			run:     func() (*big.Int, error) { return request("alice", keys["alice"], second, second) },
+			wantErr: ErrAlreadySigned,
+			outcome: AuditAlreadySigned,
+		},
+		{
+			name:    "нет в списке",
+			run:     func() (*big.Int, error) { return request("mallory", mallory, first, first) },
+			wantErr: ErrNotEligible,
+			outcome: AuditNotEligible,
+		},
+		{
+			name:    "чужой ключ",
+			run:     func() (*big.Int, error) { return request("bob", mallory, first, first) },
+			wantErr: common.ErrVerification,
+			outcome: AuditBadSignature,
+		},
+		{
+			name:    "подпись под другим хэшем",
+			run:     func() (*big.Int, error) { return request("bob", keys["bob"], second, first) },
+			wantErr: common.ErrVerification,
+			outcome: AuditBadSignature,
+		},
+		{
+			name:    "подпись от имени другого избирателя",
+			run:     func() (*big.Int, error) { return request("bob", keys["carol"], first, first) },
+			wantErr: common.ErrVerification,
+			outcome: AuditBadSignature,
+		},
+		{
+			name:    "хэш вне [1, n)",
+			run:     func() (*big.Int, error) { return request("bob", keys["bob"], server.key.N, server.key.N) },
+			wantErr: common.ErrInvalidParameters,
+			outcome: AuditMalformed,
+		},
+		{
+			name:    "после отказов подпись выдаётся",
+			run:     func() (*big.Int, error) { return request("bob", keys["bob"], second, second) },
+			outcome: AuditSigned,
+		},
+	}
+	var want []AuditOutcome
+	var signatures []*big.Int
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signature, err := tt.run()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			signatures = append(signatures, signature)
+			if blinded := new(big.Int).Exp(signature, server.key.D, server.key.N); blinded.Cmp(first) != 0 && blinded.Cmp(second) != 0 {
+				t.Errorf("signature^d = %s, want a signed blinded hash", blinded)
+			}
+		})
+		want = append(want, tt.outcome)
+	}
+	if len(signatures) != 3 || signatures[0].Cmp(signatures[1]) != 0 {
+		t.Errorf("repeated request got a different signature")
+	}
+	// Запрос без подписи избирателя при списке не принимается
+	if signature := server.GetBlindSignature("carol", first); signature != nil {
+		t.Error("GetBlindSignature() signed an unauthenticated request")
+	}
+	want = append(want, AuditUnauthenticated)
+
+	var got []AuditOutcome
+	scanner := bufio.NewScanner(&audit)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("audit line %q: %v", scanner.Text(), err)
+		}
+		if entry.Time.IsZero() || entry.Username == "" {
+			t.Errorf("audit entry %+v has no time or username", entry)
+		}
+		got = append(got, entry.Outcome)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("audit outcomes = %v, want %v", got, want)
+	}
+}
+
+func TestPrivateKeyArmor(t *testing.T) {
+	_, keys := newTestRoll(t)
+	key := keys["alice"]
+	block, err := key.ExportPrivateKey()
+	if err != nil {
+		t.Fatalf("ExportPrivateKey() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "alice.key")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = common.EncodeArmor(f, block); err != nil {
+		t.Fatalf("EncodeArmor() error = %v", err)
+	}
+	f.Close()
+	f, err = os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	got, err := gost.ReadPrivateKey(f)
+	if err != nil {
+		t.Fatalf("ReadPrivateKey() error = %v", err)
+	}
+	if got.X.Cmp(key.X) != 0 || !got.Equal(&key.PublicKey) {
+		t.Error("ReadPrivateKey() returned a different key")
+	}
+	r, s, err := got.Sign([]byte("message"))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if !key.Verify([]byte("message"), r, s) || key.Verify([]byte("other"), r, s) {
+		t.Error("Verify() does not match the signed message")
+	}
+
+	// Подмена X делает ключ несогласованным с Y
+	forged := *key
+	forged.X = new(big.Int).Add(key.X, big.NewInt(1))
+	if block, err = forged.ExportPrivateKey(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = gost.ImportPrivateKey(block); !errors.Is(err, common.ErrInvalidParameters) {
+		t.Errorf("ImportPrivateKey() of a mismatched key error = %v, want %v", err, common.ErrInvalidParameters)
+	}
+	pub, err := key.ExportPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = gost.ImportPrivateKey(pub); !errors.Is(err, common.ErrArmorMalformed) {
+		t.Errorf("ImportPrivateKey() of a public key error = %v, want %v", err, common.ErrArmorMalformed)
+	}
+}
@@ -0,0 +1,166 @@
+package election
+
+import (
+	"crypto/rand"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+	"sort"
+)
+
+// Формат бюллетеня в байтах числа m:
+//
+//	версия (1) | случайное заполнение (16) | длина id | id |
+//	число вопросов | для каждого вопроса: число ответов k, k номеров кандидатов
+//
+// Ненулевой первый байт сохраняет длину при переводе в big.Int, заполнение делает
+// хэши одинаковых по содержанию бюллетеней разными.
+const (
+	ballotVersion = 1
+	nonceSize     = 16
+)
+
+// Ballot - ответы избирателя: для каждого вопроса по порядку номера кандидатов.
+// Для Ranked порядок - порядок предпочтения; пустой ответ - воздержался по вопросу.
+type Ballot struct {
+	Election string
+	Answers  [][]int
+}
+
+// Check - бюллетень соответствует определению выборов
+func (e *Election) Check(b *Ballot) error {
+	if b.Election != e.ID {
+		return &common.ParameterError{Name: "ballot", Reason: fmt.Sprintf("ballot for election %q, not %q", b.Election, e.ID)}
+	}
+	if len(b.Answers) != len(e.Questions) {
+		return &common.ParameterError{Name: "ballot", Reason: fmt.Sprintf("%d answers for %d questions", len(b.Answers), len(e.Questions))}
+	}
+	for i, answer := range b.Answers {
+		q := &e.Questions[i]
+		limit := q.MaxChoices
+		if q.Method == Plurality {
+			limit = 1
+		}
+		if limit > 0 && len(answer) > limit {
+			return &common.ParameterError{Name: "ballot", Reason: fmt.Sprintf("question %s: more than %d choices", q.ID, limit)}
+		}
+		seen := make(map[int]bool, len(answer))
+		for _, c := range answer {
+			if c < 0 || c >= len(q.Candidates) || seen[c] {
+				return &common.ParameterError{Name: "ballot", Reason: fmt.Sprintf("question %s: invalid or repeated candidate %d", q.ID, c)}
+			}
+			seen[c] = true
+		}
+	}
+	return nil
+}
+
+// NewBallot - бюллетень по названиям кандидатов: answers[id вопроса] - выбранные
+// кандидаты (для Ranked - по порядку предпочтения); вопрос без ответа - воздержался
+func (e *Election) NewBallot(answers map[string][]string) (*Ballot, error) {
+	b := &Ballot{Election: e.ID, Answers: make([][]int, len(e.Questions))}
+	used := 0
+	for i := range e.Questions {
+		q := &e.Questions[i]
+		names, ok := answers[q.ID]
+		if !ok {
+			continue
+		}
+		used++
+		for _, name := range names {
+			c := q.candidate(name)
+			if c < 0 {
+				return nil, &common.ParameterError{Name: "ballot", Reason: fmt.Sprintf("question %s has no candidate %q", q.ID, name)}
+			}
+			b.Answers[i] = append(b.Answers[i], c)
+		}
+		if q.Method == Approval {
+			sort.Ints(b.Answers[i])
+		}
+	}
+	if used != len(answers) {
+		return nil, &common.ParameterError{Name: "ballot", Reason: "answer to an unknown question"}
+	}
+	return b, e.Check(b)
+}
+
+// candidate - номер кандидата по названию; -1, если такого нет
+func (q *Question) candidate(name string) int {
+	for i, c := range q.Candidates {
+		if c == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Encode - сообщение m для слепой подписи со свежим случайным заполнением
+func (e *Election) Encode(b *Ballot) (*big.Int, error) {
+	if err := e.Check(b); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 1+nonceSize, 64)
+	buf[0] = ballotVersion
+	if _, err := rand.Read(buf[1:]); err != nil {
+		return nil, err
+	}
+	buf = append(buf, byte(len(b.Election)))
+	buf = append(buf, b.Election...)
+	buf = append(buf, byte(len(b.Answers)))
+	for _, answer := range b.Answers {
+		buf = append(buf, byte(len(answer)))
+		for _, c := range answer {
+			buf = append(buf, byte(c))
+		}
+	}
+	return new(big.Int).SetBytes(buf), nil
+}
+
+// Decode - бюллетень из сообщения m; лишние или недостающие байты - ошибка
+func (e *Election) Decode(m *big.Int) (*Ballot, error) {
+	data := m.Bytes()
+	malformed := &common.ParameterError{Name: "ballot", Reason: "malformed encoding"}
+	if len(data) < 1+nonceSize+1 || data[0] != ballotVersion {
+		return nil, malformed
+	}
+	data = data[1+nonceSize:]
+	// next - очередные n байт
+	next := func(n int) ([]byte, bool) {
+		if n > len(data) {
+			return nil, false
+		}
+		chunk := data[:n]
+		data = data[n:]
+		return chunk, true
+	}
+	idLen, _ := next(1)
+	id, ok := next(int(idLen[0]))
+	if !ok {
+		return nil, malformed
+	}
+	count, ok := next(1)
+	if !ok {
+		return nil, malformed
+	}
+	b := &Ballot{Election: string(id), Answers: make([][]int, count[0])}
+	for i := range b.Answers {
+		k, ok := next(1)
+		if !ok {
+			return nil, malformed
+		}
+		choices, ok := next(int(k[0]))
+		if !ok {
+			return nil, malformed
+		}
+		for _, c := range choices {
+			b.Answers[i] = append(b.Answers[i], int(c))
+		}
+	}
+	if len(data) != 0 {
+		return nil, malformed
+	}
+	if err := e.Check(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,179 @@
+// Package election - определения выборов lab5: несколько вопросов со своими списками
+// кандидатов, способ подсчёта для каждого вопроса (относительное большинство, одобрение,
+// рейтинговое голосование с мгновенным вторым туром) и время проведения. Определения
+// читаются из файла JSON; здесь же кодирование бюллетеней в число и подсчёт итогов.
+package election
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/blindsig"
+	"github.com/Raimguzhinov/protect-information/common"
+	"io"
+	"math/big"
+	"os"
+	"time"
+)
+
+// Method - способ подсчёта голосов по вопросу
+type Method string
+
+const (
+	Plurality Method = "plurality" // один кандидат, побеждает набравший больше всех
+	Approval  Method = "approval"  // любое число одобренных кандидатов
+	Ranked    Method = "ranked"    // кандидаты по порядку предпочтения, мгновенный второй тур
+)
+
+// Ограничения определения: номера кандидатов и вопросов кодируются одним байтом
+const (
+	maxCandidates = 255
+	maxQuestions  = 255
+	maxIDLength   = 64
+)
+
+// ErrNotOpen - выборы ещё не начались или уже закончились
+var ErrNotOpen = errors.New("election: not open")
+
+// Question - вопрос бюллетеня. MaxChoices ограничивает число одобренных
+// (Approval) или упорядоченных (Ranked) кандидатов; 0 - без ограничения.
+type Question struct {
+	ID         string   `json:"id"`
+	Text       string   `json:"text"`
+	Method     Method   `json:"method"`
+	Candidates []string `json:"candidates"`
+	MaxChoices int      `json:"max_choices,omitempty"`
+}
+
+// Election - выборы: бюллетени принимаются с Opens до Closes; нулевое время - без ограничения
+type Election struct {
+	ID        string     `json:"id"`
+	Title     string     `json:"title"`
+	Opens     time.Time  `json:"opens"`
+	Closes    time.Time  `json:"closes"`
+	Questions []Question `json:"questions"`
+}
+
+// File - содержимое файла определений: несколько выборов одновременно
+type File struct {
+	Elections []Election `json:"elections"`
+}
+
+// validID - идентификатор из строчных латинских букв, цифр, '-' и '_':
+// он входит в имена файлов журналов и в адреса запросов
+func validID(id string) bool {
+	if id == "" || len(id) > maxIDLength {
+		return false
+	}
+	for _, r := range id {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate - проверка определения вопроса
+func (q *Question) Validate() error {
+	if !validID(q.ID) {
+		return &common.ParameterError{Name: "question", Reason: fmt.Sprintf("invalid id %q", q.ID)}
+	}
+	switch q.Method {
+	case Plurality, Approval, Ranked:
+	default:
+		return &common.ParameterError{Name: "question " + q.ID, Reason: fmt.Sprintf("unknown method %q", q.Method)}
+	}
+	if len(q.Candidates) == 0 || len(q.Candidates) > maxCandidates {
+		return &common.ParameterError{Name: "question " + q.ID, Reason: fmt.Sprintf("needs 1 to %d candidates", maxCandidates)}
+	}
+	seen := make(map[string]bool, len(q.Candidates))
+	for _, c := range q.Candidates {
+		if c == "" || seen[c] {
+			return &common.ParameterError{Name: "question " + q.ID, Reason: fmt.Sprintf("empty or duplicate candidate %q", c)}
+		}
+		seen[c] = true
+	}
+	if q.MaxChoices < 0 || (q.Method == Plurality && q.MaxChoices > 1) {
+		return &common.ParameterError{Name: "question " + q.ID, Reason: "invalid max_choices"}
+	}
+	return nil
+}
+
+// Validate - проверка определения выборов и всех его вопросов
+func (e *Election) Validate() error {
+	if !validID(e.ID) {
+		return &common.ParameterError{Name: "election", Reason: fmt.Sprintf("invalid id %q", e.ID)}
+	}
+	if !e.Opens.IsZero() && !e.Closes.IsZero() && !e.Closes.After(e.Opens) {
+		return &common.ParameterError{Name: "election " + e.ID, Reason: "closes before it opens"}
+	}
+	if len(e.Questions) == 0 || len(e.Questions) > maxQuestions {
+		return &common.ParameterError{Name: "election " + e.ID, Reason: fmt.Sprintf("needs 1 to %d questions", maxQuestions)}
+	}
+	seen := make(map[string]bool, len(e.Questions))
+	for i := range e.Questions {
+		q := &e.Questions[i]
+		if err := q.Validate(); err != nil {
+			return fmt.Errorf("election %s: %w", e.ID, err)
+		}
+		if seen[q.ID] {
+			return &common.ParameterError{Name: "election " + e.ID, Reason: fmt.Sprintf("duplicate question %q", q.ID)}
+		}
+		seen[q.ID] = true
+	}
+	return nil
+}
+
+// CheckOpen - ErrNotOpen, если в момент now бюллетени не принимаются
+func (e *Election) CheckOpen(now time.Time) error {
+	if !e.Opens.IsZero() && now.Before(e.Opens) {
+		return fmt.Errorf("election %s opens at %s: %w", e.ID, e.Opens.Format(time.RFC3339), ErrNotOpen)
+	}
+	if !e.Closes.IsZero() && !now.Before(e.Closes) {
+		return fmt.Errorf("election %s closed at %s: %w", e.ID, e.Closes.Format(time.RFC3339), ErrNotOpen)
+	}
+	return nil
+}
+
+// Read - определения выборов из JSON; идентификаторы выборов не повторяются
+func Read(r io.Reader) ([]Election, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var f File
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("elections: %w", err)
+	}
+	if len(f.Elections) == 0 {
+		return nil, &common.ParameterError{Name: "elections", Reason: "no elections defined"}
+	}
+	seen := make(map[string]bool, len(f.Elections))
+	for i := range f.Elections {
+		e := &f.Elections[i]
+		if err := e.Validate(); err != nil {
+			return nil, err
+		}
+		if seen[e.ID] {
+			return nil, &common.ParameterError{Name: "elections", Reason: fmt.Sprintf("duplicate election %q", e.ID)}
+		}
+		seen[e.ID] = true
+	}
+	return f.Elections, nil
+}
+
+// Load - определения выборов из файла path
+func Load(path string) ([]Election, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Exponent - открытый показатель ключа слепой подписи выборов id: частично слепая
+// подпись с открытой информацией id (blindsig.InfoExponent). Модуль у всех выборов общий
+// с ключом сервера, а разные показатели не дают использовать подпись, полученную
+// для одних выборов, в других.
+func Exponent(id string) *big.Int {
+	return blindsig.InfoExponent([]byte(id))
+}
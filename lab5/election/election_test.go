@@ -0,0 +1,171 @@
+package election
+
+import (
+	"errors"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testElections - двое выборов с вопросами всех видов
+const testElections = `{
+  "elections": [
+    {
+      "id": "council",
+      "title": "Выборы совета",
+      "opens": "2026-10-01T09:00:00Z",
+      "closes": "2026-10-02T21:00:00Z",
+      "questions": [
+        {"id": "chair", "text": "Председатель", "method": "ranked", "candidates": ["Анна", "Борис", "Вера", "Глеб"]},
+        {"id": "members", "text": "Члены совета", "method": "approval", "candidates": ["Дина", "Егор", "Жанна"], "max_choices": 2},
+        {"id": "budget", "text": "Утвердить бюджет", "method": "plurality", "candidates": ["за", "против"]}
+      ]
+    },
+    {
+      "id": "poll",
+      "title": "Опрос",
+      "questions": [{"id": "day", "text": "День встречи", "method": "plurality", "candidates": ["пн", "ср", "пт"]}]
+    }
+  ]
+}`
+
+func readTestElections(t *testing.T) []Election {
+	t.Helper()
+	elections, err := Read(strings.NewReader(testElections))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	return elections
+}
+
+func TestRead(t *testing.T) {
+	elections := readTestElections(t)
+	if len(elections) != 2 || elections[0].Questions[1].MaxChoices != 2 || !elections[1].Opens.IsZero() {
+		t.Fatalf("Read() = %+v", elections)
+	}
+
+	question := `{"id": "q", "method": "plurality", "candidates": ["a", "b"]}`
+	define := func(election string) string { return `{"elections": [` + election + `]}` }
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "нет выборов", data: `{"elections": []}`},
+		{name: "неизвестное поле", data: define(`{"id": "e", "questions": [` + question + `], "extra": 1}`)},
+		{name: "недопустимый id", data: define(`{"id": "Выборы 1", "questions": [` + question + `]}`)},
+		{name: "повтор выборов", data: `{"elections": [{"id": "e", "questions": [` + question + `]}, {"id": "e", "questions": [` + question + `]}]}`},
+		{name: "нет вопросов", data: define(`{"id": "e", "questions": []}`)},
+		{name: "повтор вопроса", data: define(`{"id": "e", "questions": [` + question + `, ` + question + `]}`)},
+		{name: "неизвестный способ", data: define(`{"id": "e", "questions": [{"id": "q", "method": "borda", "candidates": ["a"]}]}`)},
+		{name: "нет кандидатов", data: define(`{"id": "e", "questions": [{"id": "q", "method": "ranked", "candidates": []}]}`)},
+		{name: "повтор кандидата", data: define(`{"id": "e", "questions": [{"id": "q", "method": "approval", "candidates": ["a", "a"]}]}`)},
+		{name: "несколько ответов при plurality", data: define(`{"id": "e", "questions": [{"id": "q", "method": "plurality", "candidates": ["a", "b"], "max_choices": 2}]}`)},
+		{name: "закрываются раньше открытия", data: define(`{"id": "e", "opens": "2026-10-02T00:00:00Z", "closes": "2026-10-01T00:00:00Z", "questions": [` + question + `]}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Read(strings.NewReader(tt.data)); err == nil {
+				t.Error("Read() error = nil")
+			}
+		})
+	}
+}
+
+func TestCheckOpen(t *testing.T) {
+	council := readTestElections(t)[0]
+	tests := []struct {
+		name    string
+		now     string
+		wantErr error
+	}{
+		{name: "до открытия", now: "2026-10-01T08:59:59Z", wantErr: ErrNotOpen},
+		{name: "в момент открытия", now: "2026-10-01T09:00:00Z"},
+		{name: "идут", now: "2026-10-02T12:00:00Z"},
+		{name: "в момент закрытия", now: "2026-10-02T21:00:00Z", wantErr: ErrNotOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now, _ := time.Parse(time.RFC3339, tt.now)
+			if err := council.CheckOpen(now); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckOpen() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBallotEncoding(t *testing.T) {
+	elections := readTestElections(t)
+	council, poll := &elections[0], &elections[1]
+	b, err := council.NewBallot(map[string][]string{"chair": {"Вера", "Анна"}, "members": {"Жанна", "Дина"}})
+	if err != nil {
+		t.Fatalf("NewBallot() error = %v", err)
+	}
+	want := &Ballot{Election: "council", Answers: [][]int{{2, 0}, {0, 2}, nil}}
+	if !reflect.DeepEqual(b, want) {
+		t.Fatalf("NewBallot() = %+v, want %+v", b, want)
+	}
+	m, err := council.Encode(b)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if m2, _ := council.Encode(b); m2.Cmp(m) == 0 {
+		t.Error("Encode() of the same ballot returned the same message twice")
+	}
+	got, err := council.Decode(m)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %+v, want %+v", got, want)
+	}
+
+	pollBallot, _ := poll.NewBallot(map[string][]string{"day": {"ср"}})
+	pollMessage, _ := poll.Encode(pollBallot)
+	bad := []struct {
+		name    string
+		answers map[string][]string
+	}{
+		{name: "неизвестный вопрос", answers: map[string][]string{"mayor": {"Анна"}}},
+		{name: "неизвестный кандидат", answers: map[string][]string{"chair": {"Зоя"}}},
+		{name: "повтор в рейтинге", answers: map[string][]string{"chair": {"Анна", "Анна"}}},
+		{name: "больше max_choices", answers: map[string][]string{"members": {"Дина", "Егор", "Жанна"}}},
+		{name: "два ответа при plurality", answers: map[string][]string{"budget": {"за", "против"}}},
+	}
+	for _, tt := range bad {
+		t.Run(tt.name, func(t *testing.T) {
+			var paramErr *common.ParameterError
+			if _, err := council.NewBallot(tt.answers); !errors.As(err, &paramErr) {
+				t.Errorf("NewBallot() error = %v, want a parameter error", err)
+			}
+		})
+	}
+	messages := []struct {
+		name string
+		m    *big.Int
+	}{
+		{name: "бюллетень других выборов", m: pollMessage},
+		{name: "лишний байт", m: new(big.Int).Lsh(m, 8)},
+		{name: "обрезан", m: new(big.Int).Rsh(m, 8)},
+		{name: "бюллетень референдума", m: big.NewInt(4*123456789 + 1)},
+	}
+	for _, tt := range messages {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := council.Decode(tt.m); err == nil {
+				t.Error("Decode() error = nil")
+			}
+		})
+	}
+}
+
+func TestExponent(t *testing.T) {
+	e := Exponent("council")
+	if e.BitLen() != 128 || !e.ProbablyPrime(20) {
+		t.Errorf("Exponent() = %v, want a 128-bit prime", e)
+	}
+	if Exponent("council").Cmp(e) != 0 || Exponent("poll").Cmp(e) == 0 {
+		t.Error("Exponent() must depend only on the election id")
+	}
+}
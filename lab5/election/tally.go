@@ -0,0 +1,161 @@
+package election
+
+// Result - итоги выборов по всем вопросам
+type Result struct {
+	Election  string           `json:"election"`
+	Ballots   int              `json:"ballots"`
+	Questions []QuestionResult `json:"questions"`
+}
+
+// QuestionResult - итоги вопроса. Votes - голоса за кандидатов (для Ranked - первые
+// предпочтения), Blank - воздержавшиеся, Rounds - туры мгновенного второго тура.
+// Winners пуст, если голосов нет, и содержит нескольких кандидатов при ничьей.
+type QuestionResult struct {
+	ID      string         `json:"id"`
+	Method  Method         `json:"method"`
+	Votes   map[string]int `json:"votes"`
+	Blank   int            `json:"blank"`
+	Rounds  []Round        `json:"rounds,omitempty"`
+	Winners []string       `json:"winners"`
+}
+
+// Round - тур подсчёта рейтингового голосования: голоса за оставшихся кандидатов,
+// бюллетени, в которых оставшихся кандидатов нет, и выбывшие по итогам тура
+type Round struct {
+	Votes      map[string]int `json:"votes"`
+	Exhausted  int            `json:"exhausted"`
+	Eliminated []string       `json:"eliminated,omitempty"`
+}
+
+// Tally - итоги по бюллетеням, уже проверенным Check
+func (e *Election) Tally(ballots []*Ballot) *Result {
+	result := &Result{Election: e.ID, Ballots: len(ballots), Questions: make([]QuestionResult, len(e.Questions))}
+	for i := range e.Questions {
+		q := &e.Questions[i]
+		answers := make([][]int, 0, len(ballots))
+		for _, b := range ballots {
+			answers = append(answers, b.Answers[i])
+		}
+		result.Questions[i] = q.tally(answers)
+	}
+	return result
+}
+
+// tally - итоги вопроса по ответам избирателей
+func (q *Question) tally(answers [][]int) QuestionResult {
+	r := QuestionResult{ID: q.ID, Method: q.Method, Winners: []string{}}
+	counts := make([]int, len(q.Candidates))
+	var cast [][]int
+	for _, answer := range answers {
+		if len(answer) == 0 {
+			r.Blank++
+			continue
+		}
+		cast = append(cast, answer)
+		if q.Method == Ranked {
+			counts[answer[0]]++
+			continue
+		}
+		for _, c := range answer {
+			counts[c]++
+		}
+	}
+	r.Votes = q.named(counts, nil)
+	if q.Method == Ranked {
+		r.Rounds, r.Winners = q.instantRunoff(cast)
+		return r
+	}
+	best := 0
+	for _, n := range counts {
+		if n > best {
+			best = n
+		}
+	}
+	for c, n := range counts {
+		if best > 0 && n == best {
+			r.Winners = append(r.Winners, q.Candidates[c])
+		}
+	}
+	return r
+}
+
+// instantRunoff - мгновенный второй тур. В каждом туре бюллетень отдаётся старшему
+// по предпочтению оставшемуся кандидату; побеждает набравший больше половины
+// неисчерпанных бюллетеней. Иначе выбывают все кандидаты с наименьшим числом голосов,
+// а если наименьшее число у всех оставшихся - они побеждают вместе (ничья).
+func (q *Question) instantRunoff(rankings [][]int) ([]Round, []string) {
+	active := make([]bool, len(q.Candidates))
+	for c := range active {
+		active[c] = true
+	}
+	var rounds []Round
+	for {
+		counts := make([]int, len(q.Candidates))
+		exhausted := 0
+		for _, ranking := range rankings {
+			top := -1
+			for _, c := range ranking {
+				if active[c] {
+					top = c
+					break
+				}
+			}
+			if top < 0 {
+				exhausted++
+				continue
+			}
+			counts[top]++
+		}
+		round := Round{Votes: q.named(counts, active), Exhausted: exhausted}
+		continuing := len(rankings) - exhausted
+		if continuing == 0 {
+			return append(rounds, round), []string{}
+		}
+
+		best, worst, remaining := -1, -1, 0
+		for c, n := range counts {
+			if !active[c] {
+				continue
+			}
+			remaining++
+			if best < 0 || n > counts[best] {
+				best = c
+			}
+			if worst < 0 || n < counts[worst] {
+				worst = c
+			}
+		}
+		if 2*counts[best] > continuing {
+			return append(rounds, round), []string{q.Candidates[best]}
+		}
+		var losers []int
+		for c, n := range counts {
+			if active[c] && n == counts[worst] {
+				losers = append(losers, c)
+			}
+		}
+		if len(losers) == remaining {
+			winners := make([]string, 0, len(losers))
+			for _, c := range losers {
+				winners = append(winners, q.Candidates[c])
+			}
+			return append(rounds, round), winners
+		}
+		for _, c := range losers {
+			active[c] = false
+			round.Eliminated = append(round.Eliminated, q.Candidates[c])
+		}
+		rounds = append(rounds, round)
+	}
+}
+
+// named - голоса по названиям кандидатов; active == nil - все кандидаты
+func (q *Question) named(counts []int, active []bool) map[string]int {
+	votes := make(map[string]int, len(counts))
+	for c, n := range counts {
+		if active == nil || active[c] {
+			votes[q.Candidates[c]] = n
+		}
+	}
+	return votes
+}
@@ -0,0 +1,77 @@
+package election
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTally(t *testing.T) {
+	council := &readTestElections(t)[0]
+	// Рейтинги по председателю: Анна(0) Борис(1) Вера(2) Глеб(3)
+	ballots := []*Ballot{
+		{Answers: [][]int{{0, 1}, {0, 1}, {0}}},
+		{Answers: [][]int{{0, 2}, {1}, {0}}},
+		{Answers: [][]int{{0}, {1, 2}, {1}}},
+		{Answers: [][]int{{1, 2}, {}, {}}},
+		{Answers: [][]int{{1, 0}, {2}, {0}}},
+		{Answers: [][]int{{2, 1}, {0, 2}, {1}}},
+		{Answers: [][]int{{2, 1, 0}, {}, {0}}},
+		{Answers: [][]int{{}, {1}, {1}}},
+	}
+	got := council.Tally(ballots)
+	if got.Election != "council" || got.Ballots != len(ballots) {
+		t.Errorf("Tally() = {%s, %d}, want {council, %d}", got.Election, got.Ballots, len(ballots))
+	}
+
+	// Тур 1: А 3, Б 2, В 2, Г 0 из 7 - выбывает Г. Тур 2: то же, выбывают Б и В.
+	// Тур 3: А 3 + 1 (Б→А) + 1 (В→Б→А) = 5 из 6 (бюллетень В→Б исчерпан).
+	wantChair := QuestionResult{
+		ID: "chair", Method: Ranked, Blank: 1,
+		Votes: map[string]int{"Анна": 3, "Борис": 2, "Вера": 2, "Глеб": 0},
+		Rounds: []Round{
+			{Votes: map[string]int{"Анна": 3, "Борис": 2, "Вера": 2, "Глеб": 0}, Eliminated: []string{"Глеб"}},
+			{Votes: map[string]int{"Анна": 3, "Борис": 2, "Вера": 2}, Eliminated: []string{"Борис", "Вера"}},
+			{Votes: map[string]int{"Анна": 5}, Exhausted: 2},
+		},
+		Winners: []string{"Анна"},
+	}
+	wantMembers := QuestionResult{
+		ID: "members", Method: Approval, Blank: 2,
+		Votes:   map[string]int{"Дина": 2, "Егор": 4, "Жанна": 3},
+		Winners: []string{"Егор"},
+	}
+	wantBudget := QuestionResult{
+		ID: "budget", Method: Plurality, Blank: 1,
+		Votes:   map[string]int{"за": 4, "против": 3},
+		Winners: []string{"за"},
+	}
+	for i, want := range []QuestionResult{wantChair, wantMembers, wantBudget} {
+		if !reflect.DeepEqual(got.Questions[i], want) {
+			t.Errorf("Tally().Questions[%d] = %+v, want %+v", i, got.Questions[i], want)
+		}
+	}
+}
+
+func TestInstantRunoff(t *testing.T) {
+	q := &Question{ID: "q", Method: Ranked, Candidates: []string{"a", "b", "c"}}
+	tests := []struct {
+		name        string
+		rankings    [][]int
+		wantWinners []string
+		wantRounds  int
+	}{
+		{name: "большинство в первом туре", rankings: [][]int{{0}, {0, 1}, {1}}, wantWinners: []string{"a"}, wantRounds: 1},
+		{name: "передача голосов", rankings: [][]int{{0}, {0}, {1}, {1}, {2, 1}}, wantWinners: []string{"b"}, wantRounds: 2},
+		{name: "ничья", rankings: [][]int{{0}, {1}, {2}}, wantWinners: []string{"a", "b", "c"}, wantRounds: 1},
+		{name: "ничья после выбывания", rankings: [][]int{{0}, {1}}, wantWinners: []string{"a", "b"}, wantRounds: 2},
+		{name: "нет бюллетеней", rankings: nil, wantWinners: []string{}, wantRounds: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rounds, winners := q.instantRunoff(tt.rankings)
+			if !reflect.DeepEqual(winners, tt.wantWinners) || len(rounds) != tt.wantRounds {
+				t.Errorf("instantRunoff() = %v after %d rounds, want %v after %d", winners, len(rounds), tt.wantWinners, tt.wantRounds)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/blindsig"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/lab5/election"
+	"github.com/Raimguzhinov/protect-information/lab5/ledger"
+	"github.com/Raimguzhinov/protect-information/lab5/voteapi"
+	"math/big"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownElection - сервер не проводит выборы с таким идентификатором
+var ErrUnknownElection = errors.New("unknown election")
+
+// electionState - выборы на сервере: ключ частично слепой подписи (модуль общий
+// с ключом сервера), выданные избирателям подписи и журнал бюллетеней
+type electionState struct {
+	def    *election.Election
+	key    *blindsig.PrivateKey
+	issued map[string]issuedSignature
+	ledger *ledger.Ledger
+}
+
+// AddElections - выборы по определениям defs. Ключ выборов - частично слепой с открытой
+// информацией id: показатель election.Exponent(id), закрытый - обратный к нему
+// по модулю φ(n). Вызывается до начала приёма запросов и до OpenLedger.
+func (s *Server) AddElections(defs []election.Election) error {
+	for i := range defs {
+		def := &defs[i]
+		if err := def.Validate(); err != nil {
+			return err
+		}
+		if _, ok := s.elections[def.ID]; ok {
+			return &common.ParameterError{Name: "election " + def.ID, Reason: "already added"}
+		}
+		key, err := s.key.Derive([]byte(def.ID))
+		if err != nil {
+			return fmt.Errorf("election %s: key exponent: %w", def.ID, err)
+		}
+		s.elections[def.ID] = &electionState{
+			def:    def,
+			key:    key,
+			issued: make(map[string]issuedSignature),
+			ledger: ledger.New(ledger.PublicKey{N: key.N, D: key.D}),
+		}
+	}
+	return nil
+}
+
+// election - выборы id или ErrUnknownElection
+func (s *Server) election(id string) (*electionState, error) {
+	st, ok := s.elections[id]
+	if !ok {
+		return nil, fmt.Errorf("%q: %w", id, ErrUnknownElection)
+	}
+	return st, nil
+}
+
+// Elections - определения выборов с открытыми показателями ключей, по идентификаторам
+func (s *Server) Elections() []voteapi.ElectionInfo {
+	infos := make([]voteapi.ElectionInfo, 0, len(s.elections))
+	for _, st := range s.elections {
+		infos = append(infos, voteapi.ElectionInfo{Election: *st.def, D: st.key.D})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// GetElectionBlindSignature - слепая подпись ключом выборов id, одна на избирателя.
+// Со списком избирателей запрос подписывается ключом избирателя (sigR, sigS) над
+// voteapi.SignRequestMessage(id, username, blindedHash); без списка избирателя
+// проверяет вызывающий (токен HTTP-сервиса), а подпись запроса не нужна.
+func (s *Server) GetElectionBlindSignature(id, username string, blindedHash, sigR, sigS *big.Int) (*big.Int, error) {
+	st, err := s.election(id)
+	if err != nil {
+		return nil, err
+	}
+	if err = st.def.CheckOpen(s.now()); err != nil {
+		return nil, err
+	}
+	if s.roll != nil {
+		if err = s.authenticate(id, username, blindedHash, sigR, sigS); err != nil {
+			return nil, err
+		}
+	} else if blindedHash == nil || blindedHash.Sign() <= 0 || blindedHash.Cmp(s.key.N) >= 0 {
+		return nil, &common.ParameterError{Name: "blinded", Reason: "blinded hash is not in [1, n)"}
+	}
+	return s.issue(id, st.issued, st.key, username, blindedHash)
+}
+
+// SubmitElectionBallot - проверка подписи ключом выборов id и содержимого бюллетеня
+// по определению, запись в журнал выборов; в ответ - квитанция
+func (s *Server) SubmitElectionBallot(id string, message, signature *big.Int) (*voteapi.Receipt, error) {
+	st, err := s.election(id)
+	if err != nil {
+		return nil, err
+	}
+	if err = st.def.CheckOpen(s.now()); err != nil {
+		return nil, err
+	}
+	if err = st.key.Verify(message.Bytes(), signature); err != nil {
+		return nil, fmt.Errorf("ballot: %w", err)
+	}
+	if _, err = st.def.Decode(message); err != nil {
+		return nil, err
+	}
+	record, fresh, err := st.ledger.Append(message, signature)
+	if err != nil {
+		return nil, fmt.Errorf("ledger: %w", err)
+	}
+	if !fresh {
+		return nil, fmt.Errorf("ballot %s (record %d): %w", record.BallotHash, record.Seq, ErrAlreadyCounted)
+	}
+	return s.receipt(record)
+}
+
+// ElectionResults - итоги выборов id, подсчитанные по журналу бюллетеней
+func (s *Server) ElectionResults(id string) (*election.Result, error) {
+	st, err := s.election(id)
+	if err != nil {
+		return nil, err
+	}
+	records := st.ledger.Records()
+	ballots := make([]*election.Ballot, len(records))
+	for i, r := range records {
+		if ballots[i], err = st.def.Decode(r.Message); err != nil {
+			return nil, fmt.Errorf("record %d: %w", r.Seq, err)
+		}
+	}
+	return st.def.Tally(ballots), nil
+}
+
+// electionLedgerPath - файл журнала выборов id рядом с журналом референдума path:
+// ledger.jsonl -> ledger-id.jsonl
+func electionLedgerPath(path, id string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + id + ext
+}
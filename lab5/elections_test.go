@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/lab5/election"
+	"github.com/Raimguzhinov/protect-information/lab5/ledger"
+	"github.com/Raimguzhinov/protect-information/lab5/voteapi"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newElectionServer - сервер с выборами из примера elections.json
+func newElectionServer(t *testing.T, server *Server) *Server {
+	t.Helper()
+	defs, err := election.Load("elections.json")
+	if err != nil {
+		t.Fatalf("election.Load() error = %v", err)
+	}
+	if err = server.AddElections(defs); err != nil {
+		t.Fatalf("AddElections() error = %v", err)
+	}
+	return server
+}
+
+// encodeBallot - сообщение бюллетеня выборов id с ответами answers
+func encodeBallot(t *testing.T, server *Server, id string, answers map[string][]string) *big.Int {
+	t.Helper()
+	def := server.elections[id].def
+	b, err := def.NewBallot(answers)
+	if err != nil {
+		t.Fatalf("NewBallot() error = %v", err)
+	}
+	m, err := def.Encode(b)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	return m
+}
+
+// electionSign - подпись ключом выборов id под m, полученная вслепую от имени username
+func electionSign(server *Server, id, username string, m *big.Int) (*big.Int, error) {
+	key := server.key.PublicKey.Derive([]byte(id))
+	blinded, state, err := key.Blind(rand.Reader, m.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	signature, err := server.GetElectionBlindSignature(id, username, blinded, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return key.Finalize(state, signature)
+}
+
+func TestElections(t *testing.T) {
+	server := newElectionServer(t, NewServer())
+	if infos := server.Elections(); len(infos) != 2 || infos[0].ID != "council" || infos[0].D.Cmp(election.Exponent("council")) != 0 {
+		t.Fatalf("Elections() = %+v", infos)
+	}
+
+	m := encodeBallot(t, server, "council", map[string][]string{"chair": {"Bob", "Alice"}, "members": {"Erin", "Grace"}, "budget": {"yes"}})
+	signature, err := electionSign(server, "council", "alice", m)
+	if err != nil {
+		t.Fatalf("GetElectionBlindSignature() error = %v", err)
+	}
+	receipt, err := server.SubmitElectionBallot("council", m, signature)
+	if err != nil {
+		t.Fatalf("SubmitElectionBallot() error = %v", err)
+	}
+	if err = receipt.Verify(&server.receiptKey.PublicKey); err != nil || receipt.BallotHash != voteapi.BallotHash(m) {
+		t.Errorf("receipt = %+v, Verify() error = %v", receipt, err)
+	}
+
+	// Подпись одних выборов не годится для других и для референдума
+	tripBallot := encodeBallot(t, server, "trip", map[string][]string{"place": {"lake"}})
+	if _, err = electionSign(server, "council", "alice", tripBallot); !errors.Is(err, ErrAlreadySigned) {
+		t.Errorf("second signature for the same voter error = %v, want %v", err, ErrAlreadySigned)
+	}
+	tripSignature, err := electionSign(server, "trip", "alice", tripBallot)
+	if err != nil {
+		t.Fatalf("GetElectionBlindSignature() for another election error = %v", err)
+	}
+	// Подпись ключом trip под бюллетенем council: подпись верна, содержимое - нет
+	foreign := encodeBallot(t, server, "council", map[string][]string{"budget": {"no"}})
+	foreignSignature, err := electionSign(server, "trip", "bob", foreign)
+	if err != nil {
+		t.Fatalf("GetElectionBlindSignature() error = %v", err)
+	}
+	var paramErr *common.ParameterError
+	tests := []struct {
+		name      string
+		election  string
+		message   *big.Int
+		signature *big.Int
+		check     func(error) bool
+	}{
+		{name: "повтор", election: "council", message: m, signature: signature, check: func(err error) bool { return errors.Is(err, ErrAlreadyCounted) }},
+		{name: "подпись других выборов", election: "trip", message: m, signature: signature, check: func(err error) bool { return errors.Is(err, common.ErrVerification) }},
+		{name: "бюллетень других выборов", election: "trip", message: foreign, signature: foreignSignature, check: func(err error) bool { return errors.As(err, &paramErr) }},
+		{name: "неизвестные выборы", election: "mayor", message: m, signature: signature, check: func(err error) bool { return errors.Is(err, ErrUnknownElection) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := server.SubmitElectionBallot(tt.election, tt.message, tt.signature); !tt.check(err) {
+				t.Errorf("SubmitElectionBallot() error = %v", err)
+			}
+		})
+	}
+	if _, err = server.SubmitBallot(m, signature); !errors.Is(err, common.ErrVerification) {
+		t.Errorf("SubmitBallot() with an election signature error = %v, want %v", err, common.ErrVerification)
+	}
+	if _, err = server.SubmitElectionBallot("trip", tripBallot, tripSignature); err != nil {
+		t.Errorf("SubmitElectionBallot() error = %v", err)
+	}
+
+	result, err := server.ElectionResults("council")
+	if err != nil {
+		t.Fatalf("ElectionResults() error = %v", err)
+	}
+	if result.Ballots != 1 || !reflect.DeepEqual(result.Questions[0].Winners, []string{"Bob"}) || result.Questions[1].Votes["Grace"] != 1 {
+		t.Errorf("ElectionResults() = %+v", result)
+	}
+
+	// До открытия выборов council подпись и бюллетени не принимаются
+	server.now = func() time.Time { return server.elections["council"].def.Opens.Add(-time.Minute) }
+	if _, err = electionSign(server, "council", "carol", m); !errors.Is(err, election.ErrNotOpen) {
+		t.Errorf("GetElectionBlindSignature() before opening error = %v, want %v", err, election.ErrNotOpen)
+	}
+	if _, err = server.SubmitElectionBallot("council", foreign, signature); !errors.Is(err, election.ErrNotOpen) {
+		t.Errorf("SubmitElectionBallot() before opening error = %v, want %v", err, election.ErrNotOpen)
+	}
+}
+
+// TestElectionLedger - журналы выборов переживают перезапуск и проходят аудит
+// ключом сервера с показателем выборов
+func TestElectionLedger(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "server.key")
+	ledgerPath := filepath.Join(dir, "ledger.jsonl")
+	server, err := LoadServer(keyPath)
+	if err != nil {
+		t.Fatalf("LoadServer() error = %v", err)
+	}
+	newElectionServer(t, server)
+	if err = server.OpenLedger(ledgerPath); err != nil {
+		t.Fatalf("OpenLedger() error = %v", err)
+	}
+	for i, place := range []string{"sea", "lake", "sea"} {
+		m := encodeBallot(t, server, "trip", map[string][]string{"place": {place}})
+		signature, err := electionSign(server, "trip", string(rune('a'+i)), m)
+		if err != nil {
+			t.Fatalf("GetElectionBlindSignature() error = %v", err)
+		}
+		if _, err = server.SubmitElectionBallot("trip", m, signature); err != nil {
+			t.Fatalf("SubmitElectionBallot() error = %v", err)
+		}
+	}
+	want, _ := server.ElectionResults("trip")
+	if err = server.CloseLedger(); err != nil {
+		t.Fatalf("CloseLedger() error = %v", err)
+	}
+
+	restarted, err := LoadServer(keyPath)
+	if err != nil {
+		t.Fatalf("LoadServer() error = %v", err)
+	}
+	newElectionServer(t, restarted)
+	if err = restarted.OpenLedger(ledgerPath); err != nil {
+		t.Fatalf("OpenLedger() after restart error = %v", err)
+	}
+	defer restarted.CloseLedger()
+	if got, _ := restarted.ElectionResults("trip"); !reflect.DeepEqual(got, want) {
+		t.Errorf("ElectionResults() after restart = %+v, want %+v", got, want)
+	}
+
+	f, err := os.Open(electionLedgerPath(ledgerPath, "trip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	key := ledger.PublicKey{N: server.key.N, D: election.Exponent("trip")}
+	report, err := ledger.AuditElection(f, &key, restarted.elections["trip"].def)
+	if err != nil {
+		t.Fatalf("AuditElection() error = %v", err)
+	}
+	if !reflect.DeepEqual(report.Result, want) {
+		t.Errorf("AuditElection().Result = %+v, want %+v", report.Result, want)
+	}
+	f.Seek(0, 0)
+	if _, err = ledger.AuditElection(f, nil, restarted.elections["council"].def); !errors.Is(err, common.ErrVerification) {
+		t.Errorf("AuditElection() against another election error = %v, want %v", err, common.ErrVerification)
+	}
+}
+
+func TestHTTPElections(t *testing.T) {
+	server := newElectionServer(t, NewServer())
+	client := newTestClient(serve(t, server))
+	ctx := context.Background()
+
+	elections, err := client.Elections(ctx)
+	if err != nil || len(elections) != 2 {
+		t.Fatalf("Elections() = %d elections, error = %v", len(elections), err)
+	}
+	ballots := []map[string][]string{
+		{"chair": {"Alice", "Bob"}, "members": {"Erin", "Frank"}, "budget": {"yes"}},
+		{"chair": {"Charlie", "Bob"}, "members": {"Frank"}, "budget": {"no"}},
+		{"chair": {"Bob"}, "budget": {"yes"}},
+		{"chair": {"Dave", "Charlie", "Alice"}, "members": {"Heidi", "Frank"}},
+		{"chair": {"Alice"}},
+	}
+	for i, answers := range ballots {
+		name := string(rune('a' + i))
+		token, err := client.Register(ctx, name)
+		if err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+		receipt, err := client.VoteElection(ctx, name, token, "council", answers)
+		if err != nil {
+			t.Fatalf("VoteElection() error = %v", err)
+		}
+		if err = client.VerifyElectionInclusion(ctx, "council", receipt); err != nil {
+			t.Errorf("VerifyElectionInclusion() error = %v", err)
+		}
+		// Тот же токен годится для других выборов, но не для второго бюллетеня тех же
+		if _, err = client.VoteElection(ctx, name, token, "trip", map[string][]string{"place": {"sea"}}); err != nil {
+			t.Errorf("VoteElection() in another election error = %v", err)
+		}
+		if i == 0 {
+			if _, err = client.VoteElection(ctx, name, token, "council", answers); !errors.Is(err, voteapi.ErrRejected) {
+				t.Errorf("second VoteElection() error = %v, want %v", err, voteapi.ErrRejected)
+			}
+		}
+	}
+	if _, err = client.VoteElection(ctx, "a", "", "mayor", nil); err == nil {
+		t.Error("VoteElection() in an unknown election error = nil")
+	}
+	if _, err = client.ElectionResults(ctx, "mayor"); err == nil {
+		t.Error("ElectionResults() of an unknown election error = nil")
+	}
+
+	result, err := client.ElectionResults(ctx, "council")
+	if err != nil {
+		t.Fatalf("ElectionResults() error = %v", err)
+	}
+	// Тур 1: Alice 2, Bob 1, Charlie 1, Dave 1 - выбывают трое; тур 2: Alice 2 + 1 (Bob)
+	chair := result.Questions[0]
+	if result.Ballots != len(ballots) || len(chair.Rounds) != 2 || !reflect.DeepEqual(chair.Winners, []string{"Alice"}) {
+		t.Errorf("chair = %+v, want Alice after 2 rounds", chair)
+	}
+	if members := result.Questions[1]; members.Votes["Frank"] != 3 || members.Blank != 2 {
+		t.Errorf("members = %+v", members)
+	}
+	trip, err := client.ElectionResults(ctx, "trip")
+	if err != nil || trip.Questions[0].Votes["sea"] != len(ballots) {
+		t.Errorf("ElectionResults(trip) = %+v, error = %v", trip, err)
+	}
+	if votes, _ := client.Results(ctx); votes["yes"]+votes["no"]+votes["abstain"] != 0 {
+		t.Errorf("referendum Results() = %v, want no votes", votes)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/jsonio"
+	"github.com/Raimguzhinov/protect-information/lab5/election"
+	"github.com/Raimguzhinov/protect-information/lab5/voteapi"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+)
+
+// maxRequestBody - предел размера JSON-запроса: ключ 2048 бит занимает ~620 цифр
+const maxRequestBody = 64 << 10
+
+// httpService - протокол голосования поверх HTTP/JSON (см. voteapi).
+// Регистрация выдаёт токен, без которого нельзя получить подпись от чужого имени.
+// Если на сервере загружен список избирателей, регистрация закрыта, а запрос
+// подписи должен быть подписан ключом избирателя из списка.
+type httpService struct {
+	server *Server
+	tokens map[string]string // имя избирателя -> токен
+	mu     sync.Mutex
+}
+
+// NewHTTPHandler - HTTP-обработчик сервиса голосования для сервера s
+func NewHTTPHandler(s *Server) http.Handler {
+	svc := &httpService{server: s, tokens: make(map[string]string)}
+	mux := http.NewServeMux()
+	mux.HandleFunc(voteapi.PathKey, jsonio.Method(http.MethodGet, writeError, svc.key))
+	mux.HandleFunc(voteapi.PathRegister, jsonio.Method(http.MethodPost, writeError, svc.register))
+	mux.HandleFunc(voteapi.PathSign, jsonio.Method(http.MethodPost, writeError, svc.sign))
+	mux.HandleFunc(voteapi.PathBallot, jsonio.Method(http.MethodPost, writeError, svc.ballot))
+	mux.HandleFunc(voteapi.PathResults, jsonio.Method(http.MethodGet, writeError, svc.results))
+	mux.HandleFunc(voteapi.PathBoard, jsonio.Method(http.MethodGet, writeError, svc.board))
+	mux.HandleFunc(voteapi.PathElections, jsonio.Method(http.MethodGet, writeError, svc.elections))
+	return mux
+}
+
+// writeJSON - ответ с кодом status и телом v
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	if err := jsonio.WriteJSON(w, status, v); err != nil {
+		log.Printf("Сервер: ошибка записи ответа: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, voteapi.ErrorResponse{Error: msg})
+}
+
+// readJSON - разбор тела запроса; при ошибке ответ уже отправлен
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	return jsonio.ReadJSON(w, r, maxRequestBody, v, writeError)
+}
+
+func (svc *httpService) key(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, voteapi.KeyResponse{N: svc.server.key.N, D: svc.server.key.D, ReceiptKey: &svc.server.receiptKey.PublicKey})
+}
+
+func (svc *httpService) register(w http.ResponseWriter, r *http.Request) {
+	var req voteapi.RegisterRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Username == "" {
+		writeError(w, http.StatusBadRequest, "empty username")
+		return
+	}
+	if svc.server.roll != nil {
+		writeError(w, http.StatusForbidden, "registration is closed: voters are enrolled in the voter roll")
+		return
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		writeError(w, http.StatusInternalServerError, "token generation failed")
+		return
+	}
+	token := hex.EncodeToString(b)
+	svc.mu.Lock()
+	_, exists := svc.tokens[req.Username]
+	if !exists {
+		svc.tokens[req.Username] = token
+	}
+	svc.mu.Unlock()
+	if exists {
+		writeError(w, http.StatusConflict, "username already registered")
+		return
+	}
+	writeJSON(w, http.StatusOK, voteapi.RegisterResponse{Token: token})
+}
+
+func (svc *httpService) sign(w http.ResponseWriter, r *http.Request) {
+	var req voteapi.SignRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if svc.server.roll != nil {
+		svc.signAuthenticated(w, req)
+		return
+	}
+	svc.mu.Lock()
+	token, ok := svc.tokens[req.Username]
+	svc.mu.Unlock()
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(req.Token)) != 1 {
+		writeError(w, http.StatusForbidden, "unknown username or wrong token")
+		return
+	}
+	if req.Election != "" {
+		// Избиратель подтвердил имя токеном, подпись запроса не нужна
+		signature, err := svc.server.GetElectionBlindSignature(req.Election, req.Username, req.Blinded, nil, nil)
+		writeSignature(w, signature, err)
+		return
+	}
+	if req.Blinded == nil || req.Blinded.Sign() <= 0 || req.Blinded.Cmp(svc.server.key.N) >= 0 {
+		writeError(w, http.StatusBadRequest, "blinded hash is not in [1, n)")
+		return
+	}
+	signature := svc.server.GetBlindSignature(req.Username, req.Blinded)
+	if signature == nil {
+		writeError(w, http.StatusConflict, "already signed for this voter")
+		return
+	}
+	writeJSON(w, http.StatusOK, voteapi.SignResponse{Signature: signature})
+}
+
+// signAuthenticated - подпись по запросу, подписанному ключом избирателя из списка
+func (svc *httpService) signAuthenticated(w http.ResponseWriter, req voteapi.SignRequest) {
+	if req.Election != "" {
+		signature, err := svc.server.GetElectionBlindSignature(req.Election, req.Username, req.Blinded, req.SignatureR, req.SignatureS)
+		writeSignature(w, signature, err)
+		return
+	}
+	signature, err := svc.server.GetAuthenticatedBlindSignature(req.Username, req.Blinded, req.SignatureR, req.SignatureS)
+	writeSignature(w, signature, err)
+}
+
+// writeSignature - ответ на запрос подписи с кодом по виду ошибки
+func writeSignature(w http.ResponseWriter, signature *big.Int, err error) {
+	var paramErr *common.ParameterError
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, voteapi.SignResponse{Signature: signature})
+	case errors.Is(err, ErrUnknownElection):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrAlreadySigned):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrNotEligible), errors.Is(err, common.ErrVerification), errors.Is(err, election.ErrNotOpen):
+		writeError(w, http.StatusForbidden, err.Error())
+	case errors.As(err, &paramErr):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func (svc *httpService) ballot(w http.ResponseWriter, r *http.Request) {
+	var req voteapi.BallotRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Message == nil || req.Signature == nil || req.Message.Sign() <= 0 || req.Signature.Sign() <= 0 {
+		writeError(w, http.StatusBadRequest, "message and signature are required")
+		return
+	}
+	var receipt *voteapi.Receipt
+	var err error
+	if req.Election != "" {
+		receipt, err = svc.server.SubmitElectionBallot(req.Election, req.Message, req.Signature)
+	} else {
+		receipt, err = svc.server.SubmitBallot(req.Message, req.Signature)
+	}
+	var paramErr *common.ParameterError
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, voteapi.BallotResponse{Accepted: true, Receipt: receipt})
+	case errors.Is(err, ErrUnknownElection):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, election.ErrNotOpen):
+		writeError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrAlreadyCounted):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, common.ErrVerification), errors.As(err, &paramErr):
+		writeError(w, http.StatusForbidden, "ballot rejected: "+err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func (svc *httpService) board(w http.ResponseWriter, r *http.Request) {
+	l := svc.server.ledger
+	if id := r.URL.Query().Get("election"); id != "" {
+		st, err := svc.server.election(id)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		l = st.ledger
+	}
+	records := l.Records()
+	entries := make([]voteapi.BoardEntry, len(records))
+	for i, r := range records {
+		entries[i] = voteapi.BoardEntry{Seq: r.Seq, BallotHash: r.BallotHash, Message: r.Message, Signature: r.Signature}
+	}
+	writeJSON(w, http.StatusOK, voteapi.BoardResponse{Entries: entries})
+}
+
+func (svc *httpService) results(w http.ResponseWriter, r *http.Request) {
+	if id := r.URL.Query().Get("election"); id != "" {
+		result, err := svc.server.ElectionResults(id)
+		switch {
+		case err == nil:
+			writeJSON(w, http.StatusOK, result)
+		case errors.Is(err, ErrUnknownElection):
+			writeError(w, http.StatusNotFound, err.Error())
+		default:
+			writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	votes := make(map[string]int, len(voteapi.OptionNames))
+	for vote, count := range svc.server.Results() {
+		if name, ok := voteapi.OptionNames[int(vote)]; ok {
+			votes[name] = count
+		}
+	}
+	writeJSON(w, http.StatusOK, voteapi.ResultsResponse{Votes: votes})
+}
+
+func (svc *httpService) elections(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, voteapi.ElectionsResponse{Elections: svc.server.Elections()})
+}
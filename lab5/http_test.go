@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/blindsig"
+	"github.com/Raimguzhinov/protect-information/gost"
+	"github.com/Raimguzhinov/protect-information/lab5/voteapi"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startService - сервис голосования на свободном порту loopback-интерфейса
+func startService(t *testing.T) (*Server, string) {
+	t.Helper()
+	server := NewServer()
+	return server, serve(t, server)
+}
+
+// serve - запуск HTTP-сервиса для уже настроенного сервера; возвращает базовый адрес
+func serve(t *testing.T, server *Server) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	srv := &http.Server{Handler: NewHTTPHandler(server), ReadHeaderTimeout: 5 * time.Second}
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(func() { _ = srv.Close() })
+	return "http://" + ln.Addr().String()
+}
+
+// startSmallService - то же с 512-битным ключом слепой подписи: сотни подписей
+// 2048-битным ключом под -race не укладываются в таймаут клиента
+func startSmallService(t *testing.T) (*Server, string) {
+	t.Helper()
+	key, err := blindsig.GenerateKey(rand.Reader, 512)
+	if err != nil {
+		t.Fatalf("blindsig.GenerateKey() error = %v", err)
+	}
+	p, q, a, err := gost.GenerateParams()
+	if err != nil {
+		t.Fatalf("gost.GenerateParams() error = %v", err)
+	}
+	receiptKey, err := gost.GenerateKey(p, q, a)
+	if err != nil {
+		t.Fatalf("gost.GenerateKey() error = %v", err)
+	}
+	server := newServer(key, receiptKey)
+	return server, serve(t, server)
+}
+
+func newTestClient(baseURL string) *voteapi.Client {
+	return &voteapi.Client{
+		BaseURL: baseURL,
+		HTTP:    &http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: 64}, Timeout: time.Minute},
+	}
+}
+
+func TestHTTPConcurrentVoters(t *testing.T) {
+	_, baseURL := startSmallService(t)
+	client := newTestClient(baseURL)
+	ctx := context.Background()
+	const voters = 300
+	options := []int{voteapi.Yes, voteapi.No, voteapi.Abstain}
+	var wg sync.WaitGroup
+	errs := make(chan error, voters)
+	for i := 0; i < voters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("voter-%d", i)
+			token, err := client.Register(ctx, name)
+			if err != nil {
+				errs <- fmt.Errorf("%s: Register() error = %w", name, err)
+				return
+			}
+			if _, err = client.Vote(ctx, name, token, options[i%len(options)]); err != nil {
+				errs <- fmt.Errorf("%s: Vote() error = %w", name, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	got, err := client.Results(ctx)
+	if err != nil {
+		t.Fatalf("Results() error = %v", err)
+	}
+	want := map[string]int{"yes": voters / 3, "no": voters / 3, "abstain": voters / 3}
+	for name, count := range want {
+		if got[name] != count {
+			t.Errorf("Results()[%s] = %d, want %d", name, got[name], count)
+		}
+	}
+}
+
+func TestHTTPRejects(t *testing.T) {
+	_, baseURL := startService(t)
+	client := newTestClient(baseURL)
+	ctx := context.Background()
+	token, err := client.Register(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if _, err = client.Vote(ctx, "alice", token, voteapi.Yes); err != nil {
+		t.Fatalf("Vote() error = %v", err)
+	}
+	bobToken, err := client.Register(ctx, "bob")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	// Ключ нужен, чтобы подделать подпись из [1, N)
+	key, err := client.Key(ctx)
+	if err != nil {
+		t.Fatalf("Key() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		run     func() error
+		wantErr error
+	}{
+		{
+			name:    "повторная регистрация",
+			run:     func() error { _, err := client.Register(ctx, "alice"); return err },
+			wantErr: voteapi.ErrRejected,
+		},
+		{
+			name:    "повторная подпись",
+			run:     func() error { _, err := client.Vote(ctx, "alice", token, voteapi.No); return err },
+			wantErr: voteapi.ErrRejected,
+		},
+		{
+			name:    "чужой токен",
+			run:     func() error { _, err := client.Vote(ctx, "bob", token, voteapi.No); return err },
+			wantErr: voteapi.ErrRejected,
+		},
+		{
+			name:    "незарегистрированный избиратель",
+			run:     func() error { _, err := client.Vote(ctx, "mallory", bobToken, voteapi.No); return err },
+			wantErr: voteapi.ErrRejected,
+		},
+		{
+			name: "поддельная подпись",
+			run: func() error {
+				_, err := client.Submit(ctx, big.NewInt(4*12345+voteapi.No), new(big.Int).Sub(key.N, big.NewInt(2)))
+				return err
+			},
+			wantErr: voteapi.ErrRejected,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Повтор подписанного бюллетеня: тот же (m, s) отправляется дважды
+	ballot, err := client.SignBallot(ctx, "bob", bobToken, voteapi.No)
+	if err != nil {
+		t.Fatalf("SignBallot() error = %v", err)
+	}
+	if _, err = client.Submit(ctx, ballot.Message, ballot.Signature); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if _, err = client.Submit(ctx, ballot.Message, ballot.Signature); !errors.Is(err, voteapi.ErrRejected) {
+		t.Errorf("Submit() of a replayed ballot error = %v, want %v", err, voteapi.ErrRejected)
+	}
+
+	resp, err := http.Get(baseURL + voteapi.PathBallot)
+	if err != nil {
+		t.Fatalf("GET %s error = %v", voteapi.PathBallot, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET %s status = %d, want %d", voteapi.PathBallot, resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+	resp, err = http.Post(baseURL+voteapi.PathSign, "application/json", strings.NewReader(`{"username": 1}`))
+	if err != nil {
+		t.Fatalf("POST %s error = %v", voteapi.PathSign, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("POST %s with malformed body status = %d, want %d", voteapi.PathSign, resp.StatusCode, http.StatusBadRequest)
+	}
+
+	results, err := client.Results(ctx)
+	if err != nil {
+		t.Fatalf("Results() error = %v", err)
+	}
+	if results["yes"] != 1 || results["no"] != 1 || results["abstain"] != 0 {
+		t.Errorf("Results() = %v, want 1 yes, 1 no", results)
+	}
+}
+
+func TestHTTPVoterRoll(t *testing.T) {
+	roll, keys := newTestRoll(t)
+	server := NewServer()
+	var audit bytes.Buffer
+	server.EnableAuthentication(roll, NewAuditLog(&audit))
+	baseURL := serve(t, server)
+	ctx := context.Background()
+	voter := func(identity *gost.PrivateKey) *voteapi.Client {
+		client := newTestClient(baseURL)
+		client.Identity = identity
+		return client
+	}
+
+	if _, err := voter(keys["alice"]).Vote(ctx, "alice", "", voteapi.Yes); err != nil {
+		t.Fatalf("Vote() error = %v", err)
+	}
+	if _, err := voter(keys["bob"]).Vote(ctx, "bob", "", voteapi.No); err != nil {
+		t.Fatalf("Vote() error = %v", err)
+	}
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{
+			name: "регистрация закрыта",
+			run:  func() error { _, err := voter(nil).Register(ctx, "mallory"); return err },
+		},
+		{
+			name: "повторное голосование",
+			run:  func() error { _, err := voter(keys["alice"]).Vote(ctx, "alice", "", voteapi.No); return err },
+		},
+		{
+			name: "чужое имя",
+			run:  func() error { _, err := voter(keys["alice"]).Vote(ctx, "carol", "", voteapi.No); return err },
+		},
+		{
+			name: "нет в списке",
+			run:  func() error { _, err := voter(keys["carol"]).Vote(ctx, "mallory", "", voteapi.No); return err },
+		},
+		{
+			name: "запрос без подписи",
+			run:  func() error { _, err := voter(nil).Vote(ctx, "carol", "", voteapi.No); return err },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); !errors.Is(err, voteapi.ErrRejected) {
+				t.Errorf("error = %v, want %v", err, voteapi.ErrRejected)
+			}
+		})
+	}
+
+	results, err := voter(nil).Results(ctx)
+	if err != nil {
+		t.Fatalf("Results() error = %v", err)
+	}
+	if results["yes"] != 1 || results["no"] != 1 {
+		t.Errorf("Results() = %v, want 1 yes, 1 no", results)
+	}
+	if n := strings.Count(audit.String(), "\n"); n != 6 {
+		t.Errorf("audit log has %d entries, want 6 (one per sign request):\n%s", n, audit.String())
+	}
+}
@@ -0,0 +1,117 @@
+package ledger
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/lab5/election"
+	"github.com/Raimguzhinov/protect-information/lab5/voteapi"
+	"io"
+	"math/big"
+)
+
+// algorithm - значение заголовка Algorithm в блоке брони ключа сервера
+const algorithm = "rsa-blind"
+
+// PublicKey - открытый ключ слепой подписи сервера: s^D mod N = blindsig.FDH(N, m)
+type PublicKey struct {
+	N *big.Int `json:"n"`
+	D *big.Int `json:"d"`
+}
+
+// ExportPublicKey - ключ в виде блока брони; по нему аудитор проверяет журнал,
+// не доверяя ключу из заголовка
+func (k PublicKey) ExportPublicKey() (*common.ArmorBlock, error) {
+	return common.PublicKeyBlock(algorithm, []string{"N", "D"}, []*big.Int{k.N, k.D})
+}
+
+// ImportPublicKey - ключ из блока ExportPublicKey
+func ImportPublicKey(block *common.ArmorBlock) (PublicKey, error) {
+	if block.Type != common.ArmorPublicKey || block.Headers["Algorithm"] != algorithm {
+		return PublicKey{}, fmt.Errorf("ledger: %s block for %q: %w", block.Type, block.Headers["Algorithm"], common.ErrArmorMalformed)
+	}
+	numbers, err := common.ReadBigNumbers(bytes.NewReader(block.Data))
+	if err != nil {
+		return PublicKey{}, err
+	}
+	if len(numbers) != 2 || numbers[0].Sign() <= 0 {
+		return PublicKey{}, fmt.Errorf("ledger: %d numbers in public key: %w", len(numbers), common.ErrArmorMalformed)
+	}
+	return PublicKey{N: numbers[0], D: numbers[1]}, nil
+}
+
+// Report - итог аудита журнала
+type Report struct {
+	Header    Header
+	Records   int              // число проверенных записей
+	Truncated bool             // в конце файла оборванная строка (сбой во время записи)
+	Head      string           // хэш последней записи: его можно опубликовать и сверять позже
+	Votes     map[string]int   // итоги референдума, пересчитанные по журналу
+	Result    *election.Result // итоги выборов (AuditElection)
+}
+
+// Audit - полная проверка журнала референдума из r: цепочка хэшей, каждая слепая подпись
+// сервера и пересчёт итогов. Если key не nil, ключ из заголовка должен с ним совпадать.
+func Audit(r io.Reader, key *PublicKey) (*Report, error) {
+	report, entries, err := check(r, key)
+	if err != nil {
+		return nil, err
+	}
+	if report.Votes, err = voteapi.Recount(&voteapi.KeyResponse{N: report.Header.Key.N, D: report.Header.Key.D}, entries); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// AuditElection - то же для журнала выборов e. Ключ key - ключ сервера с показателем
+// выборов election.Exponent(e.ID); показатель в заголовке проверяется и без key.
+func AuditElection(r io.Reader, key *PublicKey, e *election.Election) (*Report, error) {
+	report, entries, err := check(r, key)
+	if err != nil {
+		return nil, err
+	}
+	info := &voteapi.ElectionInfo{Election: *e, D: report.Header.Key.D}
+	if info.D.Cmp(election.Exponent(e.ID)) != 0 {
+		return nil, fmt.Errorf("ledger is not signed with the key of election %s: %w", e.ID, common.ErrVerification)
+	}
+	if report.Result, err = voteapi.RecountElection(report.Header.Key.N, info, entries); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// check - чтение журнала, проверка цепочки и ключа из заголовка; записи - в виде доски
+func check(r io.Reader, key *PublicKey) (*Report, []voteapi.BoardEntry, error) {
+	counter := &countingReader{r: r}
+	header, records, size, err := read(counter)
+	if err != nil {
+		return nil, nil, err
+	}
+	if header == nil {
+		return nil, nil, fmt.Errorf("no header: %w", ErrCorrupt)
+	}
+	if key != nil && (header.Key.N.Cmp(key.N) != 0 || header.Key.D.Cmp(key.D) != 0) {
+		return nil, nil, fmt.Errorf("ledger header key differs from the expected server key: %w", common.ErrVerification)
+	}
+	entries := make([]voteapi.BoardEntry, len(records))
+	for i, rec := range records {
+		entries[i] = voteapi.BoardEntry{Seq: rec.Seq, BallotHash: rec.BallotHash, Message: rec.Message, Signature: rec.Signature}
+	}
+	report := &Report{Header: *header, Records: len(records), Truncated: counter.n > size, Head: header.Hash()}
+	if len(records) > 0 {
+		report.Head = records[len(records)-1].Hash
+	}
+	return report, entries, nil
+}
+
+// countingReader - число прочитанных байт, чтобы заметить оборванный хвост
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,248 @@
+// Package ledger - журнал учтённых бюллетеней lab5: записи только добавляются и связаны
+// цепочкой хэшей. Журнал может храниться в файле JSON Lines: первая строка - заголовок
+// с открытым ключом сервера, далее по строке на бюллетень. Каждая запись сбрасывается
+// на диск до ответа избирателю; оборванная при сбое последняя строка отбрасывается при открытии.
+package ledger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/jsonio"
+	"github.com/Raimguzhinov/protect-information/lab5/voteapi"
+	"io"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// version - версия формата журнала; во второй версии подписан хэш blindsig.FDH
+// вместо SHA-512
+const version = 2
+
+// ErrCorrupt - запись журнала повреждена или цепочка хэшей разорвана
+var ErrCorrupt = errors.New("ledger: corrupted")
+
+// Header - первая строка журнала: открытый ключ слепой подписи сервера
+type Header struct {
+	Version int       `json:"version"`
+	Key     PublicKey `json:"key"`
+	Created time.Time `json:"created"`
+}
+
+// Hash - хэш заголовка; с него начинается цепочка записей
+func (h Header) Hash() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("lab5 ledger\n%d\n%x\n%x\n%s",
+		h.Version, h.Key.N, h.Key.D, h.Created.UTC().Format(time.RFC3339Nano))))
+	return hex.EncodeToString(sum[:])
+}
+
+// Record - учтённый бюллетень: сообщение, подпись сервера, время учёта
+// и хэш предыдущей записи (для первой - хэш заголовка)
+type Record struct {
+	Seq        int       `json:"seq"`
+	Time       time.Time `json:"time"`
+	BallotHash string    `json:"ballot_hash"`
+	Message    *big.Int  `json:"message"`
+	Signature  *big.Int  `json:"signature"`
+	Prev       string    `json:"prev"`
+	Hash       string    `json:"hash"`
+}
+
+// computeHash - хэш записи по всем полям, кроме самого Hash
+func (r *Record) computeHash() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("lab5 ledger record\n%d\n%s\n%s\n%x\n%x\n%s",
+		r.Seq, r.Time.UTC().Format(time.RFC3339Nano), r.BallotHash, r.Message, r.Signature, r.Prev)))
+	return hex.EncodeToString(sum[:])
+}
+
+// check - согласованность записи с номером seq и хэшем предыдущей записи prev
+func (r *Record) check(seq int, prev string) error {
+	switch {
+	case r.Message == nil || r.Signature == nil:
+		return fmt.Errorf("record %d: missing ballot: %w", seq, ErrCorrupt)
+	case r.Seq != seq:
+		return fmt.Errorf("record %d: seq = %d: %w", seq, r.Seq, ErrCorrupt)
+	case r.Prev != prev:
+		return fmt.Errorf("record %d: chain broken: %w", seq, ErrCorrupt)
+	case r.BallotHash != voteapi.BallotHash(r.Message):
+		return fmt.Errorf("record %d: ballot hash mismatch: %w", seq, ErrCorrupt)
+	case r.Hash != r.computeHash():
+		return fmt.Errorf("record %d: record hash mismatch: %w", seq, ErrCorrupt)
+	}
+	return nil
+}
+
+// Ledger - журнал с атомарной проверкой и добавлением по хэшу бюллетеня. Подпись RSA
+// можно изменить (s + n проходит ту же проверку), поэтому повтор определяется
+// только по сообщению.
+type Ledger struct {
+	header  Header
+	head    string // хэш последней записи
+	records []Record
+	index   map[string]int // хэш бюллетеня -> номер записи
+	file    *jsonio.Log    // nil - журнал только в памяти
+	mu      sync.RWMutex
+}
+
+// New - пустой журнал в памяти для ключа сервера key
+func New(key PublicKey) *Ledger {
+	l := &Ledger{header: Header{Version: version, Key: key, Created: time.Now().UTC()}, index: make(map[string]int)}
+	l.head = l.header.Hash()
+	return l
+}
+
+// Open - журнал в файле path: новый создаётся, существующий читается с проверкой
+// цепочки. Заголовок должен содержать тот же ключ key, иначе журнал принадлежит другому серверу.
+func Open(path string, key PublicKey) (*Ledger, error) {
+	var rd reader
+	file, err := jsonio.OpenLog(path, rd.decode)
+	if err != nil {
+		return nil, err
+	}
+	l, err := rd.ledger(key)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if l == nil {
+		// Файл пуст или оборван до конца заголовка: начинаем журнал заново
+		l = New(key)
+		if err = file.Append(l.header); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	l.file = file
+	return l, nil
+}
+
+// reader - разбор строк журнала: заголовок и записи с проверкой цепочки
+type reader struct {
+	header  *Header
+	records []Record
+	prev    string
+}
+
+// decode - строка line журнала; первая строка - заголовок
+func (rd *reader) decode(line int, dec *json.Decoder) error {
+	if line == 0 {
+		header := new(Header)
+		if err := dec.Decode(header); err != nil || header.Version != version || header.Key.N == nil || header.Key.D == nil {
+			return fmt.Errorf("header: %w", ErrCorrupt)
+		}
+		rd.header, rd.prev = header, header.Hash()
+		return nil
+	}
+	var rec Record
+	if err := dec.Decode(&rec); err != nil {
+		return fmt.Errorf("record %d: %v: %w", line-1, err, ErrCorrupt)
+	}
+	if err := rec.check(line-1, rd.prev); err != nil {
+		return err
+	}
+	rd.records = append(rd.records, rec)
+	rd.prev = rec.Hash
+	return nil
+}
+
+// ledger - журнал из прочитанных строк; nil без ошибки - в файле нет полного заголовка
+func (rd *reader) ledger(key PublicKey) (*Ledger, error) {
+	if rd.header == nil {
+		return nil, nil
+	}
+	if rd.header.Key.N.Cmp(key.N) != 0 || rd.header.Key.D.Cmp(key.D) != 0 {
+		return nil, fmt.Errorf("ledger was written for another server key: %w", common.ErrVerification)
+	}
+	l := &Ledger{header: *rd.header, head: rd.header.Hash(), records: rd.records, index: make(map[string]int, len(rd.records))}
+	for _, r := range rd.records {
+		if _, ok := l.index[r.BallotHash]; ok {
+			return nil, fmt.Errorf("record %d: ballot counted twice: %w", r.Seq, ErrCorrupt)
+		}
+		l.index[r.BallotHash] = r.Seq
+		l.head = r.Hash
+	}
+	return l, nil
+}
+
+// read - заголовок и записи с проверкой цепочки; size - длина целых строк.
+// Заголовок nil, если файл пуст или первая строка оборвана.
+func read(r io.Reader) (header *Header, records []Record, size int64, err error) {
+	var rd reader
+	if size, err = jsonio.ReadLines(r, rd.decode); err != nil {
+		return nil, nil, 0, err
+	}
+	return rd.header, rd.records, size, nil
+}
+
+// Header - заголовок журнала
+func (l *Ledger) Header() Header {
+	return l.header
+}
+
+// Append - атомарная проверка и добавление. Если бюллетень уже учтён, возвращается
+// прежняя запись и false. Запись файлового журнала сброшена на диск до возврата.
+func (l *Ledger) Append(message, signature *big.Int) (Record, bool, error) {
+	hash := voteapi.BallotHash(message)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if seq, ok := l.index[hash]; ok {
+		return l.records[seq], false, nil
+	}
+	r := Record{
+		Seq:        len(l.records),
+		Time:       time.Now().UTC(),
+		BallotHash: hash,
+		Message:    new(big.Int).Set(message),
+		Signature:  new(big.Int).Set(signature),
+		Prev:       l.head,
+	}
+	r.Hash = r.computeHash()
+	if l.file != nil {
+		if err := l.file.Append(r); err != nil {
+			return Record{}, false, err
+		}
+	}
+	l.records = append(l.records, r)
+	l.index[hash] = r.Seq
+	l.head = r.Hash
+	return r, true, nil
+}
+
+// Lookup - запись по хэшу бюллетеня
+func (l *Ledger) Lookup(hash string) (Record, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	seq, ok := l.index[hash]
+	if !ok {
+		return Record{}, false
+	}
+	return l.records[seq], true
+}
+
+// Records - копия всех записей в порядке учёта
+func (l *Ledger) Records() []Record {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]Record(nil), l.records...)
+}
+
+// Len - число учтённых бюллетеней
+func (l *Ledger) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.records)
+}
+
+// Close - закрытие файла журнала; после него Append файлового журнала возвращает ошибку
+func (l *Ledger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,242 @@
+package ledger
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"github.com/Raimguzhinov/protect-information/blindsig"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/lab5/voteapi"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testSigner - ключ RSA для слепой подписи с небольшим модулем
+type testSigner struct {
+	key PublicKey
+	c   *big.Int
+}
+
+func newTestSigner(t *testing.T) *testSigner {
+	t.Helper()
+	e := big.NewInt(65537)
+	for {
+		p, err := rand.Prime(rand.Reader, 320)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q, err := rand.Prime(rand.Reader, 320)
+		if err != nil {
+			t.Fatal(err)
+		}
+		phi := new(big.Int).Mul(new(big.Int).Sub(p, big.NewInt(1)), new(big.Int).Sub(q, big.NewInt(1)))
+		if c := new(big.Int).ModInverse(e, phi); c != nil && p.Cmp(q) != 0 {
+			return &testSigner{key: PublicKey{N: new(big.Int).Mul(p, q), D: e}, c: c}
+		}
+	}
+}
+
+// sign - подпись сервера под бюллетенем с голосом vote
+func (s *testSigner) sign(seed int64, vote int) (*big.Int, *big.Int) {
+	m := big.NewInt(seed<<2 | int64(vote))
+	return m, new(big.Int).Exp(blindsig.FDH(s.key.N, m.Bytes()), s.c, s.key.N)
+}
+
+func TestAppend(t *testing.T) {
+	l := New(PublicKey{N: big.NewInt(35), D: big.NewInt(5)})
+	const workers = 50
+	var wg sync.WaitGroup
+	fresh := make(chan Record, 2*workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Все пытаются записать один и тот же бюллетень и каждый - свой
+			for _, m := range []int64{7, int64(100 + i)} {
+				r, ok, err := l.Append(big.NewInt(m), big.NewInt(1))
+				if err != nil {
+					t.Errorf("Append() error = %v", err)
+				}
+				if ok {
+					fresh <- r
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(fresh)
+	seqs := make(map[int]bool)
+	for r := range fresh {
+		if seqs[r.Seq] {
+			t.Errorf("Seq %d issued twice", r.Seq)
+		}
+		seqs[r.Seq] = true
+	}
+	if len(seqs) != workers+1 || l.Len() != workers+1 {
+		t.Errorf("fresh records = %d, Len() = %d, want %d", len(seqs), l.Len(), workers+1)
+	}
+	prev := l.Header().Hash()
+	for i, r := range l.Records() {
+		if err := r.check(i, prev); err != nil {
+			t.Errorf("Records()[%d]: %v", i, err)
+		}
+		prev = r.Hash
+	}
+	if r, ok := l.Lookup(voteapi.BallotHash(big.NewInt(7))); !ok || r.Message.Int64() != 7 {
+		t.Errorf("Lookup(7) = %v, %v, want the counted ballot", r, ok)
+	}
+}
+
+func TestOpenRecover(t *testing.T) {
+	signer := newTestSigner(t)
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	l, err := Open(path, signer.key)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	for i, vote := range []int{voteapi.Yes, voteapi.No, voteapi.Yes} {
+		m, s := signer.sign(int64(1000+i), vote)
+		if _, ok, err := l.Append(m, s); !ok || err != nil {
+			t.Fatalf("Append() = %v, %v", ok, err)
+		}
+	}
+	want := l.Records()
+	if err = l.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, _, err = l.Append(big.NewInt(5), big.NewInt(5)); err == nil {
+		t.Error("Append() after Close() error = nil")
+	}
+
+	// Сбой во время записи: последняя строка оборвана
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.WriteString(`{"seq":3,"time":"2024-`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	data, _ := os.ReadFile(path)
+	report, err := Audit(bytes.NewReader(data), &signer.key)
+	if err != nil {
+		t.Fatalf("Audit() of a torn ledger error = %v", err)
+	}
+	if !report.Truncated || report.Records != 3 || report.Head != want[2].Hash {
+		t.Errorf("Audit() = %+v, want 3 records and a truncated tail", report)
+	}
+
+	l, err = Open(path, signer.key)
+	if err != nil {
+		t.Fatalf("Open() after a crash error = %v", err)
+	}
+	if got := l.Records(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Records() after recovery = %v, want %v", got, want)
+	}
+	// Повтор после восстановления отклоняется, новый бюллетень продолжает цепочку
+	if _, ok, _ := l.Append(want[0].Message, want[0].Signature); ok {
+		t.Error("Append() accepted a ballot counted before the restart")
+	}
+	m, s := signer.sign(2000, voteapi.Abstain)
+	if _, ok, err := l.Append(m, s); !ok || err != nil {
+		t.Fatalf("Append() after recovery = %v, %v", ok, err)
+	}
+	l.Close()
+
+	data, _ = os.ReadFile(path)
+	report, err = Audit(bytes.NewReader(data), &signer.key)
+	if err != nil {
+		t.Fatalf("Audit() error = %v", err)
+	}
+	wantVotes := map[string]int{"yes": 2, "no": 1, "abstain": 1}
+	if report.Truncated || report.Records != 4 || !reflect.DeepEqual(report.Votes, wantVotes) {
+		t.Errorf("Audit() = %+v, want 4 records with votes %v", report, wantVotes)
+	}
+}
+
+func TestAuditRejects(t *testing.T) {
+	signer := newTestSigner(t)
+	other := newTestSigner(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ledger.jsonl")
+	l, err := Open(path, signer.key)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		m, s := signer.sign(int64(500+i), voteapi.No)
+		if _, _, err = l.Append(m, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.Close()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+
+	// Запись с чужой подписью: цепочка цела, но аудит подпись не пропускает
+	forgedPath := filepath.Join(dir, "forged.jsonl")
+	if err = os.WriteFile(forgedPath, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	forged, err := Open(forgedPath, signer.key)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	m, s := other.sign(900, voteapi.Yes)
+	if _, _, err = forged.Append(m, s); err != nil {
+		t.Fatal(err)
+	}
+	forged.Close()
+	forgedData, _ := os.ReadFile(forgedPath)
+
+	tests := []struct {
+		name    string
+		data    string
+		key     *PublicKey
+		wantErr error
+	}{
+		{name: "изменён голос", data: strings.Replace(string(data), `"message":`+messageField(t, lines[2]), `"message":1`, 1), wantErr: ErrCorrupt},
+		{name: "удалена запись", data: lines[0] + lines[1] + lines[3], wantErr: ErrCorrupt},
+		{name: "переставлены записи", data: lines[0] + lines[2] + lines[1] + lines[3], wantErr: ErrCorrupt},
+		{name: "нет заголовка", data: lines[1] + lines[2], wantErr: ErrCorrupt},
+		{name: "чужой ключ", data: string(data), key: &other.key, wantErr: common.ErrVerification},
+		{name: "подпись не сервера", data: string(forgedData), wantErr: common.ErrVerification},
+		{name: "пустой журнал", data: "", wantErr: ErrCorrupt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Audit(strings.NewReader(tt.data), tt.key); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Audit() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Сервер не продолжает повреждённый журнал и журнал другого ключа
+	if err = os.WriteFile(path, []byte(lines[0]+lines[2]), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Open(path, signer.key); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Open() of a broken chain error = %v, want %v", err, ErrCorrupt)
+	}
+	if _, err = Open(forgedPath, other.key); !errors.Is(err, common.ErrVerification) {
+		t.Errorf("Open() with another key error = %v, want %v", err, common.ErrVerification)
+	}
+}
+
+// messageField - десятичная запись сообщения из строки журнала
+func messageField(t *testing.T, line string) string {
+	t.Helper()
+	_, rest, ok := strings.Cut(line, `"message":`)
+	if !ok {
+		t.Fatalf("no message in %q", line)
+	}
+	return rest[:strings.IndexByte(rest, ',')]
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+	"sync"
+)
+
+// DefaultShuffleRounds - число теневых перемешиваний в доказательстве: нечестный
+// сервер проходит проверку с вероятностью 2^-DefaultShuffleRounds
+const DefaultShuffleRounds = 80
+
+// maxShuffleRounds - биты вызова берутся из одного хэша SHA-256
+const maxShuffleRounds = 8 * sha256.Size
+
+// Ciphertext - шифртекст Эль-Гамаля (A, B) = (G^r, H^r * M) элемента M группы выборов
+type Ciphertext struct {
+	A, B *big.Int
+}
+
+// ShuffleOpening - раскрытие перемешивания from -> to: to[Permutation[i]] = from[i],
+// перешифрованный со случайностью Randomness[i]
+type ShuffleOpening struct {
+	Permutation []int
+	Randomness  []*big.Int
+}
+
+// ShuffleProof - доказательство Сако-Килиана. Shadows[j] - теневое перемешивание входа;
+// по j-му биту вызова Openings[j] раскрывает либо вход -> тень (бит 0), либо тень -> выход
+// (бит 1). Раскрытие одной стороны ничего не говорит о перестановке вход -> выход,
+// а нечестный сервер не может ответить на оба бита сразу.
+type ShuffleProof struct {
+	Shadows  [][]Ciphertext
+	Openings []ShuffleOpening
+}
+
+// Shuffle - опубликованный результат сервера перемешивания с номером Mix
+type Shuffle struct {
+	Mix    int
+	Output []Ciphertext
+	Proof  ShuffleProof
+}
+
+// MixFault - нечестный сервер перемешивания в тестах и демонстрации
+type MixFault struct {
+	Substitute bool // вместо первого бюллетеня выдаёт копию второго
+}
+
+// reencrypt - тот же открытый текст с новой случайностью: (A * G^s, B * H^s)
+func (ek *ElectionKey) reencrypt(c Ciphertext, s *big.Int) Ciphertext {
+	a := new(big.Int).Exp(ek.G, s, ek.P)
+	a.Mul(a, c.A).Mod(a, ek.P)
+	b := new(big.Int).Exp(ek.H, s, ek.P)
+	b.Mul(b, c.B).Mod(b, ek.P)
+	return Ciphertext{A: a, B: b}
+}
+
+// randomShuffle - случайная перестановка входа с перешифрованием и её раскрытие
+func (ek *ElectionKey) randomShuffle(input []Ciphertext) ([]Ciphertext, ShuffleOpening, error) {
+	n := len(input)
+	opening := ShuffleOpening{Permutation: make([]int, n), Randomness: make([]*big.Int, n)}
+	for i := range opening.Permutation {
+		opening.Permutation[i] = i
+	}
+	// Тасование Фишера-Йетса криптографическим генератором
+	for i := n - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return nil, ShuffleOpening{}, err
+		}
+		k := int(j.Int64())
+		opening.Permutation[i], opening.Permutation[k] = opening.Permutation[k], opening.Permutation[i]
+	}
+	output := make([]Ciphertext, n)
+	for i, c := range input {
+		s, err := randomBelow(ek.Q)
+		if err != nil {
+			return nil, ShuffleOpening{}, err
+		}
+		opening.Randomness[i] = s
+		output[opening.Permutation[i]] = ek.reencrypt(c, s)
+	}
+	return output, opening, nil
+}
+
+// checkOpening - to получен из from перестановкой и перешифрованием из раскрытия
+func (ek *ElectionKey) checkOpening(from, to []Ciphertext, opening ShuffleOpening) bool {
+	n := len(from)
+	if len(to) != n || len(opening.Permutation) != n || len(opening.Randomness) != n {
+		return false
+	}
+	used := make([]bool, n)
+	for i, c := range from {
+		j, s := opening.Permutation[i], opening.Randomness[i]
+		if j < 0 || j >= n || used[j] || s == nil || s.Sign() < 0 || s.Cmp(ek.Q) >= 0 {
+			return false
+		}
+		used[j] = true
+		re := ek.reencrypt(c, s)
+		if re.A.Cmp(to[j].A) != 0 || re.B.Cmp(to[j].B) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// shuffleChallenge - биты вызова Фиата-Шамира: хэш ключа, номера сервера, входа, выхода и теней
+func (ek *ElectionKey) shuffleChallenge(mix int, input, output []Ciphertext, shadows [][]Ciphertext) []bool {
+	values := []*big.Int{ek.P, ek.Q, ek.G, ek.H, new(big.Int).SetBytes(ek.Context), big.NewInt(int64(mix))}
+	for _, list := range append([][]Ciphertext{input, output}, shadows...) {
+		values = append(values, big.NewInt(int64(len(list))))
+		for _, c := range list {
+			values = append(values, c.A, c.B)
+		}
+	}
+	var buf bytes.Buffer
+	_ = common.WriteBigNumbers(&buf, values) // запись в bytes.Buffer не завершается ошибкой
+	h := sha256.Sum256(buf.Bytes())
+	bits := make([]bool, len(shadows))
+	for j := range bits {
+		bits[j] = h[j/8]>>(j%8)&1 == 1
+	}
+	return bits
+}
+
+// MixShuffle - работа сервера перемешивания mix: выход и доказательство с rounds тенями
+func (ek *ElectionKey) MixShuffle(mix int, input []Ciphertext, rounds int, fault MixFault) (*Shuffle, error) {
+	if rounds < 1 || rounds > maxShuffleRounds {
+		return nil, &common.ParameterError{Name: "rounds", Reason: fmt.Sprintf("must lie in [1, %d]", maxShuffleRounds)}
+	}
+	output, secret, err := ek.randomShuffle(input)
+	if err != nil {
+		return nil, err
+	}
+	if fault.Substitute && len(input) > 1 {
+		s, err := randomBelow(ek.Q)
+		if err != nil {
+			return nil, err
+		}
+		output[secret.Permutation[0]] = ek.reencrypt(input[1], s)
+	}
+	shadows := make([][]Ciphertext, rounds)
+	openings := make([]ShuffleOpening, rounds)
+	for j := range shadows {
+		if shadows[j], openings[j], err = ek.randomShuffle(input); err != nil {
+			return nil, err
+		}
+	}
+	for j, bit := range ek.shuffleChallenge(mix, input, output, shadows) {
+		if !bit {
+			continue
+		}
+		// Тень -> выход: элемент i входа лежит в тени под номером π_j(i)
+		// и в выходе под номером π(i) со случайностью r_i - s_ji
+		link := ShuffleOpening{Permutation: make([]int, len(input)), Randomness: make([]*big.Int, len(input))}
+		for i := range input {
+			u := openings[j].Permutation[i]
+			link.Permutation[u] = secret.Permutation[i]
+			t := new(big.Int).Sub(secret.Randomness[i], openings[j].Randomness[i])
+			link.Randomness[u] = t.Mod(t, ek.Q)
+		}
+		openings[j] = link
+	}
+	if common.Tracing() {
+		common.Trace("vote", "mix", common.ValInt("mix", int64(mix)), common.ValInt("ballots", int64(len(input))))
+	}
+	return &Shuffle{Mix: mix, Output: output, Proof: ShuffleProof{Shadows: shadows, Openings: openings}}, nil
+}
+
+// VerifyShuffle - выход перемешивания - перестановка перешифрованного входа
+func (ek *ElectionKey) VerifyShuffle(input []Ciphertext, sh *Shuffle) error {
+	rounds := len(sh.Proof.Shadows)
+	if rounds < 1 || rounds > maxShuffleRounds || len(sh.Proof.Openings) != rounds || len(sh.Output) != len(input) {
+		return fmt.Errorf("mix %d: malformed shuffle: %w", sh.Mix, common.ErrVerification)
+	}
+	for _, list := range append([][]Ciphertext{sh.Output}, sh.Proof.Shadows...) {
+		for _, c := range list {
+			if !ek.inGroup(c.A) || !ek.inGroup(c.B) {
+				return fmt.Errorf("mix %d: ciphertext is not in the election group: %w", sh.Mix, common.ErrVerification)
+			}
+		}
+	}
+	for j, bit := range ek.shuffleChallenge(sh.Mix, input, sh.Output, sh.Proof.Shadows) {
+		from, to := input, sh.Proof.Shadows[j]
+		if bit {
+			from, to = sh.Proof.Shadows[j], sh.Output
+		}
+		if !ek.checkOpening(from, to, sh.Proof.Openings[j]) {
+			return fmt.Errorf("mix %d: shadow %d does not open: %w", sh.Mix, j, common.ErrVerification)
+		}
+	}
+	return nil
+}
+
+// RunMixnet - цепочка из mixes серверов перемешивания, каждый в своей горутине: сервер
+// получает шифртексты от предыдущего по каналу, перемешивает их и передаёт дальше.
+// Возвращает опубликованные перемешивания по порядку; faults - нечестные серверы (1..mixes).
+func (ek *ElectionKey) RunMixnet(ctx context.Context, input []Ciphertext, mixes, rounds int, faults map[int]MixFault) ([]*Shuffle, error) {
+	if mixes < 1 {
+		return nil, &common.ParameterError{Name: "mixes", Reason: "at least one mix server is needed"}
+	}
+	links := make([]chan []Ciphertext, mixes+1)
+	for i := range links {
+		links[i] = make(chan []Ciphertext, 1)
+	}
+	shuffles := make([]*Shuffle, mixes)
+	errs := make(chan error, mixes)
+	var wg sync.WaitGroup
+	for m := 1; m <= mixes; m++ {
+		wg.Add(1)
+		go func(m int) {
+			defer wg.Done()
+			var in []Ciphertext
+			var ok bool
+			select {
+			case in, ok = <-links[m-1]:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				close(links[m])
+				return
+			}
+			if !ok {
+				// Предыдущий сервер не справился: цепочка обрывается
+				close(links[m])
+				return
+			}
+			sh, err := ek.MixShuffle(m, in, rounds, faults[m])
+			if err != nil {
+				errs <- fmt.Errorf("mix %d: %w", m, err)
+				close(links[m])
+				return
+			}
+			shuffles[m-1] = sh
+			links[m] <- sh.Output
+		}(m)
+	}
+	links[0] <- input
+	wg.Wait()
+	close(errs)
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	return shuffles, nil
+}
+
+// VerifyMixnet - проверка цепочки: вход первого сервера - input, вход каждого следующего -
+// выход предыдущего, все доказательства верны. Возвращает выход последнего сервера.
+func (ek *ElectionKey) VerifyMixnet(input []Ciphertext, shuffles []*Shuffle) ([]Ciphertext, error) {
+	if len(shuffles) == 0 {
+		return nil, fmt.Errorf("no shuffles: %w", common.ErrVerification)
+	}
+	current := input
+	for i, sh := range shuffles {
+		if sh == nil || sh.Mix != i+1 {
+			return nil, fmt.Errorf("shuffle %d is out of order: %w", i+1, common.ErrVerification)
+		}
+		if err := ek.VerifyShuffle(current, sh); err != nil {
+			return nil, err
+		}
+		current = sh.Output
+	}
+	return current, nil
+}
+
+// countVotes - подсчёт расшифрованных бюллетеней: ax[i] = A_i^x для cts[i],
+// M = B / A^x должно быть G^(base^k) для одного из вариантов
+func (ek *ElectionKey) countVotes(cts []Ciphertext, ax []*big.Int, base int64) (map[Vote]int, error) {
+	encoded := make(map[string]Vote, len(options))
+	results := make(map[Vote]int, len(options))
+	for i, o := range options {
+		encoded[new(big.Int).Exp(ek.G, optionValue(i, base), ek.P).String()] = o
+		results[o] = 0
+	}
+	for i, c := range cts {
+		m := new(big.Int).ModInverse(ax[i], ek.P)
+		m.Mul(m, c.B).Mod(m, ek.P)
+		vote, ok := encoded[m.String()]
+		if !ok {
+			return nil, fmt.Errorf("ciphertext %d does not decrypt to an option: %w", i, common.ErrDecryption)
+		}
+		results[vote]++
+	}
+	return results, nil
+}
+
+// DecryptVotes - расшифрование каждого перемешанного бюллетеня закрытым ключом
+func (a *Authority) DecryptVotes(cts []Ciphertext, base int64) (map[Vote]int, error) {
+	ax := make([]*big.Int, len(cts))
+	for i, c := range cts {
+		ax[i] = new(big.Int).Exp(c.A, a.x, a.P)
+	}
+	return a.countVotes(cts, ax, base)
+}
+
+// DecryptVotes - расшифрование каждого перемешанного бюллетеня частичными
+// расшифровками доверенных лиц (см. DecryptTally)
+func (c *Committee) DecryptVotes(ctx context.Context, cts []Ciphertext, base int64) (map[Vote]int, error) {
+	ax := make([]*big.Int, len(cts))
+	for i, ct := range cts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var err error
+		if ax[i], err = c.decryptA(ct.A); err != nil {
+			return nil, fmt.Errorf("ciphertext %d: %w", i, err)
+		}
+	}
+	return c.countVotes(cts, ax, base)
+}
+
+// mixAndDecrypt - этап перемешивания перед подсчётом: принятые бюллетени проходят цепочку
+// из mixes серверов (cheating - номер нечестного, 0 - все честные), проверяющий проверяет
+// доказательства всей цепочки, и только после этого комитет расшифровывает каждый
+// бюллетень. Порядок поступления и сетевой источник бюллетеня с голосом не связаны.
+func mixAndDecrypt(ctx context.Context, committee *Committee, t *Tally, mixes, cheating int) (map[Vote]int, error) {
+	input := t.Ciphertexts()
+	faults := make(map[int]MixFault)
+	if cheating > 0 {
+		faults[cheating] = MixFault{Substitute: true}
+	}
+	shuffles, err := committee.RunMixnet(ctx, input, mixes, DefaultShuffleRounds, faults)
+	if err != nil {
+		return nil, err
+	}
+	for _, sh := range shuffles {
+		fmt.Printf("Сервер перемешивания %d: опубликовано %d бюллетеней и %d теней\n", sh.Mix, len(sh.Output), len(sh.Proof.Shadows))
+	}
+	output, err := committee.VerifyMixnet(input, shuffles)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println("Проверяющий: доказательства перемешивания верны")
+	return committee.DecryptVotes(ctx, output, t.base)
+}
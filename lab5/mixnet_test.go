@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+// testShuffleRounds - меньше теней, чем по умолчанию: вероятность пропустить
+// нечестный сервер 2^-40 достаточно мала для тестов
+const testShuffleRounds = 40
+
+// mixTestBallots - зашифрованные голоса votes, принятые подсчётом
+func mixTestBallots(t *testing.T, ek *ElectionKey, votes []Vote) *Tally {
+	t.Helper()
+	tally := NewTally(ek, 10)
+	for _, v := range votes {
+		ballot, err := ek.EncryptVote(v, tally.base)
+		if err != nil {
+			t.Fatalf("EncryptVote() error = %v", err)
+		}
+		if err = tally.Add(ballot); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	return tally
+}
+
+func TestMixnet(t *testing.T) {
+	a := newTestAuthority(t)
+	votes := []Vote{YES, NO, YES, ABSTAIN, NO, YES}
+	tally := mixTestBallots(t, &a.ElectionKey, votes)
+	input := tally.Ciphertexts()
+	ctx := context.Background()
+	want := map[Vote]int{YES: 3, NO: 2, ABSTAIN: 1}
+
+	shuffles, err := a.RunMixnet(ctx, input, 3, testShuffleRounds, nil)
+	if err != nil {
+		t.Fatalf("RunMixnet() error = %v", err)
+	}
+	output, err := a.VerifyMixnet(input, shuffles)
+	if err != nil {
+		t.Fatalf("VerifyMixnet() error = %v", err)
+	}
+	for i, c := range output {
+		for _, in := range input {
+			if c.A.Cmp(in.A) == 0 || c.B.Cmp(in.B) == 0 {
+				t.Errorf("output[%d] repeats an input ciphertext", i)
+			}
+		}
+	}
+	got, err := a.DecryptVotes(output, tally.base)
+	if err != nil {
+		t.Fatalf("DecryptVotes() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecryptVotes() = %v, want %v", got, want)
+	}
+
+	// Пустой ящик тоже перемешивается и проверяется
+	empty, err := a.RunMixnet(ctx, nil, 2, testShuffleRounds, nil)
+	if err != nil {
+		t.Fatalf("RunMixnet() without ballots error = %v", err)
+	}
+	if _, err = a.VerifyMixnet(nil, empty); err != nil {
+		t.Errorf("VerifyMixnet() without ballots error = %v", err)
+	}
+	if _, err = a.RunMixnet(ctx, input, 0, testShuffleRounds, nil); !errors.Is(err, common.ErrInvalidParameters) {
+		t.Errorf("RunMixnet() without mixes error = %v, want %v", err, common.ErrInvalidParameters)
+	}
+}
+
+// TestMixnetRejected - проверяющий отвергает любое отступление от честного перемешивания
+func TestMixnetRejected(t *testing.T) {
+	a := newTestAuthority(t)
+	tally := mixTestBallots(t, &a.ElectionKey, []Vote{YES, NO, ABSTAIN, NO})
+	input := tally.Ciphertexts()
+	ctx := context.Background()
+
+	// honest - свежая честная цепочка из двух серверов
+	honest := func(t *testing.T) []*Shuffle {
+		shuffles, err := a.RunMixnet(ctx, input, 2, testShuffleRounds, nil)
+		if err != nil {
+			t.Fatalf("RunMixnet() error = %v", err)
+		}
+		return shuffles
+	}
+	other := mixTestBallots(t, &a.ElectionKey, []Vote{YES, YES, YES, YES}).Ciphertexts()
+	tests := []struct {
+		name   string
+		chain  func(t *testing.T) []*Shuffle
+		inputs []Ciphertext
+	}{
+		{
+			name: "подмена бюллетеня сервером",
+			chain: func(t *testing.T) []*Shuffle {
+				shuffles, err := a.RunMixnet(ctx, input, 2, testShuffleRounds, map[int]MixFault{2: {Substitute: true}})
+				if err != nil {
+					t.Fatalf("RunMixnet() error = %v", err)
+				}
+				return shuffles
+			},
+		},
+		{
+			name: "выход изменён после доказательства",
+			chain: func(t *testing.T) []*Shuffle {
+				shuffles := honest(t)
+				shuffles[0].Output[1] = a.reencrypt(shuffles[0].Output[1], big.NewInt(1))
+				return shuffles
+			},
+		},
+		{
+			name: "переставленный выход",
+			chain: func(t *testing.T) []*Shuffle {
+				shuffles := honest(t)
+				out := shuffles[1].Output
+				out[0], out[1] = out[1], out[0]
+				return shuffles
+			},
+		},
+		{
+			name: "испорченное раскрытие",
+			chain: func(t *testing.T) []*Shuffle {
+				shuffles := honest(t)
+				r := shuffles[0].Proof.Openings[3].Randomness
+				r[0] = new(big.Int).Add(r[0], big.NewInt(1))
+				return shuffles
+			},
+		},
+		{
+			name: "шифртекст вне группы",
+			chain: func(t *testing.T) []*Shuffle {
+				shuffles := honest(t)
+				shuffles[1].Output[2].A = big.NewInt(0)
+				return shuffles
+			},
+		},
+		{
+			name: "разрыв цепочки",
+			chain: func(t *testing.T) []*Shuffle {
+				shuffles := honest(t)
+				return []*Shuffle{shuffles[1]}
+			},
+		},
+		{
+			name: "серверы переставлены",
+			chain: func(t *testing.T) []*Shuffle {
+				shuffles := honest(t)
+				return []*Shuffle{shuffles[1], shuffles[0]}
+			},
+		},
+		{
+			name:   "другие входные бюллетени",
+			chain:  honest,
+			inputs: other,
+		},
+		{
+			name:  "нет перемешиваний",
+			chain: func(t *testing.T) []*Shuffle { return nil },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := input
+			if tt.inputs != nil {
+				in = tt.inputs
+			}
+			if _, err := a.VerifyMixnet(in, tt.chain(t)); !errors.Is(err, common.ErrVerification) {
+				t.Errorf("VerifyMixnet() error = %v, want %v", err, common.ErrVerification)
+			}
+		})
+	}
+}
+
+// TestMixnetThreshold - этап перемешивания с расшифрованием каждого бюллетеня комитетом
+func TestMixnetThreshold(t *testing.T) {
+	group, err := NewElectionGroup(512, 160, []byte("test"))
+	if err != nil {
+		t.Fatalf("NewElectionGroup() error = %v", err)
+	}
+	committee, err := RunDKG(group, 5, 3, map[int]Fault{2: {BadPartial: true}, 4: {Offline: true}})
+	if err != nil {
+		t.Fatalf("RunDKG() error = %v", err)
+	}
+	votes := []Vote{NO, YES, NO, ABSTAIN, NO}
+	tally := mixTestBallots(t, &committee.ElectionKey, votes)
+	ctx := context.Background()
+
+	got, err := mixAndDecrypt(ctx, committee, tally, 3, 0)
+	if err != nil {
+		t.Fatalf("mixAndDecrypt() error = %v", err)
+	}
+	if want := (map[Vote]int{YES: 1, NO: 3, ABSTAIN: 1}); !reflect.DeepEqual(got, want) {
+		t.Errorf("mixAndDecrypt() = %v, want %v", got, want)
+	}
+	if _, err = mixAndDecrypt(ctx, committee, tally, 3, 2); !errors.Is(err, common.ErrVerification) {
+		t.Errorf("mixAndDecrypt() with a cheating mix error = %v, want %v", err, common.ErrVerification)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/gost"
+	"github.com/Raimguzhinov/protect-information/lab5/voteapi"
+	"math/big"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSubmitBallot(t *testing.T) {
+	server := NewServer()
+	m := big.NewInt(4*987654321 + int64(YES))
+	signature := NewClient(server, "alice").blindSign(m.Bytes())
+	receipt, err := server.SubmitBallot(m, signature)
+	if err != nil {
+		t.Fatalf("SubmitBallot() error = %v", err)
+	}
+	if err = receipt.Verify(&server.receiptKey.PublicKey); err != nil {
+		t.Errorf("Receipt.Verify() error = %v", err)
+	}
+	if receipt.Seq != 0 || receipt.BallotHash != voteapi.BallotHash(m) {
+		t.Errorf("receipt = {Seq: %d, BallotHash: %s}, want {0, %s}", receipt.Seq, receipt.BallotHash, voteapi.BallotHash(m))
+	}
+	// Квитанция не проходит проверку чужим ключом с теми же параметрами
+	rk := server.receiptKey
+	other, err := gost.GenerateKey(rk.P, rk.Q, rk.A)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	if err = receipt.Verify(&other.PublicKey); !errors.Is(err, common.ErrVerification) {
+		t.Errorf("Receipt.Verify() with another key error = %v, want %v", err, common.ErrVerification)
+	}
+
+	tests := []struct {
+		name      string
+		message   *big.Int
+		signature *big.Int
+		wantErr   error
+	}{
+		{name: "повтор", message: m, signature: signature, wantErr: ErrAlreadyCounted},
+		{name: "изменённая подпись s + n", message: m, signature: new(big.Int).Add(signature, server.key.N), wantErr: common.ErrVerification},
+		{name: "чужая подпись", message: new(big.Int).Add(m, big.NewInt(4)), signature: signature, wantErr: common.ErrVerification},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := server.SubmitBallot(tt.message, tt.signature); !errors.Is(err, tt.wantErr) {
+				t.Errorf("SubmitBallot() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Одновременные повторы нового бюллетеня: учитывается ровно один
+	m2 := big.NewInt(4*123456789 + int64(NO))
+	signature2 := NewClient(server, "bob").blindSign(m2.Bytes())
+	var wg sync.WaitGroup
+	accepted := make(chan *voteapi.Receipt, 20)
+	for i := 0; i < cap(accepted); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if r, err := server.SubmitBallot(m2, signature2); err == nil {
+				accepted <- r
+			}
+		}()
+	}
+	wg.Wait()
+	close(accepted)
+	if len(accepted) != 1 {
+		t.Errorf("%d concurrent replays accepted, want 1", len(accepted))
+	}
+	if got, want := server.Results(), (map[Vote]int{YES: 1, NO: 1, ABSTAIN: 0}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Results() = %v, want %v", got, want)
+	}
+}
+
+func TestHTTPBulletinBoard(t *testing.T) {
+	_, baseURL := startService(t)
+	client := newTestClient(baseURL)
+	ctx := context.Background()
+	votes := []int{voteapi.Yes, voteapi.No, voteapi.Yes, voteapi.Abstain, voteapi.Yes}
+	receipts := make([]*voteapi.Receipt, len(votes))
+	for i, vote := range votes {
+		name := fmt.Sprintf("voter-%d", i)
+		token, err := client.Register(ctx, name)
+		if err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+		if receipts[i], err = client.Vote(ctx, name, token, vote); err != nil {
+			t.Fatalf("Vote() error = %v", err)
+		}
+	}
+	for i, receipt := range receipts {
+		if receipt.Seq != i {
+			t.Errorf("receipt %d Seq = %d", i, receipt.Seq)
+		}
+		if err := client.VerifyInclusion(ctx, receipt); err != nil {
+			t.Errorf("VerifyInclusion(%d) error = %v", i, err)
+		}
+	}
+
+	key, err := client.Key(ctx)
+	if err != nil {
+		t.Fatalf("Key() error = %v", err)
+	}
+	board, err := client.Board(ctx)
+	if err != nil {
+		t.Fatalf("Board() error = %v", err)
+	}
+	recount, err := voteapi.Recount(key, board)
+	if err != nil {
+		t.Fatalf("Recount() error = %v", err)
+	}
+	results, err := client.Results(ctx)
+	if err != nil {
+		t.Fatalf("Results() error = %v", err)
+	}
+	if !reflect.DeepEqual(recount, results) || recount["yes"] != 3 {
+		t.Errorf("Recount() = %v, Results() = %v, want equal with 3 yes", recount, results)
+	}
+
+	// Подделки квитанции и доски обнаруживаются
+	moved := *receipts[1]
+	moved.Seq = 0
+	if err = client.VerifyInclusion(ctx, &moved); !errors.Is(err, common.ErrVerification) {
+		t.Errorf("VerifyInclusion() of a renumbered receipt error = %v, want %v", err, common.ErrVerification)
+	}
+	tampered := append([]voteapi.BoardEntry(nil), board...)
+	tampered[2].Message = new(big.Int).Add(tampered[2].Message, big.NewInt(1))
+	tampered[2].BallotHash = voteapi.BallotHash(tampered[2].Message)
+	if _, err = voteapi.Recount(key, tampered); !errors.Is(err, common.ErrVerification) {
+		t.Errorf("Recount() of a tampered board error = %v, want %v", err, common.ErrVerification)
+	}
+	if _, err = voteapi.Recount(key, append(board, board[0])); !errors.Is(err, common.ErrVerification) {
+		t.Errorf("Recount() of a board with a repeated ballot error = %v, want %v", err, common.ErrVerification)
+	}
+}
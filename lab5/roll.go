@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/gost"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// rollHeader - столбцы CSV-списка избирателей; числа записываются в десятичном виде
+var rollHeader = []string{"username", "p", "q", "a", "y"}
+
+// VoterRoll - список избирателей, допущенных к голосованию, с их долговременными
+// открытыми ключами подписи ГОСТ. Параметры (P, Q, A) могут быть общими или своими у каждого.
+type VoterRoll struct {
+	voters map[string]*gost.PublicKey
+}
+
+// rollEntry - запись JSON-списка избирателей
+type rollEntry struct {
+	Username string   `json:"username"`
+	P        *big.Int `json:"p"`
+	Q        *big.Int `json:"q"`
+	A        *big.Int `json:"a"`
+	Y        *big.Int `json:"y"`
+}
+
+// NewVoterRoll - пустой список избирателей
+func NewVoterRoll() *VoterRoll {
+	return &VoterRoll{voters: make(map[string]*gost.PublicKey)}
+}
+
+// Add - включение избирателя в список; ключ проверяется, имя не должно повторяться
+func (roll *VoterRoll) Add(username string, key *gost.PublicKey) error {
+	if username == "" {
+		return &common.ParameterError{Name: "username", Reason: "empty username"}
+	}
+	if _, ok := roll.voters[username]; ok {
+		return &common.ParameterError{Name: "username", Reason: fmt.Sprintf("%q is listed twice", username)}
+	}
+	if err := key.Validate(); err != nil {
+		return fmt.Errorf("voter %q: %w", username, err)
+	}
+	roll.voters[username] = key
+	return nil
+}
+
+// Key - открытый ключ избирателя; false, если его нет в списке
+func (roll *VoterRoll) Key(username string) (*gost.PublicKey, bool) {
+	key, ok := roll.voters[username]
+	return key, ok
+}
+
+// Len - число избирателей в списке
+func (roll *VoterRoll) Len() int {
+	return len(roll.voters)
+}
+
+// EnrollVoters - ключи ГОСТ для избирателей names с общими параметрами и список с их
+// открытыми ключами. Закрытые ключи передаются избирателям, у сервера остаётся только список.
+func EnrollVoters(names []string) (*VoterRoll, map[string]*gost.PrivateKey, error) {
+	p, q, a, err := gost.GenerateParams()
+	if err != nil {
+		return nil, nil, err
+	}
+	roll := NewVoterRoll()
+	keys := make(map[string]*gost.PrivateKey, len(names))
+	for _, name := range names {
+		key, err := gost.GenerateKey(p, q, a)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err = roll.Add(name, &key.PublicKey); err != nil {
+			return nil, nil, err
+		}
+		keys[name] = key
+	}
+	return roll, keys, nil
+}
+
+// usernames - имена избирателей по алфавиту
+func (roll *VoterRoll) usernames() []string {
+	names := make([]string, 0, len(roll.voters))
+	for name := range roll.voters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ReadRollCSV - список из CSV с заголовком username,p,q,a,y
+func ReadRollCSV(r io.Reader) (*VoterRoll, error) {
+	records := csv.NewReader(r)
+	records.FieldsPerRecord = len(rollHeader)
+	header, err := records.Read()
+	if err != nil {
+		return nil, fmt.Errorf("voter roll header: %w", err)
+	}
+	if strings.Join(header, ",") != strings.Join(rollHeader, ",") {
+		return nil, &common.ParameterError{Name: "voter roll", Reason: fmt.Sprintf("header %v, want %v", header, rollHeader)}
+	}
+	roll := NewVoterRoll()
+	for {
+		record, err := records.Read()
+		if errors.Is(err, io.EOF) {
+			return roll, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("voter roll: %w", err)
+		}
+		line, _ := records.FieldPos(0)
+		numbers := make([]*big.Int, len(record)-1)
+		for i, field := range record[1:] {
+			var ok bool
+			if numbers[i], ok = new(big.Int).SetString(field, 10); !ok {
+				return nil, &common.ParameterError{Name: "voter roll", Reason: fmt.Sprintf("line %d: %s = %q is not a decimal number", line, rollHeader[i+1], field)}
+			}
+		}
+		key := &gost.PublicKey{P: numbers[0], Q: numbers[1], A: numbers[2], Y: numbers[3]}
+		if err = roll.Add(record[0], key); err != nil {
+			return nil, fmt.Errorf("voter roll line %d: %w", line, err)
+		}
+	}
+}
+
+// ReadRollJSON - список из JSON-массива объектов {"username", "p", "q", "a", "y"}
+func ReadRollJSON(r io.Reader) (*VoterRoll, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var entries []rollEntry
+	if err := dec.Decode(&entries); err != nil {
+		return nil, fmt.Errorf("voter roll: %w", err)
+	}
+	roll := NewVoterRoll()
+	for i, e := range entries {
+		key := &gost.PublicKey{P: e.P, Q: e.Q, A: e.A, Y: e.Y}
+		if err := roll.Add(e.Username, key); err != nil {
+			return nil, fmt.Errorf("voter roll entry %d: %w", i, err)
+		}
+	}
+	return roll, nil
+}
+
+// WriteCSV - запись списка в формате ReadRollCSV
+func (roll *VoterRoll) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(rollHeader); err != nil {
+		return err
+	}
+	for _, name := range roll.usernames() {
+		key := roll.voters[name]
+		if err := out.Write([]string{name, key.P.String(), key.Q.String(), key.A.String(), key.Y.String()}); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// WriteJSON - запись списка в формате ReadRollJSON
+func (roll *VoterRoll) WriteJSON(w io.Writer) error {
+	entries := make([]rollEntry, 0, len(roll.voters))
+	for _, name := range roll.usernames() {
+		key := roll.voters[name]
+		entries = append(entries, rollEntry{Username: name, P: key.P, Q: key.Q, A: key.A, Y: key.Y})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+// rollFormat - формат файла списка по расширению: .csv или .json
+func rollFormat(path string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv", ".json":
+		return ext, nil
+	default:
+		return "", &common.ParameterError{Name: "voter roll", Reason: fmt.Sprintf("unknown file extension %q, want .csv or .json", ext)}
+	}
+}
+
+// LoadRoll - список избирателей из файла .csv или .json
+func LoadRoll(path string) (*VoterRoll, error) {
+	format, err := rollFormat(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if format == ".csv" {
+		return ReadRollCSV(f)
+	}
+	return ReadRollJSON(f)
+}
+
+// Save - запись списка избирателей в файл .csv или .json
+func (roll *VoterRoll) Save(path string) error {
+	format, err := rollFormat(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if format == ".csv" {
+		err = roll.WriteCSV(f)
+	} else {
+		err = roll.WriteJSON(f)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/blindsig"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/gost"
+	"github.com/Raimguzhinov/protect-information/lab5/ledger"
+	"io/fs"
+	"math/big"
+	"os"
+)
+
+// serverKeyAlgorithm - значение заголовка Algorithm в файле ключей сервера
+const serverKeyAlgorithm = "lab5-server"
+
+// ExportPrivateKey - ключ слепой подписи (N, D, C) и ключ квитанций (P, Q, A, X)
+// в одном блоке брони: после перезапуска сервер продолжает тот же журнал
+func (s *Server) ExportPrivateKey() (*common.ArmorBlock, error) {
+	rk := s.receiptKey
+	var buf bytes.Buffer
+	if err := common.WriteBigNumbers(&buf, []*big.Int{s.key.N, s.key.D, s.key.C, rk.P, rk.Q, rk.A, rk.X}); err != nil {
+		return nil, err
+	}
+	return &common.ArmorBlock{
+		Type:    common.ArmorPrivateKey,
+		Headers: map[string]string{"Algorithm": serverKeyAlgorithm, "Params": "N,D,C,P,Q,A,X"},
+		Data:    buf.Bytes(),
+	}, nil
+}
+
+// importServer - сервер из блока ExportPrivateKey
+func importServer(block *common.ArmorBlock) (*Server, error) {
+	if block.Type != common.ArmorPrivateKey || block.Headers["Algorithm"] != serverKeyAlgorithm {
+		return nil, fmt.Errorf("server key: %s block for %q: %w", block.Type, block.Headers["Algorithm"], common.ErrArmorMalformed)
+	}
+	numbers, err := common.ReadBigNumbers(bytes.NewReader(block.Data))
+	if err != nil {
+		return nil, err
+	}
+	if len(numbers) != 7 {
+		return nil, fmt.Errorf("server key: %d numbers: %w", len(numbers), common.ErrArmorMalformed)
+	}
+	// Множители n восстанавливаются по (d, c); несогласованные показатели отвергаются
+	key, err := blindsig.NewPrivateKey(numbers[0], numbers[1], numbers[2])
+	if err != nil {
+		return nil, fmt.Errorf("server key: %w", err)
+	}
+	receiptKey, err := gost.NewPrivateKey(numbers[3], numbers[4], numbers[5], numbers[6])
+	if err != nil {
+		return nil, err
+	}
+	return newServer(key, receiptKey), nil
+}
+
+// LoadServer - сервер с ключами из файла path; если файла нет, ключи создаются
+// и записываются в path, а открытый ключ для аудитора - в path + ".pub"
+func LoadServer(path string) (*Server, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		server := NewServer()
+		return server, server.saveKeys(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	block, err := common.DecodeArmor(f)
+	if err != nil {
+		return nil, err
+	}
+	return importServer(block)
+}
+
+// saveKeys - запись закрытых ключей в path и открытого ключа в path + ".pub"
+func (s *Server) saveKeys(path string) error {
+	private, err := s.ExportPrivateKey()
+	if err != nil {
+		return err
+	}
+	public, err := s.ledger.Header().Key.ExportPublicKey()
+	if err != nil {
+		return err
+	}
+	if err = writeArmor(path, private, 0o600); err != nil {
+		return err
+	}
+	return writeArmor(path+".pub", public, 0o644)
+}
+
+// writeArmor - новый файл с блоком брони; существующий файл не перезаписывается
+func writeArmor(path string, block *common.ArmorBlock, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	err = common.EncodeArmor(f, block)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// OpenLedger - журнал бюллетеней в файле path вместо журнала в памяти; журнал каждых
+// выборов - в отдельном файле рядом (см. electionLedgerPath). Учтённые ранее бюллетени
+// восстанавливаются, итоги пересчитываются по журналу. Вызывается до начала приёма
+// бюллетеней, но после AddElections.
+func (s *Server) OpenLedger(path string) error {
+	l, err := ledger.Open(path, ledger.PublicKey{N: s.key.N, D: s.key.D})
+	if err != nil {
+		return err
+	}
+	opened := make(map[string]*ledger.Ledger, len(s.elections))
+	for id, st := range s.elections {
+		el, err := ledger.Open(electionLedgerPath(path, id), ledger.PublicKey{N: st.key.N, D: st.key.D})
+		if err != nil {
+			l.Close()
+			for _, o := range opened {
+				o.Close()
+			}
+			return fmt.Errorf("election %s: %w", id, err)
+		}
+		opened[id] = el
+	}
+	votes := map[Vote]int{YES: 0, NO: 0, ABSTAIN: 0}
+	for _, r := range l.Records() {
+		votes[Vote(new(big.Int).And(r.Message, big.NewInt(3)).Int64())]++
+	}
+	s.mu.Lock()
+	s.ledger = l
+	s.votes = votes
+	for id, el := range opened {
+		s.elections[id].ledger = el
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// CloseLedger - закрытие файлов журналов референдума и выборов
+func (s *Server) CloseLedger() error {
+	err := s.ledger.Close()
+	for _, st := range s.elections {
+		if closeErr := st.ledger.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
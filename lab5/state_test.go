@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/lab5/ledger"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestServerRestart - после перезапуска с теми же ключами журнал продолжается,
+// итоги восстанавливаются, учтённые бюллетени повторно не принимаются
+func TestServerRestart(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "server.key")
+	ledgerPath := filepath.Join(dir, "ledger.jsonl")
+
+	server, err := LoadServer(keyPath)
+	if err != nil {
+		t.Fatalf("LoadServer() error = %v", err)
+	}
+	if err = server.OpenLedger(ledgerPath); err != nil {
+		t.Fatalf("OpenLedger() error = %v", err)
+	}
+	type ballot struct{ m, s *big.Int }
+	var ballots []ballot
+	for i, vote := range []Vote{YES, NO, YES} {
+		m := big.NewInt(int64(4*(777+i)) + int64(vote))
+		s := NewClient(server, fmt.Sprintf("voter%d", i)).blindSign(m.Bytes())
+		if _, err = server.SubmitBallot(m, s); err != nil {
+			t.Fatalf("SubmitBallot() error = %v", err)
+		}
+		ballots = append(ballots, ballot{m, s})
+	}
+	want := server.Results()
+	if err = server.ledger.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	restarted, err := LoadServer(keyPath)
+	if err != nil {
+		t.Fatalf("LoadServer() of an existing key error = %v", err)
+	}
+	if restarted.key.N.Cmp(server.key.N) != 0 || !restarted.receiptKey.Equal(&server.receiptKey.PublicKey) {
+		t.Fatal("LoadServer() returned different keys")
+	}
+	if err = restarted.OpenLedger(ledgerPath); err != nil {
+		t.Fatalf("OpenLedger() after restart error = %v", err)
+	}
+	defer restarted.ledger.Close()
+	if got := restarted.Results(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Results() after restart = %v, want %v", got, want)
+	}
+	if _, err = restarted.SubmitBallot(ballots[1].m, ballots[1].s); !errors.Is(err, ErrAlreadyCounted) {
+		t.Errorf("SubmitBallot() of a ballot counted before restart error = %v, want %v", err, ErrAlreadyCounted)
+	}
+	m := big.NewInt(4*999 + int64(ABSTAIN))
+	receipt, err := restarted.SubmitBallot(m, NewClient(restarted, "late").blindSign(m.Bytes()))
+	if err != nil {
+		t.Fatalf("SubmitBallot() after restart error = %v", err)
+	}
+	if receipt.Seq != 3 {
+		t.Errorf("receipt Seq = %d, want 3", receipt.Seq)
+	}
+
+	// Аудитор проверяет журнал опубликованным открытым ключом
+	pub, err := os.Open(keyPath + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := common.DecodeArmor(pub)
+	pub.Close()
+	if err != nil {
+		t.Fatalf("DecodeArmor() error = %v", err)
+	}
+	key, err := ledger.ImportPublicKey(block)
+	if err != nil {
+		t.Fatalf("ImportPublicKey() error = %v", err)
+	}
+	f, err := os.Open(ledgerPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	report, err := ledger.Audit(f, &key)
+	if err != nil {
+		t.Fatalf("Audit() error = %v", err)
+	}
+	if wantVotes := (map[string]int{"yes": 2, "no": 1, "abstain": 1}); !reflect.DeepEqual(report.Votes, wantVotes) {
+		t.Errorf("Audit().Votes = %v, want %v", report.Votes, wantVotes)
+	}
+
+	// Журнал другого сервера не открывается
+	if err = NewServer().OpenLedger(ledgerPath); !errors.Is(err, common.ErrVerification) {
+		t.Errorf("OpenLedger() with another server key error = %v, want %v", err, common.ErrVerification)
+	}
+}
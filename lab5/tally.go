@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/dlog"
+	"math/big"
+	"sync"
+)
+
+// Варианты голоса в порядке кодирования: вариант i шифруется как g^(base^i)
+var options = []Vote{YES, NO, ABSTAIN}
+
+// ElectionKey - открытый ключ выборов: подгруппа простого порядка Q в Z*_P,
+// порождённая G, и ключ H = G^x. Голос m шифруется экспоненциальным Эль-Гамалем
+// (G^r, H^r * G^m), поэтому произведение шифртекстов шифрует сумму голосов.
+type ElectionKey struct {
+	P, Q, G, H *big.Int
+	Context    []byte // Идентификатор выборов, входит в хэш доказательств
+}
+
+// Authority - держатель закрытого ключа выборов; расшифровывает только итог
+type Authority struct {
+	ElectionKey
+	x *big.Int
+}
+
+// Ballot - зашифрованный бюллетень и дизъюнктивное доказательство Чаума-Педерсена
+// того, что он шифрует один из вариантов options
+type Ballot struct {
+	A, B *big.Int   // A = G^r, B = H^r * G^m
+	C, Z []*big.Int // Вызов и ответ для каждого варианта
+}
+
+// randomBelow - случайное число из [1, n)
+func randomBelow(n *big.Int) (*big.Int, error) {
+	for {
+		r, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			return nil, err
+		}
+		if r.Sign() > 0 {
+			return r, nil
+		}
+	}
+}
+
+// genSchnorrGroup - простые Q (qBits бит) и P = k*Q + 1 (pBits бит), порождающий G порядка Q
+func genSchnorrGroup(pBits, qBits int) (p, q, g *big.Int, err error) {
+	one := big.NewInt(1)
+	q = common.GenPrimeBitsBig(qBits)
+	pMin := new(big.Int).Lsh(one, uint(pBits-1))
+	kMin := new(big.Int).Quo(pMin, q)
+	kMax := new(big.Int).Quo(new(big.Int).Lsh(one, uint(pBits)), q)
+	for {
+		k, err := rand.Int(rand.Reader, new(big.Int).Sub(kMax, kMin))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		k.Add(k, kMin).SetBit(k, 0, 0) // k чётное, иначе P чётное
+		p = new(big.Int).Mul(k, q)
+		p.Add(p, one)
+		if p.Cmp(pMin) >= 0 && p.BitLen() == pBits && p.ProbablyPrime(20) {
+			// G = h^k != 1 имеет порядок Q
+			for {
+				h, err := randomBelow(p)
+				if err != nil {
+					return nil, nil, nil, err
+				}
+				if g = new(big.Int).Exp(h, k, p); g.Cmp(one) != 0 {
+					return p, q, g, nil
+				}
+			}
+		}
+	}
+}
+
+// NewElectionGroup - группа Шнорра для выборов: P длиной pBits, Q длиной qBits бит.
+// Ключ H не задан: его выбирает Authority или совместно генерируют доверенные лица.
+func NewElectionGroup(pBits, qBits int, electionContext []byte) (*ElectionKey, error) {
+	if qBits < 32 || pBits <= qBits {
+		return nil, &common.ParameterError{Name: "bits", Reason: fmt.Sprintf("group %d/%d bits is too small", pBits, qBits)}
+	}
+	p, q, g, err := genSchnorrGroup(pBits, qBits)
+	if err != nil {
+		return nil, err
+	}
+	return &ElectionKey{P: p, Q: q, G: g, Context: electionContext}, nil
+}
+
+// NewAuthority - ключ выборов H = G^x в новой группе Шнорра
+func NewAuthority(pBits, qBits int, electionContext []byte) (*Authority, error) {
+	group, err := NewElectionGroup(pBits, qBits, electionContext)
+	if err != nil {
+		return nil, err
+	}
+	x, err := randomBelow(group.Q)
+	if err != nil {
+		return nil, err
+	}
+	group.H = new(big.Int).Exp(group.G, x, group.P)
+	if common.Tracing() {
+		common.Trace("vote", "election keygen", common.Val("P", group.P), common.Val("Q", group.Q), common.Val("G", group.G), common.Val("H", group.H))
+	}
+	return &Authority{ElectionKey: *group, x: x}, nil
+}
+
+// inGroup - x лежит в подгруппе порядка Q
+func (ek *ElectionKey) inGroup(x *big.Int) bool {
+	return x != nil && x.Sign() > 0 && x.Cmp(ek.P) < 0 && new(big.Int).Exp(x, ek.Q, ek.P).Cmp(big.NewInt(1)) == 0
+}
+
+// optionValue - показатель, которым кодируется вариант с номером i: base^i.
+// base больше числа голосующих, поэтому счётчики вариантов не перетекают друг в друга.
+func optionValue(i int, base int64) *big.Int {
+	return new(big.Int).Exp(big.NewInt(base), big.NewInt(int64(i)), nil)
+}
+
+// challenge - вызов Фиата-Шамира: хэш ключа, контекста, шифртекста и обязательств
+func (ek *ElectionKey) challenge(a, b *big.Int, commitments []*big.Int) *big.Int {
+	var buf bytes.Buffer
+	values := append([]*big.Int{ek.P, ek.Q, ek.G, ek.H, new(big.Int).SetBytes(ek.Context), a, b}, commitments...)
+	_ = common.WriteBigNumbers(&buf, values) // запись в bytes.Buffer не завершается ошибкой
+	h := sha256.Sum256(buf.Bytes())
+	return new(big.Int).Mod(new(big.Int).SetBytes(h[:]), ek.Q)
+}
+
+// shifted - B / G^v mod P: для верного варианта v равно H^r
+func (ek *ElectionKey) shifted(b, v *big.Int) *big.Int {
+	gv := new(big.Int).Exp(ek.G, v, ek.P)
+	return gv.ModInverse(gv, ek.P).Mul(gv, b).Mod(gv, ek.P)
+}
+
+// EncryptVote - бюллетень для варианта vote. Для верного варианта j доказательство
+// строится честно (A_j = G^w, B_j = H^w), для остальных вызовы и ответы выбираются
+// заранее и обязательства подгоняются под них; сумма вызовов равна хэшу.
+func (ek *ElectionKey) EncryptVote(vote Vote, base int64) (*Ballot, error) {
+	j := -1
+	for i, o := range options {
+		if o == vote {
+			j = i
+		}
+	}
+	if j < 0 {
+		return nil, &common.ParameterError{Name: "vote", Reason: fmt.Sprintf("unknown option %d", vote)}
+	}
+	r, err := randomBelow(ek.Q)
+	if err != nil {
+		return nil, err
+	}
+	ballot := &Ballot{
+		A: new(big.Int).Exp(ek.G, r, ek.P),
+		C: make([]*big.Int, len(options)),
+		Z: make([]*big.Int, len(options)),
+	}
+	ballot.B = new(big.Int).Exp(ek.G, optionValue(j, base), ek.P)
+	ballot.B.Mul(ballot.B, new(big.Int).Exp(ek.H, r, ek.P)).Mod(ballot.B, ek.P)
+
+	commitments := make([]*big.Int, 0, 2*len(options))
+	var w *big.Int
+	sumC := big.NewInt(0)
+	for i := range options {
+		if i == j {
+			if w, err = randomBelow(ek.Q); err != nil {
+				return nil, err
+			}
+			commitments = append(commitments, new(big.Int).Exp(ek.G, w, ek.P), new(big.Int).Exp(ek.H, w, ek.P))
+			continue
+		}
+		if ballot.C[i], err = randomBelow(ek.Q); err != nil {
+			return nil, err
+		}
+		if ballot.Z[i], err = randomBelow(ek.Q); err != nil {
+			return nil, err
+		}
+		sumC.Add(sumC, ballot.C[i])
+		commitments = append(commitments, ek.commitments(ballot, i, base)...)
+	}
+	c := ek.challenge(ballot.A, ballot.B, commitments)
+	ballot.C[j] = c.Sub(c, sumC).Mod(c, ek.Q)
+	// z_j = w + c_j * r mod Q
+	ballot.Z[j] = new(big.Int).Mul(ballot.C[j], r)
+	ballot.Z[j].Add(ballot.Z[j], w).Mod(ballot.Z[j], ek.Q)
+	if common.Tracing() {
+		common.Trace("vote", "encrypt ballot", common.Val("A", ballot.A), common.Val("B", ballot.B), common.Val("c", ballot.C[j]))
+	}
+	return ballot, nil
+}
+
+// commitments - обязательства варианта i, восстановленные из вызова и ответа:
+// A_i = G^z / A^c, B_i = H^z / (B / G^v)^c
+func (ek *ElectionKey) commitments(ballot *Ballot, i int, base int64) []*big.Int {
+	negC := new(big.Int).Sub(ek.Q, ballot.C[i])
+	ai := new(big.Int).Exp(ek.G, ballot.Z[i], ek.P)
+	ai.Mul(ai, new(big.Int).Exp(ballot.A, negC, ek.P)).Mod(ai, ek.P)
+	bi := new(big.Int).Exp(ek.H, ballot.Z[i], ek.P)
+	bi.Mul(bi, new(big.Int).Exp(ek.shifted(ballot.B, optionValue(i, base)), negC, ek.P)).Mod(bi, ek.P)
+	return []*big.Int{ai, bi}
+}
+
+// VerifyBallot - шифртекст лежит в группе и доказательство верно
+func (ek *ElectionKey) VerifyBallot(ballot *Ballot, base int64) error {
+	if ballot == nil || !ek.inGroup(ballot.A) || !ek.inGroup(ballot.B) {
+		return fmt.Errorf("ballot is not in the election group: %w", common.ErrVerification)
+	}
+	if len(ballot.C) != len(options) || len(ballot.Z) != len(options) {
+		return fmt.Errorf("proof has %d/%d branches for %d options: %w", len(ballot.C), len(ballot.Z), len(options), common.ErrVerification)
+	}
+	commitments := make([]*big.Int, 0, 2*len(options))
+	sumC := big.NewInt(0)
+	for i := range options {
+		if ballot.C[i] == nil || ballot.Z[i] == nil || ballot.C[i].Sign() < 0 || ballot.C[i].Cmp(ek.Q) >= 0 ||
+			ballot.Z[i].Sign() < 0 || ballot.Z[i].Cmp(ek.Q) >= 0 {
+			return fmt.Errorf("proof branch %d is out of range: %w", i, common.ErrVerification)
+		}
+		sumC.Add(sumC, ballot.C[i])
+		commitments = append(commitments, ek.commitments(ballot, i, base)...)
+	}
+	if sumC.Mod(sumC, ek.Q).Cmp(ek.challenge(ballot.A, ballot.B, commitments)) != 0 {
+		return fmt.Errorf("challenges do not sum to the hash: %w", common.ErrVerification)
+	}
+	return nil
+}
+
+// Bytes - сериализация бюллетеня, которую подписывает сервер
+func (b *Ballot) Bytes() []byte {
+	var buf bytes.Buffer
+	_ = common.WriteBigNumbers(&buf, append(append([]*big.Int{b.A, b.B}, b.C...), b.Z...))
+	return buf.Bytes()
+}
+
+// Tally - гомоморфная сумма принятых бюллетеней
+type Tally struct {
+	key      *ElectionKey
+	base     int64 // основание кодирования вариантов, больше числа голосующих
+	a, b     *big.Int
+	count    int
+	accepted map[[sha256.Size]byte]bool // повторная отправка бюллетеня отклоняется
+	ballots  []Ciphertext               // принятые бюллетени без доказательств, для перемешивания
+	mu       sync.Mutex
+}
+
+// NewTally - пустой подсчёт не более чем для maxVoters бюллетеней
+func NewTally(key *ElectionKey, maxVoters int) *Tally {
+	return &Tally{
+		key:      key,
+		base:     int64(maxVoters) + 1,
+		a:        big.NewInt(1),
+		b:        big.NewInt(1),
+		accepted: make(map[[sha256.Size]byte]bool),
+	}
+}
+
+// Add - проверка бюллетеня и умножение суммы на него
+func (t *Tally) Add(ballot *Ballot) error {
+	if err := t.key.VerifyBallot(ballot, t.base); err != nil {
+		return err
+	}
+	id := sha256.Sum256(ballot.Bytes())
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.accepted[id] {
+		return fmt.Errorf("ballot already counted: %w", common.ErrVerification)
+	}
+	if int64(t.count) >= t.base-1 {
+		return &common.ParameterError{Name: "ballot", Reason: fmt.Sprintf("tally is full with %d ballots", t.count)}
+	}
+	t.accepted[id] = true
+	t.count++
+	t.ballots = append(t.ballots, Ciphertext{A: ballot.A, B: ballot.B})
+	t.a.Mul(t.a, ballot.A).Mod(t.a, t.key.P)
+	t.b.Mul(t.b, ballot.B).Mod(t.b, t.key.P)
+	return nil
+}
+
+// Ciphertexts - принятые бюллетени в порядке поступления
+func (t *Tally) Ciphertexts() []Ciphertext {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Ciphertext(nil), t.ballots...)
+}
+
+// DecryptTally - G^T = B / A^x, T = сумма count_i * base^i. Показатель T не превосходит
+// base^len(options), поэтому восстанавливается ограниченным BSGS.
+func (a *Authority) DecryptTally(ctx context.Context, t *Tally) (map[Vote]int, error) {
+	ta, tb := t.Sum()
+	return t.decode(ctx, tb, new(big.Int).Exp(ta, a.x, a.P))
+}
+
+// Sum - текущая сумма бюллетеней (A, B)
+func (t *Tally) Sum() (*big.Int, *big.Int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return new(big.Int).Set(t.a), new(big.Int).Set(t.b)
+}
+
+// decode - счётчики вариантов по компоненте B суммы и значению A^x: G^T = B / A^x
+func (t *Tally) decode(ctx context.Context, tb, ax *big.Int) (map[Vote]int, error) {
+	gT := new(big.Int).ModInverse(ax, t.key.P)
+	gT.Mul(gT, tb).Mod(gT, t.key.P)
+	results := make(map[Vote]int, len(options))
+	if gT.Cmp(big.NewInt(1)) == 0 {
+		for _, o := range options {
+			results[o] = 0
+		}
+		return results, nil
+	}
+	bound := optionValue(len(options), t.base)
+	total, err := (&dlog.BSGS{}).Solve(ctx, dlog.Problem{G: t.key.G, H: gT, P: t.key.P, Order: bound})
+	if errors.Is(err, common.ErrDiscreteLogNotFound) {
+		return nil, fmt.Errorf("tally exceeds %s: %w", bound, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if common.Tracing() {
+		common.Trace("vote", "decrypt tally", common.Val("G^T", gT), common.Val("T", total))
+	}
+	// Цифры T в системе счисления с основанием base - счётчики вариантов
+	b := big.NewInt(t.base)
+	for _, o := range options {
+		digit := new(big.Int)
+		total.QuoRem(total, b, digit)
+		results[o] = int(digit.Int64())
+	}
+	return results, nil
+}
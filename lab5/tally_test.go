@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+	mrand "math/rand"
+	"testing"
+)
+
+func newTestAuthority(t *testing.T) *Authority {
+	t.Helper()
+	a, err := NewAuthority(512, 160, []byte("test"))
+	if err != nil {
+		t.Fatalf("NewAuthority() error = %v", err)
+	}
+	return a
+}
+
+func TestTally(t *testing.T) {
+	a := newTestAuthority(t)
+	rnd := mrand.New(mrand.NewSource(1))
+	tests := []struct {
+		name      string
+		voters    int
+		maxVoters int
+	}{
+		{name: "без бюллетеней", voters: 0, maxVoters: 10},
+		{name: "один голос", voters: 1, maxVoters: 10},
+		{name: "все места заняты", voters: 20, maxVoters: 20},
+		{name: "сотня голосов", voters: 100, maxVoters: 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tally := NewTally(&a.ElectionKey, tt.maxVoters)
+			want := map[Vote]int{YES: 0, NO: 0, ABSTAIN: 0}
+			for i := 0; i < tt.voters; i++ {
+				v := options[rnd.Intn(len(options))]
+				ballot, err := a.EncryptVote(v, tally.base)
+				if err != nil {
+					t.Fatalf("EncryptVote(%d) error = %v", v, err)
+				}
+				if err = tally.Add(ballot); err != nil {
+					t.Fatalf("Add() error = %v", err)
+				}
+				want[v]++
+			}
+			got, err := a.DecryptTally(context.Background(), tally)
+			if err != nil {
+				t.Fatalf("DecryptTally() error = %v", err)
+			}
+			for _, o := range options {
+				if got[o] != want[o] {
+					t.Errorf("DecryptTally()[%d] = %d, want %d", o, got[o], want[o])
+				}
+			}
+		})
+	}
+}
+
+func TestBallotRejected(t *testing.T) {
+	a := newTestAuthority(t)
+	tally := NewTally(&a.ElectionKey, 1)
+	valid, err := a.EncryptVote(NO, tally.base)
+	if err != nil {
+		t.Fatalf("EncryptVote() error = %v", err)
+	}
+	clone := func() *Ballot {
+		return &Ballot{
+			A: new(big.Int).Set(valid.A), B: new(big.Int).Set(valid.B),
+			C: append([]*big.Int(nil), valid.C...), Z: append([]*big.Int(nil), valid.Z...),
+		}
+	}
+	// Бюллетень за "два голоса": B умножен на G, доказательство прежнее
+	double := clone()
+	double.B.Mul(double.B, a.G).Mod(double.B, a.P)
+	// Вызов перенесён между ветвями: сумма та же, но обязательства меняются
+	shifted := clone()
+	shifted.C[0] = new(big.Int).Add(shifted.C[0], big.NewInt(1))
+	shifted.C[0].Mod(shifted.C[0], a.Q)
+	shifted.C[1] = new(big.Int).Sub(shifted.C[1], big.NewInt(1))
+	shifted.C[1].Mod(shifted.C[1], a.Q)
+	// Элемент вне подгруппы порядка Q
+	outside := clone()
+	outside.A = new(big.Int).Sub(a.P, big.NewInt(1))
+	truncated := clone()
+	truncated.Z = truncated.Z[:2]
+	_, errOption := a.EncryptVote(Vote(7), tally.base)
+
+	tests := []struct {
+		name   string
+		ballot *Ballot
+	}{
+		{name: "шифрует недопустимое значение", ballot: double},
+		{name: "изменены вызовы", ballot: shifted},
+		{name: "вне группы", ballot: outside},
+		{name: "неполное доказательство", ballot: truncated},
+		{name: "пустой бюллетень", ballot: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tally.Add(tt.ballot); !errors.Is(err, common.ErrVerification) {
+				t.Errorf("Add() error = %v, want %v", err, common.ErrVerification)
+			}
+		})
+	}
+	if !errors.Is(errOption, common.ErrInvalidParameters) {
+		t.Errorf("EncryptVote(7) error = %v, want %v", errOption, common.ErrInvalidParameters)
+	}
+
+	if err = tally.Add(valid); err != nil {
+		t.Fatalf("Add(valid) error = %v", err)
+	}
+	if err = tally.Add(valid); !errors.Is(err, common.ErrVerification) {
+		t.Errorf("Add(valid) again error = %v, want %v", err, common.ErrVerification)
+	}
+	other, _ := a.EncryptVote(YES, tally.base)
+	if err = tally.Add(other); !errors.Is(err, common.ErrInvalidParameters) {
+		t.Errorf("Add() beyond maxVoters error = %v, want %v", err, common.ErrInvalidParameters)
+	}
+}
+
+// TestSubmitEncryptedVote - сервер принимает подписанные бюллетени и не принимает
+// бюллетень, подпись которого получена для другого бюллетеня
+func TestSubmitEncryptedVote(t *testing.T) {
+	server := NewServer()
+	a := newTestAuthority(t)
+	server.tally = NewTally(&a.ElectionKey, 10)
+	NewClient(server, "Alice").VoteEncrypted(YES)
+	NewClient(server, "Bob").VoteEncrypted(ABSTAIN)
+
+	mallory := NewClient(server, "Mallory")
+	ballot, _ := a.EncryptVote(NO, server.tally.base)
+	signature := mallory.blindSign(ballot.Bytes())
+	forged, _ := a.EncryptVote(NO, server.tally.base)
+	if server.SubmitEncryptedVote(forged, signature) {
+		t.Error("SubmitEncryptedVote() accepted a ballot with someone else's signature")
+	}
+	got, err := a.DecryptTally(context.Background(), server.tally)
+	if err != nil {
+		t.Fatalf("DecryptTally() error = %v", err)
+	}
+	if got[YES] != 1 || got[NO] != 0 || got[ABSTAIN] != 1 {
+		t.Errorf("DecryptTally() = %v, want 1 yes, 0 no, 1 abstain", got)
+	}
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+	"sort"
+	"sync"
+)
+
+// Fault - нечестное поведение доверенного лица в тестах и демонстрации
+type Fault struct {
+	BadShares  []int // Номера получателей, которым при DKG отправляется неверная доля
+	Stubborn   bool  // На жалобу отвечает той же неверной долей
+	BadPartial bool  // Публикует неверную частичную расшифровку
+	Offline    bool  // Не участвует в расшифровании итога
+}
+
+// Trustee - доверенное лицо с номером ID (1..n): хранит только свою долю x_i
+// общего закрытого ключа x = sum f_d(0)
+type Trustee struct {
+	ID    int
+	share *big.Int
+	fault Fault
+}
+
+// Committee - результат распределённой генерации ключа (DKG Педерсена):
+// общий ключ H = G^x, проверочные ключи Y_i = G^x_i и множество честных дилеров
+type Committee struct {
+	ElectionKey
+	T            int              // Порог: столько частичных расшифровок нужно для итога
+	Qual         []int            // Дилеры, не дисквалифицированные при DKG
+	Verification map[int]*big.Int // Y_i = G^x_i для каждого доверенного лица
+	trustees     []*Trustee
+}
+
+// PartialDecryption - D_i = A^x_i и доказательство Чаума-Педерсена log_G Y_i = log_A D_i
+type PartialDecryption struct {
+	ID   int
+	D    *big.Int
+	C, Z *big.Int
+}
+
+// dkgMessage - сообщение DKG. Обязательства, жалобы и раскрытые доли рассылаются
+// всем одинаково (надёжная широковещательная рассылка), доля - лично получателю.
+type dkgMessage struct {
+	from        int
+	commitments []*big.Int       // раунд 1: C_k = G^a_k для коэффициентов многочлена
+	share       *big.Int         // раунд 1: f(получатель)
+	complaints  []int            // раунд 2: дилеры, приславшие неверную долю
+	revealed    map[int]*big.Int // раунд 3: доли, раскрытые в ответ на жалобы
+}
+
+// dkgResult - то, что доверенное лицо узнало по итогам DKG
+type dkgResult struct {
+	id           int
+	share        *big.Int
+	h            *big.Int
+	qual         []int
+	verification map[int]*big.Int
+}
+
+// evalCommitments - G^f(j) = prod C_k^(j^k), проверка доли без знания многочлена
+func (ek *ElectionKey) evalCommitments(commitments []*big.Int, j int) *big.Int {
+	res := big.NewInt(1)
+	jk := big.NewInt(1)
+	for _, c := range commitments {
+		res.Mul(res, new(big.Int).Exp(c, jk, ek.P)).Mod(res, ek.P)
+		jk.Mul(jk, big.NewInt(int64(j))).Mod(jk, ek.Q)
+	}
+	return res
+}
+
+// validShare - G^share = G^f(j) по обязательствам дилера
+func (ek *ElectionKey) validShare(commitments []*big.Int, j int, share *big.Int) bool {
+	return share != nil && new(big.Int).Exp(ek.G, share, ek.P).Cmp(ek.evalCommitments(commitments, j)) == 0
+}
+
+// runDKG - протокол одного доверенного лица с многочленом coeffs степени t-1.
+// rounds[r][j] - входящие раунда r для лица j+1.
+func (ek *ElectionKey) runDKG(id, n int, coeffs []*big.Int, fault Fault, rounds [3][]chan dkgMessage) *dkgResult {
+	t := len(coeffs)
+	broadcast := func(round int, msg dkgMessage) {
+		for _, inbox := range rounds[round] {
+			inbox <- msg
+		}
+	}
+	// Раунд 1: обязательства C_k = G^a_k и доли f(j)
+	commitments := make([]*big.Int, t)
+	for k, a := range coeffs {
+		commitments[k] = new(big.Int).Exp(ek.G, a, ek.P)
+	}
+	eval := func(j int) *big.Int {
+		// Схема Горнера по модулю Q
+		s := big.NewInt(0)
+		for k := t - 1; k >= 0; k-- {
+			s.Mul(s, big.NewInt(int64(j))).Add(s, coeffs[k]).Mod(s, ek.Q)
+		}
+		return s
+	}
+	shareFor := func(j int) *big.Int {
+		s := eval(j)
+		for _, bad := range fault.BadShares {
+			if bad == j {
+				s.Add(s, big.NewInt(1)).Mod(s, ek.Q)
+			}
+		}
+		return s
+	}
+	for j := 1; j <= n; j++ {
+		rounds[0][j-1] <- dkgMessage{from: id, commitments: commitments, share: shareFor(j)}
+	}
+	dealt := make(map[int]dkgMessage, n)
+	var complaints []int
+	for i := 0; i < n; i++ {
+		msg := <-rounds[0][id-1]
+		dealt[msg.from] = msg
+		if len(msg.commitments) != t || !ek.validShare(msg.commitments, id, msg.share) {
+			complaints = append(complaints, msg.from)
+		}
+	}
+	// Раунд 2: жалобы на дилеров
+	broadcast(1, dkgMessage{from: id, complaints: complaints})
+	against := make(map[int][]int) // дилер -> пожаловавшиеся
+	for i := 0; i < n; i++ {
+		msg := <-rounds[1][id-1]
+		for _, d := range msg.complaints {
+			against[d] = append(against[d], msg.from)
+		}
+	}
+	// Раунд 3: дилер раскрывает доли пожаловавшихся; упрямый повторяет неверную
+	revealed := make(map[int]*big.Int)
+	for _, j := range against[id] {
+		if fault.Stubborn {
+			revealed[j] = shareFor(j)
+		} else {
+			revealed[j] = eval(j)
+		}
+	}
+	broadcast(2, dkgMessage{from: id, revealed: revealed})
+	answers := make(map[int]map[int]*big.Int, n)
+	for i := 0; i < n; i++ {
+		msg := <-rounds[2][id-1]
+		answers[msg.from] = msg.revealed
+	}
+	// QUAL: дилер дисквалифицируется, если хотя бы одна раскрытая доля неверна.
+	// Решение зависит только от разосланных всем данных, поэтому у честных лиц совпадает.
+	res := &dkgResult{id: id, share: big.NewInt(0), h: big.NewInt(1), verification: make(map[int]*big.Int, n)}
+	for d := 1; d <= n; d++ {
+		msg := dealt[d]
+		ok := len(msg.commitments) == t
+		for _, j := range against[d] {
+			ok = ok && ek.validShare(msg.commitments, j, answers[d][j])
+		}
+		if !ok {
+			continue
+		}
+		res.qual = append(res.qual, d)
+		share := msg.share
+		if s, complained := answers[d][id]; complained {
+			share = s
+		}
+		res.share.Add(res.share, share).Mod(res.share, ek.Q)
+		res.h.Mul(res.h, msg.commitments[0]).Mod(res.h, ek.P)
+	}
+	// Y_j = prod_{d in QUAL} G^f_d(j)
+	for j := 1; j <= n; j++ {
+		y := big.NewInt(1)
+		for _, d := range res.qual {
+			y.Mul(y, ek.evalCommitments(dealt[d].commitments, j)).Mod(y, ek.P)
+		}
+		res.verification[j] = y
+	}
+	return res
+}
+
+// RunDKG - распределённая генерация ключа выборов n доверенными лицами с порогом t
+// в группе group. Каждое лицо - отдельная горутина, сообщения идут по каналам.
+// faults задаёт нечестное поведение отдельных лиц (по номеру).
+func RunDKG(group *ElectionKey, n, t int, faults map[int]Fault) (*Committee, error) {
+	if t < 1 || t > n {
+		return nil, &common.ParameterError{Name: "t", Reason: fmt.Sprintf("threshold %d is not in [1, %d]", t, n)}
+	}
+	var rounds [3][]chan dkgMessage
+	for r := range rounds {
+		rounds[r] = make([]chan dkgMessage, n)
+		for j := range rounds[r] {
+			rounds[r][j] = make(chan dkgMessage, n)
+		}
+	}
+	// Случайные многочлены выбираются заранее: ошибка посреди протокола оставила бы
+	// остальных участников ждать сообщений
+	polys := make([][]*big.Int, n)
+	for i := range polys {
+		polys[i] = make([]*big.Int, t)
+		for k := range polys[i] {
+			a, err := randomBelow(group.Q)
+			if err != nil {
+				return nil, err
+			}
+			polys[i][k] = a
+		}
+	}
+	results := make([]*dkgResult, n)
+	var wg sync.WaitGroup
+	for id := 1; id <= n; id++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			results[id-1] = group.runDKG(id, n, polys[id-1], faults[id], rounds)
+		}(id)
+	}
+	wg.Wait()
+	// Честные участники должны прийти к одному ключу
+	first := results[0]
+	for _, res := range results[1:] {
+		if res.h.Cmp(first.h) != 0 || len(res.qual) != len(first.qual) {
+			return nil, fmt.Errorf("trustees %d and %d disagree on the election key: %w", first.id, res.id, common.ErrVerification)
+		}
+	}
+	if len(first.qual) < t {
+		return nil, fmt.Errorf("only %d of %d dealers qualified, threshold %d: %w", len(first.qual), n, t, common.ErrVerification)
+	}
+	committee := &Committee{
+		ElectionKey:  *group,
+		T:            t,
+		Qual:         first.qual,
+		Verification: first.verification,
+	}
+	committee.H = first.h
+	for _, res := range results {
+		committee.trustees = append(committee.trustees, &Trustee{ID: res.id, share: res.share, fault: faults[res.id]})
+	}
+	if common.Tracing() {
+		common.Trace("vote", "dkg", common.ValInt("n", int64(n)), common.ValInt("t", int64(t)),
+			common.ValInt("qual", int64(len(first.qual))), common.Val("H", committee.H))
+	}
+	return committee, nil
+}
+
+// partialChallenge - вызов Фиата-Шамира для доказательства частичной расшифровки
+func (c *Committee) partialChallenge(id int, a, d, y, a1, a2 *big.Int) *big.Int {
+	var buf bytes.Buffer
+	_ = common.WriteBigNumbers(&buf, []*big.Int{c.P, c.Q, c.G, new(big.Int).SetBytes(c.Context),
+		big.NewInt(int64(id)), a, d, y, a1, a2})
+	h := sha256.Sum256(buf.Bytes())
+	return new(big.Int).Mod(new(big.Int).SetBytes(h[:]), c.Q)
+}
+
+// partialDecrypt - D_i = A^x_i с доказательством: w случайно, a1 = G^w, a2 = A^w, z = w + c*x_i
+func (c *Committee) partialDecrypt(tr *Trustee, a *big.Int) (*PartialDecryption, error) {
+	w, err := randomBelow(c.Q)
+	if err != nil {
+		return nil, err
+	}
+	pd := &PartialDecryption{ID: tr.ID, D: new(big.Int).Exp(a, tr.share, c.P)}
+	pd.C = c.partialChallenge(tr.ID, a, pd.D, c.Verification[tr.ID], new(big.Int).Exp(c.G, w, c.P), new(big.Int).Exp(a, w, c.P))
+	pd.Z = new(big.Int).Mul(pd.C, tr.share)
+	pd.Z.Add(pd.Z, w).Mod(pd.Z, c.Q)
+	if tr.fault.BadPartial {
+		pd.D.Mul(pd.D, c.G).Mod(pd.D, c.P)
+	}
+	return pd, nil
+}
+
+// VerifyPartial - проверка доказательства: a1 = G^z / Y_i^c, a2 = A^z / D_i^c
+func (c *Committee) VerifyPartial(a *big.Int, pd *PartialDecryption) error {
+	y, ok := c.Verification[pd.ID]
+	if !ok || pd.D == nil || pd.C == nil || pd.Z == nil || !c.inGroup(pd.D) {
+		return fmt.Errorf("partial decryption of trustee %d is malformed: %w", pd.ID, common.ErrVerification)
+	}
+	negC := new(big.Int).Sub(c.Q, new(big.Int).Mod(pd.C, c.Q))
+	a1 := new(big.Int).Exp(c.G, pd.Z, c.P)
+	a1.Mul(a1, new(big.Int).Exp(y, negC, c.P)).Mod(a1, c.P)
+	a2 := new(big.Int).Exp(a, pd.Z, c.P)
+	a2.Mul(a2, new(big.Int).Exp(pd.D, negC, c.P)).Mod(a2, c.P)
+	if c.partialChallenge(pd.ID, a, pd.D, y, a1, a2).Cmp(pd.C) != 0 {
+		return fmt.Errorf("proof of trustee %d does not verify: %w", pd.ID, common.ErrVerification)
+	}
+	return nil
+}
+
+// Combine - A^x = prod D_i^λ_i по t частичным расшифровкам, λ_i = prod_{j != i} j / (j - i) mod Q
+func (c *Committee) Combine(partials []*PartialDecryption) (*big.Int, error) {
+	if len(partials) < c.T {
+		return nil, fmt.Errorf("%d partial decryptions, threshold %d: %w", len(partials), c.T, common.ErrVerification)
+	}
+	partials = partials[:c.T]
+	ax := big.NewInt(1)
+	for _, pi := range partials {
+		num, den := big.NewInt(1), big.NewInt(1)
+		for _, pj := range partials {
+			if pj.ID == pi.ID {
+				continue
+			}
+			num.Mul(num, big.NewInt(int64(pj.ID))).Mod(num, c.Q)
+			den.Mul(den, big.NewInt(int64(pj.ID-pi.ID))).Mod(den, c.Q)
+		}
+		inv, err := common.ModInverseBig(den, c.Q)
+		if err != nil {
+			return nil, err
+		}
+		lambda := num.Mul(num, inv).Mod(num, c.Q)
+		ax.Mul(ax, new(big.Int).Exp(pi.D, lambda, c.P)).Mod(ax, c.P)
+	}
+	return ax, nil
+}
+
+// DecryptTally - расшифрование суммы бюллетеней комитетом (см. decryptA)
+func (c *Committee) DecryptTally(ctx context.Context, t *Tally) (map[Vote]int, error) {
+	ta, tb := t.Sum()
+	ax, err := c.decryptA(ta)
+	if err != nil {
+		return nil, err
+	}
+	return t.decode(ctx, tb, ax)
+}
+
+// decryptA - доверенные лица параллельно публикуют частичные расшифровки A;
+// неверные отбрасываются, из первых t верных собирается A^x
+func (c *Committee) decryptA(ta *big.Int) (*big.Int, error) {
+	published := make(chan *PartialDecryption)
+	errs := make(chan error, len(c.trustees))
+	var wg sync.WaitGroup
+	for _, tr := range c.trustees {
+		if tr.fault.Offline {
+			continue
+		}
+		wg.Add(1)
+		go func(tr *Trustee) {
+			defer wg.Done()
+			pd, err := c.partialDecrypt(tr, ta)
+			if err != nil {
+				errs <- err
+				return
+			}
+			published <- pd
+		}(tr)
+	}
+	go func() {
+		wg.Wait()
+		close(published)
+	}()
+	var valid []*PartialDecryption
+	for pd := range published {
+		if err := c.VerifyPartial(ta, pd); err != nil {
+			if common.Tracing() {
+				common.Trace("vote", "reject partial", common.ValInt("trustee", int64(pd.ID)))
+			}
+			continue
+		}
+		valid = append(valid, pd)
+	}
+	select {
+	case err := <-errs:
+		return nil, err
+	default:
+	}
+	// Порядок прихода случаен; сортировка делает выбор t лиц воспроизводимым
+	sort.Slice(valid, func(i, j int) bool { return valid[i].ID < valid[j].ID })
+	return c.Combine(valid)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestThresholdTally(t *testing.T) {
+	group, err := NewElectionGroup(512, 160, []byte("test"))
+	if err != nil {
+		t.Fatalf("NewElectionGroup() error = %v", err)
+	}
+	votes := []Vote{YES, YES, NO, ABSTAIN, YES, NO}
+	tests := []struct {
+		name     string
+		faults   map[int]Fault
+		wantQual []int
+		wantErr  error
+	}{
+		{
+			name:     "все честные",
+			wantQual: []int{1, 2, 3, 4, 5},
+		},
+		{
+			name:     "неверная доля, исправленная после жалобы",
+			faults:   map[int]Fault{2: {BadShares: []int{4}}},
+			wantQual: []int{1, 2, 3, 4, 5},
+		},
+		{
+			name:     "упрямый дилер дисквалифицирован",
+			faults:   map[int]Fault{2: {BadShares: []int{1, 3}, Stubborn: true}},
+			wantQual: []int{1, 3, 4, 5},
+		},
+		{
+			name:     "неверная частичная расшифровка и отсутствие",
+			faults:   map[int]Fault{1: {BadPartial: true}, 5: {Offline: true}},
+			wantQual: []int{1, 2, 3, 4, 5},
+		},
+		{
+			name:     "нечестных больше, чем n - t",
+			faults:   map[int]Fault{1: {BadPartial: true}, 2: {Offline: true}, 4: {BadPartial: true, BadShares: []int{3}, Stubborn: true}},
+			wantQual: []int{1, 2, 3, 5},
+			wantErr:  common.ErrVerification,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			committee, err := RunDKG(group, 5, 3, tt.faults)
+			if err != nil {
+				t.Fatalf("RunDKG() error = %v", err)
+			}
+			if !reflect.DeepEqual(committee.Qual, tt.wantQual) {
+				t.Errorf("Qual = %v, want %v", committee.Qual, tt.wantQual)
+			}
+			tally := NewTally(&committee.ElectionKey, 10)
+			want := map[Vote]int{YES: 0, NO: 0, ABSTAIN: 0}
+			for _, v := range votes {
+				ballot, err := committee.EncryptVote(v, tally.base)
+				if err != nil {
+					t.Fatalf("EncryptVote() error = %v", err)
+				}
+				if err = tally.Add(ballot); err != nil {
+					t.Fatalf("Add() error = %v", err)
+				}
+				want[v]++
+			}
+			got, err := committee.DecryptTally(context.Background(), tally)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DecryptTally() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !reflect.DeepEqual(got, want) {
+				t.Errorf("DecryptTally() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// TestThresholdShares - доли согласованы с общим ключом: любые t проверочных ключей
+// дают H интерполяцией в показателе, а меньше t частичных расшифровок не принимаются
+func TestThresholdShares(t *testing.T) {
+	group, err := NewElectionGroup(512, 160, []byte("test"))
+	if err != nil {
+		t.Fatalf("NewElectionGroup() error = %v", err)
+	}
+	if _, err = RunDKG(group, 3, 4, nil); !errors.Is(err, common.ErrInvalidParameters) {
+		t.Errorf("RunDKG(n=3, t=4) error = %v, want %v", err, common.ErrInvalidParameters)
+	}
+	committee, err := RunDKG(group, 4, 2, nil)
+	if err != nil {
+		t.Fatalf("RunDKG() error = %v", err)
+	}
+	for _, ids := range [][]int{{1, 2}, {2, 4}, {3, 1}} {
+		// Y_i = G^x_i играют роль частичных расшифровок A = G
+		partials := make([]*PartialDecryption, len(ids))
+		for i, id := range ids {
+			partials[i] = &PartialDecryption{ID: id, D: committee.Verification[id]}
+		}
+		h, err := committee.Combine(partials)
+		if err != nil {
+			t.Fatalf("Combine(%v) error = %v", ids, err)
+		}
+		if h.Cmp(committee.H) != 0 {
+			t.Errorf("Combine(%v) = %s, want H = %s", ids, h, committee.H)
+		}
+	}
+
+	ballot, err := committee.EncryptVote(YES, 2)
+	if err != nil {
+		t.Fatalf("EncryptVote() error = %v", err)
+	}
+	pd, err := committee.partialDecrypt(committee.trustees[0], ballot.A)
+	if err != nil {
+		t.Fatalf("partialDecrypt() error = %v", err)
+	}
+	if err = committee.VerifyPartial(ballot.A, pd); err != nil {
+		t.Errorf("VerifyPartial() error = %v", err)
+	}
+	if _, err = committee.Combine([]*PartialDecryption{pd}); !errors.Is(err, common.ErrVerification) {
+		t.Errorf("Combine() of one partial error = %v, want %v", err, common.ErrVerification)
+	}
+	// Доказательство привязано к лицу: чужой номер его не проходит
+	stolen := &PartialDecryption{ID: 2, D: pd.D, C: pd.C, Z: pd.Z}
+	if err = committee.VerifyPartial(ballot.A, stolen); !errors.Is(err, common.ErrVerification) {
+		t.Errorf("VerifyPartial() of a relabelled partial error = %v, want %v", err, common.ErrVerification)
+	}
+	if err = committee.VerifyPartial(ballot.A, &PartialDecryption{ID: 9, D: big.NewInt(1), C: pd.C, Z: pd.Z}); !errors.Is(err, common.ErrVerification) {
+		t.Errorf("VerifyPartial() of an unknown trustee error = %v, want %v", err, common.ErrVerification)
+	}
+}
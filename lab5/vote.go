@@ -1,12 +1,25 @@
 package main
 
 import (
-	"crypto/sha512"
+	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
 	"fmt"
+	"github.com/Raimguzhinov/protect-information/blindsig"
 	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/gost"
+	"github.com/Raimguzhinov/protect-information/lab5/election"
+	"github.com/Raimguzhinov/protect-information/lab5/ledger"
+	"github.com/Raimguzhinov/protect-information/lab5/voteapi"
 	"log"
 	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 )
 
 // Тип для представления варианта голоса
@@ -20,94 +33,189 @@ const (
 
 // Структура сервера
 type Server struct {
-	n     *big.Int        // Модуль RSA
-	d     *big.Int        // Публичный экспонент
-	c     *big.Int        // Приватный экспонент
-	voted map[string]bool // Отслеживание проголосовавших пользователей
-	votes map[Vote]int    // Результаты голосования
-	mu    sync.Mutex      // Мьютекс для синхронизации
+	key        *blindsig.PrivateKey // Ключ слепой подписи RSA (N, D, C)
+	voted      map[string]bool      // Отслеживание проголосовавших пользователей
+	votes      map[Vote]int         // Результаты голосования
+	ledger     *ledger.Ledger       // Учтённые бюллетени: повторная отправка не засчитывается
+	tally      *Tally               // Гомоморфный подсчёт; nil - голоса считаются открыто
+	receiptKey *gost.PrivateKey     // Ключ подписи квитанций; отличен от ключа слепой подписи
+	mu         sync.Mutex           // Мьютекс для синхронизации
+
+	roll   *VoterRoll                 // Список избирателей; nil - имя в запросе не проверяется
+	audit  *AuditLog                  // Журнал решений по запросам подписи
+	issued map[string]issuedSignature // Выданные подписи по именам избирателей
+
+	elections map[string]*electionState // Выборы из файла определений
+	now       func() time.Time          // Часы для проверки времени проведения выборов
+
+	// logf получает сообщения о решениях по голосам в демонстрации в одном процессе;
+	// nil - сообщения не выводятся (HTTP-сервис отвечает клиенту и пишет журнал аудита)
+	logf func(format string, args ...any)
 }
 
 // Создание нового сервера
 func NewServer() *Server {
-	// Генерируем простые числа p и q
-	bitSize := 1024
-	minV := new(big.Int).Lsh(big.NewInt(1), uint(bitSize-1))
-	maxV := new(big.Int).Lsh(big.NewInt(1), uint(bitSize))
-
-	p := common.GenPrimeBig(minV, maxV)
-	q := common.GenPrimeBig(minV, maxV)
-	for p.Cmp(q) == 0 {
-		q = common.GenPrimeBig(minV, maxV)
+	// Ключ слепой подписи с 2048-битным модулем
+	key, err := blindsig.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatalf("Ошибка при генерации ключа слепой подписи: %v", err)
 	}
 
-	// Вычисляем n = p * q и φ(n) = (p - 1) * (q - 1)
-	n := new(big.Int).Mul(p, q)
-	phi := new(big.Int).Mul(new(big.Int).Sub(p, big.NewInt(1)), new(big.Int).Sub(q, big.NewInt(1)))
-
-	// Выбираем d, взаимно простое с φ(n)
-	d := common.GenCoprimeBig(phi, big.NewInt(3), phi)
-
-	// Вычисляем c, обратное к d по модулю φ(n)
-	c, err := common.ModInverseBig(d, phi)
+	// Отдельный ключ ГОСТ для квитанций
+	gp, gq, ga, err := gost.GenerateParams()
 	if err != nil {
-		log.Fatalf("Ошибка при вычислении c: %v", err)
+		log.Fatalf("Ошибка при генерации параметров ключа квитанций: %v", err)
 	}
+	receiptKey, err := gost.GenerateKey(gp, gq, ga)
+	if err != nil {
+		log.Fatalf("Ошибка при генерации ключа квитанций: %v", err)
+	}
+	return newServer(key, receiptKey)
+}
 
+// Сервер с заданными ключами слепой подписи и квитанций
+func newServer(key *blindsig.PrivateKey, receiptKey *gost.PrivateKey) *Server {
 	return &Server{
-		n:     n,
-		d:     d,
-		c:     c,
-		voted: make(map[string]bool),
-		votes: map[Vote]int{YES: 0, NO: 0, ABSTAIN: 0},
+		key:        key,
+		voted:      make(map[string]bool),
+		ledger:     ledger.New(ledger.PublicKey{N: key.N, D: key.D}),
+		receiptKey: receiptKey,
+		votes:      map[Vote]int{YES: 0, NO: 0, ABSTAIN: 0},
+		elections:  make(map[string]*electionState),
+		now:        time.Now,
 	}
 }
 
-// Метод сервера для подписи слепого сообщения
+// Метод сервера для подписи слепого сообщения. При загруженном списке избирателей
+// запрос без подписи избирателя отклоняется (см. GetAuthenticatedBlindSignature).
 func (s *Server) GetBlindSignature(username string, blindedHash *big.Int) *big.Int {
+	if s.roll != nil {
+		s.audit.Record(username, blindedHash, AuditUnauthenticated)
+		s.printf("Сервер: Запрос пользователя %s не подписан", username)
+		return nil
+	}
+	if blindedHash == nil || blindedHash.Sign() <= 0 || blindedHash.Cmp(s.key.N) >= 0 {
+		s.printf("Сервер: Запрос пользователя %s вне [1, n)", username)
+		return nil
+	}
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	if s.voted[username] {
-		fmt.Printf("Сервер: Пользователь %s уже голосовал\n", username)
+		s.mu.Unlock()
+		s.printf("Сервер: Пользователь %s уже голосовал", username)
 		return nil
 	}
 
 	// Отмечаем, что пользователь проголосовал
 	s.voted[username] = true
+	s.mu.Unlock()
 
-	// Подписываем слепое сообщение
-	signature := common.ModularExponentiationBig(blindedHash, s.c, s.n)
+	// Подписываем слепое сообщение; ключ не меняется, поэтому блокировка не нужна
+	signature, err := s.key.BlindSign(blindedHash)
+	if err != nil {
+		s.printf("Сервер: Ошибка при подписи: %v", err)
+		return nil
+	}
 	return signature
 }
 
+// Проверка подписи сервера под сообщением
+func (s *Server) verifySignature(message []byte, signature *big.Int) bool {
+	return s.key.Verify(message, signature) == nil
+}
+
 // Метод сервера для проверки и учёта голоса
 func (s *Server) SubmitVote(voteValue *big.Int, signature *big.Int) bool {
-	// Вычисляем хэш от голоса
-	hash := sha512.Sum512(voteValue.Bytes())
-	hashInt := new(big.Int).SetBytes(hash[:])
+	if _, err := s.SubmitBallot(voteValue, signature); err != nil {
+		s.printf("Сервер: Голос отклонён: %v", err)
+		return false
+	}
+	s.printf("Сервер: Голос принят")
+	return true
+}
 
-	// Проверяем подпись
-	expectedHash := common.ModularExponentiationBig(signature, s.d, s.n)
+// ErrAlreadyCounted - бюллетень с тем же хэшем уже учтён
+var ErrAlreadyCounted = errors.New("ballot already counted")
 
-	if hashInt.Cmp(expectedHash) == 0 {
-		// Извлекаем значение голоса (1 - YES, 2 - NO, 3 - ABSTAIN)
-		voteInt := new(big.Int).And(voteValue, big.NewInt(3)).Int64()
-		s.mu.Lock()
+// SubmitBallot - проверка подписи, атомарная запись в журнал по хэшу бюллетеня
+// и учёт голоса; в ответ - квитанция с номером записи на доске объявлений
+func (s *Server) SubmitBallot(voteValue *big.Int, signature *big.Int) (*voteapi.Receipt, error) {
+	if !s.verifySignature(voteValue.Bytes(), signature) {
+		return nil, fmt.Errorf("ballot signature: %w", common.ErrVerification)
+	}
+	// Извлекаем значение голоса (1 - YES, 2 - NO, 3 - ABSTAIN)
+	voteInt := new(big.Int).And(voteValue, big.NewInt(3)).Int64()
+	if voteInt == 0 {
+		return nil, &common.ParameterError{Name: "vote", Reason: "unknown option"}
+	}
+	s.mu.Lock()
+	record, fresh, err := s.ledger.Append(voteValue, signature)
+	if fresh {
 		s.votes[Vote(voteInt)]++
-		s.mu.Unlock()
-		fmt.Println("Сервер: Голос принят")
-		return true
 	}
+	s.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("ledger: %w", err)
+	}
+	if !fresh {
+		return nil, fmt.Errorf("ballot %s (record %d): %w", record.BallotHash, record.Seq, ErrAlreadyCounted)
+	}
+	return s.receipt(record)
+}
+
+// receipt - квитанция на запись журнала, подписанная ключом квитанций
+func (s *Server) receipt(record ledger.Record) (*voteapi.Receipt, error) {
+	r, sig, err := s.receiptKey.Sign(voteapi.ReceiptMessage(record.Seq, record.BallotHash))
+	if err != nil {
+		return nil, err
+	}
+	if common.Tracing() {
+		common.Trace("vote", "receipt", common.ValInt("seq", int64(record.Seq)), common.Val("r", r), common.Val("s", sig))
+	}
+	return &voteapi.Receipt{Seq: record.Seq, BallotHash: record.BallotHash, R: r, S: sig}, nil
+}
+
+// Метод сервера для проверки и учёта зашифрованного бюллетеня: сервер проверяет
+// подпись и доказательство корректности, но не видит выбранный вариант
+func (s *Server) SubmitEncryptedVote(ballot *Ballot, signature *big.Int) bool {
+	if !s.verifySignature(ballot.Bytes(), signature) {
+		s.printf("Сервер: Бюллетень отклонён: некорректная подпись")
+		return false
+	}
+	if err := s.tally.Add(ballot); err != nil {
+		s.printf("Сервер: Бюллетень отклонён: %v", err)
+		return false
+	}
+	s.printf("Сервер: Бюллетень принят")
+	return true
+}
+
+// printf - сообщение в logf, если он задан
+func (s *Server) printf(format string, args ...any) {
+	if s.logf != nil {
+		s.logf(format, args...)
+	}
+}
 
-	fmt.Println("Сервер: Голос отклонён: некорректная подпись")
-	return false
+// Копия текущих результатов голосования
+func (s *Server) Results() map[Vote]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	votes := make(map[Vote]int, len(s.votes))
+	for vote, count := range s.votes {
+		votes[vote] = count
+	}
+	return votes
 }
 
 // Метод для отображения результатов голосования
 func (s *Server) ShowResults() {
+	showResults(s.Results())
+}
+
+// Вывод результатов голосования
+func showResults(votes map[Vote]int) {
 	fmt.Println("\nСервер: Результаты голосования:")
-	for vote, count := range s.votes {
+	for vote, count := range votes {
 		var voteStr string
 		switch vote {
 		case YES:
@@ -125,6 +233,7 @@ func (s *Server) ShowResults() {
 type Client struct {
 	server   *Server
 	username string
+	key      *gost.PrivateKey // Долговременный ключ из списка избирателей
 }
 
 // Создание нового клиента
@@ -135,40 +244,66 @@ func NewClient(server *Server, username string) *Client {
 	}
 }
 
+// Создание клиента с ключом подписи запросов
+func NewAuthenticatedClient(server *Server, username string, key *gost.PrivateKey) *Client {
+	return &Client{
+		server:   server,
+		username: username,
+		key:      key,
+	}
+}
+
+// Слепая подпись сервера под сообщением; nil, если сервер отказал
+func (c *Client) blindSign(message []byte) *big.Int {
+	// Ослепляем хэш сообщения случайным r: blinded = FDH(m) * r^d mod n
+	blindedHash, state, err := c.server.key.Blind(rand.Reader, message)
+	if err != nil {
+		log.Fatalf("Ошибка при ослеплении: %v", err)
+	}
+
+	// Получаем слепую подпись от сервера; с ключом запрос подписывается
+	var blindSignature *big.Int
+	if c.key != nil {
+		sigR, sigS, err := c.key.Sign(voteapi.SignRequestMessage("", c.username, blindedHash))
+		if err != nil {
+			log.Fatalf("Ошибка при подписи запроса: %v", err)
+		}
+		if blindSignature, err = c.server.GetAuthenticatedBlindSignature(c.username, blindedHash, sigR, sigS); err != nil {
+			fmt.Printf("Сервер: Запрос пользователя %s отклонён: %v\n", c.username, err)
+			return nil
+		}
+	} else {
+		blindSignature = c.server.GetBlindSignature(c.username, blindedHash)
+	}
+	if blindSignature == nil {
+		return nil
+	}
+
+	// Снимаем слепоту с подписи: s = s' * r^-1 mod n
+	signature, err := c.server.key.Finalize(state, blindSignature)
+	if err != nil {
+		fmt.Printf("Клиент: Подпись сервера неверна: %v\n", err)
+		return nil
+	}
+	return signature
+}
+
 // Метод клиента для голосования
 func (c *Client) Vote(vote Vote) {
-	// Генерируем случайное число r, взаимно простое с n
-	r := common.GenCoprimeBig(c.server.n, big.NewInt(2), c.server.n)
-
 	// Формируем сообщение m (голос)
 	minV := big.NewInt(1_000_000_000_000_000)
 	maxV := big.NewInt(1_000_000_000_000_000_000)
 	randomPadding := common.GenPrimeBig(minV, maxV)
 	m := new(big.Int).Lsh(randomPadding, 2)
 	m = new(big.Int).Or(m, big.NewInt(int64(vote)))
-
-	// Вычисляем хэш от m
-	hash := sha512.Sum512(m.Bytes())
-	hashInt := new(big.Int).SetBytes(hash[:])
-
-	// Вычисляем слепое сообщение
-	rExpE := common.ModularExponentiationBig(r, c.server.d, c.server.n)
-	blindedHash := new(big.Int).Mul(hashInt, rExpE)
-	//blindedHash.Mod(blindedHash, c.server.n)
-
-	// Получаем слепую подпись от сервера
-	blindSignature := c.server.GetBlindSignature(c.username, blindedHash)
-	if blindSignature == nil {
-		return
+	if common.Tracing() {
+		common.Trace("vote", "ballot", common.Val("m", m))
 	}
 
-	// Снимаем слепоту с подписи
-	rInv, err := common.ModInverseBig(r, c.server.n)
-	if err != nil {
-		log.Fatalf("Ошибка при вычислении обратного к r: %v", err)
+	signature := c.blindSign(m.Bytes())
+	if signature == nil {
+		return
 	}
-	signature := new(big.Int).Mul(blindSignature, rInv)
-	//signature.Mod(signature, c.server.n)
 
 	// Отправляем голос и подпись на сервер
 	if c.server.SubmitVote(m, signature) {
@@ -178,18 +313,205 @@ func (c *Client) Vote(vote Vote) {
 	}
 }
 
+// Метод клиента для тайного голосования: голос шифруется ключом выборов,
+// сервер подписывает вслепую сам бюллетень
+func (c *Client) VoteEncrypted(vote Vote) {
+	ballot, err := c.server.tally.key.EncryptVote(vote, c.server.tally.base)
+	if err != nil {
+		log.Fatalf("Ошибка при шифровании бюллетеня: %v", err)
+	}
+
+	signature := c.blindSign(ballot.Bytes())
+	if signature == nil {
+		return
+	}
+
+	if c.server.SubmitEncryptedVote(ballot, signature) {
+		fmt.Printf("Клиент: Бюллетень пользователя %s принят\n", c.username)
+	} else {
+		fmt.Printf("Клиент: Бюллетень пользователя %s отклонён\n", c.username)
+	}
+}
+
 // Главная функция
 func main() {
+	traceFormat := flag.String("trace", "", "трассировка шагов алгоритма: text или json")
+	tallyMode := flag.String("tally", "plain", "подсчёт голосов: plain (сервер видит голоса), homomorphic, threshold или mixnet")
+	maxVoters := flag.Int("voters", 1000, "наибольшее число бюллетеней при гомоморфном подсчёте")
+	trustees := flag.Int("trustees", 5, "число доверенных лиц в режиме threshold")
+	threshold := flag.Int("threshold", 3, "сколько доверенных лиц нужно для расшифрования итога")
+	mixes := flag.Int("mixes", 3, "число серверов перемешивания в режиме mixnet")
+	cheatingMix := flag.Int("cheating-mix", 0, "номер сервера перемешивания, подменяющего бюллетень (0 - все честные)")
+	listen := flag.String("listen", "", "адрес HTTP-сервиса голосования, например 127.0.0.1:8080; пусто - демонстрация в одном процессе")
+	rollPath := flag.String("roll", "", "список избирателей с ключами ГОСТ (.csv или .json) для HTTP-сервиса")
+	auditPath := flag.String("audit", "", "журнал аудита запросов подписи (JSON Lines); пусто - stderr")
+	enroll := flag.String("enroll", "", "имена избирателей через запятую: создать ключи и список -roll, затем выйти")
+	keysDir := flag.String("keys", ".", "каталог для закрытых ключей избирателей при -enroll")
+	serverKey := flag.String("server-key", "", "файл ключей сервера; если его нет, ключи создаются, открытый ключ - в <файл>.pub")
+	ledgerPath := flag.String("ledger", "", "файл журнала бюллетеней (нужен -server-key); пусто - журнал только в памяти")
+	electionsPath := flag.String("elections", "", "файл определений выборов (JSON) для HTTP-сервиса")
+	flag.Parse()
+	tracer, err := common.NewTracer(*traceFormat, os.Stderr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	common.SetTracer(tracer)
+
+	if *enroll != "" {
+		if err = enrollVoters(strings.Split(*enroll, ","), *rollPath, *keysDir); err != nil {
+			log.Fatalf("Ошибка при регистрации избирателей: %v", err)
+		}
+		return
+	}
+	audit := NewAuditLog(os.Stderr)
+	if *auditPath != "" {
+		f, err := os.OpenFile(*auditPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			log.Fatalf("Ошибка при открытии журнала аудита: %v", err)
+		}
+		defer f.Close()
+		audit = NewAuditLog(f)
+	}
+
 	// Создаём сервер
-	server := NewServer()
-	fmt.Printf("Параметры сервера:\n n = %s\n d = %s\n c = %s\n", server.n.String(), server.d.String(), server.c.String())
+	var server *Server
+	if *serverKey != "" {
+		if server, err = LoadServer(*serverKey); err != nil {
+			log.Fatalf("Ошибка при загрузке ключей сервера: %v", err)
+		}
+	} else {
+		server = NewServer()
+	}
+	if common.Tracing() {
+		common.Trace("vote", "server keygen", common.Val("n", server.key.N), common.Val("d", server.key.D), common.Val("c", server.key.C))
+	}
+	if *electionsPath != "" {
+		if *listen == "" {
+			log.Fatalf("Выборы из файла проводятся только с -listen")
+		}
+		elections, err := election.Load(*electionsPath)
+		if err != nil {
+			log.Fatalf("Ошибка при загрузке определений выборов: %v", err)
+		}
+		if err = server.AddElections(elections); err != nil {
+			log.Fatalf("Ошибка при подготовке выборов: %v", err)
+		}
+		for _, e := range elections {
+			log.Printf("Сервер: выборы %s (%s), вопросов: %d", e.ID, e.Title, len(e.Questions))
+		}
+	}
+	if *ledgerPath != "" {
+		// Без сохранённого ключа журнал нельзя было бы продолжить после перезапуска
+		if *serverKey == "" {
+			log.Fatalf("Журнал в файле требует -server-key")
+		}
+		if err = server.OpenLedger(*ledgerPath); err != nil {
+			log.Fatalf("Ошибка при открытии журнала: %v", err)
+		}
+		defer server.CloseLedger()
+		log.Printf("Сервер: в журнале %s учтено бюллетеней: %d", *ledgerPath, server.ledger.Len())
+	}
+
+	if *listen != "" {
+		if *tallyMode != "plain" {
+			log.Fatalf("HTTP-сервис поддерживает только подсчёт plain")
+		}
+		if *rollPath != "" {
+			roll, err := LoadRoll(*rollPath)
+			if err != nil {
+				log.Fatalf("Ошибка при загрузке списка избирателей: %v", err)
+			}
+			server.EnableAuthentication(roll, audit)
+			log.Printf("Сервер: в списке избирателей %d человек", roll.Len())
+		}
+		log.Printf("Сервер: сервис голосования на http://%s", *listen)
+		log.Fatal(http.ListenAndServe(*listen, NewHTTPHandler(server)))
+	}
+	// В демонстрации решения сервера выводятся вместе с сообщениями клиентов
+	server.logf = func(format string, args ...any) { fmt.Printf(format+"\n", args...) }
+
+	var authority interface {
+		DecryptTally(ctx context.Context, t *Tally) (map[Vote]int, error)
+	}
+	switch *tallyMode {
+	case "plain":
+	case "homomorphic":
+		// Закрытый ключ выборов остаётся у комиссии, сервер получает только открытый
+		a, err := NewAuthority(1024, 256, []byte("lab5"))
+		if err != nil {
+			log.Fatalf("Ошибка при генерации ключа выборов: %v", err)
+		}
+		server.tally = NewTally(&a.ElectionKey, *maxVoters)
+		authority = a
+	case "threshold", "mixnet":
+		// Ключ генерируют доверенные лица; целиком он не известен никому
+		group, err := NewElectionGroup(1024, 256, []byte("lab5"))
+		if err != nil {
+			log.Fatalf("Ошибка при генерации группы: %v", err)
+		}
+		committee, err := RunDKG(group, *trustees, *threshold, nil)
+		if err != nil {
+			log.Fatalf("Ошибка при распределённой генерации ключа: %v", err)
+		}
+		fmt.Printf("Доверенные лица: %d, порог %d, честные дилеры %v\n", *trustees, *threshold, committee.Qual)
+		server.tally = NewTally(&committee.ElectionKey, *maxVoters)
+		authority = committee
+	default:
+		log.Fatalf("Неизвестный режим подсчёта: %s", *tallyMode)
+	}
+
+	if *rollPath != "" {
+		log.Fatalf("Список избирателей из файла используется только с -listen")
+	}
+	// Регистрируем избирателей: у каждого долговременный ключ, у сервера - список открытых ключей
+	roll, keys, err := EnrollVoters([]string{"Alice", "Bob", "Charlie"})
+	if err != nil {
+		log.Fatalf("Ошибка при регистрации избирателей: %v", err)
+	}
+	server.EnableAuthentication(roll, audit)
 
 	// Создаём клиентов
-	alice := NewClient(server, "Alice")
-	bob := NewClient(server, "Bob")
-	charlie := NewClient(server, "Charlie")
+	alice := NewAuthenticatedClient(server, "Alice", keys["Alice"])
+	bob := NewAuthenticatedClient(server, "Bob", keys["Bob"])
+	charlie := NewAuthenticatedClient(server, "Charlie", keys["Charlie"])
+
+	// Mallory выдаёт себя за Alice, но подписывает запрос своим ключом
+	malloryKey, err := gost.GenerateKey(keys["Alice"].P, keys["Alice"].Q, keys["Alice"].A)
+	if err != nil {
+		log.Fatalf("Ошибка при генерации ключа: %v", err)
+	}
+	impostor := NewAuthenticatedClient(server, "Alice", malloryKey)
+
+	if authority != nil {
+		// Клиенты голосуют тайно
+		impostor.VoteEncrypted(NO)
+		alice.VoteEncrypted(YES)
+		bob.VoteEncrypted(NO)
+		charlie.VoteEncrypted(ABSTAIN)
+
+		// Попытка повторного голосования
+		alice.VoteEncrypted(NO)
+
+		if *tallyMode == "mixnet" {
+			results, err := mixAndDecrypt(context.Background(), authority.(*Committee), server.tally, *mixes, *cheatingMix)
+			if err != nil {
+				log.Fatalf("Ошибка при перемешивании: %v", err)
+			}
+			showResults(results)
+			return
+		}
+
+		// Комиссия расшифровывает только итог
+		results, err := authority.DecryptTally(context.Background(), server.tally)
+		if err != nil {
+			log.Fatalf("Ошибка при расшифровании итога: %v", err)
+		}
+		showResults(results)
+		return
+	}
 
 	// Клиенты голосуют
+	impostor.Vote(NO)
 	alice.Vote(YES)
 	bob.Vote(NO)
 	charlie.Vote(ABSTAIN)
@@ -200,3 +522,37 @@ func main() {
 	// Отображаем результаты
 	server.ShowResults()
 }
+
+// Регистрация избирателей names: список открытых ключей записывается в rollPath,
+// закрытый ключ каждого избирателя - в файл <имя>.key каталога keysDir
+func enrollVoters(names []string, rollPath, keysDir string) error {
+	if rollPath == "" {
+		return &common.ParameterError{Name: "roll", Reason: "-enroll needs -roll"}
+	}
+	roll, keys, err := EnrollVoters(names)
+	if err != nil {
+		return err
+	}
+	if err = roll.Save(rollPath); err != nil {
+		return err
+	}
+	for name, key := range keys {
+		block, err := key.ExportPrivateKey()
+		if err != nil {
+			return err
+		}
+		f, err := os.OpenFile(filepath.Join(keysDir, name+".key"), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		err = common.EncodeArmor(f, block)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Избиратели (%d) записаны в %s, ключи - в %s\n", len(names), rollPath, keysDir)
+	return nil
+}
@@ -0,0 +1,573 @@
+// Package voteapi - HTTP/JSON-протокол голосования со слепой подписью: типы запросов
+// и ответов и клиент избирателя. Сервер реализован в lab5.
+//
+//	GET  /key       - открытый ключ подписи сервера (N, D) и ключ квитанций
+//	POST /register  - регистрация избирателя, в ответ - секретный токен
+//	POST /sign      - слепая подпись; один раз на избирателя
+//	                  (при списке избирателей запрос подписывается ключом ГОСТ избирателя)
+//	POST /ballot    - анонимная отправка голоса с подписью, в ответ - подписанная квитанция
+//	GET  /results   - текущие итоги
+//	GET  /board     - доска объявлений: все учтённые бюллетени для проверки и пересчёта
+//	GET  /elections - выборы из файла определений с показателями их ключей подписи
+//
+// Запросы /sign и /ballot с полем election и запросы /results и /board
+// с параметром ?election=id относятся к выборам; без них - к референдуму
+// с вариантами yes, no и abstain.
+package voteapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/blindsig"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/gost"
+	"github.com/Raimguzhinov/protect-information/lab5/election"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Пути сервиса
+const (
+	PathKey       = "/key"
+	PathRegister  = "/register"
+	PathSign      = "/sign"
+	PathBallot    = "/ballot"
+	PathResults   = "/results"
+	PathBoard     = "/board"
+	PathElections = "/elections"
+)
+
+// Варианты голоса; значения совпадают с константами lab5
+const (
+	Yes     = 1
+	No      = 2
+	Abstain = 3
+)
+
+// OptionNames - названия вариантов в ответе /results и в командной строке
+var OptionNames = map[int]string{Yes: "yes", No: "no", Abstain: "abstain"}
+
+// ErrRejected - сервер отклонил запрос (повторная подпись, неверный токен или подпись)
+var ErrRejected = errors.New("voteapi: request rejected")
+
+// KeyResponse - ответ GET /key. Квитанции подписываются отдельным ключом ГОСТ:
+// ключом слепой подписи сервер подписывает любое число, и им нельзя заверять квитанции.
+type KeyResponse struct {
+	N          *big.Int        `json:"n"`
+	D          *big.Int        `json:"d"`
+	ReceiptKey *gost.PublicKey `json:"receipt_key"`
+}
+
+// RegisterRequest - запрос POST /register
+type RegisterRequest struct {
+	Username string `json:"username"`
+}
+
+// RegisterResponse - ответ POST /register
+type RegisterResponse struct {
+	Token string `json:"token"`
+}
+
+// SignRequest - запрос POST /sign: слепой хэш blinded = FDH(m) * r^D mod N, для выборов
+// Election - с показателем D этих выборов. Сервер со списком избирателей вместо токена
+// требует подпись (R, S) сообщения SignRequestMessage долговременным ключом избирателя.
+type SignRequest struct {
+	Election   string   `json:"election,omitempty"`
+	Username   string   `json:"username"`
+	Token      string   `json:"token,omitempty"`
+	Blinded    *big.Int `json:"blinded"`
+	SignatureR *big.Int `json:"signature_r,omitempty"`
+	SignatureS *big.Int `json:"signature_s,omitempty"`
+}
+
+// SignResponse - ответ POST /sign: blinded^C mod N
+type SignResponse struct {
+	Signature *big.Int `json:"signature"`
+}
+
+// BallotRequest - запрос POST /ballot; имя избирателя не передаётся
+type BallotRequest struct {
+	Election  string   `json:"election,omitempty"`
+	Message   *big.Int `json:"message"`
+	Signature *big.Int `json:"signature"`
+}
+
+// BallotResponse - ответ POST /ballot
+type BallotResponse struct {
+	Accepted bool     `json:"accepted"`
+	Receipt  *Receipt `json:"receipt"`
+}
+
+// Receipt - квитанция: сервер подписывает номер записи на доске и хэш бюллетеня
+type Receipt struct {
+	Seq        int      `json:"seq"`
+	BallotHash string   `json:"ballot_hash"`
+	R          *big.Int `json:"r"`
+	S          *big.Int `json:"s"`
+}
+
+// BoardEntry - учтённый бюллетень на доске объявлений
+type BoardEntry struct {
+	Seq        int      `json:"seq"`
+	BallotHash string   `json:"ballot_hash"`
+	Message    *big.Int `json:"message"`
+	Signature  *big.Int `json:"signature"`
+}
+
+// BoardResponse - ответ GET /board: записи в порядке учёта
+type BoardResponse struct {
+	Entries []BoardEntry `json:"entries"`
+}
+
+// ResultsResponse - ответ GET /results: число голосов по названиям вариантов
+type ResultsResponse struct {
+	Votes map[string]int `json:"votes"`
+}
+
+// ElectionInfo - определение выборов и открытый показатель D их ключа слепой подписи;
+// модуль N общий с ключом сервера
+type ElectionInfo struct {
+	election.Election
+	D *big.Int `json:"d"`
+}
+
+// ElectionsResponse - ответ GET /elections
+type ElectionsResponse struct {
+	Elections []ElectionInfo `json:"elections"`
+}
+
+// ErrorResponse - тело ответа с кодом ошибки
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// BallotHash - ключ бюллетеня на доске: SHA-256 от сообщения в шестнадцатеричной записи
+func BallotHash(message *big.Int) string {
+	h := sha256.Sum256(message.Bytes())
+	return hex.EncodeToString(h[:])
+}
+
+// ReceiptMessage - сообщение, которое сервер подписывает в квитанции
+func ReceiptMessage(seq int, ballotHash string) []byte {
+	return []byte(fmt.Sprintf("lab5 ballot receipt\n%d\n%s", seq, ballotHash))
+}
+
+// Verify - проверка подписи квитанции ключом квитанций сервера
+func (r *Receipt) Verify(key *gost.PublicKey) error {
+	if key == nil || !key.Verify(ReceiptMessage(r.Seq, r.BallotHash), r.R, r.S) {
+		return fmt.Errorf("receipt %d: %w", r.Seq, common.ErrVerification)
+	}
+	return nil
+}
+
+// verifyEntries - номера записей доски идут подряд с нуля, хэши совпадают с сообщениями
+// и не повторяются, каждое сообщение подписано ключом (n, d)
+func verifyEntries(n, d *big.Int, entries []BoardEntry) error {
+	key := &blindsig.PublicKey{N: n, D: d}
+	seen := make(map[string]bool, len(entries))
+	for i, e := range entries {
+		if e.Message == nil || e.Signature == nil || e.Seq != i || e.BallotHash != BallotHash(e.Message) || seen[e.BallotHash] {
+			return fmt.Errorf("board entry %d is inconsistent: %w", i, common.ErrVerification)
+		}
+		seen[e.BallotHash] = true
+		if err := key.Verify(e.Message.Bytes(), e.Signature); err != nil {
+			return fmt.Errorf("board entry %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Recount - пересчёт итогов референдума по доске: каждая запись проверяется подписью
+// сервера (N, D), номера идут подряд с нуля, хэши совпадают с сообщениями и не повторяются
+func Recount(key *KeyResponse, entries []BoardEntry) (map[string]int, error) {
+	if err := verifyEntries(key.N, key.D, entries); err != nil {
+		return nil, err
+	}
+	votes := make(map[string]int, len(OptionNames))
+	for _, name := range OptionNames {
+		votes[name] = 0
+	}
+	for i, e := range entries {
+		name, ok := OptionNames[int(e.Message.Bit(1)<<1|e.Message.Bit(0))]
+		if !ok {
+			return nil, fmt.Errorf("board entry %d: unknown option: %w", i, common.ErrVerification)
+		}
+		votes[name]++
+	}
+	return votes, nil
+}
+
+// RecountElection - пересчёт итогов выборов по доске: записи проверяются ключом (n, info.D),
+// каждый бюллетень разбирается по определению выборов
+func RecountElection(n *big.Int, info *ElectionInfo, entries []BoardEntry) (*election.Result, error) {
+	if err := verifyEntries(n, info.D, entries); err != nil {
+		return nil, err
+	}
+	ballots := make([]*election.Ballot, len(entries))
+	for i, e := range entries {
+		b, err := info.Decode(e.Message)
+		if err != nil {
+			return nil, fmt.Errorf("board entry %d: %v: %w", i, err, common.ErrVerification)
+		}
+		ballots[i] = b
+	}
+	return info.Tally(ballots), nil
+}
+
+// SignRequestMessage - сообщение, которое избиратель подписывает в запросе слепой подписи;
+// electionID пуст для референдума. Имя записано с длиной, чтобы граница между именем
+// и числом была однозначной.
+func SignRequestMessage(electionID, username string, blinded *big.Int) []byte {
+	if electionID == "" {
+		return []byte(fmt.Sprintf("lab5 blind signature request\n%d:%s\n%x", len(username), username, blinded))
+	}
+	return []byte(fmt.Sprintf("lab5 election blind signature request\n%s\n%d:%s\n%x", electionID, len(username), username, blinded))
+}
+
+// Client - избиратель, работающий с сервисом по адресу BaseURL
+type Client struct {
+	BaseURL  string
+	HTTP     *http.Client     // nil - http.DefaultClient
+	Identity *gost.PrivateKey // Ключ из списка избирателей; nil - запросы подтверждаются токеном
+}
+
+// do - JSON-запрос; при коде ответа не 200 возвращается ошибка с текстом сервера
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseURL, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var e ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusConflict {
+			return fmt.Errorf("%s %s: %s: %w", method, path, e.Error, ErrRejected)
+		}
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, e.Error)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Key - открытый ключ подписи сервера
+func (c *Client) Key(ctx context.Context) (*KeyResponse, error) {
+	var key KeyResponse
+	if err := c.do(ctx, http.MethodGet, PathKey, nil, &key); err != nil {
+		return nil, err
+	}
+	if key.N == nil || key.D == nil || key.N.Sign() <= 0 {
+		return nil, &common.ParameterError{Name: "key", Reason: "server returned an empty key"}
+	}
+	return &key, nil
+}
+
+// Register - регистрация избирателя; токен нужен для получения подписи
+func (c *Client) Register(ctx context.Context, username string) (string, error) {
+	var resp RegisterResponse
+	if err := c.do(ctx, http.MethodPost, PathRegister, RegisterRequest{Username: username}, &resp); err != nil {
+		return "", err
+	}
+	return resp.Token, nil
+}
+
+// Results - текущие итоги голосования
+func (c *Client) Results(ctx context.Context) (map[string]int, error) {
+	var resp ResultsResponse
+	if err := c.do(ctx, http.MethodGet, PathResults, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Votes, nil
+}
+
+// Board - все учтённые бюллетени референдума
+func (c *Client) Board(ctx context.Context) ([]BoardEntry, error) {
+	return c.board(ctx, PathBoard)
+}
+
+// ElectionBoard - все учтённые бюллетени выборов id
+func (c *Client) ElectionBoard(ctx context.Context, id string) ([]BoardEntry, error) {
+	return c.board(ctx, PathBoard+"?election="+url.QueryEscape(id))
+}
+
+func (c *Client) board(ctx context.Context, path string) ([]BoardEntry, error) {
+	var resp BoardResponse
+	if err := c.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Entries, nil
+}
+
+// Elections - выборы, которые проводит сервер. Показатель ключа каждых выборов
+// сверяется с election.Exponent: иначе сервер мог бы выдать избирателю особый
+// ключ и узнать его бюллетень по подписи.
+func (c *Client) Elections(ctx context.Context) ([]ElectionInfo, error) {
+	var resp ElectionsResponse
+	if err := c.do(ctx, http.MethodGet, PathElections, nil, &resp); err != nil {
+		return nil, err
+	}
+	for _, info := range resp.Elections {
+		if info.D == nil || info.D.Cmp(election.Exponent(info.ID)) != 0 {
+			return nil, fmt.Errorf("election %s: key exponent does not match its id: %w", info.ID, common.ErrVerification)
+		}
+		if err := info.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return resp.Elections, nil
+}
+
+// Election - выборы id из списка Elections
+func (c *Client) Election(ctx context.Context, id string) (*ElectionInfo, error) {
+	elections, err := c.Elections(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range elections {
+		if elections[i].ID == id {
+			return &elections[i], nil
+		}
+	}
+	return nil, &common.ParameterError{Name: "election", Reason: fmt.Sprintf("server has no election %q", id)}
+}
+
+// ElectionResults - текущие итоги выборов id
+func (c *Client) ElectionResults(ctx context.Context, id string) (*election.Result, error) {
+	var result election.Result
+	if err := c.do(ctx, http.MethodGet, PathResults+"?election="+url.QueryEscape(id), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Submit - анонимная отправка голоса референдума с подписью (см. SubmitBallot)
+func (c *Client) Submit(ctx context.Context, message, signature *big.Int) (*Receipt, error) {
+	return c.SubmitBallot(ctx, &BallotRequest{Message: message, Signature: signature})
+}
+
+// SubmitBallot - анонимная отправка подписанного бюллетеня; квитанция проверяется
+// ключом сервера и должна относиться именно к этому бюллетеню
+func (c *Client) SubmitBallot(ctx context.Context, ballot *BallotRequest) (*Receipt, error) {
+	var resp BallotResponse
+	if err := c.do(ctx, http.MethodPost, PathBallot, ballot, &resp); err != nil {
+		return nil, err
+	}
+	if !resp.Accepted || resp.Receipt == nil {
+		return nil, fmt.Errorf("ballot not accepted: %w", ErrRejected)
+	}
+	key, err := c.Key(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Receipt.BallotHash != BallotHash(ballot.Message) {
+		return nil, fmt.Errorf("receipt for another ballot: %w", common.ErrVerification)
+	}
+	if err = resp.Receipt.Verify(key.ReceiptKey); err != nil {
+		return nil, err
+	}
+	return resp.Receipt, nil
+}
+
+// Vote - полный цикл голосования зарегистрированного избирателя: SignBallot и Submit.
+// С ключом Identity токен не нужен.
+func (c *Client) Vote(ctx context.Context, username, token string, vote int) (*Receipt, error) {
+	ballot, err := c.SignBallot(ctx, username, token, vote)
+	if err != nil {
+		return nil, err
+	}
+	return c.SubmitBallot(ctx, ballot)
+}
+
+// VoteElection - полный цикл голосования на выборах id: answers[id вопроса] -
+// выбранные кандидаты (для рейтингового вопроса - по порядку предпочтения)
+func (c *Client) VoteElection(ctx context.Context, username, token, id string, answers map[string][]string) (*Receipt, error) {
+	info, err := c.Election(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	b, err := info.NewBallot(answers)
+	if err != nil {
+		return nil, err
+	}
+	ballot, err := c.SignElectionBallot(ctx, info, username, token, b)
+	if err != nil {
+		return nil, err
+	}
+	return c.SubmitBallot(ctx, ballot)
+}
+
+// VerifyInclusion - бюллетень с квитанцией есть на доске под её номером, а итоги
+// по доске пересчитываются и совпадают с объявленными сервером
+func (c *Client) VerifyInclusion(ctx context.Context, receipt *Receipt) error {
+	key, err := c.Key(ctx)
+	if err != nil {
+		return err
+	}
+	if err = receipt.Verify(key.ReceiptKey); err != nil {
+		return err
+	}
+	entries, err := c.Board(ctx)
+	if err != nil {
+		return err
+	}
+	if err = onBoard(receipt, entries); err != nil {
+		return err
+	}
+	recount, err := Recount(key, entries)
+	if err != nil {
+		return err
+	}
+	results, err := c.Results(ctx)
+	if err != nil {
+		return err
+	}
+	for name, count := range recount {
+		// Между запросами могли прийти новые бюллетени, но не исчезнуть учтённые
+		if results[name] < count {
+			return fmt.Errorf("results %v do not cover the board recount %v: %w", results, recount, common.ErrVerification)
+		}
+	}
+	return nil
+}
+
+// VerifyElectionInclusion - то же, что VerifyInclusion, для выборов id: бюллетень
+// на доске выборов, все бюллетени доски подписаны ключом выборов и разбираются,
+// итоги сервера учитывают не меньше бюллетеней, чем пересчёт
+func (c *Client) VerifyElectionInclusion(ctx context.Context, id string, receipt *Receipt) error {
+	key, err := c.Key(ctx)
+	if err != nil {
+		return err
+	}
+	if err = receipt.Verify(key.ReceiptKey); err != nil {
+		return err
+	}
+	info, err := c.Election(ctx, id)
+	if err != nil {
+		return err
+	}
+	entries, err := c.ElectionBoard(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err = onBoard(receipt, entries); err != nil {
+		return err
+	}
+	recount, err := RecountElection(key.N, info, entries)
+	if err != nil {
+		return err
+	}
+	results, err := c.ElectionResults(ctx, id)
+	if err != nil {
+		return err
+	}
+	if results.Election != id || results.Ballots < recount.Ballots {
+		return fmt.Errorf("results of %s count %d ballots, the board has %d: %w", id, results.Ballots, recount.Ballots, common.ErrVerification)
+	}
+	return nil
+}
+
+// onBoard - бюллетень квитанции записан на доске под её номером
+func onBoard(receipt *Receipt, entries []BoardEntry) error {
+	if receipt.Seq < 0 || receipt.Seq >= len(entries) || entries[receipt.Seq].BallotHash != receipt.BallotHash {
+		return fmt.Errorf("ballot %s is missing from the board: %w", receipt.BallotHash, common.ErrVerification)
+	}
+	return nil
+}
+
+// SignBallot - подписанный сервером бюллетень: сообщение m = (случайное заполнение << 2) | vote,
+// слепая подпись хэша, снятие слепоты и проверка подписи. Бюллетень можно отправить
+// позже и с другого соединения, чтобы время отправки не связывало его с избирателем.
+func (c *Client) SignBallot(ctx context.Context, username, token string, vote int) (*BallotRequest, error) {
+	if _, ok := OptionNames[vote]; !ok {
+		return nil, &common.ParameterError{Name: "vote", Reason: fmt.Sprintf("unknown option %d", vote)}
+	}
+	key, err := c.Key(ctx)
+	if err != nil {
+		return nil, err
+	}
+	padding, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	m := new(big.Int).Lsh(padding.SetBit(padding, 128, 1), 2)
+	m.Or(m, big.NewInt(int64(vote)))
+	signature, err := c.blindSign(ctx, key.N, key.D, SignRequest{Username: username, Token: token}, m)
+	if err != nil {
+		return nil, err
+	}
+	return &BallotRequest{Message: m, Signature: signature}, nil
+}
+
+// SignElectionBallot - подписанный ключом выборов info бюллетень b (см. SignBallot)
+func (c *Client) SignElectionBallot(ctx context.Context, info *ElectionInfo, username, token string, b *election.Ballot) (*BallotRequest, error) {
+	if info.D == nil || info.D.Cmp(election.Exponent(info.ID)) != 0 {
+		return nil, fmt.Errorf("election %s: key exponent does not match its id: %w", info.ID, common.ErrVerification)
+	}
+	key, err := c.Key(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m, err := info.Encode(b)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := c.blindSign(ctx, key.N, info.D, SignRequest{Election: info.ID, Username: username, Token: token}, m)
+	if err != nil {
+		return nil, err
+	}
+	return &BallotRequest{Election: info.ID, Message: m, Signature: signature}, nil
+}
+
+// blindSign - подпись сервера ключом (n, d) под сообщением m: хэш ослепляется,
+// запрос req дополняется слепым хэшем (и подписью ключом Identity), ответ проверяется
+func (c *Client) blindSign(ctx context.Context, n, d *big.Int, req SignRequest, m *big.Int) (*big.Int, error) {
+	key := &blindsig.PublicKey{N: n, D: d}
+	blinded, state, err := key.Blind(rand.Reader, m.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	req.Blinded = blinded
+	if c.Identity != nil {
+		if req.SignatureR, req.SignatureS, err = c.Identity.Sign(SignRequestMessage(req.Election, req.Username, blinded)); err != nil {
+			return nil, err
+		}
+	}
+	var signed SignResponse
+	if err = c.do(ctx, http.MethodPost, PathSign, req, &signed); err != nil {
+		return nil, err
+	}
+	if signed.Signature == nil {
+		return nil, fmt.Errorf("empty blind signature: %w", common.ErrVerification)
+	}
+	// Снятие слепоты проверяет, что сервер подписал именно хэш m
+	signature, err := key.Finalize(state, signed.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("blind signature: %w", err)
+	}
+	return signature, nil
+}
@@ -0,0 +1,213 @@
+// Команда voter - избиратель для HTTP-сервиса голосования lab5:
+// регистрируется (или подписывает запросы ключом из списка избирателей),
+// получает слепую подпись и анонимно отправляет голос на референдуме
+// или бюллетень выборов из файла определений сервера
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/gost"
+	"github.com/Raimguzhinov/protect-information/lab5/voteapi"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// answers - ответы бюллетеня выборов из повторяемого флага -answer вопрос=кандидат,...
+type answers map[string][]string
+
+func (a answers) String() string {
+	parts := make([]string, 0, len(a))
+	for q, names := range a {
+		parts = append(parts, q+"="+strings.Join(names, ","))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+func (a answers) Set(value string) error {
+	question, list, ok := strings.Cut(value, "=")
+	if !ok || question == "" {
+		return fmt.Errorf("ожидается вопрос=кандидат,...: %q", value)
+	}
+	if _, dup := a[question]; dup {
+		return fmt.Errorf("повторный ответ на вопрос %s", question)
+	}
+	var names []string
+	if list != "" {
+		names = strings.Split(list, ",")
+	}
+	a[question] = names
+	return nil
+}
+
+func main() {
+	server := flag.String("server", "http://127.0.0.1:8080", "адрес сервиса голосования")
+	username := flag.String("user", "", "имя избирателя")
+	choice := flag.String("vote", "", "голос на референдуме: yes, no или abstain; пусто - только показать итоги")
+	timeout := flag.Duration("timeout", 30*time.Second, "ограничение времени на все запросы")
+	keyPath := flag.String("key", "", "закрытый ключ избирателя (lab5 -enroll), если сервер работает со списком избирателей")
+	token := flag.String("token", "", "токен, выданный при прошлой регистрации; пусто - зарегистрироваться")
+	electionID := flag.String("election", "", "идентификатор выборов; пусто - референдум")
+	ballot := answers{}
+	flag.Var(ballot, "answer", "ответ на вопрос выборов: вопрос=кандидат,... (для рейтингового - по порядку предпочтения); флаг повторяется")
+	list := flag.Bool("list", false, "показать выборы, которые проводит сервер")
+	flag.Parse()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	client := &voteapi.Client{BaseURL: *server, HTTP: &http.Client{}}
+
+	if *list {
+		showElections(ctx, client)
+		return
+	}
+	if *electionID != "" && *choice != "" {
+		log.Fatal("На выборах ответы задаются флагами -answer, -vote - только для референдума")
+	}
+	if *electionID == "" && len(ballot) > 0 {
+		log.Fatal("Ответы -answer требуют -election")
+	}
+
+	if *choice != "" || len(ballot) > 0 {
+		vote := 0
+		if *choice != "" {
+			for v, name := range voteapi.OptionNames {
+				if name == *choice {
+					vote = v
+				}
+			}
+			if vote == 0 {
+				log.Fatalf("Неизвестный вариант голоса: %s", *choice)
+			}
+		}
+		if *username == "" {
+			log.Fatal("Не задано имя избирателя (-user)")
+		}
+		if *keyPath != "" {
+			// Избиратель уже в списке: запросы подписываются его ключом
+			f, err := os.Open(*keyPath)
+			if err != nil {
+				log.Fatalf("Ошибка открытия ключа: %v", err)
+			}
+			client.Identity, err = gost.ReadPrivateKey(f)
+			f.Close()
+			if err != nil {
+				log.Fatalf("Ошибка чтения ключа: %v", err)
+			}
+		} else if *token == "" {
+			var err error
+			if *token, err = client.Register(ctx, *username); err != nil {
+				log.Fatalf("Ошибка регистрации: %v", err)
+			}
+			// Токен выдаётся один раз: с ним же голосуют на других выборах
+			fmt.Printf("Пользователь %s зарегистрирован, токен: %s\n", *username, *token)
+		}
+
+		var receipt *voteapi.Receipt
+		var err error
+		if *electionID != "" {
+			receipt, err = client.VoteElection(ctx, *username, *token, *electionID, ballot)
+		} else {
+			receipt, err = client.Vote(ctx, *username, *token, vote)
+		}
+		if err != nil {
+			log.Fatalf("Ошибка голосования: %v", err)
+		}
+		fmt.Printf("Голос пользователя %s принят, квитанция: запись %d, бюллетень %s\n", *username, receipt.Seq, receipt.BallotHash)
+		// Проверка по доске объявлений: бюллетень учтён, итоги пересчитываются
+		if *electionID != "" {
+			err = client.VerifyElectionInclusion(ctx, *electionID, receipt)
+		} else {
+			err = client.VerifyInclusion(ctx, receipt)
+		}
+		if err != nil {
+			log.Fatalf("Проверка доски объявлений не пройдена: %v", err)
+		}
+		fmt.Println("Бюллетень найден на доске объявлений, итоги сходятся с пересчётом")
+	}
+
+	if *electionID != "" {
+		showElectionResults(ctx, client, *electionID)
+		return
+	}
+	results, err := client.Results(ctx)
+	if err != nil {
+		log.Fatalf("Ошибка получения итогов: %v", err)
+	}
+	fmt.Println("Итоги голосования:")
+	printVotes(results, "")
+}
+
+// showElections - выборы сервера с вопросами и кандидатами
+func showElections(ctx context.Context, client *voteapi.Client) {
+	elections, err := client.Elections(ctx)
+	if err != nil {
+		log.Fatalf("Ошибка получения списка выборов: %v", err)
+	}
+	if len(elections) == 0 {
+		fmt.Println("Сервер не проводит выборов")
+	}
+	for _, e := range elections {
+		fmt.Printf("%s: %s", e.ID, e.Title)
+		if !e.Opens.IsZero() || !e.Closes.IsZero() {
+			fmt.Printf(" (%s - %s)", formatTime(e.Opens), formatTime(e.Closes))
+		}
+		fmt.Println()
+		for _, q := range e.Questions {
+			fmt.Printf("  %s [%s]: %s\n    %s\n", q.ID, q.Method, q.Text, strings.Join(q.Candidates, ", "))
+		}
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "..."
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// showElectionResults - итоги выборов по вопросам, для рейтинговых - по турам
+func showElectionResults(ctx context.Context, client *voteapi.Client, id string) {
+	result, err := client.ElectionResults(ctx, id)
+	if err != nil {
+		log.Fatalf("Ошибка получения итогов: %v", err)
+	}
+	fmt.Printf("Итоги выборов %s, бюллетеней: %d\n", result.Election, result.Ballots)
+	for _, q := range result.Questions {
+		fmt.Printf("%s [%s], воздержались: %d\n", q.ID, q.Method, q.Blank)
+		printVotes(q.Votes, "  ")
+		for i, round := range q.Rounds {
+			fmt.Printf("  тур %d, исчерпано бюллетеней: %d\n", i+1, round.Exhausted)
+			printVotes(round.Votes, "    ")
+			if len(round.Eliminated) > 0 {
+				fmt.Printf("    выбывают: %s\n", strings.Join(round.Eliminated, ", "))
+			}
+		}
+		switch len(q.Winners) {
+		case 0:
+			fmt.Println("  голосов нет")
+		case 1:
+			fmt.Printf("  победитель: %s\n", q.Winners[0])
+		default:
+			fmt.Printf("  ничья: %s\n", strings.Join(q.Winners, ", "))
+		}
+	}
+}
+
+// printVotes - голоса по названиям в алфавитном порядке
+func printVotes(votes map[string]int, indent string) {
+	names := make([]string, 0, len(votes))
+	for name := range votes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s%s: %d\n", indent, name, votes[name])
+	}
+}
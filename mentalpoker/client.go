@@ -0,0 +1,236 @@
+package mentalpoker
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"io"
+	"net"
+	"time"
+)
+
+// Client - игрок сетевого стола. Ключ создаётся и хранится только в процессе игрока:
+// раздающему уходят лишь зашифрованные колоды и частично расшифрованные карты, а ключ -
+// только после раздачи и только если стол требует проверки. Каждый шаг, разосланный
+// раздающим, игрок проверяет своей копией Protocol.
+type Client struct {
+	Name string
+	// Timeout - ожидание каждого сообщения раздающего после начала раздачи; должен быть
+	// больше TurnTimeout раздающего. 0 - удвоенный DefaultTimeout. Ожидание в лобби
+	// ограничено только ctx и JoinTimeout раздающего.
+	Timeout time.Duration
+	// Random - источник случайности для ключа и перемешивания; nil - crypto/rand
+	Random io.Reader
+
+	// tamper - подмена своего шага перед отправкой; задаётся только тестами нечестного игрока
+	tamper func(s *Step)
+}
+
+// Outcome - итог раздачи у игрока
+type Outcome struct {
+	Player int
+	Names  []string
+	Hand   []Card
+	Board  []Card
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return 2 * DefaultTimeout
+}
+
+func (c *Client) random() io.Reader {
+	if c.Random != nil {
+		return c.Random
+	}
+	return rand.Reader
+}
+
+// Join - подключение к раздающему по адресу addr и игра одной раздачи
+func (c *Client) Join(ctx context.Context, addr string) (*Outcome, error) {
+	var dialer net.Dialer
+	dialCtx, cancel := context.WithTimeout(ctx, c.timeout())
+	defer cancel()
+	conn, err := dialer.DialContext(dialCtx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return c.Play(ctx, conn)
+}
+
+// Play - одна раздача по установленному соединению; conn закрывается по возвращении
+func (c *Client) Play(ctx context.Context, conn net.Conn) (*Outcome, error) {
+	defer conn.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	out, err := c.play(conn)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return out, err
+}
+
+// play - приветствие, ходы по очереди, открытие своей руки и проверка итога
+func (c *Client) play(conn net.Conn) (*Outcome, error) {
+	if err := c.send(conn, &Message{Type: MsgHello, Name: c.Name}); err != nil {
+		return nil, err
+	}
+	start, err := c.receive(conn, MsgStart, 0)
+	if err != nil {
+		return nil, err
+	}
+	deck, err := NewDeck(start.P)
+	if err != nil {
+		return nil, err
+	}
+	pr, err := NewProtocol(deck, len(start.Names))
+	if err != nil {
+		return nil, err
+	}
+	if start.Player < 0 || start.Player >= pr.Players() {
+		return nil, &common.ParameterError{Name: "player", Reason: fmt.Sprintf("seat %d at a table of %d", start.Player, pr.Players())}
+	}
+	player, err := NewPlayer(start.Player, deck, c.random())
+	if err != nil {
+		return nil, err
+	}
+
+	for pr.Phase() < PhaseDone {
+		var mine *Step
+		if turn, _, _ := pr.Turn(); turn == player.ID {
+			s, err := player.Step(c.random(), pr)
+			if err != nil {
+				return nil, err
+			}
+			if c.tamper != nil {
+				c.tamper(&s)
+			}
+			if err = c.send(conn, &Message{Type: MsgStep, Step: &s}); err != nil {
+				return nil, err
+			}
+			mine = &s
+		}
+		m, err := c.receive(conn, MsgStep, c.timeout())
+		if err != nil {
+			return nil, err
+		}
+		if m.Step == nil {
+			return nil, &common.ParameterError{Name: "step", Reason: "dealer sent an empty step"}
+		}
+		if mine != nil && !sameStep(*mine, *m.Step) {
+			return nil, fmt.Errorf("dealer replaced the step of player %d: %w", player.ID, common.ErrVerification)
+		}
+		if err = pr.Apply(*m.Step); err != nil {
+			err = fmt.Errorf("dealer step: %w", err)
+			if pr.Phase() == PhaseFailed && start.Audit {
+				// Карты стола не открылись: ключи раскрываются, чтобы найти нарушителя
+				if auditErr := c.audit(conn, player, pr); auditErr != nil {
+					return nil, fmt.Errorf("%w; audit: %w", err, auditErr)
+				}
+			}
+			return nil, err
+		}
+	}
+	hand, err := player.OpenHand(pr)
+	if err != nil {
+		return nil, err
+	}
+
+	if start.Audit {
+		if err = c.audit(conn, player, pr); err != nil {
+			return nil, err
+		}
+	}
+
+	m, err := c.receive(conn, MsgResult, c.timeout())
+	if err != nil {
+		return nil, err
+	}
+	board, err := pr.Board()
+	if err != nil {
+		return nil, err
+	}
+	if !sameCards(board, m.Board) {
+		return nil, fmt.Errorf("dealer announced board %v, opened %v: %w", m.Board, board, common.ErrVerification)
+	}
+	return &Outcome{Player: player.ID, Names: start.Names, Hand: hand, Board: board}, nil
+}
+
+// audit - раскрытие своего ключа, получение ключей всех игроков и проверка всех шагов
+func (c *Client) audit(conn net.Conn, player *Player, pr *Protocol) error {
+	key := player.Key()
+	if err := c.send(conn, &Message{Type: MsgKey, Key: &key}); err != nil {
+		return err
+	}
+	m, err := c.receive(conn, MsgKeys, c.timeout())
+	if err != nil {
+		return err
+	}
+	return pr.Audit(m.Keys)
+}
+
+// sameStep - раздающий разослал шаг игрока без изменений
+func sameStep(a, b Step) bool {
+	if a.Phase != b.Phase || a.Player != b.Player || a.Target != b.Target || len(a.Cards) != len(b.Cards) {
+		return false
+	}
+	for i := range a.Cards {
+		if b.Cards[i] == nil || a.Cards[i].Cmp(b.Cards[i]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// sameCards - одинаковые карты в одинаковом порядке
+func sameCards(a, b []Card) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// send - сообщение раздающему
+func (c *Client) send(conn net.Conn, m *Message) error {
+	conn.SetWriteDeadline(time.Now().Add(c.timeout()))
+	if err := WriteMessage(conn, m); err != nil {
+		return netError("dealer", err)
+	}
+	return nil
+}
+
+// receive - следующее сообщение раздающего типа typ; timeout 0 - без ограничения.
+// MsgAbort превращается в ErrAborted с причиной, названной раздающим.
+func (c *Client) receive(conn net.Conn, typ string, timeout time.Duration) (*Message, error) {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	conn.SetReadDeadline(deadline)
+	m, err := ReadMessage(conn)
+	if err != nil {
+		return nil, netError("dealer", err)
+	}
+	switch m.Type {
+	case typ:
+		return m, nil
+	case MsgAbort:
+		return nil, fmt.Errorf("%s: %w", m.Error, ErrAborted)
+	}
+	return nil, fmt.Errorf("dealer sent %q, want %q: %w", m.Type, typ, ErrOutOfTurn)
+}
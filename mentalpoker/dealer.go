@@ -0,0 +1,382 @@
+package mentalpoker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"net"
+	"time"
+)
+
+// DefaultTimeout - ожидание хода игрока по умолчанию
+const DefaultTimeout = 30 * time.Second
+
+var (
+	// ErrTimeout - игрок или раздающий не ответил вовремя
+	ErrTimeout = errors.New("mentalpoker: peer timed out")
+	// ErrDisconnected - соединение с игроком или раздающим разорвано
+	ErrDisconnected = errors.New("mentalpoker: peer disconnected")
+	// ErrAborted - раздающий прервал раздачу
+	ErrAborted = errors.New("mentalpoker: hand aborted by dealer")
+)
+
+// Dealer - раздающий сетевого стола: собирает игроков в лобби, пересылает всем шаги
+// того, чей ход, и проверяет их своей копией Protocol. Ключей игроков раздающий не
+// знает: перемешивают и снимают слои сами игроки в своих процессах.
+type Dealer struct {
+	Deck    *Deck
+	Players int
+	// Audit - после раздачи игроки раскрывают ключи, и все проверяют все шаги
+	Audit bool
+	// JoinTimeout - ожидание всех игроков в лобби; 0 - без ограничения
+	JoinTimeout time.Duration
+	// TurnTimeout - ожидание каждого сообщения игрока; 0 - DefaultTimeout
+	TurnTimeout time.Duration
+	// Logf - журнал лобби и раздачи; nil - без журнала
+	Logf func(format string, args ...any)
+}
+
+// Result - итог раздачи у раздающего
+type Result struct {
+	Names []string
+	Board []Card
+	Keys  []Key // только при Audit
+}
+
+// seat - игрок за столом
+type seat struct {
+	name string
+	conn net.Conn
+}
+
+// event - сообщение игрока from или ошибка его соединения
+type event struct {
+	from int
+	msg  *Message
+	err  error
+}
+
+// game - одна раздача после заполнения стола
+type game struct {
+	d      *Dealer
+	seats  []*seat
+	events chan event
+	quit   chan struct{}
+}
+
+func (d *Dealer) turnTimeout() time.Duration {
+	if d.TurnTimeout > 0 {
+		return d.TurnTimeout
+	}
+	return DefaultTimeout
+}
+
+func (d *Dealer) logf(format string, args ...any) {
+	if d.Logf != nil {
+		d.Logf(format, args...)
+	}
+}
+
+// Serve - одна раздача: приём Players игроков на ln, раздача и рассылка итога.
+// ln закрывается, как только стол заполнен. При таймауте, разрыве соединения или
+// неверном шаге раздача прерывается, и всем игрокам уходит MsgAbort с причиной.
+// Если не открылись карты стола, а Audit включён, игроки сначала раскрывают ключи,
+// и причина называет нарушителя.
+func (d *Dealer) Serve(ctx context.Context, ln net.Listener) (*Result, error) {
+	pr, err := NewProtocol(d.Deck, d.Players)
+	if err != nil {
+		ln.Close()
+		return nil, err
+	}
+	seats, err := d.lobby(ctx, ln)
+	if err != nil {
+		return nil, err
+	}
+	g := &game{d: d, seats: seats, events: make(chan event), quit: make(chan struct{})}
+	defer g.close()
+	go func() {
+		select {
+		case <-ctx.Done():
+			g.closeConns()
+		case <-g.quit:
+		}
+	}()
+	res, err := g.play(ctx, pr)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		g.abort(err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// lobby - ожидание Players игроков; игроки получают номера в порядке прихода
+func (d *Dealer) lobby(ctx context.Context, ln net.Listener) ([]*seat, error) {
+	quit := make(chan struct{})
+	defer close(quit)
+	defer ln.Close()
+	joined := make(chan *seat)
+	failed := make(chan error, 1)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				failed <- err
+				return
+			}
+			go func() {
+				s, err := d.hello(conn)
+				if err != nil {
+					d.logf("Раздающий: %s отклонён: %v", conn.RemoteAddr(), err)
+					conn.Close()
+					return
+				}
+				select {
+				case joined <- s:
+				case <-quit:
+					WriteMessage(conn, &Message{Type: MsgAbort, Error: "table is full"})
+					conn.Close()
+				}
+			}()
+		}
+	}()
+
+	var timeout <-chan time.Time
+	if d.JoinTimeout > 0 {
+		timer := time.NewTimer(d.JoinTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var seats []*seat
+	for len(seats) < d.Players {
+		var err error
+		select {
+		case s := <-joined:
+			if s.name == "" {
+				s.name = fmt.Sprintf("player %d", len(seats)+1)
+			}
+			seats = append(seats, s)
+			d.logf("Раздающий: %s сел за стол (%d из %d)", s.name, len(seats), d.Players)
+			continue
+		case <-timeout:
+			err = fmt.Errorf("%d of %d players joined in %v: %w", len(seats), d.Players, d.JoinTimeout, ErrTimeout)
+		case err = <-failed:
+			err = fmt.Errorf("lobby: %w", err)
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		(&game{seats: seats}).abort(err)
+		return nil, err
+	}
+	return seats, nil
+}
+
+// hello - приветствие нового игрока
+func (d *Dealer) hello(conn net.Conn) (*seat, error) {
+	conn.SetDeadline(time.Now().Add(d.turnTimeout()))
+	m, err := ReadMessage(conn)
+	if err != nil {
+		return nil, netError("hello", err)
+	}
+	if m.Type != MsgHello {
+		return nil, fmt.Errorf("got %q, want %q: %w", m.Type, MsgHello, ErrOutOfTurn)
+	}
+	conn.SetDeadline(time.Time{})
+	return &seat{name: m.Name, conn: conn}, nil
+}
+
+// play - раздача от перемешивания до рассылки карт стола
+func (g *game) play(ctx context.Context, pr *Protocol) (*Result, error) {
+	res := &Result{Names: make([]string, len(g.seats))}
+	for i, s := range g.seats {
+		res.Names[i] = s.name
+	}
+	for i := range g.seats {
+		err := g.send(i, &Message{Type: MsgStart, Player: i, Names: res.Names, P: g.d.Deck.P, Audit: g.d.Audit})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i, s := range g.seats {
+		go g.read(i, s)
+	}
+
+	for pr.Phase() < PhaseDone {
+		player, _, _ := pr.Turn()
+		m, err := g.receive(ctx, MsgStep, func(from int) bool { return from == player })
+		if err != nil {
+			return nil, err
+		}
+		if m.Step == nil {
+			return nil, &common.ParameterError{Name: "step", Reason: fmt.Sprintf("player %d sent an empty step", player)}
+		}
+		if err = pr.Apply(*m.Step); err != nil {
+			if pr.Phase() == PhaseFailed {
+				// Виноват не обязательно последний игрок: найти нарушителя может только Audit
+				if g.d.Audit {
+					return nil, g.failed(ctx, pr, m.Step, err)
+				}
+				return nil, fmt.Errorf("%w (keys are not revealed without audit)", err)
+			}
+			return nil, fmt.Errorf("player %d (%s): %w", player, g.seats[player].name, err)
+		}
+		if err = g.broadcast(&Message{Type: MsgStep, Step: m.Step}); err != nil {
+			return nil, err
+		}
+	}
+
+	if g.d.Audit {
+		keys, err := g.keys(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err = pr.Audit(keys); err != nil {
+			return nil, err
+		}
+		res.Keys = keys
+		g.d.logf("Раздающий: ключи раскрыты, все шаги проверены")
+	}
+
+	board, err := pr.Board()
+	if err != nil {
+		return nil, err
+	}
+	res.Board = board
+	if err = g.broadcast(&Message{Type: MsgResult, Board: board}); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// failed - карты стола не открылись: последний шаг рассылается, чтобы игроки тоже
+// перешли в PhaseFailed, ключи раскрываются, и Audit называет нарушителя
+func (g *game) failed(ctx context.Context, pr *Protocol, s *Step, boardErr error) error {
+	if err := g.broadcast(&Message{Type: MsgStep, Step: s}); err != nil {
+		return err
+	}
+	keys, err := g.keys(ctx)
+	if err != nil {
+		return fmt.Errorf("%w; audit: %w", boardErr, err)
+	}
+	if err = pr.Audit(keys); err != nil {
+		g.d.logf("Раздающий: раздача сорвана, %v", err)
+		return fmt.Errorf("%w; audit: %w", boardErr, err)
+	}
+	return fmt.Errorf("%w; audit found no cheating player", boardErr)
+}
+
+// keys - сбор ключей всех игроков в любом порядке и рассылка их всем
+func (g *game) keys(ctx context.Context) ([]Key, error) {
+	keys := make([]Key, len(g.seats))
+	got := make([]bool, len(g.seats))
+	for range g.seats {
+		m, err := g.receive(ctx, MsgKey, func(from int) bool { return !got[from] })
+		if err != nil {
+			return nil, err
+		}
+		if m.Key == nil {
+			return nil, &common.ParameterError{Name: "key", Reason: fmt.Sprintf("player %d sent an empty key", m.Player)}
+		}
+		got[m.Player], keys[m.Player] = true, *m.Key
+	}
+	if err := g.broadcast(&Message{Type: MsgKeys, Keys: keys}); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// read - чтение сообщений игрока i до первой ошибки соединения
+func (g *game) read(i int, s *seat) {
+	for {
+		m, err := ReadMessage(s.conn)
+		select {
+		case g.events <- event{from: i, msg: m, err: err}:
+		case <-g.quit:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// receive - следующее сообщение типа typ от игрока, для которого want истинно.
+// Сообщение не от того игрока или не того типа прерывает раздачу. В Message.Player
+// записывается номер отправителя.
+func (g *game) receive(ctx context.Context, typ string, want func(from int) bool) (*Message, error) {
+	timer := time.NewTimer(g.d.turnTimeout())
+	defer timer.Stop()
+	select {
+	case e := <-g.events:
+		name := g.seats[e.from].name
+		if e.err != nil {
+			return nil, netError(fmt.Sprintf("player %d (%s)", e.from, name), e.err)
+		}
+		if e.msg.Type != typ || !want(e.from) {
+			return nil, fmt.Errorf("player %d (%s) sent %q out of turn: %w", e.from, name, e.msg.Type, ErrOutOfTurn)
+		}
+		e.msg.Player = e.from
+		return e.msg, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("no %q in %v: %w", typ, g.d.turnTimeout(), ErrTimeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// send - сообщение игроку i
+func (g *game) send(i int, m *Message) error {
+	s := g.seats[i]
+	s.conn.SetWriteDeadline(time.Now().Add(g.d.turnTimeout()))
+	if err := WriteMessage(s.conn, m); err != nil {
+		return netError(fmt.Sprintf("player %d (%s)", i, s.name), err)
+	}
+	return nil
+}
+
+// broadcast - сообщение всем игрокам
+func (g *game) broadcast(m *Message) error {
+	for i := range g.seats {
+		if err := g.send(i, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// abort - рассылка причины прерывания раздачи и закрытие соединений
+func (g *game) abort(reason error) {
+	for _, s := range g.seats {
+		s.conn.SetWriteDeadline(time.Now().Add(time.Second))
+		WriteMessage(s.conn, &Message{Type: MsgAbort, Error: reason.Error()})
+	}
+	g.closeConns()
+}
+
+func (g *game) closeConns() {
+	for _, s := range g.seats {
+		s.conn.Close()
+	}
+}
+
+// close - завершение раздачи: соединения закрываются, читатели останавливаются
+func (g *game) close() {
+	close(g.quit)
+	g.closeConns()
+}
+
+// netError - ошибка соединения с peer: таймаут - ErrTimeout, ошибки кадра - как есть,
+// остальное - ErrDisconnected
+func netError(peer string, err error) error {
+	var ne net.Error
+	switch {
+	case errors.As(err, &ne) && ne.Timeout():
+		return fmt.Errorf("%s: %w", peer, ErrTimeout)
+	case errors.Is(err, ErrFrameTooLarge), errors.Is(err, common.ErrInvalidParameters):
+		return fmt.Errorf("%s: %w", peer, err)
+	}
+	return fmt.Errorf("%s: %w (%v)", peer, ErrDisconnected, err)
+}
@@ -0,0 +1,125 @@
+// Package mentalpoker - ментальный покер SRA (Шамир, Ривест, Адлеман): игроки по очереди
+// шифруют колоду коммутативным шифром x^C mod p и перемешивают её, поэтому ни один
+// игрок не знает, где какая карта. Карта открывается, только когда свои слои с неё
+// сняли все игроки; карту руки последним открывает её владелец. Ход раздачи задаёт
+// Protocol - конечный автомат с проверкой каждого шага, Table разыгрывает раздачу
+// в одном процессе, а Dealer и Client - по TCP, где каждый игрок держит свой ключ
+// в своём процессе.
+package mentalpoker
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"io"
+	"math/big"
+)
+
+// DeckSize - карт в колоде
+const DeckSize = 52
+
+// minPrimeBits - наименьшая длина простого p
+const minPrimeBits = 64
+
+// ErrUnknownCard - число не кодирует ни одну карту: кто-то из игроков снял слой неверно
+var ErrUnknownCard = errors.New("mentalpoker: value does not encode a card")
+
+// Card - карта: номер от 0 до DeckSize-1, достоинство Card/4, масть Card%4
+type Card int
+
+var (
+	ranks = []string{"2", "3", "4", "5", "6", "7", "8", "9", "10", "J", "Q", "K", "A"}
+	suits = []string{"♥", "♠", "♣", "♦"}
+)
+
+func (c Card) String() string {
+	if c < 0 || c >= DeckSize {
+		return fmt.Sprintf("Card(%d)", int(c))
+	}
+	return ranks[c/4] + suits[c%4]
+}
+
+// Deck - общие параметры раздачи: безопасное простое p = 2q + 1 и коды карт.
+// Карта c кодируется квадратом (c + 2)^2 mod p: все коды лежат в подгруппе квадратичных
+// вычетов порядка q, а шифрование сохраняет квадратичность, поэтому символ Лежандра
+// зашифрованной карты ничего не говорит о ней.
+type Deck struct {
+	P, Q  *big.Int
+	mc    *common.ModContext
+	codes []*big.Int
+	index map[string]Card // код -> карта
+}
+
+// NewDeck - колода над безопасным простым p
+func NewDeck(p *big.Int) (*Deck, error) {
+	if p == nil || p.BitLen() < minPrimeBits {
+		return nil, &common.ParameterError{Name: "p", Reason: fmt.Sprintf("prime must have at least %d bits", minPrimeBits)}
+	}
+	q := new(big.Int).Rsh(p, 1)
+	if p.Bit(0) == 0 || !p.ProbablyPrime(20) || !q.ProbablyPrime(20) {
+		return nil, &common.ParameterError{Name: "p", Reason: "p and (p - 1) / 2 must be prime"}
+	}
+	mc, err := common.NewModContext(p)
+	if err != nil {
+		return nil, err
+	}
+	d := &Deck{P: new(big.Int).Set(p), Q: q, mc: mc, codes: make([]*big.Int, DeckSize), index: make(map[string]Card, DeckSize)}
+	for c := Card(0); c < DeckSize; c++ {
+		x := big.NewInt(int64(c) + 2)
+		d.codes[c] = mc.Mul(x, x)
+		d.index[string(d.codes[c].Bytes())] = c
+	}
+	return d, nil
+}
+
+// GenerateDeck - колода над случайным безопасным простым из bits бит
+func GenerateDeck(random io.Reader, bits int) (*Deck, error) {
+	if bits < minPrimeBits {
+		return nil, &common.ParameterError{Name: "bits", Reason: fmt.Sprintf("prime must have at least %d bits", minPrimeBits)}
+	}
+	for {
+		q, err := rand.Prime(random, bits-1)
+		if err != nil {
+			return nil, err
+		}
+		p := new(big.Int).Lsh(q, 1)
+		p.Add(p, big.NewInt(1)) // P = 2 * q + 1
+		if p.BitLen() == bits && p.ProbablyPrime(20) {
+			if common.Tracing() {
+				common.Trace("mentalpoker", "prime", common.Val("p", p))
+			}
+			return NewDeck(p)
+		}
+	}
+}
+
+// Encode - код карты c
+func (d *Deck) Encode(c Card) *big.Int {
+	return new(big.Int).Set(d.codes[c])
+}
+
+// Codes - коды всех карт по порядку: открытая колода перед первым перемешиванием
+func (d *Deck) Codes() []*big.Int {
+	out := make([]*big.Int, DeckSize)
+	for i, x := range d.codes {
+		out[i] = new(big.Int).Set(x)
+	}
+	return out
+}
+
+// Decode - карта по коду
+func (d *Deck) Decode(x *big.Int) (Card, error) {
+	if x != nil {
+		if c, ok := d.index[string(x.Bytes())]; ok {
+			return c, nil
+		}
+	}
+	return 0, ErrUnknownCard
+}
+
+// InGroup - 1 < x < p и x - квадратичный вычет, т. е. x^q = 1 mod p. Только такие
+// значения могут быть зашифрованными картами.
+func (d *Deck) InGroup(x *big.Int) bool {
+	return x != nil && x.Cmp(big.NewInt(1)) > 0 && x.Cmp(d.P) < 0 && d.mc.Exp(x, d.Q).Cmp(big.NewInt(1)) == 0
+}
@@ -0,0 +1,219 @@
+package mentalpoker
+
+import (
+	"crypto/rand"
+	"errors"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+	"testing"
+)
+
+// testPrime - 256-битное безопасное простое p = 2q + 1
+const testPrime = "d83086cce440bc26e3dd065f02ee86648dee82f6d0fe498808168204911ffa37"
+
+// newTestDeck - колода над testPrime
+func newTestDeck(t *testing.T) *Deck {
+	t.Helper()
+	p, _ := new(big.Int).SetString(testPrime, 16)
+	deck, err := NewDeck(p)
+	if err != nil {
+		t.Fatalf("NewDeck() error = %v", err)
+	}
+	return deck
+}
+
+// newTestTable - стол с розданными картами
+func newTestTable(t *testing.T, players int) *Table {
+	t.Helper()
+	table, err := NewTable(rand.Reader, newTestDeck(t), players)
+	if err != nil {
+		t.Fatalf("NewTable() error = %v", err)
+	}
+	if err = table.Deal(rand.Reader); err != nil {
+		t.Fatalf("Deal() error = %v", err)
+	}
+	return table
+}
+
+func TestCardString(t *testing.T) {
+	tests := []struct {
+		card Card
+		want string
+	}{
+		{0, "2♥"},
+		{1, "2♠"},
+		{4, "3♥"},
+		{35, "10♦"},
+		{51, "A♦"},
+		{52, "Card(52)"},
+	}
+	for _, tt := range tests {
+		if got := tt.card.String(); got != tt.want {
+			t.Errorf("Card(%d).String() = %q, want %q", int(tt.card), got, tt.want)
+		}
+	}
+}
+
+func TestNewDeck(t *testing.T) {
+	deck := newTestDeck(t)
+	for c := Card(0); c < DeckSize; c++ {
+		x := deck.Encode(c)
+		if !deck.InGroup(x) {
+			t.Errorf("Encode(%s) is not a quadratic residue", c)
+		}
+		if got, err := deck.Decode(x); err != nil || got != c {
+			t.Errorf("Decode(Encode(%s)) = %s, %v", c, got, err)
+		}
+	}
+	if _, err := deck.Decode(big.NewInt(2)); !errors.Is(err, ErrUnknownCard) {
+		t.Errorf("Decode(2) error = %v, want %v", err, ErrUnknownCard)
+	}
+
+	p, _ := new(big.Int).SetString(testPrime, 16)
+	tests := []struct {
+		name string
+		p    *big.Int
+	}{
+		{"нет простого", nil},
+		{"короткое простое", big.NewInt(1019)},
+		{"не безопасное простое", new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 127), big.NewInt(1))}, // (2^127 - 2) / 2 делится на 3
+		{"составное", new(big.Int).Mul(p, big.NewInt(3))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewDeck(tt.p); !errors.Is(err, common.ErrInvalidParameters) {
+				t.Errorf("NewDeck() error = %v, want %v", err, common.ErrInvalidParameters)
+			}
+		})
+	}
+}
+
+func TestDeal(t *testing.T) {
+	for _, players := range []int{2, 3, 5, 23} {
+		table := newTestTable(t, players)
+		hands, err := table.Hands()
+		if err != nil {
+			t.Fatalf("%d players: Hands() error = %v", players, err)
+		}
+		board, err := table.Protocol.Board()
+		if err != nil {
+			t.Fatalf("%d players: Board() error = %v", players, err)
+		}
+		seen := make(map[Card]bool)
+		for _, c := range append(board, flatten(hands)...) {
+			if seen[c] {
+				t.Errorf("%d players: card %s dealt twice", players, c)
+			}
+			seen[c] = true
+		}
+		if len(seen) != players*HandSize+BoardSize {
+			t.Errorf("%d players: %d cards dealt, want %d", players, len(seen), players*HandSize+BoardSize)
+		}
+		if n := len(table.Protocol.Remaining()); n != DeckSize-len(seen) {
+			t.Errorf("%d players: Remaining() has %d cards, want %d", players, n, DeckSize-len(seen))
+		}
+		if err = table.Audit(); err != nil {
+			t.Errorf("%d players: Audit() error = %v", players, err)
+		}
+	}
+	if _, err := NewProtocol(newTestDeck(t), 1); !errors.Is(err, common.ErrInvalidParameters) {
+		t.Errorf("NewProtocol() for 1 player error = %v, want %v", err, common.ErrInvalidParameters)
+	}
+	if _, err := NewProtocol(newTestDeck(t), 24); !errors.Is(err, common.ErrInvalidParameters) {
+		t.Errorf("NewProtocol() for 24 players error = %v, want %v", err, common.ErrInvalidParameters)
+	}
+}
+
+// flatten - карты всех рук подряд
+func flatten(hands [][]Card) []Card {
+	var out []Card
+	for _, h := range hands {
+		out = append(out, h...)
+	}
+	return out
+}
+
+// stripWith - снятие слоёв ключами игроков коалиции
+func stripWith(coalition []*Player, cards []*big.Int) []*big.Int {
+	for _, p := range coalition {
+		cards = p.Strip(cards)
+	}
+	return cards
+}
+
+// decodable - сколько значений кодирует карты
+func decodable(deck *Deck, cards []*big.Int) int {
+	n := 0
+	for _, x := range cards {
+		if _, err := deck.Decode(x); err == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// TestCoalition - любая коалиция, в которую входят не все игроки, даже сложив ключи,
+// не открывает ни одной нерозданной карты и ни одной карты чужой руки; открывают
+// их только все игроки вместе
+func TestCoalition(t *testing.T) {
+	const players = 4
+	table := newTestTable(t, players)
+	deck := table.Deck
+	remaining := table.Protocol.Remaining()
+	for mask := 0; mask < 1<<players; mask++ {
+		var coalition []*Player
+		for i, p := range table.Players {
+			if mask&(1<<i) != 0 {
+				coalition = append(coalition, p)
+			}
+		}
+		everyone := len(coalition) == players
+
+		got := decodable(deck, stripWith(coalition, remaining))
+		if everyone && got != len(remaining) {
+			t.Errorf("all players open %d of %d undealt cards", got, len(remaining))
+		}
+		if !everyone && got != 0 {
+			t.Errorf("coalition %04b opens %d undealt cards", mask, got)
+		}
+
+		for k := 0; k < players; k++ {
+			if mask&(1<<k) != 0 {
+				continue
+			}
+			hand, err := table.Protocol.Hand(k)
+			if err != nil {
+				t.Fatalf("Hand(%d) error = %v", k, err)
+			}
+			// Карты руки без снятия слоёв и со снятием слоёв коалиции
+			if decodable(deck, hand)+decodable(deck, stripWith(coalition, hand)) != 0 {
+				t.Errorf("coalition %04b opens a card of player %d", mask, k)
+			}
+		}
+	}
+}
+
+// TestResidues - все значения, которые видят игроки на каждом шаге, - квадратичные
+// вычеты, поэтому символ Лежандра не выдаёт ни одного бита о карте (атака Липтона на SRA)
+func TestResidues(t *testing.T) {
+	table, err := NewTable(rand.Reader, newTestDeck(t), 3)
+	if err != nil {
+		t.Fatalf("NewTable() error = %v", err)
+	}
+	pr := table.Protocol
+	for pr.Phase() < PhaseDone {
+		player, phase, _ := pr.Turn()
+		step, err := table.Players[player].Step(rand.Reader, pr)
+		if err != nil {
+			t.Fatalf("Step() error = %v", err)
+		}
+		for i, x := range step.Cards {
+			if big.Jacobi(x, table.Deck.P) != 1 {
+				t.Fatalf("player %d %s card %d is a quadratic non-residue", player, phase, i)
+			}
+		}
+		if err = pr.Apply(step); err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package mentalpoker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"github.com/Raimguzhinov/protect-information/common"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// netResult - итог сетевой раздачи у игрока
+type netResult struct {
+	out *Outcome
+	err error
+}
+
+// listen - слушатель на свободном порту localhost
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	return ln
+}
+
+// serve - раздающий в отдельной горутине
+func serve(d *Dealer, ln net.Listener) (<-chan *Result, <-chan error) {
+	results, errs := make(chan *Result, 1), make(chan error, 1)
+	go func() {
+		res, err := d.Serve(context.Background(), ln)
+		results <- res
+		errs <- err
+	}()
+	return results, errs
+}
+
+// join - игрок в отдельной горутине
+func join(c *Client, addr string) <-chan netResult {
+	ch := make(chan netResult, 1)
+	go func() {
+		out, err := c.Join(context.Background(), addr)
+		ch <- netResult{out, err}
+	}()
+	return ch
+}
+
+// dial - «сырое» соединение, которое ведёт себя как задано тестом
+func dial(t *testing.T, addr, name string) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("net.Dial() error = %v", err)
+	}
+	if err = WriteMessage(conn, &Message{Type: MsgHello, Name: name}); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+	return conn
+}
+
+// waitStart - ожидание начала раздачи на «сыром» соединении
+func waitStart(t *testing.T, conn net.Conn) *Message {
+	t.Helper()
+	m, err := ReadMessage(conn)
+	if err != nil || m.Type != MsgStart {
+		t.Fatalf("ReadMessage() = %v, %v, want %q", m, err, MsgStart)
+	}
+	return m
+}
+
+func TestWire(t *testing.T) {
+	var buf bytes.Buffer
+	want := &Message{Type: MsgStep, Step: &Step{Phase: PhaseHands, Player: 1, Target: 2, Cards: newTestDeck(t).Codes()[:2]}}
+	if err := WriteMessage(&buf, want); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+	got, err := ReadMessage(&buf)
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	if got.Type != want.Type || !sameStep(*got.Step, *want.Step) {
+		t.Errorf("ReadMessage() = %+v, want %+v", got.Step, want.Step)
+	}
+
+	frame := func(size uint32, body string) []byte {
+		b := binary.BigEndian.AppendUint32(nil, size)
+		return append(b, body...)
+	}
+	tests := []struct {
+		name  string
+		frame []byte
+		want  error
+	}{
+		{"пустой поток", nil, io.EOF},
+		{"обрезанная длина", []byte{0, 0}, io.ErrUnexpectedEOF},
+		{"обрезанное тело", frame(10, `{"type"`), io.ErrUnexpectedEOF},
+		{"слишком длинный кадр", frame(MaxFrameSize+1, ""), ErrFrameTooLarge},
+		{"не JSON", frame(3, "abc"), common.ErrInvalidParameters},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadMessage(bytes.NewReader(tt.frame)); !errors.Is(err, tt.want) {
+				t.Errorf("ReadMessage() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetworkDeal(t *testing.T) {
+	for _, audit := range []bool{false, true} {
+		const players = 3
+		ln := listen(t)
+		results, errs := serve(&Dealer{Deck: newTestDeck(t), Players: players, Audit: audit, JoinTimeout: 10 * time.Second, TurnTimeout: 10 * time.Second}, ln)
+		var outs []<-chan netResult
+		for _, name := range []string{"alice", "bob", "carol"} {
+			outs = append(outs, join(&Client{Name: name, Timeout: 20 * time.Second}, ln.Addr().String()))
+		}
+		if err := <-errs; err != nil {
+			t.Fatalf("audit %v: Serve() error = %v", audit, err)
+		}
+		res := <-results
+		if (len(res.Keys) == players) != audit {
+			t.Errorf("audit %v: dealer got %d keys", audit, len(res.Keys))
+		}
+		seen := make(map[Card]bool)
+		for _, c := range res.Board {
+			seen[c] = true
+		}
+		for _, ch := range outs {
+			r := <-ch
+			if r.err != nil {
+				t.Fatalf("audit %v: Join() error = %v", audit, r.err)
+			}
+			if !sameCards(r.out.Board, res.Board) {
+				t.Errorf("audit %v: player %d board %v, dealer board %v", audit, r.out.Player, r.out.Board, res.Board)
+			}
+			for _, c := range r.out.Hand {
+				if seen[c] {
+					t.Errorf("audit %v: card %s dealt twice", audit, c)
+				}
+				seen[c] = true
+			}
+		}
+		if len(seen) != players*HandSize+BoardSize {
+			t.Errorf("audit %v: %d cards dealt, want %d", audit, len(seen), players*HandSize+BoardSize)
+		}
+	}
+}
+
+// TestNetworkFailures - при таймауте, разрыве или нарушении очереди раздающий
+// прерывает раздачу, а честные игроки получают ErrAborted
+func TestNetworkFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		// cheat - поведение второго игрока после начала раздачи
+		cheat func(conn net.Conn)
+		want  error
+	}{
+		{"игрок молчит", func(conn net.Conn) {
+			time.Sleep(time.Second)
+		}, ErrTimeout},
+		{"игрок отключился", func(conn net.Conn) {
+			conn.Close()
+		}, ErrDisconnected},
+		{"ход не в свою очередь", func(conn net.Conn) {
+			WriteMessage(conn, &Message{Type: MsgStep, Step: &Step{Phase: PhaseBoard, Player: 1}})
+			time.Sleep(time.Second)
+		}, ErrOutOfTurn},
+		{"мусор вместо кадра", func(conn net.Conn) {
+			conn.Write([]byte{0xff, 0xff, 0xff, 0xff})
+			time.Sleep(time.Second)
+		}, ErrFrameTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln := listen(t)
+			_, errs := serve(&Dealer{Deck: newTestDeck(t), Players: 3, JoinTimeout: 5 * time.Second, TurnTimeout: 300 * time.Millisecond}, ln)
+			honest := join(&Client{Name: "alice", Timeout: 5 * time.Second}, ln.Addr().String())
+			// Игрок 0 подключается раньше, чтобы нечестный игрок гарантированно был вторым
+			time.Sleep(50 * time.Millisecond)
+			conn := dial(t, ln.Addr().String(), "mallory")
+			defer conn.Close()
+			time.Sleep(50 * time.Millisecond)
+			other := join(&Client{Name: "carol", Timeout: 5 * time.Second}, ln.Addr().String())
+			waitStart(t, conn)
+			tt.cheat(conn)
+
+			if err := <-errs; !errors.Is(err, tt.want) {
+				t.Errorf("Serve() error = %v, want %v", err, tt.want)
+			}
+			for _, ch := range []<-chan netResult{honest, other} {
+				if r := <-ch; !errors.Is(r.err, ErrAborted) {
+					t.Errorf("Join() error = %v, want %v", r.err, ErrAborted)
+				}
+			}
+		})
+	}
+}
+
+// TestNetworkCheater - игрок 1 неверно снимает свой слой с карт стола, и они не
+// открываются. С проверкой игроки раскрывают ключи, и раздающий и все игроки
+// называют нарушителя; без проверки раздача просто прерывается.
+func TestNetworkCheater(t *testing.T) {
+	deck := newTestDeck(t)
+	tamper := func(s *Step) {
+		if s.Phase == PhaseBoard {
+			for i, x := range s.Cards {
+				s.Cards[i] = deck.mc.Exp(x, big.NewInt(3))
+			}
+		}
+	}
+	for _, audit := range []bool{true, false} {
+		ln := listen(t)
+		_, errs := serve(&Dealer{Deck: deck, Players: 3, Audit: audit, JoinTimeout: 5 * time.Second, TurnTimeout: 5 * time.Second}, ln)
+		var outs []<-chan netResult
+		for i, name := range []string{"alice", "mallory", "carol"} {
+			c := &Client{Name: name, Timeout: 10 * time.Second}
+			if i == 1 {
+				c.tamper = tamper
+			}
+			outs = append(outs, join(c, ln.Addr().String()))
+			// Игроки садятся за стол по очереди, чтобы нарушитель был игроком 1
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		err := <-errs
+		if !errors.Is(err, ErrUnknownCard) {
+			t.Errorf("audit %v: Serve() error = %v, want %v", audit, err, ErrUnknownCard)
+		}
+		if audit && (!errors.Is(err, common.ErrVerification) || !strings.Contains(err.Error(), "player 1 board")) {
+			t.Errorf("audit %v: Serve() error = %v, want audit naming player 1", audit, err)
+		}
+		for i, ch := range outs {
+			r := <-ch
+			switch {
+			case audit && (!errors.Is(r.err, common.ErrVerification) || !strings.Contains(r.err.Error(), "player 1 board")):
+				t.Errorf("audit %v: player %d Join() error = %v, want audit naming player 1", audit, i, r.err)
+			case !audit && !errors.Is(r.err, ErrAborted):
+				t.Errorf("audit %v: player %d Join() error = %v, want %v", audit, i, r.err, ErrAborted)
+			}
+		}
+	}
+}
+
+func TestLobbyTimeout(t *testing.T) {
+	ln := listen(t)
+	_, errs := serve(&Dealer{Deck: newTestDeck(t), Players: 3, JoinTimeout: 200 * time.Millisecond}, ln)
+	alone := join(&Client{Name: "alice"}, ln.Addr().String())
+	if err := <-errs; !errors.Is(err, ErrTimeout) {
+		t.Errorf("Serve() error = %v, want %v", err, ErrTimeout)
+	}
+	if r := <-alone; !errors.Is(r.err, ErrAborted) {
+		t.Errorf("Join() error = %v, want %v", r.err, ErrAborted)
+	}
+}
+
+// TestDealerCheats - игрок не доверяет раздающему: подменённый шаг, чужой ход
+// и пропавший раздающий обнаруживаются копией Protocol и таймаутами игрока
+func TestDealerCheats(t *testing.T) {
+	deck := newTestDeck(t)
+	tests := []struct {
+		name   string
+		player int
+		// dealer - поведение раздающего после отправки MsgStart игроку player из двух
+		dealer func(conn net.Conn)
+		want   error
+	}{
+		{"подменённый шаг игрока", 0, func(conn net.Conn) {
+			m, _ := ReadMessage(conn)
+			m.Step.Cards[0], m.Step.Cards[1] = m.Step.Cards[1], m.Step.Cards[0]
+			WriteMessage(conn, m)
+		}, common.ErrVerification},
+		{"ход вне очереди", 1, func(conn net.Conn) {
+			WriteMessage(conn, &Message{Type: MsgStep, Step: &Step{Phase: PhaseBoard, Player: 1}})
+		}, ErrOutOfTurn},
+		{"раздающий молчит", 0, func(conn net.Conn) {
+			ReadMessage(conn)
+			time.Sleep(time.Second)
+		}, ErrTimeout},
+		{"раздающий отключился", 0, func(conn net.Conn) {
+			conn.Close()
+		}, ErrDisconnected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer server.Close()
+			go func() {
+				ReadMessage(server)
+				WriteMessage(server, &Message{Type: MsgStart, Player: tt.player, Names: []string{"alice", "bob"}, P: deck.P})
+				tt.dealer(server)
+			}()
+			c := &Client{Name: "alice", Timeout: 300 * time.Millisecond}
+			if _, err := c.Play(context.Background(), client); !errors.Is(err, tt.want) {
+				t.Errorf("Play() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetworkCancel(t *testing.T) {
+	ln := listen(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := (&Dealer{Deck: newTestDeck(t), Players: 2}).Serve(ctx, ln)
+		errs <- err
+	}()
+	conn := dial(t, ln.Addr().String(), "alice")
+	defer conn.Close()
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("Serve() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := net.Dial("tcp", ln.Addr().String()); err == nil {
+		t.Errorf("listener is still open after Serve()")
+	}
+}
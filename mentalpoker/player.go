@@ -0,0 +1,115 @@
+package mentalpoker
+
+import (
+	"crypto/rand"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"io"
+	"math/big"
+)
+
+// Key - ключ игрока: C взаимно просто с p - 1, D = C^-1 mod (p - 1). Шифры разных
+// игроков коммутируют: (x^Ca)^Cb = (x^Cb)^Ca. Ключ раскрывается после раздачи для Audit.
+type Key struct {
+	C *big.Int `json:"c"`
+	D *big.Int `json:"d"`
+}
+
+// Player - игрок со своим ключом; ключ не покидает игрока до конца раздачи
+type Player struct {
+	ID   int
+	deck *Deck
+	key  Key
+}
+
+// NewPlayer - игрок id со случайным ключом
+func NewPlayer(id int, deck *Deck, random io.Reader) (*Player, error) {
+	phi := new(big.Int).Sub(deck.P, big.NewInt(1)) // φ(p) = p - 1
+	for {
+		c, err := rand.Int(random, phi)
+		if err != nil {
+			return nil, err
+		}
+		// C нечётно и не кратно q, иначе не обратимо по модулю 2q
+		if c.Cmp(big.NewInt(1)) <= 0 || c.Bit(0) == 0 || c.Cmp(deck.Q) == 0 {
+			continue
+		}
+		d, err := common.ModInverseBig(c, phi)
+		if err != nil {
+			continue
+		}
+		if common.Tracing() {
+			common.Trace("mentalpoker", fmt.Sprintf("player %d keys", id), common.Val("c", c), common.Val("d", d))
+		}
+		return &Player{ID: id, deck: deck, key: Key{C: c, D: d}}, nil
+	}
+}
+
+// Key - ключ игрока; раскрывается только после раздачи
+func (p *Player) Key() Key {
+	return Key{C: new(big.Int).Set(p.key.C), D: new(big.Int).Set(p.key.D)}
+}
+
+// exp - каждое значение в степени e
+func (p *Player) exp(cards []*big.Int, e *big.Int) []*big.Int {
+	out := make([]*big.Int, len(cards))
+	for i, x := range cards {
+		out[i] = p.deck.mc.Exp(x, e)
+	}
+	return out
+}
+
+// Shuffle - шаг перемешивания: каждая карта шифруется ключом игрока, затем колода
+// переставляется случайной перестановкой Фишера - Йетса
+func (p *Player) Shuffle(random io.Reader, cards []*big.Int) ([]*big.Int, error) {
+	out := p.exp(cards, p.key.C)
+	for i := len(out) - 1; i > 0; i-- {
+		j, err := rand.Int(random, big.NewInt(int64(i+1)))
+		if err != nil {
+			return nil, err
+		}
+		k := int(j.Int64())
+		out[i], out[k] = out[k], out[i]
+	}
+	return out, nil
+}
+
+// Strip - снятие своего слоя шифрования с карт
+func (p *Player) Strip(cards []*big.Int) []*big.Int {
+	return p.exp(cards, p.key.D)
+}
+
+// Step - ход игрока в текущем состоянии протокола
+func (p *Player) Step(random io.Reader, pr *Protocol) (Step, error) {
+	player, phase, target := pr.Turn()
+	if player != p.ID {
+		return Step{}, fmt.Errorf("player %d, turn of %d: %w", p.ID, player, ErrOutOfTurn)
+	}
+	s := Step{Phase: phase, Player: p.ID, Target: target}
+	if phase == PhaseShuffle {
+		cards, err := p.Shuffle(random, pr.Input())
+		if err != nil {
+			return Step{}, err
+		}
+		s.Cards = cards
+		return s, nil
+	}
+	s.Cards = p.Strip(pr.Input())
+	return s, nil
+}
+
+// OpenHand - карты своей руки: остальные игроки уже сняли свои слои, последний
+// слой снимает владелец, и больше никто не видит результата
+func (p *Player) OpenHand(pr *Protocol) ([]Card, error) {
+	hand, err := pr.Hand(p.ID)
+	if err != nil {
+		return nil, err
+	}
+	cards := make([]Card, len(hand))
+	for i, x := range p.Strip(hand) {
+		if cards[i], err = p.deck.Decode(x); err != nil {
+			return nil, fmt.Errorf("player %d hand card %d: %w", p.ID, i, err)
+		}
+	}
+	return cards, nil
+}
@@ -0,0 +1,303 @@
+package mentalpoker
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+)
+
+const (
+	// HandSize - карт в руке (техасский холдем)
+	HandSize = 2
+	// BoardSize - общих карт на столе
+	BoardSize = 5
+)
+
+var (
+	// ErrOutOfTurn - шаг не того игрока, не той фазы или не той руки
+	ErrOutOfTurn = errors.New("mentalpoker: step out of turn")
+	// ErrFinished - раздача уже завершена
+	ErrFinished = errors.New("mentalpoker: hand is already finished")
+)
+
+// Phase - этап раздачи
+type Phase int
+
+const (
+	// PhaseShuffle - игроки по очереди шифруют и перемешивают колоду
+	PhaseShuffle Phase = iota
+	// PhaseHands - с руки каждого игрока свои слои снимают все остальные игроки
+	PhaseHands
+	// PhaseBoard - с карт стола свои слои снимают все игроки
+	PhaseBoard
+	// PhaseDone - карты стола открыты, руки готовы к открытию владельцами
+	PhaseDone
+	// PhaseFailed - открытая карта стола не декодируется: кто-то снял слой неверно,
+	// виновного находит Audit
+	PhaseFailed
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseShuffle:
+		return "shuffle"
+	case PhaseHands:
+		return "hands"
+	case PhaseBoard:
+		return "board"
+	case PhaseDone:
+		return "done"
+	case PhaseFailed:
+		return "failed"
+	}
+	return fmt.Sprintf("Phase(%d)", int(p))
+}
+
+// Step - ход игрока: результат обработки Protocol.Input. Для PhaseHands Target -
+// владелец руки, с которой снимается слой.
+type Step struct {
+	Phase  Phase      `json:"phase"`
+	Player int        `json:"player"`
+	Target int        `json:"target"`
+	Cards  []*big.Int `json:"cards"`
+}
+
+// record - принятый шаг вместе с его входом для Audit
+type record struct {
+	step  Step
+	input []*big.Int
+}
+
+// Protocol - общее состояние раздачи, одинаковое у всех игроков: каждый применяет
+// к нему одни и те же шаги в одном порядке. Apply проверяет очередь хода, размеры
+// и то, что все значения - квадратичные вычеты; корректность шифрования и снятия
+// слоёв проверяет Audit после раскрытия ключей.
+type Protocol struct {
+	deck      *Deck
+	players   int
+	phase     Phase
+	turn      int // номер хода внутри фазы (и руки)
+	target    int // рука, с которой снимаются слои в PhaseHands
+	deckCards []*big.Int
+	hands     [][]*big.Int
+	board     []*big.Int
+	cards     []Card // открытые карты стола
+	history   []record
+}
+
+// NewProtocol - раздача на players игроков с номерами от 0 до players-1
+func NewProtocol(deck *Deck, players int) (*Protocol, error) {
+	if deck == nil {
+		return nil, &common.ParameterError{Name: "deck", Reason: "missing"}
+	}
+	if players < 2 || players*HandSize+BoardSize > DeckSize {
+		return nil, &common.ParameterError{Name: "players", Reason: fmt.Sprintf("need 2..%d players", (DeckSize-BoardSize)/HandSize)}
+	}
+	return &Protocol{deck: deck, players: players, deckCards: deck.Codes()}, nil
+}
+
+// Players - число игроков
+func (pr *Protocol) Players() int {
+	return pr.players
+}
+
+// Phase - текущий этап
+func (pr *Protocol) Phase() Phase {
+	return pr.phase
+}
+
+// Turn - чей ход: игрок, этап и, для PhaseHands, владелец руки. После раздачи игрок -1.
+func (pr *Protocol) Turn() (player int, phase Phase, target int) {
+	switch pr.phase {
+	case PhaseShuffle, PhaseBoard:
+		return pr.turn, pr.phase, -1
+	case PhaseHands:
+		// Слои с руки снимают все, кроме её владельца
+		player = pr.turn
+		if player >= pr.target {
+			player++
+		}
+		return player, pr.phase, pr.target
+	}
+	return -1, pr.phase, -1
+}
+
+// Input - карты, которые должен обработать игрок, чей сейчас ход
+func (pr *Protocol) Input() []*big.Int {
+	switch pr.phase {
+	case PhaseShuffle:
+		return copyCards(pr.deckCards)
+	case PhaseHands:
+		return copyCards(pr.hands[pr.target])
+	case PhaseBoard:
+		return copyCards(pr.board)
+	}
+	return nil
+}
+
+// copyCards - глубокая копия значений
+func copyCards(cards []*big.Int) []*big.Int {
+	out := make([]*big.Int, len(cards))
+	for i, x := range cards {
+		out[i] = new(big.Int).Set(x)
+	}
+	return out
+}
+
+// Apply - проверка шага и переход в следующее состояние
+func (pr *Protocol) Apply(s Step) error {
+	if pr.phase == PhaseDone || pr.phase == PhaseFailed {
+		return ErrFinished
+	}
+	player, phase, target := pr.Turn()
+	if s.Phase != phase || s.Player != player || (phase == PhaseHands && s.Target != target) {
+		return fmt.Errorf("step %s of player %d (hand %d), expected %s of player %d (hand %d): %w",
+			s.Phase, s.Player, s.Target, phase, player, target, ErrOutOfTurn)
+	}
+	input := pr.Input()
+	if len(s.Cards) != len(input) {
+		return &common.ParameterError{Name: "cards", Reason: fmt.Sprintf("got %d cards, want %d", len(s.Cards), len(input))}
+	}
+	seen := make(map[string]bool, len(s.Cards))
+	for i, x := range s.Cards {
+		if !pr.deck.InGroup(x) {
+			return fmt.Errorf("player %d card %d is not a quadratic residue: %w", s.Player, i, common.ErrVerification)
+		}
+		// Шифрование - перестановка подгруппы, поэтому различные карты остаются различными
+		if seen[string(x.Bytes())] {
+			return fmt.Errorf("player %d card %d is repeated: %w", s.Player, i, common.ErrVerification)
+		}
+		seen[string(x.Bytes())] = true
+	}
+	s.Cards = copyCards(s.Cards)
+	pr.history = append(pr.history, record{step: s, input: input})
+	traceCards(fmt.Sprintf("player %d %s (hand %d)", s.Player, s.Phase, s.Target), s.Cards)
+	pr.turn++
+	switch pr.phase {
+	case PhaseShuffle:
+		pr.deckCards = s.Cards
+		if pr.turn == pr.players {
+			pr.deal()
+		}
+	case PhaseHands:
+		pr.hands[pr.target] = s.Cards
+		if pr.turn == pr.players-1 {
+			pr.turn, pr.target = 0, pr.target+1
+			if pr.target == pr.players {
+				pr.phase = PhaseBoard
+			}
+		}
+	case PhaseBoard:
+		pr.board = s.Cards
+		if pr.turn == pr.players {
+			return pr.openBoard()
+		}
+	}
+	return nil
+}
+
+// traceCards - передача карт шага в трассировщик
+func traceCards(step string, cards []*big.Int) {
+	values := make([]common.TraceValue, len(cards))
+	for i, x := range cards {
+		values[i] = common.Val(fmt.Sprintf("card%d", i+1), x)
+	}
+	if common.Tracing() {
+		common.Trace("mentalpoker", step, values...)
+	}
+}
+
+// deal - раздача перемешанной колоды сверху: по HandSize карт каждому игроку, затем стол
+func (pr *Protocol) deal() {
+	pr.hands = make([][]*big.Int, pr.players)
+	for k := range pr.hands {
+		pr.hands[k] = pr.deckCards[k*HandSize : (k+1)*HandSize]
+	}
+	pr.board = pr.deckCards[pr.players*HandSize : pr.players*HandSize+BoardSize]
+	pr.phase, pr.turn, pr.target = PhaseHands, 0, 0
+	if common.Tracing() {
+		common.Trace("mentalpoker", "deal", common.ValInt("players", int64(pr.players)))
+	}
+}
+
+// openBoard - декодирование карт стола после снятия всех слоёв
+func (pr *Protocol) openBoard() error {
+	cards := make([]Card, len(pr.board))
+	for i, x := range pr.board {
+		c, err := pr.deck.Decode(x)
+		if err != nil {
+			pr.phase = PhaseFailed
+			return fmt.Errorf("board card %d: %w", i, err)
+		}
+		cards[i] = c
+	}
+	pr.cards, pr.phase = cards, PhaseDone
+	return nil
+}
+
+// Hand - рука игрока player, с которой сняты все слои, кроме его собственного
+func (pr *Protocol) Hand(player int) ([]*big.Int, error) {
+	if player < 0 || player >= pr.players {
+		return nil, &common.ParameterError{Name: "player", Reason: fmt.Sprintf("no player %d", player)}
+	}
+	if pr.phase < PhaseBoard {
+		return nil, fmt.Errorf("hand %d is not open yet: %w", player, ErrOutOfTurn)
+	}
+	return copyCards(pr.hands[player]), nil
+}
+
+// Board - открытые карты стола
+func (pr *Protocol) Board() ([]Card, error) {
+	if pr.phase != PhaseDone {
+		return nil, fmt.Errorf("board is not open in phase %s: %w", pr.phase, ErrOutOfTurn)
+	}
+	return append([]Card(nil), pr.cards...), nil
+}
+
+// Remaining - нерозданные карты колоды, зашифрованные всеми игроками
+func (pr *Protocol) Remaining() []*big.Int {
+	if pr.phase == PhaseShuffle {
+		return nil
+	}
+	return copyCards(pr.deckCards[pr.players*HandSize+BoardSize:])
+}
+
+// Audit - проверка всей раздачи по раскрытым после неё ключам: каждое перемешивание -
+// перестановка входа, зашифрованного ключом C игрока, каждое снятие слоя - вход в степени D.
+// Ошибка называет первого игрока, нарушившего протокол.
+func (pr *Protocol) Audit(keys []Key) error {
+	if pr.phase != PhaseDone && pr.phase != PhaseFailed {
+		return fmt.Errorf("audit in phase %s: %w", pr.phase, ErrOutOfTurn)
+	}
+	if len(keys) != pr.players {
+		return &common.ParameterError{Name: "keys", Reason: fmt.Sprintf("need %d keys", pr.players)}
+	}
+	phi := new(big.Int).Sub(pr.deck.P, big.NewInt(1))
+	for i, k := range keys {
+		if k.C == nil || k.D == nil || new(big.Int).Mod(new(big.Int).Mul(k.C, k.D), phi).Cmp(big.NewInt(1)) != 0 {
+			return fmt.Errorf("player %d key: C * D != 1 mod p - 1: %w", i, common.ErrVerification)
+		}
+	}
+	for _, r := range pr.history {
+		s := r.step
+		if s.Phase == PhaseShuffle {
+			want := make(map[string]bool, len(r.input))
+			for _, x := range r.input {
+				want[string(pr.deck.mc.Exp(x, keys[s.Player].C).Bytes())] = true
+			}
+			for i, y := range s.Cards {
+				if !want[string(y.Bytes())] {
+					return fmt.Errorf("player %d shuffle: card %d is not an encrypted input card: %w", s.Player, i, common.ErrVerification)
+				}
+			}
+			continue
+		}
+		for i, x := range r.input {
+			if pr.deck.mc.Exp(x, keys[s.Player].D).Cmp(s.Cards[i]) != 0 {
+				return fmt.Errorf("player %d %s step (hand %d): card %d is stripped incorrectly: %w", s.Player, s.Phase, s.Target, i, common.ErrVerification)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package mentalpoker
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// advance - честные ходы, пока не наступит ход игрока player в фазе phase
+func advance(t *testing.T, table *Table, phase Phase, player int) {
+	t.Helper()
+	for {
+		p, ph, _ := table.Protocol.Turn()
+		if ph == phase && p == player {
+			return
+		}
+		if ph >= PhaseDone {
+			t.Fatalf("phase %s of player %d never came", phase, player)
+		}
+		step, err := table.Players[p].Step(rand.Reader, table.Protocol)
+		if err != nil {
+			t.Fatalf("Step() error = %v", err)
+		}
+		if err = table.Protocol.Apply(step); err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+	}
+}
+
+// TestProtocolRejects - шаг не в очередь или неверной формы не меняет состояние
+func TestProtocolRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		phase  Phase // до какого хода доиграть честно
+		player int
+		change func(s *Step, deck *Deck)
+		want   error
+	}{
+		{"ход не того игрока", PhaseShuffle, 0, func(s *Step, _ *Deck) { s.Player = 1 }, ErrOutOfTurn},
+		{"не та фаза", PhaseShuffle, 1, func(s *Step, _ *Deck) { s.Phase = PhaseBoard }, ErrOutOfTurn},
+		{"чужая рука", PhaseHands, 2, func(s *Step, _ *Deck) { s.Target = 2 }, ErrOutOfTurn},
+		{"неполная колода", PhaseShuffle, 2, func(s *Step, _ *Deck) { s.Cards = s.Cards[1:] }, common.ErrInvalidParameters},
+		{"лишняя карта стола", PhaseBoard, 0, func(s *Step, d *Deck) { s.Cards = append(s.Cards, d.Encode(0)) }, common.ErrInvalidParameters},
+		{
+			name:   "квадратичный невычет",
+			phase:  PhaseShuffle,
+			player: 1,
+			// p ≡ 3 (mod 4), поэтому -1 - невычет
+			change: func(s *Step, d *Deck) { s.Cards[7] = new(big.Int).Sub(d.P, big.NewInt(1)) },
+			want:   common.ErrVerification,
+		},
+		{"единица", PhaseHands, 1, func(s *Step, _ *Deck) { s.Cards[0] = big.NewInt(1) }, common.ErrVerification},
+		{"повтор карты", PhaseShuffle, 0, func(s *Step, _ *Deck) { s.Cards[5] = s.Cards[9] }, common.ErrVerification},
+		{"нет карты", PhaseBoard, 2, func(s *Step, _ *Deck) { s.Cards[3] = nil }, common.ErrVerification},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := NewTable(rand.Reader, newTestDeck(t), 3)
+			if err != nil {
+				t.Fatalf("NewTable() error = %v", err)
+			}
+			advance(t, table, tt.phase, tt.player)
+			step, err := table.Players[tt.player].Step(rand.Reader, table.Protocol)
+			if err != nil {
+				t.Fatalf("Step() error = %v", err)
+			}
+			bad := step
+			bad.Cards = append([]*big.Int(nil), step.Cards...)
+			tt.change(&bad, table.Deck)
+			if err = table.Protocol.Apply(bad); !errors.Is(err, tt.want) {
+				t.Fatalf("Apply() error = %v, want %v", err, tt.want)
+			}
+			// Отвергнутый шаг не сдвигает очередь: честный шаг принимается
+			if err = table.Protocol.Apply(step); err != nil {
+				t.Errorf("Apply() of the honest step error = %v", err)
+			}
+		})
+	}
+
+	table := newTestTable(t, 2)
+	if err := table.Protocol.Apply(Step{Phase: PhaseDone}); !errors.Is(err, ErrFinished) {
+		t.Errorf("Apply() after the deal error = %v, want %v", err, ErrFinished)
+	}
+	if _, err := table.Players[0].Step(rand.Reader, table.Protocol); !errors.Is(err, ErrOutOfTurn) {
+		t.Errorf("Step() after the deal error = %v, want %v", err, ErrOutOfTurn)
+	}
+	early, err := NewProtocol(table.Deck, 2)
+	if err != nil {
+		t.Fatalf("NewProtocol() error = %v", err)
+	}
+	if _, err = early.Hand(0); !errors.Is(err, ErrOutOfTurn) {
+		t.Errorf("Hand() before the deal error = %v, want %v", err, ErrOutOfTurn)
+	}
+	if err = early.Audit(nil); !errors.Is(err, ErrOutOfTurn) {
+		t.Errorf("Audit() before the end error = %v, want %v", err, ErrOutOfTurn)
+	}
+}
+
+// TestAudit - шаг, прошедший проверку формы, но нарушающий протокол, находится
+// по раскрытым после раздачи ключам
+func TestAudit(t *testing.T) {
+	tests := []struct {
+		name    string
+		phase   Phase
+		cheater int
+		// cheat - нечестный шаг игрока cheater вместо честного step
+		cheat func(table *Table, step *Step)
+	}{
+		{
+			name:    "подмена карты при перемешивании",
+			phase:   PhaseShuffle,
+			cheater: 1,
+			cheat: func(table *Table, step *Step) {
+				// x^3 - вычет, не совпадающий с другими картами, но не зашифрованная карта входа
+				step.Cards[0] = table.Players[1].exp(step.Cards[:1], big.NewInt(3))[0]
+			},
+		},
+		{
+			name:    "неверное снятие слоя с руки",
+			phase:   PhaseHands,
+			cheater: 2,
+			cheat: func(table *Table, step *Step) {
+				step.Cards[1] = table.Players[2].exp([]*big.Int{step.Cards[1]}, big.NewInt(3))[0]
+			},
+		},
+		{
+			name:    "неверное снятие слоя со стола",
+			phase:   PhaseBoard,
+			cheater: 0,
+			cheat: func(table *Table, step *Step) {
+				step.Cards[0], step.Cards[4] = step.Cards[4], step.Cards[0]
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := NewTable(rand.Reader, newTestDeck(t), 3)
+			if err != nil {
+				t.Fatalf("NewTable() error = %v", err)
+			}
+			advance(t, table, tt.phase, tt.cheater)
+			step, err := table.Players[tt.cheater].Step(rand.Reader, table.Protocol)
+			if err != nil {
+				t.Fatalf("Step() error = %v", err)
+			}
+			tt.cheat(table, &step)
+			if err = table.Protocol.Apply(step); err != nil {
+				t.Fatalf("Apply() of a well-formed step error = %v", err)
+			}
+			// Раздача доходит до конца или срывается на открытии стола
+			if err = table.Deal(rand.Reader); err != nil && !errors.Is(err, ErrUnknownCard) {
+				t.Fatalf("Deal() error = %v", err)
+			}
+			err = table.Audit()
+			if !errors.Is(err, common.ErrVerification) {
+				t.Fatalf("Audit() error = %v, want %v", err, common.ErrVerification)
+			}
+			if want := fmt.Sprintf("player %d ", tt.cheater); !strings.HasPrefix(err.Error(), want) {
+				t.Errorf("Audit() error = %q, want it to blame %q", err, want)
+			}
+		})
+	}
+
+	table := newTestTable(t, 3)
+	keys := []Key{table.Players[0].Key(), table.Players[2].Key(), table.Players[1].Key()}
+	if err := table.Protocol.Audit(keys); !errors.Is(err, common.ErrVerification) {
+		t.Errorf("Audit() with swapped keys error = %v, want %v", err, common.ErrVerification)
+	}
+	keys[1].D = big.NewInt(3)
+	if err := table.Protocol.Audit(keys); !errors.Is(err, common.ErrVerification) {
+		t.Errorf("Audit() with a broken key error = %v, want %v", err, common.ErrVerification)
+	}
+}
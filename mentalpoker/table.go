@@ -0,0 +1,65 @@
+package mentalpoker
+
+import (
+	"io"
+)
+
+// Table - раздача в одном процессе: колода, игроки со своими ключами и общее состояние.
+// Каждый игрок обращается только к своему ключу и к Protocol.
+type Table struct {
+	Deck     *Deck
+	Players  []*Player
+	Protocol *Protocol
+}
+
+// NewTable - стол на players игроков со случайными ключами
+func NewTable(random io.Reader, deck *Deck, players int) (*Table, error) {
+	pr, err := NewProtocol(deck, players)
+	if err != nil {
+		return nil, err
+	}
+	t := &Table{Deck: deck, Players: make([]*Player, players), Protocol: pr}
+	for i := range t.Players {
+		if t.Players[i], err = NewPlayer(i, deck, random); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Deal - ходы игроков по очереди до открытия карт стола
+func (t *Table) Deal(random io.Reader) error {
+	for t.Protocol.Phase() < PhaseDone {
+		player, _, _ := t.Protocol.Turn()
+		step, err := t.Players[player].Step(random, t.Protocol)
+		if err != nil {
+			return err
+		}
+		if err = t.Protocol.Apply(step); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Hands - руки всех игроков; каждую открывает её владелец
+func (t *Table) Hands() ([][]Card, error) {
+	hands := make([][]Card, len(t.Players))
+	for i, p := range t.Players {
+		hand, err := p.OpenHand(t.Protocol)
+		if err != nil {
+			return nil, err
+		}
+		hands[i] = hand
+	}
+	return hands, nil
+}
+
+// Audit - раскрытие ключей всех игроков после раздачи и проверка всех шагов
+func (t *Table) Audit() error {
+	keys := make([]Key, len(t.Players))
+	for i, p := range t.Players {
+		keys[i] = p.Key()
+	}
+	return t.Protocol.Audit(keys)
+}
@@ -0,0 +1,85 @@
+package mentalpoker
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"io"
+	"math/big"
+)
+
+// MaxFrameSize - предел длины кадра: колода из 52 чисел по 2048 бит в JSON занимает ~32 КБ
+const MaxFrameSize = 1 << 20
+
+// ErrFrameTooLarge - длина кадра больше MaxFrameSize
+var ErrFrameTooLarge = errors.New("mentalpoker: frame too large")
+
+// Типы сообщений сетевого протокола
+const (
+	MsgHello  = "hello"  // игрок -> раздающий: имя
+	MsgStart  = "start"  // раздающий -> игрок: номер, имена, простое p, будет ли проверка
+	MsgStep   = "step"   // игрок -> раздающий: свой ход; раздающий -> все: принятый ход
+	MsgKey    = "key"    // игрок -> раздающий: ключ после раздачи, если проверка включена
+	MsgKeys   = "keys"   // раздающий -> все: ключи всех игроков
+	MsgResult = "result" // раздающий -> все: карты стола, раздача завершена
+	MsgAbort  = "abort"  // раздающий -> все: раздача прервана
+)
+
+// Message - кадр протокола: 4 байта длины JSON (big-endian) и сам JSON. Игроки
+// обмениваются только зашифрованными колодами и частично расшифрованными картами;
+// ключи пересылаются лишь после раздачи для проверки.
+type Message struct {
+	Type   string   `json:"type"`
+	Name   string   `json:"name,omitempty"`
+	Player int      `json:"player,omitempty"`
+	Names  []string `json:"names,omitempty"`
+	P      *big.Int `json:"p,omitempty"`
+	Audit  bool     `json:"audit,omitempty"`
+	Step   *Step    `json:"step,omitempty"`
+	Key    *Key     `json:"key,omitempty"`
+	Keys   []Key    `json:"keys,omitempty"`
+	Board  []Card   `json:"board,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// WriteMessage - запись кадра
+func WriteMessage(w io.Writer, m *Message) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if len(data) > MaxFrameSize {
+		return fmt.Errorf("%d bytes: %w", len(data), ErrFrameTooLarge)
+	}
+	frame := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(frame, uint32(len(data)))
+	copy(frame[4:], data)
+	_, err = w.Write(frame)
+	return err
+}
+
+// ReadMessage - чтение кадра; длина проверяется до выделения памяти
+func ReadMessage(r io.Reader) (*Message, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > MaxFrameSize {
+		return nil, fmt.Errorf("%d bytes: %w", n, ErrFrameTooLarge)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	var m Message
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, &common.ParameterError{Name: "frame", Reason: err.Error()}
+	}
+	return &m, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/paillier"
+	"log"
+	"math/big"
+	mrand "math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+// Сообщение клиента агрегатору: значение передаётся только в зашифрованном виде
+type report struct {
+	Client int                  `json:"client"`
+	Value  *paillier.Ciphertext `json:"value"`
+}
+
+// Клиент шифрует своё значение открытым ключом и отправляет отчёт в JSON
+func client(id int, pk *paillier.PublicKey, value int64, out chan<- []byte) error {
+	ct, err := pk.Encrypt(big.NewInt(value))
+	if err != nil {
+		return err
+	}
+	msg, err := json.Marshal(report{Client: id, Value: ct})
+	if err != nil {
+		return err
+	}
+	out <- msg
+	return nil
+}
+
+// Агрегатор складывает шифртексты, не зная закрытого ключа, и перерандомизирует сумму
+func aggregate(pk *paillier.PublicKey, in <-chan []byte) (*paillier.Ciphertext, int, error) {
+	sum, err := pk.Encrypt(big.NewInt(0))
+	if err != nil {
+		return nil, 0, err
+	}
+	count := 0
+	for msg := range in {
+		var r report
+		if err = json.Unmarshal(msg, &r); err != nil {
+			return nil, 0, err
+		}
+		if sum, err = pk.Add(sum, r.Value); err != nil {
+			return nil, 0, fmt.Errorf("отчёт клиента %d: %w", r.Client, err)
+		}
+		count++
+	}
+	sum, err = pk.Rerandomize(sum)
+	return sum, count, err
+}
+
+func main() {
+	clients := flag.Int("clients", 100, "число клиентов")
+	bits := flag.Int("bits", 1024, "длина модуля N в битах")
+	maxValue := flag.Int64("max", 1000, "значения клиентов берутся из [0, max)")
+	traceFormat := flag.String("trace", "", "трассировка шагов алгоритма: text или json")
+	flag.Parse()
+	tracer, err := common.NewTracer(*traceFormat, os.Stderr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	common.SetTracer(tracer)
+
+	start := time.Now()
+	sk, err := paillier.GenerateKey(*bits)
+	if err != nil {
+		log.Fatalf("Ошибка генерации ключа: %v", err)
+	}
+	fmt.Printf("Ключ %d бит сгенерирован за %s\n", sk.N.BitLen(), time.Since(start))
+
+	// Значения известны только клиентам; сумма считается здесь лишь для сверки
+	values := make([]int64, *clients)
+	want := big.NewInt(0)
+	rnd := mrand.New(mrand.NewSource(time.Now().UnixNano()))
+	for i := range values {
+		values[i] = rnd.Int63n(*maxValue)
+		want.Add(want, big.NewInt(values[i]))
+	}
+
+	start = time.Now()
+	reports := make(chan []byte)
+	var wg sync.WaitGroup
+	for i, v := range values {
+		wg.Add(1)
+		go func(id int, v int64) {
+			defer wg.Done()
+			if err := client(id, &sk.PublicKey, v, reports); err != nil {
+				log.Printf("Клиент %d: %v", id, err)
+			}
+		}(i+1, v)
+	}
+	go func() {
+		wg.Wait()
+		close(reports)
+	}()
+	sum, count, err := aggregate(&sk.PublicKey, reports)
+	if err != nil {
+		log.Fatalf("Ошибка агрегации: %v", err)
+	}
+	fmt.Printf("Агрегатор: принято %d отчётов за %s\n", count, time.Since(start))
+
+	got, err := sk.Decrypt(sum)
+	if err != nil {
+		log.Fatalf("Ошибка дешифрования: %v", err)
+	}
+	fmt.Printf("Сумма: %s (ожидается %s)\n", got, want)
+	if got.Cmp(want) != 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,62 @@
+package paillier
+
+import (
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"io"
+	"math/big"
+)
+
+// ExportPublicKey - открытый ключ N в виде блока брони
+func (pk *PublicKey) ExportPublicKey() (*common.ArmorBlock, error) {
+	return common.PublicKeyBlock(algorithm, []string{"N"}, []*big.Int{pk.N})
+}
+
+// ImportPublicKey - открытый ключ из блока брони, созданного ExportPublicKey
+func ImportPublicKey(block *common.ArmorBlock) (*PublicKey, error) {
+	numbers, err := common.KeyNumbers(block, common.ArmorPublicKey, algorithm, 1)
+	if err != nil {
+		return nil, err
+	}
+	return NewPublicKey(numbers[0])
+}
+
+// MarshalText - шифртекст в шестнадцатеричной записи, в том числе для encoding/json
+func (ct *Ciphertext) MarshalText() ([]byte, error) {
+	if ct.C == nil {
+		return nil, &common.ParameterError{Name: "ciphertext", Reason: "empty ciphertext"}
+	}
+	return []byte(ct.C.Text(16)), nil
+}
+
+// UnmarshalText - разбор записи MarshalText; принадлежность Z*_{N^2} проверяется при использовании
+func (ct *Ciphertext) UnmarshalText(text []byte) error {
+	c, ok := new(big.Int).SetString(string(text), 16)
+	if !ok || c.Sign() <= 0 {
+		return &common.ParameterError{Name: "ciphertext", Reason: fmt.Sprintf("%q is not a positive hex number", text)}
+	}
+	ct.C = c
+	return nil
+}
+
+// WriteCiphertexts - запись шифртекстов в двоичном формате common.WriteBigNumbers
+func WriteCiphertexts(w io.Writer, cts []*Ciphertext) error {
+	numbers := make([]*big.Int, len(cts))
+	for i, ct := range cts {
+		numbers[i] = ct.C
+	}
+	return common.WriteBigNumbers(w, numbers)
+}
+
+// ReadCiphertexts - чтение шифртекстов, записанных WriteCiphertexts
+func ReadCiphertexts(r io.Reader) ([]*Ciphertext, error) {
+	numbers, err := common.ReadBigNumbers(r)
+	if err != nil {
+		return nil, err
+	}
+	cts := make([]*Ciphertext, len(numbers))
+	for i, c := range numbers {
+		cts[i] = &Ciphertext{C: c}
+	}
+	return cts, nil
+}
@@ -0,0 +1,207 @@
+// Package paillier - аддитивно гомоморфная криптосистема Пэйе: E(m1) * E(m2) = E(m1 + m2),
+// E(m)^k = E(k*m) по модулю N^2. Используется для приватного суммирования значений.
+package paillier
+
+import (
+	"crypto/rand"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+)
+
+const algorithm = "paillier" // имя алгоритма в блоке брони открытого ключа
+
+// PublicKey - открытый ключ: модуль N = P*Q, генератор G = N + 1
+type PublicKey struct {
+	N, G *big.Int
+	N2   *big.Int // N^2 - модуль шифртекстов
+}
+
+// PrivateKey - закрытый ключ с предвычислениями для дешифрования по КТО
+type PrivateKey struct {
+	PublicKey
+	P, Q   *big.Int
+	p2, q2 *big.Int // P^2, Q^2
+	hp, hq *big.Int // L_p(G^(P-1) mod P^2)^-1 mod P и то же для Q
+}
+
+// Ciphertext - шифртекст, число из [1, N^2), взаимно простое с N
+type Ciphertext struct {
+	C *big.Int
+}
+
+// NewPublicKey - открытый ключ по модулю N
+func NewPublicKey(n *big.Int) (*PublicKey, error) {
+	if n == nil || n.Cmp(big.NewInt(15)) < 0 || n.Bit(0) == 0 {
+		return nil, &common.ParameterError{Name: "N", Reason: fmt.Sprintf("%v is not an odd modulus greater than 14", n)}
+	}
+	return &PublicKey{
+		N:  new(big.Int).Set(n),
+		G:  new(big.Int).Add(n, big.NewInt(1)),
+		N2: new(big.Int).Mul(n, n),
+	}, nil
+}
+
+// GenerateKey - пара ключей с модулем N длиной около bits бит
+func GenerateKey(bits int) (*PrivateKey, error) {
+	if bits < 16 {
+		return nil, &common.ParameterError{Name: "bits", Reason: fmt.Sprintf("%d-bit modulus is too small", bits)}
+	}
+	one := big.NewInt(1)
+	for {
+		p := common.GenPrimeBitsBig(bits / 2)
+		q := common.GenPrimeBitsBig(bits - bits/2)
+		if p.Cmp(q) == 0 {
+			continue
+		}
+		// НОД(N, φ(N)) = 1 гарантирует, что G = N + 1 порождает нужную подгруппу
+		n := new(big.Int).Mul(p, q)
+		phi := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+		if common.GCDBig(n, phi).Cmp(one) != 0 {
+			continue
+		}
+		return NewPrivateKey(p, q)
+	}
+}
+
+// NewPrivateKey - закрытый ключ по простым P и Q
+func NewPrivateKey(p, q *big.Int) (*PrivateKey, error) {
+	if p.Cmp(q) == 0 {
+		return nil, &common.ParameterError{Name: "Q", Reason: "P and Q must be distinct"}
+	}
+	pk, err := NewPublicKey(new(big.Int).Mul(p, q))
+	if err != nil {
+		return nil, err
+	}
+	sk := &PrivateKey{
+		PublicKey: *pk,
+		P:         new(big.Int).Set(p),
+		Q:         new(big.Int).Set(q),
+		p2:        new(big.Int).Mul(p, p),
+		q2:        new(big.Int).Mul(q, q),
+	}
+	if sk.hp, err = sk.h(sk.P, sk.p2); err != nil {
+		return nil, err
+	}
+	if sk.hq, err = sk.h(sk.Q, sk.q2); err != nil {
+		return nil, err
+	}
+	if common.Tracing() {
+		common.Trace("paillier", "keygen", common.Val("P", sk.P), common.Val("Q", sk.Q), common.Val("N", sk.N))
+	}
+	return sk, nil
+}
+
+// l - функция L(x) = (x - 1) / p
+func l(x, p *big.Int) *big.Int {
+	return new(big.Int).Quo(new(big.Int).Sub(x, big.NewInt(1)), p)
+}
+
+// h - L_p(G^(p-1) mod p^2)^-1 mod p
+func (sk *PrivateKey) h(p, p2 *big.Int) (*big.Int, error) {
+	pm1 := new(big.Int).Sub(p, big.NewInt(1))
+	return common.ModInverseBig(l(new(big.Int).Exp(sk.G, pm1, p2), p), p)
+}
+
+// random - случайное r из [1, N), взаимно простое с N
+func (pk *PublicKey) random() (*big.Int, error) {
+	for {
+		r, err := rand.Int(rand.Reader, pk.N)
+		if err != nil {
+			return nil, err
+		}
+		if r.Sign() != 0 && common.GCDBig(r, pk.N).Cmp(big.NewInt(1)) == 0 {
+			return r, nil
+		}
+	}
+}
+
+// check - шифртекст лежит в [1, N^2) и взаимно прост с N
+func (pk *PublicKey) check(ct *Ciphertext) error {
+	if ct == nil || ct.C == nil || ct.C.Sign() <= 0 || ct.C.Cmp(pk.N2) >= 0 ||
+		common.GCDBig(ct.C, pk.N).Cmp(big.NewInt(1)) != 0 {
+		return &common.ParameterError{Name: "ciphertext", Reason: "not an element of Z*_{N^2}"}
+	}
+	return nil
+}
+
+// Encrypt - E(m) = G^m * r^N = (1 + m*N) * r^N mod N^2 для 0 <= m < N
+func (pk *PublicKey) Encrypt(m *big.Int) (*Ciphertext, error) {
+	if m.Sign() < 0 || m.Cmp(pk.N) >= 0 {
+		return nil, &common.MessageTooLargeError{Value: new(big.Int).Set(m), Limit: new(big.Int).Set(pk.N)}
+	}
+	r, err := pk.random()
+	if err != nil {
+		return nil, err
+	}
+	c := new(big.Int).Mul(m, pk.N)
+	c.Add(c, big.NewInt(1))
+	c.Mul(c, new(big.Int).Exp(r, pk.N, pk.N2)).Mod(c, pk.N2)
+	if common.Tracing() {
+		common.Trace("paillier", "encrypt", common.Val("m", m), common.Val("r", r), common.Val("c", c))
+	}
+	return &Ciphertext{C: c}, nil
+}
+
+// Add - E(m1 + m2) = E(m1) * E(m2) mod N^2
+func (pk *PublicKey) Add(a, b *Ciphertext) (*Ciphertext, error) {
+	if err := pk.check(a); err != nil {
+		return nil, err
+	}
+	if err := pk.check(b); err != nil {
+		return nil, err
+	}
+	return &Ciphertext{C: new(big.Int).Mod(new(big.Int).Mul(a.C, b.C), pk.N2)}, nil
+}
+
+// AddPlain - E(m + k) = E(m) * G^k = E(m) * (1 + k*N) mod N^2
+func (pk *PublicKey) AddPlain(a *Ciphertext, k *big.Int) (*Ciphertext, error) {
+	if err := pk.check(a); err != nil {
+		return nil, err
+	}
+	g := new(big.Int).Mod(k, pk.N)
+	g.Mul(g, pk.N).Add(g, big.NewInt(1))
+	return &Ciphertext{C: g.Mul(g, a.C).Mod(g, pk.N2)}, nil
+}
+
+// ScalarMul - E(k*m) = E(m)^k mod N^2; k приводится по модулю N
+func (pk *PublicKey) ScalarMul(a *Ciphertext, k *big.Int) (*Ciphertext, error) {
+	if err := pk.check(a); err != nil {
+		return nil, err
+	}
+	return &Ciphertext{C: new(big.Int).Exp(a.C, new(big.Int).Mod(k, pk.N), pk.N2)}, nil
+}
+
+// Rerandomize - другой шифртекст того же сообщения: E(m) * r^N mod N^2.
+// Скрывает связь результата гомоморфных операций с исходными шифртекстами.
+func (pk *PublicKey) Rerandomize(a *Ciphertext) (*Ciphertext, error) {
+	if err := pk.check(a); err != nil {
+		return nil, err
+	}
+	r, err := pk.random()
+	if err != nil {
+		return nil, err
+	}
+	c := new(big.Int).Exp(r, pk.N, pk.N2)
+	return &Ciphertext{C: c.Mul(c, a.C).Mod(c, pk.N2)}, nil
+}
+
+// Decrypt - m = L_p(c^(P-1) mod P^2) * hp mod P, то же по модулю Q, результат собирается по КТО
+func (sk *PrivateKey) Decrypt(ct *Ciphertext) (*big.Int, error) {
+	if err := sk.check(ct); err != nil {
+		return nil, fmt.Errorf("paillier: %v: %w", err, common.ErrDecryption)
+	}
+	one := big.NewInt(1)
+	mp := l(new(big.Int).Exp(ct.C, new(big.Int).Sub(sk.P, one), sk.p2), sk.P)
+	mp.Mul(mp, sk.hp).Mod(mp, sk.P)
+	mq := l(new(big.Int).Exp(ct.C, new(big.Int).Sub(sk.Q, one), sk.q2), sk.Q)
+	mq.Mul(mq, sk.hq).Mod(mq, sk.Q)
+	m, err := common.CRTBig([]*big.Int{mp, mq}, []*big.Int{sk.P, sk.Q})
+	if err != nil {
+		return nil, err
+	}
+	if common.Tracing() {
+		common.Trace("paillier", "decrypt", common.Val("c", ct.C), common.Val("m mod P", mp), common.Val("m mod Q", mq), common.Val("m", m))
+	}
+	return m, nil
+}
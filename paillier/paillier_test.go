@@ -0,0 +1,244 @@
+package paillier
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/Raimguzhinov/protect-information/common"
+	"math/big"
+	"sync"
+	"testing"
+)
+
+func newTestKey(t *testing.T, bits int) *PrivateKey {
+	t.Helper()
+	sk, err := GenerateKey(bits)
+	if err != nil {
+		t.Fatalf("GenerateKey(%d) error = %v", bits, err)
+	}
+	return sk
+}
+
+func encrypt(t *testing.T, pk *PublicKey, m int64) *Ciphertext {
+	t.Helper()
+	ct, err := pk.Encrypt(big.NewInt(m))
+	if err != nil {
+		t.Fatalf("Encrypt(%d) error = %v", m, err)
+	}
+	return ct
+}
+
+func decrypt(t *testing.T, sk *PrivateKey, ct *Ciphertext) *big.Int {
+	t.Helper()
+	m, err := sk.Decrypt(ct)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	return m
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	sk := newTestKey(t, 256)
+	nm1 := new(big.Int).Sub(sk.N, big.NewInt(1))
+	tests := []struct {
+		name string
+		m    *big.Int
+	}{
+		{name: "ноль", m: big.NewInt(0)},
+		{name: "единица", m: big.NewInt(1)},
+		{name: "среднее", m: big.NewInt(1_000_000_007)},
+		{name: "N-1", m: nm1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ct, err := sk.Encrypt(tt.m)
+			if err != nil {
+				t.Fatalf("Encrypt(%s) error = %v", tt.m, err)
+			}
+			if got := decrypt(t, sk, ct); got.Cmp(tt.m) != 0 {
+				t.Errorf("Decrypt(Encrypt(%s)) = %s", tt.m, got)
+			}
+		})
+	}
+}
+
+func TestHomomorphism(t *testing.T) {
+	sk := newTestKey(t, 256)
+	pk := &sk.PublicKey
+	a, b := encrypt(t, pk, 1234), encrypt(t, pk, 5678)
+	sum, err := pk.Add(a, b)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	plus, err := pk.AddPlain(a, big.NewInt(-34))
+	if err != nil {
+		t.Fatalf("AddPlain() error = %v", err)
+	}
+	scaled, err := pk.ScalarMul(b, big.NewInt(3))
+	if err != nil {
+		t.Fatalf("ScalarMul() error = %v", err)
+	}
+	negated, err := pk.ScalarMul(a, big.NewInt(-1))
+	if err != nil {
+		t.Fatalf("ScalarMul() error = %v", err)
+	}
+	tests := []struct {
+		name string
+		ct   *Ciphertext
+		want *big.Int
+	}{
+		{name: "E(a) * E(b)", ct: sum, want: big.NewInt(1234 + 5678)},
+		{name: "E(a) * G^k", ct: plus, want: big.NewInt(1200)},
+		{name: "E(b)^3", ct: scaled, want: big.NewInt(3 * 5678)},
+		{name: "E(a)^-1", ct: negated, want: new(big.Int).Sub(sk.N, big.NewInt(1234))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decrypt(t, sk, tt.ct); got.Cmp(tt.want) != 0 {
+				t.Errorf("Decrypt() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRerandomize(t *testing.T) {
+	sk := newTestKey(t, 256)
+	ct := encrypt(t, &sk.PublicKey, 42)
+	again, err := sk.Rerandomize(ct)
+	if err != nil {
+		t.Fatalf("Rerandomize() error = %v", err)
+	}
+	if again.C.Cmp(ct.C) == 0 {
+		t.Error("Rerandomize() returned the same ciphertext")
+	}
+	if got := decrypt(t, sk, again); got.Int64() != 42 {
+		t.Errorf("Decrypt(Rerandomize(E(42))) = %s", got)
+	}
+	if other := encrypt(t, &sk.PublicKey, 42); other.C.Cmp(ct.C) == 0 {
+		t.Error("Encrypt() is deterministic")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	sk := newTestKey(t, 64)
+	pk := &sk.PublicKey
+	_, errKey := GenerateKey(8)
+	_, errLarge := pk.Encrypt(sk.N)
+	_, errNeg := pk.Encrypt(big.NewInt(-1))
+	_, errAdd := pk.Add(&Ciphertext{C: new(big.Int).Set(sk.P)}, encrypt(t, pk, 1))
+	_, errRange := pk.ScalarMul(&Ciphertext{C: new(big.Int).Set(pk.N2)}, big.NewInt(2))
+	_, errDec := sk.Decrypt(&Ciphertext{C: big.NewInt(0)})
+	_, errImport := ImportPublicKey(&common.ArmorBlock{Type: common.ArmorPublicKey, Headers: map[string]string{"Algorithm": "rsa"}})
+	tests := []struct {
+		name   string
+		err    error
+		target error
+	}{
+		{name: "короткий ключ", err: errKey, target: common.ErrInvalidParameters},
+		{name: "m = N", err: errLarge, target: common.ErrMessageTooLarge},
+		{name: "m < 0", err: errNeg, target: common.ErrMessageTooLarge},
+		{name: "шифртекст не взаимно прост с N", err: errAdd, target: common.ErrInvalidParameters},
+		{name: "шифртекст вне Z_{N^2}", err: errRange, target: common.ErrInvalidParameters},
+		{name: "дешифрование нуля", err: errDec, target: common.ErrDecryption},
+		{name: "чужой ключ", err: errImport, target: common.ErrArmorMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !errors.Is(tt.err, tt.target) {
+				t.Errorf("error = %v, want %v", tt.err, tt.target)
+			}
+		})
+	}
+}
+
+func TestSerialization(t *testing.T) {
+	sk := newTestKey(t, 128)
+	block, err := sk.ExportPublicKey()
+	if err != nil {
+		t.Fatalf("ExportPublicKey() error = %v", err)
+	}
+	var armored bytes.Buffer
+	if err = common.EncodeArmor(&armored, block); err != nil {
+		t.Fatalf("EncodeArmor() error = %v", err)
+	}
+	decoded, err := common.DecodeArmor(&armored)
+	if err != nil {
+		t.Fatalf("DecodeArmor() error = %v", err)
+	}
+	pk, err := ImportPublicKey(decoded)
+	if err != nil {
+		t.Fatalf("ImportPublicKey() error = %v", err)
+	}
+	if pk.N.Cmp(sk.N) != 0 || pk.N2.Cmp(sk.N2) != 0 {
+		t.Fatalf("ImportPublicKey() N = %s, want %s", pk.N, sk.N)
+	}
+
+	// Шифртексты, созданные импортированным ключом, передаются в JSON и двоичном виде
+	cts := []*Ciphertext{encrypt(t, pk, 7), encrypt(t, pk, 0)}
+	data, err := json.Marshal(cts)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var fromJSON []*Ciphertext
+	if err = json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatalf("json.Unmarshal(%s) error = %v", data, err)
+	}
+	var buf bytes.Buffer
+	if err = WriteCiphertexts(&buf, cts); err != nil {
+		t.Fatalf("WriteCiphertexts() error = %v", err)
+	}
+	fromBinary, err := ReadCiphertexts(&buf)
+	if err != nil {
+		t.Fatalf("ReadCiphertexts() error = %v", err)
+	}
+	for _, got := range [][]*Ciphertext{fromJSON, fromBinary} {
+		if len(got) != len(cts) {
+			t.Fatalf("decoded %d ciphertexts, want %d", len(got), len(cts))
+		}
+		for i := range cts {
+			if got[i].C.Cmp(cts[i].C) != 0 {
+				t.Errorf("ciphertext %d = %s, want %s", i, got[i].C, cts[i].C)
+			}
+		}
+	}
+	var bad Ciphertext
+	if err = json.Unmarshal([]byte(`"xyz"`), &bad); !errors.Is(err, common.ErrInvalidParameters) {
+		t.Errorf("json.Unmarshal(xyz) error = %v, want %v", err, common.ErrInvalidParameters)
+	}
+}
+
+// TestConcurrentSum - клиенты параллельно шифруют свои значения, агрегатор
+// складывает шифртексты, не видя слагаемых
+func TestConcurrentSum(t *testing.T) {
+	sk := newTestKey(t, 256)
+	pk := &sk.PublicKey
+	const clients = 200
+	ch := make(chan *Ciphertext)
+	var wg sync.WaitGroup
+	for i := 1; i <= clients; i++ {
+		wg.Add(1)
+		go func(v int64) {
+			defer wg.Done()
+			ct, err := pk.Encrypt(big.NewInt(v))
+			if err != nil {
+				t.Errorf("Encrypt(%d) error = %v", v, err)
+				return
+			}
+			ch <- ct
+		}(int64(i))
+	}
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+	sum := encrypt(t, pk, 0)
+	for ct := range ch {
+		var err error
+		if sum, err = pk.Add(sum, ct); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if got, want := decrypt(t, sk, sum), big.NewInt(clients*(clients+1)/2); got.Cmp(want) != 0 {
+		t.Errorf("sum = %s, want %s", got, want)
+	}
+}
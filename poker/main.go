@@ -1,175 +1,126 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"flag"
 	"fmt"
 	"github.com/Raimguzhinov/protect-information/common"
-	"github.com/samber/lo"
+	"github.com/Raimguzhinov/protect-information/mentalpoker"
 	"log"
-	"math/big"
+	"net"
+	"os"
+	"os/signal"
+	"time"
 )
 
-// Карта представляется числом от 2 до p-1
-type Card struct {
-	ID   *big.Int // Уникальный идентификатор карты
-	Name string   // Название карты (например, "2♥")
-}
-
-func (c Card) String() string {
-	return c.ID.String()
-}
-
-// Игрок
-type Player struct {
-	ID            int
-	EncryptionKey *big.Int // c_i (взаимно простое с p)
-	DecryptionKey *big.Int // d_i (обратное к c_i по модулю p)
-}
-
-// Генерация простого числа p
-func generatePrime() *big.Int {
-	minV := big.NewInt(1_000_000_000_000_000)
-	maxV := big.NewInt(1_000_000_000_000_000_000)
-	var p *big.Int
-	//p := common.GenPrimeBig(minV, maxV)
-	for {
-		q := common.GenPrimeBig(minV, maxV)
-		p = new(big.Int).Mul(q, big.NewInt(2))
-		p.Add(p, big.NewInt(1)) // P = 2 * q + 1
-
-		if common.IsPrimeBig(p) {
-			break
-		}
+func main() {
+	numPlayers := flag.Int("players", 5, "число игроков")
+	bits := flag.Int("bits", 256, "длина безопасного простого p в битах")
+	audit := flag.Bool("audit", true, "после раздачи раскрыть ключи и проверить все шаги")
+	traceFormat := flag.String("trace", "", "трассировка шагов алгоритма: text или json")
+	serveAddr := flag.String("serve", "", "адрес раздающего, например :7000: собрать -players игроков по TCP")
+	joinAddr := flag.String("join", "", "адрес раздающего: сесть за сетевой стол игроком")
+	name := flag.String("name", "", "имя игрока за сетевым столом")
+	timeout := flag.Duration("timeout", mentalpoker.DefaultTimeout, "ожидание хода игрока за сетевым столом")
+	joinTimeout := flag.Duration("join-timeout", 5*time.Minute, "ожидание всех игроков в лобби")
+	flag.Parse()
+	tracer, err := common.NewTracer(*traceFormat, os.Stderr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	common.SetTracer(tracer)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	switch {
+	case *serveAddr != "":
+		serve(ctx, *serveAddr, *numPlayers, *bits, *audit, *timeout, *joinTimeout)
+		return
+	case *joinAddr != "":
+		join(ctx, *joinAddr, *name, *timeout)
+		return
 	}
-	return p
-}
 
-// Генерация взаимно простого числа с p и его обратного элемента
-func generateEncryptionKeys(p *big.Int) (*big.Int, *big.Int) {
-	phiP := new(big.Int).Sub(p, big.NewInt(1)) // φ(p) = p - 1
-	c := common.GenCoprimeBig(phiP, big.NewInt(2), phiP)
-	d, err := common.ModInverseBig(c, phiP)
+	deck, err := mentalpoker.GenerateDeck(rand.Reader, *bits)
 	if err != nil {
 		log.Fatal(err)
 	}
-	if d.Cmp(phiP) >= 0 || c.Cmp(big.NewInt(1)) <= 0 {
-		return nil, nil
+	table, err := mentalpoker.NewTable(rand.Reader, deck, *numPlayers)
+	if err != nil {
+		log.Fatal(err)
 	}
-	return c, d
-}
-
-// Генерация колоды карт с уникальными случайными идентификаторами
-func generateDeck() []Card {
-	suits := []string{"♥", "♠", "♣", "♦"}
-	values := []string{"2", "3", "4", "5", "6", "7", "8", "9", "10", "J", "Q", "K", "A"}
-	deck := make([]Card, 0, 52)
-
-	i := 2
-	for _, value := range values {
-		for _, suit := range suits {
-			cardName := value + suit
-			deck = append(deck, Card{
-				ID:   big.NewInt(int64(i)),
-				Name: cardName,
-			})
-			i++
+	if err = table.Deal(rand.Reader); err != nil {
+		// Карты стола не открылись: нарушителя находит проверка по раскрытым ключам
+		if *audit && table.Protocol.Phase() == mentalpoker.PhaseFailed {
+			if auditErr := table.Audit(); auditErr != nil {
+				log.Fatalf("Раздача сорвана: %v; проверка: %v", err, auditErr)
+			}
 		}
+		log.Fatalf("Раздача сорвана: %v", err)
 	}
-	return deck
-}
 
-func encryptDeck(deck []Card, key *big.Int, p *big.Int) []Card {
-	encryptedDeck := make([]Card, len(deck))
-	for i, card := range deck {
-		encryptedID := common.ModularExponentiationBig(card.ID, key, p)
-		encryptedDeck[i] = Card{
-			ID:   encryptedID,
-			Name: card.Name,
-		}
+	board, err := table.Protocol.Board()
+	if err != nil {
+		log.Fatal(err)
 	}
-	return encryptedDeck
-}
+	fmt.Println("Карты на столе:")
+	fmt.Println(board)
 
-func decryptDeck(deck []Card, key *big.Int, p *big.Int) []Card {
-	decryptedDeck := make([]Card, len(deck))
-	for i, card := range deck {
-		decryptedID := common.ModularExponentiationBig(card.ID, key, p)
-		decryptedDeck[i] = Card{
-			ID:   decryptedID,
-			Name: card.Name,
-		}
+	hands, err := table.Hands()
+	if err != nil {
+		log.Fatal(err)
 	}
-	return decryptedDeck
-}
-
-// Поиск названия карты по её идентификатору
-func findCardByID(deck []Card, id *big.Int) string {
-	for _, card := range deck {
-		if card.ID.Cmp(id) == 0 {
-			return card.Name
-		}
+	for i, hand := range hands {
+		fmt.Printf("\nКарты игрока %d:\n%v\n", table.Players[i].ID+1, hand)
 	}
-	return "Unknown"
-}
 
-func main() {
-	numPlayers := 5
-	p := generatePrime()
-	fmt.Printf("p: %s\n", p.String())
-	players := make([]Player, numPlayers)
-	for i := 0; i < numPlayers; i++ {
-		c, d := generateEncryptionKeys(p)
-		players[i] = Player{
-			ID:            i + 1,
-			EncryptionKey: c,
-			DecryptionKey: d,
+	if *audit {
+		if err = table.Audit(); err != nil {
+			log.Fatalf("Проверка раздачи: %v", err)
 		}
-		fmt.Printf("Player %d: c = %s, d = %s\n", players[i].ID, c.String(), d.String())
+		fmt.Println("\nКлючи раскрыты, все шаги раздачи проверены")
 	}
+}
 
-	originalDeck := generateDeck()
-	fmt.Printf("Original Deck:\n%s\n\n", originalDeck)
-
-	deck := make([]Card, len(originalDeck))
-	copy(deck, originalDeck)
-
-	for _, player := range players {
-		deck = encryptDeck(deck, player.EncryptionKey, p)
-		deck = lo.Shuffle(deck)
-		fmt.Printf("Shuffled deck after encryption player %d:\n%s\n\n", player.ID, deck)
+// serve - раздающий сетевого стола: ключей игроков не знает, только пересылает
+// и проверяет их шаги
+func serve(ctx context.Context, addr string, players, bits int, audit bool, timeout, joinTimeout time.Duration) {
+	deck, err := mentalpoker.GenerateDeck(rand.Reader, bits)
+	if err != nil {
+		log.Fatal(err)
 	}
-
-	playerHands := make([][]Card, numPlayers)
-	for i := 0; i < numPlayers; i++ {
-		playerHands[i] = []Card{deck[0], deck[1]}
-		deck = deck[2:]
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatal(err)
 	}
-	tableCards := deck[:5]
-
-	fmt.Println("\nКарты на столе:")
-	fmt.Println(tableCards)
-	for _, player := range players {
-		tableCards = decryptDeck(tableCards, player.DecryptionKey, p)
+	log.Printf("Раздающий: ждём %d игроков на %s", players, ln.Addr())
+	dealer := &mentalpoker.Dealer{
+		Deck:        deck,
+		Players:     players,
+		Audit:       audit,
+		JoinTimeout: joinTimeout,
+		TurnTimeout: timeout,
+		Logf:        log.Printf,
 	}
-	for _, card := range tableCards {
-		cardName := findCardByID(originalDeck, card.ID)
-		fmt.Printf("%s ", cardName)
+	res, err := dealer.Serve(ctx, ln)
+	if err != nil {
+		log.Fatalf("Раздача сорвана: %v", err)
 	}
-	fmt.Println()
+	fmt.Printf("Игроки: %v\n", res.Names)
+	fmt.Println("Карты на столе:")
+	fmt.Println(res.Board)
+}
 
-	for i := 0; i < numPlayers; i++ {
-		fmt.Printf("\nКарты игрока %d:\n", players[i].ID)
-		fmt.Println(playerHands[i])
-		hand := playerHands[i]
-		for j := len(players) - 1; j >= 0; j-- {
-			if players[j].ID != players[i].ID {
-				hand = decryptDeck(hand, players[j].DecryptionKey, p)
-			}
-		}
-		hand = decryptDeck(hand, players[i].DecryptionKey, p)
-		for _, card := range hand {
-			cardName := findCardByID(originalDeck, card.ID)
-			fmt.Printf("%s ", cardName)
-		}
-		fmt.Println()
+// join - игрок сетевого стола: ключ создаётся и остаётся в этом процессе
+func join(ctx context.Context, addr, name string, timeout time.Duration) {
+	client := &mentalpoker.Client{Name: name, Timeout: 2 * timeout}
+	out, err := client.Join(ctx, addr)
+	if err != nil {
+		log.Fatalf("Раздача сорвана: %v", err)
 	}
+	fmt.Printf("Игроки: %v\n", out.Names)
+	fmt.Println("Карты на столе:")
+	fmt.Println(out.Board)
+	fmt.Printf("\nКарты игрока %d (%s):\n%v\n", out.Player+1, out.Names[out.Player], out.Hand)
 }
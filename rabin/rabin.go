@@ -0,0 +1,195 @@
+// Package rabin - криптосистема Рабина: шифрование возведением в квадрат по модулю
+// N = P*Q, P = Q = 3 (mod 4). Взлом Рабина равносилен разложению N на множители.
+package rabin
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/Raimguzhinov/protect-information/common"
+	"io"
+	"math/big"
+)
+
+const (
+	defaultBits     = 1024 // длина модуля N по умолчанию
+	redundancyBits  = 64   // младшие биты блока, повторяемые для выбора верного корня
+	blockMarker     = 0x01 // первый байт блока: сохраняет ведущие нули данных
+	redundancyBytes = redundancyBits / 8
+)
+
+type rabinCipher struct {
+	P, Q, N         *big.Int // Закрытые P, Q и открытый модуль N = P*Q
+	Input           io.Reader
+	OutputEncrypted io.Writer
+	OutputDecrypted io.Writer
+	buffer          []*big.Int
+	blockSize       int // байт данных в одном блоке
+}
+
+func newRabinAlgorithm(bits int) (*rabinCipher, error) {
+	// Блок: маркер, хотя бы один байт данных и избыточность должны быть меньше N
+	if bits < 8*(redundancyBytes+3) {
+		return nil, &common.ParameterError{Name: "bits", Reason: fmt.Sprintf("%d-bit modulus is too small", bits)}
+	}
+	p := common.GenBlumPrimeBig(bits / 2)
+	q := common.GenBlumPrimeBig(bits - bits/2)
+	for p.Cmp(q) == 0 {
+		q = common.GenBlumPrimeBig(bits - bits/2)
+	}
+	c := &rabinCipher{P: p, Q: q, N: new(big.Int).Mul(p, q)}
+	c.blockSize = (c.N.BitLen()-1)/8 - redundancyBytes - 1
+	if common.Tracing() {
+		common.Trace("rabin", "keygen", common.Val("P", c.P), common.Val("Q", c.Q), common.Val("N", c.N))
+	}
+	return c, nil
+}
+
+func NewCipher(input io.Reader, encOut, decOut io.Writer) (common.Cipher, error) {
+	c, err := newRabinAlgorithm(defaultBits)
+	if err != nil {
+		return nil, err
+	}
+	c.Input = input
+	c.OutputEncrypted = encOut
+	c.OutputDecrypted = decOut
+	return c, nil
+}
+
+// pad - блок m' = (0x01 || data) * 2^64 + (младшие 64 бита (0x01 || data))
+func pad(data []byte) *big.Int {
+	m := new(big.Int).SetBytes(append([]byte{blockMarker}, data...))
+	low := new(big.Int).And(m, new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), redundancyBits), big.NewInt(1)))
+	m.Lsh(m, redundancyBits)
+	return m.Or(m, low)
+}
+
+// unpad - данные блока, если избыточность совпадает
+func unpad(m *big.Int) ([]byte, bool) {
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), redundancyBits), big.NewInt(1))
+	low := new(big.Int).And(m, mask)
+	high := new(big.Int).Rsh(m, redundancyBits)
+	if new(big.Int).And(high, mask).Cmp(low) != 0 {
+		return nil, false
+	}
+	b := high.Bytes()
+	if len(b) == 0 || b[0] != blockMarker {
+		return nil, false
+	}
+	return b[1:], true
+}
+
+func (rc *rabinCipher) Encrypt() error {
+	message, err := io.ReadAll(rc.Input)
+	if err != nil {
+		return err
+	}
+	var encrypted []*big.Int
+	for start := 0; start < len(message); start += rc.blockSize {
+		end := start + rc.blockSize
+		if end > len(message) {
+			end = len(message)
+		}
+		m := pad(message[start:end])
+		// c = m'^2 mod N
+		e := new(big.Int).Exp(m, big.NewInt(2), rc.N)
+		if common.Tracing() {
+			common.Trace("rabin", "encrypt block", common.Val("m", m), common.Val("c", e))
+		}
+		encrypted = append(encrypted, e)
+	}
+	rc.buffer = encrypted
+	return common.WriteBigNumbers(rc.OutputEncrypted, encrypted)
+}
+
+// roots - четыре квадратных корня из c по модулю N: корни по модулям P и Q
+// (r = c^((p+1)/4) для p = 3 mod 4), собранные по китайской теореме об остатках
+func (rc *rabinCipher) roots(c *big.Int) ([]*big.Int, error) {
+	one := big.NewInt(1)
+	mp := common.ModularExponentiationBig(c, new(big.Int).Rsh(new(big.Int).Add(rc.P, one), 2), rc.P)
+	mq := common.ModularExponentiationBig(c, new(big.Int).Rsh(new(big.Int).Add(rc.Q, one), 2), rc.Q)
+	var res []*big.Int
+	for _, rp := range []*big.Int{mp, new(big.Int).Sub(rc.P, mp)} {
+		for _, rq := range []*big.Int{mq, new(big.Int).Sub(rc.Q, mq)} {
+			r, err := common.CRTBig([]*big.Int{rp, rq}, []*big.Int{rc.P, rc.Q})
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, r)
+		}
+	}
+	return res, nil
+}
+
+func (rc *rabinCipher) Decrypt() error {
+	var decrypted bytes.Buffer
+	for i, c := range rc.buffer {
+		roots, err := rc.roots(c)
+		if err != nil {
+			return err
+		}
+		// Избыточность выбирает единственный из четырёх корней
+		var data []byte
+		found := 0
+		for _, r := range roots {
+			if d, ok := unpad(r); ok {
+				data = d
+				found++
+			}
+		}
+		if found != 1 {
+			return fmt.Errorf("rabin: block %d: %d roots with valid redundancy: %w", i, found, common.ErrDecryption)
+		}
+		if common.Tracing() {
+			common.Trace("rabin", "decrypt block", common.Val("c", c), common.Val("root1", roots[0]), common.Val("root2", roots[1]),
+				common.Val("root3", roots[2]), common.Val("root4", roots[3]))
+		}
+		decrypted.Write(data)
+	}
+	return common.WriteData(rc.OutputDecrypted, decrypted.Bytes())
+}
+
+// EncryptAndDecrypt - объединяет шифрование и дешифрование
+func (rc *rabinCipher) EncryptAndDecrypt() error {
+	if err := rc.Encrypt(); err != nil {
+		return err
+	}
+	return rc.Decrypt()
+}
+
+// ExportPublicKey - открытый ключ N в виде блока брони
+func (rc *rabinCipher) ExportPublicKey() (*common.ArmorBlock, error) {
+	return common.PublicKeyBlock("rabin", []string{"N"}, []*big.Int{rc.N})
+}
+
+// ExportPrivateKey - закрытый ключ (P, Q) в виде блока брони
+func (rc *rabinCipher) ExportPrivateKey() (*common.ArmorBlock, error) {
+	return common.PrivateKeyBlock("rabin", []string{"P", "Q"}, []*big.Int{rc.P, rc.Q})
+}
+
+// DecryptArmored - расшифрование блоков CIPHERTEXT закрытым ключом из ExportPrivateKey
+func DecryptArmored(key *common.ArmorBlock, ciphertext []byte, decOut io.Writer) error {
+	numbers, err := common.KeyNumbers(key, common.ArmorPrivateKey, "rabin", 2)
+	if err != nil {
+		return err
+	}
+	p, q := numbers[0], numbers[1]
+	for _, prime := range numbers {
+		// Корни c^((p+1)/4) верны только для простых p = 3 (mod 4)
+		if prime.Bit(0) == 0 || prime.Bit(1) == 0 || !prime.ProbablyPrime(20) {
+			return &common.ParameterError{Name: "rabin key", Reason: fmt.Sprintf("%s is not a prime = 3 (mod 4)", prime)}
+		}
+	}
+	if p.Cmp(q) == 0 {
+		return &common.ParameterError{Name: "rabin key", Reason: "P and Q must differ"}
+	}
+	rc := &rabinCipher{P: p, Q: q, N: new(big.Int).Mul(p, q), OutputDecrypted: decOut}
+	if rc.buffer, err = common.ReadBigNumbers(bytes.NewReader(ciphertext)); err != nil {
+		return err
+	}
+	for i, c := range rc.buffer {
+		if c.Cmp(rc.N) >= 0 {
+			return fmt.Errorf("rabin: block %d is not reduced modulo N: %w", i, common.ErrDecryption)
+		}
+	}
+	return rc.Decrypt()
+}
@@ -0,0 +1,184 @@
+package rabin
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"github.com/Raimguzhinov/protect-information/common"
+	"github.com/Raimguzhinov/protect-information/factor"
+	"io"
+	"math/big"
+	mrand "math/rand"
+	"testing"
+)
+
+func newTestCipher(t *testing.T, bits int, message []byte) (*rabinCipher, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	rc, err := newRabinAlgorithm(bits)
+	if err != nil {
+		t.Fatalf("newRabinAlgorithm(%d) error = %v", bits, err)
+	}
+	var enc, dec bytes.Buffer
+	rc.Input = bytes.NewReader(message)
+	rc.OutputEncrypted = &enc
+	rc.OutputDecrypted = &dec
+	return rc, &enc, &dec
+}
+
+func TestEncryptAndDecrypt(t *testing.T) {
+	tests := []struct {
+		name    string
+		bits    int
+		message []byte
+	}{
+		{name: "пустое сообщение", bits: 256, message: nil},
+		{name: "короткий текст", bits: 256, message: []byte("Hello, Rabin!")},
+		{name: "ведущие нули", bits: 256, message: []byte{0, 0, 0, 1, 2, 3}},
+		{name: "несколько блоков", bits: 256, message: bytes.Repeat([]byte("0123456789"), 20)},
+		{name: "минимальный модуль", bits: 88, message: []byte("abcdefgh")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc, _, dec := newTestCipher(t, tt.bits, tt.message)
+			if err := rc.EncryptAndDecrypt(); err != nil {
+				t.Fatalf("EncryptAndDecrypt() error = %v", err)
+			}
+			if !bytes.Equal(dec.Bytes(), tt.message) {
+				t.Errorf("decrypted %q, want %q", dec.Bytes(), tt.message)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := newRabinAlgorithm(64); !errors.Is(err, common.ErrInvalidParameters) {
+		t.Errorf("newRabinAlgorithm(64) error = %v, want %v", err, common.ErrInvalidParameters)
+	}
+	rc, _, _ := newTestCipher(t, 128, []byte("message"))
+	if err := rc.Encrypt(); err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	// Случайный квадрат почти наверняка не имеет корня с верной избыточностью
+	r, _ := rand.Int(rand.Reader, rc.N)
+	rc.buffer[0] = r.Mul(r, r).Mod(r, rc.N)
+	if err := rc.Decrypt(); !errors.Is(err, common.ErrDecryption) {
+		t.Errorf("Decrypt() of a forged block error = %v, want %v", err, common.ErrDecryption)
+	}
+}
+
+// TestSqrtOracleFactorsN - тот, кто умеет извлекать квадратные корни по модулю N
+// (т.е. расшифровывать без избыточности), раскладывает N: для случайного m оракул
+// с вероятностью 1/2 возвращает корень r != ±m, и тогда НОД(m - r, N) - делитель N
+func TestSqrtOracleFactorsN(t *testing.T) {
+	rc, _, _ := newTestCipher(t, 256, nil)
+	rnd := mrand.New(mrand.NewSource(1))
+	oracle := func(c *big.Int) *big.Int {
+		roots, err := rc.roots(c)
+		if err != nil {
+			t.Fatalf("roots(%s) error = %v", c, err)
+		}
+		return roots[rnd.Intn(len(roots))]
+	}
+	one := big.NewInt(1)
+	for attempt := 1; attempt <= 64; attempt++ {
+		m, _ := rand.Int(rand.Reader, rc.N)
+		if common.GCDBig(m, rc.N).Cmp(one) != 0 {
+			continue
+		}
+		r := oracle(new(big.Int).Exp(m, big.NewInt(2), rc.N))
+		d := common.GCDBig(new(big.Int).Sub(m, r), rc.N)
+		if d.Cmp(one) == 0 || d.Cmp(rc.N) == 0 {
+			continue
+		}
+		if d.Cmp(rc.P) != 0 && d.Cmp(rc.Q) != 0 {
+			t.Fatalf("gcd(m - r, N) = %s, want P or Q", d)
+		}
+		t.Logf("N factored after %d oracle queries", attempt)
+		return
+	}
+	t.Fatal("square root oracle did not factor N in 64 queries")
+}
+
+// TestFactoringBreaksRabin - обратная сторона: разложив N, противник расшифровывает
+func TestFactoringBreaksRabin(t *testing.T) {
+	message := []byte("attack at dawn")
+	rc, _, _ := newTestCipher(t, 96, message)
+	if err := rc.Encrypt(); err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	factors, err := factor.Factor(rc.N)
+	if err != nil {
+		t.Fatalf("Factor(N) error = %v", err)
+	}
+	if len(factors) != 2 {
+		t.Fatalf("Factor(N) = %v, want two primes", factors)
+	}
+	var dec bytes.Buffer
+	attacker := &rabinCipher{
+		P: factors[0].P, Q: factors[1].P, N: rc.N,
+		OutputDecrypted: &dec,
+		buffer:          rc.buffer,
+	}
+	if err := attacker.Decrypt(); err != nil {
+		t.Fatalf("Decrypt() with recovered factors error = %v", err)
+	}
+	if !bytes.Equal(dec.Bytes(), message) {
+		t.Errorf("attacker decrypted %q, want %q", dec.Bytes(), message)
+	}
+}
+
+func TestDecryptArmored(t *testing.T) {
+	message := bytes.Repeat([]byte("armored "), 10)
+	rc, enc, _ := newTestCipher(t, 256, message)
+	if err := rc.Encrypt(); err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	key, err := rc.ExportPrivateKey()
+	if err != nil {
+		t.Fatalf("ExportPrivateKey() error = %v", err)
+	}
+	var armored bytes.Buffer
+	if err = common.EncodeArmor(&armored, key); err != nil {
+		t.Fatal(err)
+	}
+	if key, err = common.DecodeArmor(&armored); err != nil {
+		t.Fatalf("DecodeArmor() error = %v", err)
+	}
+	var dec bytes.Buffer
+	if err = DecryptArmored(key, enc.Bytes(), &dec); err != nil {
+		t.Fatalf("DecryptArmored() error = %v", err)
+	}
+	if !bytes.Equal(dec.Bytes(), message) {
+		t.Errorf("DecryptArmored() = %q, want %q", dec.Bytes(), message)
+	}
+
+	other, _, _ := newTestCipher(t, 256, nil)
+	otherKey, err := other.ExportPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := rc.ExportPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	composite, err := common.PrivateKeyBlock("rabin", []string{"P", "Q"}, []*big.Int{big.NewInt(15), rc.Q})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		key     *common.ArmorBlock
+		wantErr error
+	}{
+		{name: "чужой ключ", key: otherKey, wantErr: common.ErrDecryption},
+		{name: "открытый ключ", key: public, wantErr: common.ErrArmorMalformed},
+		{name: "составной P", key: composite, wantErr: common.ErrInvalidParameters},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := DecryptArmored(tt.key, enc.Bytes(), io.Discard); !errors.Is(err, tt.wantErr) {
+				t.Errorf("DecryptArmored() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"image/color"
 	"log"
@@ -176,15 +177,26 @@ func checkGraphColoring(graph *Graph) string {
 		n[i] = new(big.Int).Mul(p[i], q[i])
 		phi[i] = new(big.Int).Mul(new(big.Int).Sub(p[i], big.NewInt(1)), new(big.Int).Sub(q[i], big.NewInt(1)))
 		// Генерируем взаимно простое число d
-		d[i] = common.GenCoprimeBig(phi[i], big.NewInt(2), phi[i])
+		var err error
+		if d[i], err = common.GenCoprimeBig(phi[i], big.NewInt(2), phi[i]); err != nil {
+			return fmt.Sprintf("Ошибка: ключ вершины %d: %v\n", i+1, err)
+		}
 		// Вычисляем обратное число c = d^-1 mod φ(n)
-		c[i], _ = common.ModInverseBig(d[i], phi[i])
+		if c[i], err = common.ModInverseBig(d[i], phi[i]); err != nil {
+			return fmt.Sprintf("Ошибка: ключ вершины %d: %v\n", i+1, err)
+		}
 		// Генерируем случайное число r
-		r[i] = common.GenCoprimeBig(n[i], big.NewInt(1), n[i])
+		if r[i], err = common.GenCoprimeBig(n[i], big.NewInt(1), n[i]); err != nil {
+			return fmt.Sprintf("Ошибка: число r вершины %d: %v\n", i+1, err)
+		}
 		// Модифицируем r по цвету
 		r[i] = modifyRByColor(r[i], recoloredColors[i])
 		// Вычисляем Z = r^d mod n
 		Z[i] = common.ModularExponentiationBig(r[i], d[i], n[i])
+		if common.Tracing() {
+			common.Trace("rgr", fmt.Sprintf("vertex %d", i+1),
+				common.Val("n", n[i]), common.Val("d", d[i]), common.Val("c", c[i]), common.Val("r", r[i]), common.Val("Z", Z[i]))
+		}
 	}
 
 	// Проверка рёбер
@@ -200,8 +212,10 @@ func checkGraphColoring(graph *Graph) string {
 		Z1LowerBits := new(big.Int).And(Z1, mask)
 		Z2LowerBits := new(big.Int).And(Z2, mask)
 
-		fmt.Printf("Проверка ребра %d-%d: Z1LowerBits = %d%d, Z2LowerBits = %d%d\n",
-			edge.From, edge.To, Z1LowerBits.Bit(1), Z1LowerBits.Bit(0), Z2LowerBits.Bit(1), Z2LowerBits.Bit(0))
+		if common.Tracing() {
+			common.Trace("rgr", fmt.Sprintf("check edge %d-%d", edge.From, edge.To),
+				common.Val("Z1", Z1), common.Val("Z2", Z2), common.Val("Z1 low bits", Z1LowerBits), common.Val("Z2 low bits", Z2LowerBits))
+		}
 
 		if Z1LowerBits.Cmp(Z2LowerBits) == 0 {
 			return fmt.Sprintf("Ошибка: совпадение младших битов у вершин %d и %d.\n", edge.From, edge.To)
@@ -227,17 +241,16 @@ func modifyRByColor(r *big.Int, color string) *big.Int {
 	r = new(big.Int).And(r, new(big.Int).Not(big.NewInt(3)))
 	switch color {
 	case "R":
-		fmt.Printf("[R]: ")
 	case "B":
-		fmt.Printf("[B]: ")
 		r = new(big.Int).Or(r, big.NewInt(1))
 	case "Y":
-		fmt.Printf("[Y]: ")
 		r = new(big.Int).Or(r, big.NewInt(2))
 	default:
 		log.Fatalf("Неизвестный цвет: %s", color)
 	}
-	fmt.Printf("r (after modification) = %d%d\n", r.Bit(1), r.Bit(0))
+	if common.Tracing() {
+		common.Trace("rgr", "recolor r ["+color+"]", common.Val("r", r), common.ValInt("low bits", int64(r.Bit(1)<<1|r.Bit(0))))
+	}
 	return r
 }
 
@@ -272,6 +285,14 @@ func equalSlices(slice1, slice2 []string) bool {
 }
 
 func main() {
+	traceFormat := flag.String("trace", "", "трассировка шагов алгоритма: text или json")
+	flag.Parse()
+	tracer, err := common.NewTracer(*traceFormat, os.Stderr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	common.SetTracer(tracer)
+
 	a := app.New()
 	w := a.NewWindow("Graph Coloring Visualizer")
 	w.Resize(fyne.NewSize(900, 700))
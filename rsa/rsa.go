@@ -1,11 +1,12 @@
 package rsa
 
 import (
+	"bytes"
 	"crypto/md5"
 	"fmt"
 	"github.com/Raimguzhinov/protect-information/common"
 	"io"
-	"os"
+	"math/big"
 )
 
 type rsaCipher struct {
@@ -30,11 +31,17 @@ func newRsaAlgorithm() (*rsaCipher, error) {
 	// Вычисляем N = P * Q и Phi = (P-1)*(Q-1)
 	c.N = c.P * c.Q // N - открытый
 	c.Phi = (c.P - 1) * (c.Q - 1)
-	var err error
 	c.PublicD = common.GenCoprime(c.Phi, 2, c.Phi-1)
-	c.PrivateC, err = common.ModInverse(c.PublicD, c.Phi)
+	// Phi и C секретны, поэтому обращение выполняется за постоянное время
+	privateC, err := common.ModInverseConstantTime(big.NewInt(c.PublicD), big.NewInt(c.Phi))
 	if err != nil {
-		return nil, fmt.Errorf("не удалось найти инверсию: %v", err)
+		return nil, fmt.Errorf("не удалось найти инверсию: %w", err)
+	}
+	c.PrivateC = privateC.Int64()
+	if common.Tracing() {
+		common.Trace("rsa", "keygen",
+			common.ValInt("P", c.P), common.ValInt("Q", c.Q), common.ValInt("N", c.N),
+			common.ValInt("Phi", c.Phi), common.ValInt("D", c.PublicD), common.ValInt("C", c.PrivateC))
 	}
 	return c, nil
 }
@@ -58,40 +65,81 @@ func (rc *rsaCipher) Encrypt() error {
 	encryptedMessage := make([]int64, len(message))
 	for i, byteVal := range message {
 		encryptedMessage[i] = common.ModularExponentiation(int64(byteVal), rc.PublicD, rc.N)
+		if common.Tracing() {
+			common.Trace("rsa", "encrypt block", common.ValInt("m", int64(byteVal)), common.ValInt("e", encryptedMessage[i]))
+		}
 	}
 	rc.buffer = encryptedMessage
 	return common.WriteNumbers(rc.OutputEncrypted, encryptedMessage)
 }
 
 func (rc *rsaCipher) Decrypt() error {
-	decryptedMessage := make([]int64, len(rc.buffer))
+	decryptedMessage := make([]byte, len(rc.buffer))
 	for i, encVal := range rc.buffer {
-		decryptedMessage[i] = common.ModularExponentiation(encVal, rc.PrivateC, rc.N)
+		m, err := common.ModularExponentiationConstantTime(encVal, rc.PrivateC, rc.N)
+		if err != nil {
+			return err
+		}
+		if common.Tracing() {
+			common.Trace("rsa", "decrypt block", common.ValInt("e", encVal), common.ValInt("m", m))
+		}
+		decryptedMessage[i] = byte(m)
 	}
-	return common.WriteNumbers(rc.OutputDecrypted, decryptedMessage)
+	return common.WriteData(rc.OutputDecrypted, decryptedMessage)
 }
 
-// EncryptAndDecrypt - объединяет шифрование и дешифрование
-func (rc *rsaCipher) EncryptAndDecrypt() error {
-	pwd, err := os.Getwd()
+// ExportPublicKey - открытый ключ (N, D) в виде блока брони
+func (rc *rsaCipher) ExportPublicKey() (*common.ArmorBlock, error) {
+	return common.PublicKeyBlock("rsa", []string{"N", "D"}, []*big.Int{big.NewInt(rc.N), big.NewInt(rc.PublicD)})
+}
+
+// ExportPrivateKey - закрытый ключ (N, D, C) в виде блока брони
+func (rc *rsaCipher) ExportPrivateKey() (*common.ArmorBlock, error) {
+	return common.PrivateKeyBlock("rsa", []string{"N", "D", "C"},
+		[]*big.Int{big.NewInt(rc.N), big.NewInt(rc.PublicD), big.NewInt(rc.PrivateC)})
+}
+
+// importKey - ключ из блока брони: N, D и, для закрытого ключа, C
+func importKey(block *common.ArmorBlock, blockType string, count int) (*rsaCipher, error) {
+	numbers, err := common.KeyNumbers(block, blockType, "rsa", count)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return nil, err
+	}
+	for _, num := range numbers {
+		if num.Sign() <= 0 || !num.IsInt64() {
+			return nil, &common.ParameterError{Name: "rsa key", Reason: fmt.Sprintf("%s is not a positive 64-bit integer", num)}
+		}
 	}
-	fmt.Print("Encrypted: ")
-	if err = rc.Encrypt(); err != nil {
+	rc := &rsaCipher{N: numbers[0].Int64(), PublicD: numbers[1].Int64()}
+	if count > 2 {
+		rc.PrivateC = numbers[2].Int64()
+	}
+	return rc, nil
+}
+
+// DecryptArmored - расшифрование данных блока CIPHERTEXT закрытым ключом из ExportPrivateKey
+func DecryptArmored(key *common.ArmorBlock, ciphertext []byte, decOut io.Writer) error {
+	rc, err := importKey(key, common.ArmorPrivateKey, 3)
+	if err != nil {
 		return err
 	}
-	if _, ok := rc.Input.(*os.File); ok {
-		fmt.Print(pwd + "/" + rc.OutputEncrypted.(*os.File).Name())
+	if rc.buffer, err = common.ReadNumbers(bytes.NewReader(ciphertext)); err != nil {
+		return err
 	}
-	fmt.Print("\nDecrypted: ")
-	defer func() {
-		if _, ok := rc.Input.(*os.File); ok {
-			fmt.Print(pwd + "/" + rc.OutputDecrypted.(*os.File).Name())
+	for i, e := range rc.buffer {
+		if e < 0 || e >= rc.N {
+			return fmt.Errorf("rsa: block %d is not reduced modulo N: %w", i, common.ErrDecryption)
 		}
-		fmt.Print("\n")
-	}()
+	}
+	rc.OutputDecrypted = decOut
+	return rc.Decrypt()
+}
+
+// EncryptAndDecrypt - объединяет шифрование и дешифрование
+func (rc *rsaCipher) EncryptAndDecrypt() error {
+	if err := rc.Encrypt(); err != nil {
+		return err
+	}
 	return rc.Decrypt()
 }
 
@@ -112,51 +160,62 @@ func (rc *rsaCipher) Sign() error {
 	}
 	hash := md5.Sum(message)
 	hashInt := int64(hash[0])
-	fmt.Printf("Message hash (as int): %d\n", hashInt)
-	rc.signature = common.ModularExponentiation(hashInt, rc.PrivateC, rc.N)
+	rc.signature, err = common.ModularExponentiationConstantTime(hashInt, rc.PrivateC, rc.N)
+	if err != nil {
+		return err
+	}
+	if common.Tracing() {
+		common.Trace("rsa", "sign", common.ValInt("h", hashInt), common.ValInt("S", rc.signature))
+	}
 	rc.msgBuf = message
-	fmt.Printf("Подпись создана: S = %d", rc.signature)
 	return common.WriteNumbers(rc.OutputSigned, []int64{rc.signature})
 }
 
 func (rc *rsaCipher) Verify() (bool, error) {
 	hash := md5.Sum(rc.msgBuf)
 	hashInt := int64(hash[0])
-	fmt.Printf("Message hash (as int): %d\n", hashInt)
 	w := common.ModularExponentiation(rc.signature, rc.PublicD, rc.N)
+	if common.Tracing() {
+		common.Trace("rsa", "verify", common.ValInt("h", hashInt), common.ValInt("S^D mod N", w))
+	}
 	return hashInt == w, nil
 }
 
+// SignAndVerify - подписывает сообщение и сразу проверяет подпись
 func (rc *rsaCipher) SignAndVerify() error {
-	pwd, err := os.Getwd()
+	if err := rc.Sign(); err != nil {
+		return err
+	}
+	ok, err := rc.Verify()
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return err
+	}
+	if !ok {
+		return common.ErrVerification
 	}
-	fmt.Print("Signed: ")
-	if err = rc.Sign(); err != nil {
+	return nil
+}
+
+// VerifyArmored - проверка подписи из блока SIGNATURE открытым ключом из блока PUBLIC KEY
+func VerifyArmored(key *common.ArmorBlock, message, signature []byte) error {
+	rc, err := importKey(key, common.ArmorPublicKey, 2)
+	if err != nil {
 		return err
 	}
-	if _, ok := rc.Input.(*os.File); ok {
-		fmt.Print(pwd + "/" + rc.OutputSigned.(*os.File).Name())
+	numbers, err := common.ReadNumbers(bytes.NewReader(signature))
+	if err != nil {
+		return err
 	}
-	if out, ok := rc.OutputSigned.(*os.File); ok {
-		_ = out.Sync()
-		defer out.Close()
-		rc.OutputSigned, err = os.OpenFile(out.Name(), os.O_RDONLY, 0600)
-		if err != nil {
-			return err
-		}
+	if len(numbers) != 1 {
+		return fmt.Errorf("rsa: %d numbers in signature: %w", len(numbers), common.ErrArmorMalformed)
 	}
-	fmt.Print("\nVerified: ")
+	rc.msgBuf, rc.signature = message, numbers[0]
 	ok, err := rc.Verify()
 	if err != nil {
 		return err
 	}
-	if ok {
-		fmt.Println("true")
-	} else {
-		fmt.Println("false")
+	if !ok {
+		return common.ErrVerification
 	}
 	return nil
 }
@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"github.com/Raimguzhinov/protect-information/common"
 	"io"
-	"os"
+	"math/big"
 )
 
 type shamirCipher struct {
@@ -18,6 +18,9 @@ type shamirCipher struct {
 }
 
 func NewCipher(p int64, input io.Reader, encOut, decOut io.Writer) (common.Cipher, error) {
+	if p < 3 {
+		return nil, &common.ParameterError{Name: "p", Reason: fmt.Sprintf("%d is not a prime greater than 2", p)}
+	}
 	c := &shamirCipher{
 		P:               p,
 		Input:           input,
@@ -34,6 +37,10 @@ func NewCipher(p int64, input io.Reader, encOut, decOut io.Writer) (common.Ciphe
 	if err != nil {
 		return nil, err
 	}
+	if common.Tracing() {
+		common.Trace("shamir", "keygen",
+			common.ValInt("P", c.P), common.ValInt("CA", c.CA), common.ValInt("DA", c.DA), common.ValInt("CB", c.CB), common.ValInt("DB", c.DB))
+	}
 	return c, nil
 }
 
@@ -54,7 +61,7 @@ func generateKeyPair(p int64) (int64, int64, error) {
 func generateSecretKey(cA, p int64) (int64, error) {
 	gcd, _, y := common.GCDExtended(p-1, cA)
 	if gcd != 1 {
-		return -1, fmt.Errorf("error: cA and p-1 are not relatively prime")
+		return -1, &common.NoInverseError{A: big.NewInt(cA), M: big.NewInt(p - 1)}
 	}
 	d := y
 	if d < 0 {
@@ -71,46 +78,36 @@ func (sc *shamirCipher) Encrypt() error {
 	encryptedMessage := make([]int64, len(message))
 	for i, byteVal := range message {
 		if int64(byteVal) >= sc.P {
-			return fmt.Errorf("byte %d is greater than or equal to p", byteVal)
+			return &common.MessageTooLargeError{Value: big.NewInt(int64(byteVal)), Limit: big.NewInt(sc.P)}
 		}
 		x1 := common.ModularExponentiation(int64(byteVal), sc.CA, sc.P)
 		x2 := common.ModularExponentiation(x1, sc.CB, sc.P)
 		encryptedMessage[i] = x2
+		if common.Tracing() {
+			common.Trace("shamir", "encrypt block", common.ValInt("m", int64(byteVal)), common.ValInt("x1", x1), common.ValInt("x2", x2))
+		}
 	}
 	sc.buffer = encryptedMessage
 	return common.WriteNumbers(sc.OutputEncrypted, encryptedMessage)
 }
 
 func (sc *shamirCipher) Decrypt() error {
-	decryptedMessage := make([]int64, len(sc.buffer))
+	decryptedMessage := make([]byte, len(sc.buffer))
 	for i, byteVal := range sc.buffer {
 		x3 := common.ModularExponentiation(byteVal, sc.DA, sc.P)
 		x4 := common.ModularExponentiation(x3, sc.DB, sc.P)
-		decryptedMessage[i] = x4
+		if common.Tracing() {
+			common.Trace("shamir", "decrypt block", common.ValInt("x2", byteVal), common.ValInt("x3", x3), common.ValInt("x4", x4))
+		}
+		decryptedMessage[i] = byte(x4)
 	}
-	return common.WriteNumbers(sc.OutputDecrypted, decryptedMessage)
+	return common.WriteData(sc.OutputDecrypted, decryptedMessage)
 }
 
-// Do - объединяет шифрование и дешифрование
+// EncryptAndDecrypt - объединяет шифрование и дешифрование
 func (sc *shamirCipher) EncryptAndDecrypt() error {
-	pwd, err := os.Getwd()
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
-	fmt.Print("Encrypted: ")
-	if err = sc.Encrypt(); err != nil {
+	if err := sc.Encrypt(); err != nil {
 		return err
 	}
-	if _, ok := sc.Input.(*os.File); ok {
-		fmt.Print(pwd + "/" + sc.OutputEncrypted.(*os.File).Name())
-	}
-	fmt.Print("\nDecrypted: ")
-	defer func() {
-		if _, ok := sc.Input.(*os.File); ok {
-			fmt.Print(pwd + "/" + sc.OutputDecrypted.(*os.File).Name())
-		}
-		fmt.Print("\n")
-	}()
 	return sc.Decrypt()
 }
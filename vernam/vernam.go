@@ -5,26 +5,31 @@ import (
 	"fmt"
 	"github.com/Raimguzhinov/protect-information/common"
 	"github.com/Raimguzhinov/protect-information/elgamal"
-	"github.com/erikgeiser/promptkit/confirmation"
-	"github.com/erikgeiser/promptkit/textinput"
 	"io"
-	"os"
 )
 
 type vernamCipher struct {
-	Key             []byte
-	Input           io.Reader
-	OutputEncrypted io.Writer
-	OutputDecrypted io.Writer
-	buffer          []byte
-	cipher          common.Cipher
+	P, G               int64 // Параметры Эль-Гамаля для передачи ключа
+	Key                []byte
+	Input              io.Reader
+	OutputEncrypted    io.Writer
+	OutputDecrypted    io.Writer
+	OutputKeyEncrypted io.Writer // Ключ, зашифрованный Эль-Гамалем
+	OutputKeyDecrypted io.Writer // Ключ после расшифровки Эль-Гамалем
+	buffer             []byte
+	cipher             common.Cipher
 }
 
-func NewCipher(input io.Reader, encOut, decOut io.Writer) (common.Cipher, error) {
+// NewCipher - шифр Вернама; одноразовый ключ передаётся шифром Эль-Гамаля с параметрами p, g
+func NewCipher(p, g int64, input io.Reader, encOut, decOut, keyEncOut, keyDecOut io.Writer) (common.Cipher, error) {
 	c := &vernamCipher{
-		Input:           input,
-		OutputEncrypted: encOut,
-		OutputDecrypted: decOut,
+		P:                  p,
+		G:                  g,
+		Input:              input,
+		OutputEncrypted:    encOut,
+		OutputDecrypted:    decOut,
+		OutputKeyEncrypted: keyEncOut,
+		OutputKeyDecrypted: keyDecOut,
 	}
 	return c, nil
 }
@@ -35,18 +40,17 @@ func (vc *vernamCipher) Encrypt() error {
 		return err
 	}
 	// Генерация ключа той же длины, что и сообщение
-	vc.Key = vc.generateKey(len(message))
+	vc.Key, err = vc.generateKey(len(message))
+	if err != nil {
+		return err
+	}
 	// Шифрование с помощью побитовой операции XOR
 	encryptedMessage := make([]byte, len(message))
 	for i := range message {
 		encryptedMessage[i] = message[i] ^ vc.Key[i]
 	}
 	vc.buffer = encryptedMessage
-	outputEncrypted, err := os.OpenFile("enkey.dat", os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0600)
-	outputDecrypted, err := os.OpenFile("deckey.dat", os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0600)
-
-	p, g, err := promptForPrimeWithRoot()
-	vc.cipher, err = elgamal.NewCipher(p, g, bytes.NewReader(vc.Key), outputEncrypted, outputDecrypted)
+	vc.cipher, err = elgamal.NewCipher(vc.P, vc.G, bytes.NewReader(vc.Key), vc.OutputKeyEncrypted, vc.OutputKeyDecrypted)
 	if err != nil {
 		return err
 	}
@@ -72,88 +76,19 @@ func (vc *vernamCipher) Decrypt() error {
 	return common.WriteData(vc.OutputDecrypted, decryptedMessage)
 }
 
-// Do - объединяет шифрование и дешифрование
+// EncryptAndDecrypt - объединяет шифрование и дешифрование
 func (vc *vernamCipher) EncryptAndDecrypt() error {
-	pwd, err := os.Getwd()
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
-	fmt.Print("Encrypted: ")
-	if err = vc.Encrypt(); err != nil {
+	if err := vc.Encrypt(); err != nil {
 		return err
 	}
-	if _, ok := vc.Input.(*os.File); ok {
-		fmt.Print(pwd + "/" + vc.OutputEncrypted.(*os.File).Name())
-	}
-	fmt.Print("\nDecrypted: ")
-	defer func() {
-		if _, ok := vc.Input.(*os.File); ok {
-			fmt.Print(pwd + "/" + vc.OutputDecrypted.(*os.File).Name())
-		}
-		fmt.Print("\n")
-	}()
 	return vc.Decrypt()
 }
 
-func (vc *vernamCipher) generateKey(length int) []byte {
+func (vc *vernamCipher) generateKey(length int) ([]byte, error) {
 	key := make([]byte, length)
 	_, err := common.Seed().Read(key)
 	if err != nil {
-		panic(fmt.Sprintf("failed to generate key: %v", err))
-	}
-	return key
-}
-
-func promptForPrimeWithRoot() (int64, int64, error) {
-	confirm := confirmation.New("Generate a random prime number?", confirmation.Yes)
-	confirmed, err := confirm.RunPrompt()
-	if err != nil {
-		return 0, 0, err
-	}
-	if confirmed {
-		var P, q int64
-		minV, maxV := 1_000_000, 1_000_000_000
-		for {
-			q = common.GenPrime(int64(minV), int64(maxV))
-			P = 2*q + 1
-			if common.IsPrime(P) {
-				break
-			}
-		}
-		g := int64(0)
-		for i := int64(2); i < P-1; i++ {
-			g = i
-			if common.ModularExponentiation(g, q, P) != 1 {
-				break
-			}
-		}
-		fmt.Printf("New prime number is: %d root: %d\n", P, g)
-		return P, g, nil
-	}
-	// Ввод значения p
-	prompt := textinput.New("Enter prime number p:")
-	prompt.Placeholder = "Example: 7, 11, 53, 131, 997"
-	response, err := prompt.RunPrompt()
-	if err != nil {
-		return 0, 0, err
-	}
-	var p int64
-	_, err = fmt.Sscanf(response, "%d", &p)
-	if err != nil {
-		return 0, 0, err
-	}
-	// Ввод значения g
-	prompt = textinput.New("Enter primitive root g:")
-	prompt.Placeholder = "Example: 2"
-	response, err = prompt.RunPrompt()
-	if err != nil {
-		return 0, 0, err
-	}
-	var g int64
-	_, err = fmt.Sscanf(response, "%d", &g)
-	if err != nil {
-		return 0, 0, err
+		return nil, fmt.Errorf("failed to generate key: %v", err)
 	}
-	return p, g, nil
+	return key, nil
 }